@@ -13,11 +13,13 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	server "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
@@ -36,6 +38,9 @@ type Config struct {
 	TargetMemPerReplicaMB float64 // MiB per replica (budget)
 	HysteresisPct         float64 // e.g., 10 => need 10% margin to trigger
 	ScaleStepLimit        int32   // max replicas to change per decision (e.g., 5)
+	CPURateWindow         string  // PromQL range for rate() over CPU counters, e.g. "30s" for bursty pods
+	MetricsBindAddress    string  // "0" disables the metrics server
+	MetricsSecure         bool    // HTTPS + Kubernetes authn/authz on /metrics
 }
 
 func mustEnv(key string, def string) string {
@@ -80,6 +85,17 @@ func parseFloat(s string, def float64) float64 {
 	return f
 }
 
+func parseBool(s string, def bool) bool {
+	if s == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
 func loadConfig() Config {
 	return Config{
 		Namespace:             mustEnv("TARGET_NAMESPACE", "default"),
@@ -94,6 +110,9 @@ func loadConfig() Config {
 		TargetMemPerReplicaMB: parseFloat(os.Getenv("TARGET_MEM_MIB"), 300.0), // 300 MiB per replica
 		HysteresisPct:         parseFloat(os.Getenv("HYSTERESIS_PCT"), 10.0),  // 10%
 		ScaleStepLimit:        parseInt32(os.Getenv("SCALE_STEP_LIMIT"), 5),
+		CPURateWindow:         mustEnv("CPU_RATE_WINDOW", "2m"),
+		MetricsBindAddress:    mustEnv("METRICS_BIND_ADDRESS", "0"),
+		MetricsSecure:         parseBool(os.Getenv("METRICS_SECURE"), true),
 	}
 }
 
@@ -130,12 +149,49 @@ func promInstantQuery(promURL, query string) (promAPIResp, error) {
 	return out, err
 }
 
+// promPing runs the cheap `up` query; used as the readiness check. It is the
+// same check as prom.Ping in nginx-operator-autoscaler; keep the two in step.
+func promPing(promURL string) error {
+	u, err := url.Parse(promURL)
+	if err != nil {
+		return err
+	}
+	u.Path = "/api/v1/query"
+	q := u.Query()
+	q.Set("query", "up")
+	u.RawQuery = q.Encode()
+
+	c := &http.Client{Timeout: 2 * time.Second}
+	resp, err := c.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var out promAPIResp
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	if out.Status != "success" {
+		return fmt.Errorf("prometheus returned status %q", out.Status)
+	}
+	return nil
+}
+
 // ---------- Reconciler ----------
 
 type Reconciler struct {
 	k8s    client.Client
 	cfg    Config
-	lastAt time.Time // last decision time (simple cooldown)
+	clock  clock.PassiveClock // nil means the real clock
+	lastAt time.Time          // last decision time (simple cooldown)
+}
+
+func (r *Reconciler) now() time.Time {
+	if r.clock == nil {
+		return time.Now()
+	}
+	return r.clock.Now()
 }
 
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -160,13 +216,13 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	current := *dep.Spec.Replicas
 
 	// Query Prometheus for workload demand
-	// 1) CPU total cores used by pods matching selector over last 2m
+	// 1) CPU total cores used by pods matching selector over the last CPU_RATE_WINDOW
 	// We rely on cAdvisor metric container_cpu_usage_seconds_total
 	// NOTE: adjust job/service labels if needed; here we filter by pod label using Kubernetes relabeling from kube-state-metrics > pod labels may not be present.
 	// Safer: sum by (pod) then join via label selector using kube-state-metrics.
 	// For simplicity, we filter by namespace + match on pod name prefix of deployment:
 	prefix := dep.Name + "-"
-	cpuQ := fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{namespace="%s",pod=~"%s.*",image!=""}[2m]))`, r.cfg.Namespace, prefix)
+	cpuQ := fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{namespace="%s",pod=~"%s.*",image!=""}[%s]))`, r.cfg.Namespace, prefix, r.cfg.CPURateWindow)
 	memQ := fmt.Sprintf(`sum(container_memory_working_set_bytes{namespace="%s",pod=~"%s.*",image!=""})`, r.cfg.Namespace, prefix)
 
 	cpuResp, err := promInstantQuery(r.cfg.PromURL, cpuQ)
@@ -212,7 +268,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	changeNeeded := shouldScale(current, desired, r.cfg.HysteresisPct)
 
 	// Cooldown
-	if time.Since(r.lastAt) < r.cfg.Cooldown {
+	if r.now().Sub(r.lastAt) < r.cfg.Cooldown {
 		logger.Info("cooldown active; skipping", "current", current, "desired", desired)
 		return ctrl.Result{RequeueAfter: r.cfg.PollInterval}, nil
 	}
@@ -239,7 +295,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{RequeueAfter: r.cfg.PollInterval}, err
 	}
 
-	r.lastAt = time.Now()
+	r.lastAt = r.now()
 	logger.Info("scaled", "from", current, "to", newReplicas,
 		"desired_raw", desired, "cpu_cores", fmt.Sprintf("%.3f", totalCPUcores),
 		"mem_mib", fmt.Sprintf("%.1f", totalMemMiB))
@@ -335,8 +391,14 @@ func main() {
 	cfg := loadConfig()
 	ctrl.SetLogger(zap.New())
 
+	metricsOpts := server.Options{BindAddress: cfg.MetricsBindAddress}
+	if cfg.MetricsSecure {
+		metricsOpts.SecureServing = true
+		metricsOpts.FilterProvider = filters.WithAuthenticationAndAuthorization
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Metrics:                server.Options{BindAddress: "0"},
+		Metrics:                metricsOpts,
 		HealthProbeBindAddress: ":8081",
 	})
 
@@ -351,6 +413,9 @@ func main() {
 
 	_ = mgr.AddHealthzCheck("ping", healthz.Ping)
 	_ = mgr.AddReadyzCheck("ping", healthz.Ping)
+	_ = mgr.AddReadyzCheck("prometheus", func(_ *http.Request) error {
+		return promPing(cfg.PromURL)
+	})
 
 	fmt.Println("db-autoscaler starting for:",
 		cfg.Namespace+"/"+cfg.DeploymentName,
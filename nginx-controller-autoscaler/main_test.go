@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	"github.com/malisettirammurthy/promtest"
+)
+
+// TestReconcileScalesTarget runs one full reconcile against envtest
+// (requires KUBEBUILDER_ASSETS, see `setup-envtest use`) and a stub Prometheus.
+func TestReconcileScalesTarget(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS not set; skipping envtest integration test")
+	}
+
+	env := &envtest.Environment{}
+	restCfg, err := env.Start()
+	if err != nil {
+		t.Fatalf("envtest start: %v", err)
+	}
+	defer env.Stop()
+
+	k8s, err := client.New(restCfg, client.Options{})
+	if err != nil {
+		t.Fatalf("client: %v", err)
+	}
+
+	// 1.0 core demand at 0.2 cores/replica => 5 replicas; memory is negligible
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0)
+	prom.SetInstant("container_memory_working_set_bytes", 10*1024*1024)
+
+	ctx := context.Background()
+	replicas := int32(2)
+	labels := map[string]string{"app": "nginx"}
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "nginx-sample-deployment"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+			},
+		},
+	}
+	if err := k8s.Create(ctx, dep); err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+
+	r := &Reconciler{k8s: k8s, cfg: Config{
+		Namespace:             "default",
+		DeploymentName:        "nginx-sample-deployment",
+		PromURL:               prom.URL,
+		PollInterval:          time.Second,
+		MinReplicas:           1,
+		MaxReplicas:           10,
+		TargetCPUPerReplica:   0.2,
+		TargetMemPerReplicaMB: 300,
+		HysteresisPct:         10,
+		ScaleStepLimit:        10,
+		CPURateWindow:         "2m",
+	}}
+	key := types.NamespacedName{Namespace: "default", Name: "nginx-sample-deployment"}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	var got appsv1.Deployment
+	if err := k8s.Get(ctx, key, &got); err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	if *got.Spec.Replicas != 5 {
+		t.Fatalf("replicas = %d, want 5", *got.Spec.Replicas)
+	}
+}
+
+func TestPromPing(t *testing.T) {
+	prom := promtest.New(t)
+	if err := promPing(prom.URL); err != nil {
+		t.Fatalf("promPing healthy: %v", err)
+	}
+	prom.SetError(promtest.ErrStatus)
+	if err := promPing(prom.URL); err == nil {
+		t.Fatal("promPing with error status: want error")
+	}
+	prom.SetError(promtest.ErrHTTP500)
+	if err := promPing(prom.URL); err == nil {
+		t.Fatal("promPing with HTTP 500: want error")
+	}
+}
@@ -0,0 +1,42 @@
+// Command decision-export dumps a decision store as CSV or JSON lines.
+//
+// The controller holds its database open, so export from a copy:
+//
+//	curl -H "Authorization: Bearer $DEBUG_TOKEN" http://<debug-addr>/debug/decisions/backup > decisions.db
+//	decision-export -db decisions.db -format csv -since 168h > decisions.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decisionstore"
+)
+
+func main() {
+	var path, format, autoscaler string
+	var since time.Duration
+	flag.StringVar(&path, "db", "decisions.db", "Decision store to read.")
+	flag.StringVar(&format, "format", "csv", "Output format: csv or json (one object per line).")
+	flag.StringVar(&autoscaler, "autoscaler", "", "Only export this namespace/name.")
+	flag.DurationVar(&since, "since", 0, "Only export decisions newer than this (0 exports everything).")
+	flag.Parse()
+
+	store, err := decisionstore.OpenReadOnly(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "open:", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	f := decisionstore.Filter{Autoscaler: autoscaler}
+	if since > 0 {
+		f.Since = time.Now().Add(-since)
+	}
+	if err := store.Export(os.Stdout, format, f); err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cacheOptions narrows what the manager's informers hold: only the given
+// namespaces (all when empty), only Deployments matching deploymentSelector
+// (all when empty), and never managedFields, which are often half of an
+// object's size and are never read here.
+func cacheOptions(namespaces []string, deploymentSelector string) (cache.Options, error) {
+	opts := cache.Options{DefaultTransform: stripManagedFields}
+	if len(namespaces) > 0 {
+		opts.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range namespaces {
+			opts.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	if deploymentSelector != "" {
+		sel, err := labels.Parse(deploymentSelector)
+		if err != nil {
+			return cache.Options{}, fmt.Errorf("--deployment-label-selector: %w", err)
+		}
+		opts.ByObject = map[client.Object]cache.ByObject{&appsv1.Deployment{}: {Label: sel}}
+	}
+	return opts, nil
+}
+
+func stripManagedFields(obj interface{}) (interface{}, error) {
+	if a, err := meta.Accessor(obj); err == nil {
+		a.SetManagedFields(nil)
+	}
+	return obj, nil
+}
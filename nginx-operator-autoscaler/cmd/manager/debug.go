@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decisionstore"
+)
+
+// addDebugServer serves routes on addr behind a static bearer token.
+func addDebugServer(mgr ctrl.Manager, addr, token string, routes map[string]http.Handler) error {
+	if token == "" {
+		return fmt.Errorf("--debug-bind-address requires --debug-token (or DEBUG_TOKEN)")
+	}
+
+	mux := http.NewServeMux()
+	for path, handler := range routes {
+		mux.Handle(path, requireBearer(token, handler))
+	}
+	return addHTTPServer(mgr, addr, mux)
+}
+
+// addHTTPServer runs an HTTP server on addr for as long as the manager runs.
+func addHTTPServer(mgr ctrl.Manager, addr string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}))
+}
+
+func requireBearer(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// decisionsHandler exports the decision store: ?format=csv|json (default
+// json), ?since=24h and ?autoscaler=namespace/name narrow it.
+func decisionsHandler(store *decisionstore.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		format := q.Get("format")
+		if format == "" {
+			format = "json"
+		}
+		f := decisionstore.Filter{Autoscaler: q.Get("autoscaler")}
+		if since := q.Get("since"); since != "" {
+			d, err := time.ParseDuration(since)
+			if err != nil {
+				http.Error(w, "bad since: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.Since = time.Now().Add(-d)
+		}
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+		} else {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+		if err := store.Export(w, format, f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+	})
+}
+
+// decisionsBackupHandler streams a consistent copy of the database, for
+// decision-export to read offline.
+func decisionsBackupHandler(store *decisionstore.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_ = store.Backup(w)
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+)
+
+// runExport is `manager export recommendations`: one JSON object per
+// autoscaler on stdout, for capacity-planning and FinOps tooling.
+func runExport(args []string) error {
+	if len(args) == 0 || args[0] != "recommendations" {
+		return fmt.Errorf("usage: manager export recommendations [--namespace=...]")
+	}
+	fset := flag.NewFlagSet("export recommendations", flag.ContinueOnError)
+	namespace := fset.String("namespace", "", "Only export autoscalers in this namespace (all if empty).")
+	if err := fset.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	recs, err := controllers.ExportRecommendations(ctx, c, *namespace)
+	if err != nil {
+		return fmt.Errorf("list NginxAutoscalers: %w", err)
+	}
+	return encodeRecommendations(os.Stdout, recs)
+}
+
+// encodeRecommendations writes recs as newline-delimited JSON.
+func encodeRecommendations(w io.Writer, recs []controllers.WorkloadRecommendation) error {
+	enc := json.NewEncoder(w)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recommendationsHandler serves the same export from the running
+// controller; ?namespace= narrows it.
+func recommendationsHandler(reader client.Reader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		recs, err := controllers.ExportRecommendations(req.Context(), reader, req.URL.Query().Get("namespace"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_ = encodeRecommendations(w, recs)
+	})
+}
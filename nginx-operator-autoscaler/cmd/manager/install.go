@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// installFieldManager owns the fields `manager install` applies.
+const installFieldManager = "nginx-operator-autoscaler-install"
+
+// runInstall is `manager install`: server-side apply the embedded CRDs, then
+// the ServiceAccount and RBAC, to the cluster of the current kubeconfig.
+func runInstall(args []string) error {
+	fset := flag.NewFlagSet("install", flag.ContinueOnError)
+	namespace := fset.String("namespace", "default", "Namespace of the controller's ServiceAccount.")
+	dryRun := fset.Bool("dry-run", false, "Print the manifests instead of applying them.")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	objs, err := installObjects(*namespace)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		return printObjects(os.Stdout, objs)
+	}
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, obj := range objs {
+		if err := c.Patch(ctx, obj, client.Apply, client.FieldOwner(installFieldManager), client.ForceOwnership); err != nil {
+			return fmt.Errorf("apply %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+		fmt.Printf("%s/%s applied\n", obj.GetKind(), obj.GetName())
+	}
+	return nil
+}
+
+// installObjects are the embedded CRDs and RBAC, CRDs first, with the
+// ServiceAccount in namespace.
+func installObjects(namespace string) ([]*unstructured.Unstructured, error) {
+	return renderManifests([]string{"crd", "rbac"}, manifestParams{Namespace: namespace})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/externalscaler"
+)
+
+// kedaStreamInterval is how often StreamIsActive re-evaluates a trigger.
+const kedaStreamInterval = 15 * time.Second
+
+// addKEDAScaler serves the KEDA external scaler gRPC API on addr, over TLS
+// with certDir's tls.crt/tls.key when certDir is set, and requiring a client
+// certificate signed by clientCA when that is set too.
+func addKEDAScaler(mgr ctrl.Manager, addr, certDir, clientCA string, names controllers.MetricNames) error {
+	srv, err := newKEDAServer(controllers.NewKEDAScaler(mgr, names), addr, certDir, clientCA)
+	if err != nil {
+		return err
+	}
+	return mgr.Add(srv)
+}
+
+// kedaServer runs the gRPC server as a manager Runnable.
+type kedaServer struct {
+	addr  string
+	srv   *grpc.Server
+	certs *certwatcher.CertWatcher // nil when serving plaintext
+}
+
+func newKEDAServer(s externalscaler.Scaler, addr, certDir, clientCA string) (*kedaServer, error) {
+	k := &kedaServer{addr: addr}
+	var opts []grpc.ServerOption
+	switch {
+	case certDir != "":
+		var err error
+		k.certs, err = certwatcher.New(filepath.Join(certDir, "tls.crt"), filepath.Join(certDir, "tls.key"))
+		if err != nil {
+			return nil, err
+		}
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: k.certs.GetCertificate}
+		if clientCA != "" {
+			pem, err := os.ReadFile(clientCA)
+			if err != nil {
+				return nil, err
+			}
+			cfg.ClientCAs = x509.NewCertPool()
+			if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%s holds no PEM certificates", clientCA)
+			}
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	case clientCA != "":
+		return nil, fmt.Errorf("--keda-scaler-client-ca needs --keda-scaler-cert-dir")
+	default:
+		ctrl.Log.Info("KEDA external scaler serves plaintext; set --keda-scaler-cert-dir to enable TLS", "address", addr)
+	}
+	k.srv = externalscaler.NewServer(s, kedaStreamInterval, opts...)
+	return k, nil
+}
+
+func (k *kedaServer) Start(ctx context.Context) error {
+	if k.certs != nil {
+		go func() {
+			if err := k.certs.Start(ctx); err != nil {
+				ctrl.Log.Error(err, "KEDA scaler certificate watcher stopped")
+			}
+		}()
+	}
+	lis, err := net.Listen("tcp", k.addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		k.srv.GracefulStop()
+	}()
+	return k.srv.Serve(lis)
+}
+
+// NeedLeaderElection is false: KEDA may call any replica behind the Service,
+// and answering only reads.
+func (k *kedaServer) NeedLeaderElection() bool { return false }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/externalscaler"
+)
+
+type activeScaler struct{}
+
+func (activeScaler) IsActive(context.Context, *externalscaler.ScaledObjectRef) (bool, error) {
+	return true, nil
+}
+
+func (activeScaler) GetMetricSpec(context.Context, *externalscaler.ScaledObjectRef) ([]*externalscaler.MetricSpec, error) {
+	return nil, nil
+}
+
+func (activeScaler) GetMetrics(context.Context, *externalscaler.ScaledObjectRef, string) ([]*externalscaler.MetricValue, error) {
+	return nil, nil
+}
+
+// issue signs a certificate for cn with parent (self-signed when nil) and
+// returns it with its key.
+func issue(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, any(key)
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writePEM(t *testing.T, path string, blocks ...*pem.Block) {
+	t.Helper()
+	var out []byte
+	for _, b := range blocks {
+		out = append(out, pem.EncodeToMemory(b)...)
+	}
+	if err := os.WriteFile(path, out, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKEDAServerRequiresClientCertificate(t *testing.T) {
+	ca := issue(t, "ca", nil)
+	serving := issue(t, "nginx-operator-autoscaler", &ca)
+	keda := issue(t, "keda-operator", &ca)
+
+	dir := t.TempDir()
+	key, _ := x509.MarshalECPrivateKey(serving.PrivateKey.(*ecdsa.PrivateKey))
+	writePEM(t, filepath.Join(dir, "tls.crt"), &pem.Block{Type: "CERTIFICATE", Bytes: serving.Certificate[0]})
+	writePEM(t, filepath.Join(dir, "tls.key"), &pem.Block{Type: "EC PRIVATE KEY", Bytes: key})
+	writePEM(t, filepath.Join(dir, "ca.crt"), &pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]})
+
+	if _, err := newKEDAServer(activeScaler{}, "127.0.0.1:0", "", filepath.Join(dir, "ca.crt")); err == nil {
+		t.Fatal("a client CA without a serving certificate was accepted")
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	k, err := newKEDAServer(activeScaler{}, addr, dir, filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.NeedLeaderElection() {
+		t.Fatal("the KEDA scaler would only be served by the leader")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = k.Start(ctx) }()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	// Only the first call waits, for the server to come up
+	call := func(certs []tls.Certificate, wait bool) error {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: roots, Certificates: certs})))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		callCtx, done := context.WithTimeout(ctx, 5*time.Second)
+		defer done()
+		_, err = externalscaler.NewExternalScalerClient(conn).IsActive(callCtx, &externalscaler.ScaledObjectRef{Name: "web"}, grpc.WaitForReady(wait))
+		return err
+	}
+	if err := call([]tls.Certificate{keda}, true); err != nil {
+		t.Fatalf("with a client certificate: %v", err)
+	}
+	if err := call(nil, false); err == nil {
+		t.Fatal("a client without a certificate was served")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// loggerOptions turns --log-level and --log-format into zap options. Debug
+// also shows the V(1) messages; error shows errors only.
+func loggerOptions(level, format string) ([]zap.Opts, error) {
+	opts := []zap.Opts{zap.UseDevMode(true)}
+	switch level {
+	case "debug":
+		opts = append(opts, zap.Level(zapcore.DebugLevel))
+	case "info":
+		opts = append(opts, zap.Level(zapcore.InfoLevel))
+	case "error":
+		opts = append(opts, zap.Level(zapcore.ErrorLevel))
+	default:
+		return nil, fmt.Errorf("--log-level: %q is not debug, info or error", level)
+	}
+	switch format {
+	case "console":
+		opts = append(opts, zap.ConsoleEncoder())
+	case "json":
+		opts = append(opts, zap.JSONEncoder())
+	default:
+		return nil, fmt.Errorf("--log-format: %q is not console or json", format)
+	}
+	return opts, nil
+}
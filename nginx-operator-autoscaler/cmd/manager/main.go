@@ -1,54 +1,429 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
+	_ "time/tzdata" // for --policy-timezone; the runtime image has no zoneinfo
 
+	"golang.org/x/time/rate"
 	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	server "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/alertmanager"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decisionstore"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/grafana"
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/version"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "install" {
+		if err := runInstall(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "install:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "metrics-proxy" {
+		if err := runMetricsProxy(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "metrics-proxy:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "migrate:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "watch" {
+		if err := runWatch(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "watch:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "export:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := runGenerate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "generate:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
+	var metricsSecure bool
 	var healthAddr string
+	var allowedTargetNamespaces string
+	var instanceName string
+	var debugAddr string
+	var debugToken string
+	var dashboardAddr string
+	var pprofAddr string
+	var readyPromURL string
+	var skipRBACCheck bool
+	var faultRate float64
+	var openCostURL string
+	var kedaAddr string
+	var kedaCertDir string
+	var kedaClientCA string
+	var policyConfigMap string
+	var policyTimeZone string
+	var eventSink string
+	var eventBus string
+	var decisionStorePath string
+	var grafanaURL string
+	var grafanaToken string
+	var alertmanagerURL string
+	var decisionRetention time.Duration
+	var pollJitter float64
+	var maxConcurrent int
+	var requeueBaseDelay, requeueMaxDelay time.Duration
+	var requeueQPS, writeQPS float64
+	var requeueBurst, writeBurst int
+	var apiQPS float64
+	var apiBurst, scalesPerMinute int
+	var watchPods bool
+	var watchNamespaces, deploymentSelector string
+	var liveTargetRead bool
+	var role string
+	var statusObservationInterval time.Duration
+	var logLevel, logFormat string
+	var hysteresisLogInterval time.Duration
+	var printVersion bool
+	var promTransport prom.TransportOptions
+	var promGoogleAuth bool
+	var promMaxConcurrent int
+	var promQueryProxy string
+	var webhookPort int
+	var webhookCertDir string
+	metricNames := controllers.DefaultMetricNames
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to.")
+	flag.BoolVar(&metricsSecure, "metrics-secure", true, "Serve metrics over HTTPS behind Kubernetes authn/authz (TokenReview + SubjectAccessReview).")
 	flag.StringVar(&healthAddr, "health-probe-bind-address", ":8081", "The address the health probe endpoint binds to.")
+	flag.StringVar(&allowedTargetNamespaces, "allowed-target-namespaces", "", "Comma-separated namespaces CRs may target outside their own (\"*\" for any).")
+	flag.StringVar(&instanceName, "instance-name", "nginx-operator-autoscaler", "Identity stamped in the managed-by annotation of scaled Deployments.")
+	flag.StringVar(&debugAddr, "debug-bind-address", "", "The address the token-protected /debug/autoscalers endpoint binds to (disabled if empty).")
+	flag.StringVar(&debugToken, "debug-token", os.Getenv("DEBUG_TOKEN"), "Bearer token required by the debug endpoint.")
+	flag.StringVar(&dashboardAddr, "dashboard-bind-address", "", "The address the read-only HTML dashboard binds to, without authentication; keep it on loopback (e.g. 127.0.0.1:8083) and reach it through kubectl port-forward (disabled if empty).")
+	flag.StringVar(&pprofAddr, "pprof-bind-address", "0", "The address pprof binds to (\"0\" disables it).")
+	flag.StringVar(&readyPromURL, "readiness-prom-url", "http://kube-prometheus-stack-prometheus.monitoring.svc:9090", "Prometheus the readiness probe must reach (disabled if empty).")
+	flag.BoolVar(&skipRBACCheck, "skip-rbac-check", false, "Skip the startup SelfSubjectAccessReview of required permissions.")
+	flag.Float64Var(&faultRate, "fault-injection", 0, "DEV ONLY: fraction (0-1) of Prometheus queries to fail, delay or answer with garbage.")
+	flag.StringVar(&openCostURL, "opencost-url", "", "OpenCost/Kubecost API used to price replicas for spec.costCap (e.g. http://opencost.opencost.svc:9003).")
+	flag.StringVar(&kedaAddr, "keda-scaler-bind-address", "", "The address the KEDA external scaler gRPC service binds to (disabled if empty).")
+	flag.StringVar(&kedaCertDir, "keda-scaler-cert-dir", "", "Directory holding the KEDA scaler's tls.crt and tls.key, reloaded when they change (plaintext if empty).")
+	flag.StringVar(&kedaClientCA, "keda-scaler-client-ca", "", "PEM CA bundle KEDA's client certificate must chain to, for mTLS (needs --keda-scaler-cert-dir; disabled if empty).")
+	flag.StringVar(&policyConfigMap, "policy-configmap", "", "namespace/name of a ConfigMap whose policy.rego may deny or clamp every scaling action (disabled if empty).")
+	flag.StringVar(&policyTimeZone, "policy-timezone", "UTC", "IANA time zone (e.g. Europe/Berlin) of the scaling policy's input.time.local, for business-hours and blackout rules.")
+	flag.StringVar(&eventSink, "cloudevents-sink", "", "Publish every scaling decision as a CloudEvent to http(s)://... or kafka://broker:9092,.../topic (disabled if empty).")
+	flag.StringVar(&eventBus, "event-bus", "", "Publish scale events and saturation alerts to nats://host:4222/subject (JetStream) or kafka://broker:9092,.../topic (disabled if empty).")
+	flag.StringVar(&decisionStorePath, "decision-store", "", "Path (on a PersistentVolume) of a bbolt database recording every decision (disabled if empty).")
+	flag.DurationVar(&decisionRetention, "decision-retention", 30*24*time.Hour, "How long the decision store keeps records (0 keeps everything).")
+	flag.StringVar(&grafanaURL, "grafana-url", "", "Grafana to annotate with every scale event, tagged nginx-autoscaler, namespace:<ns> and deployment:<name> (disabled if empty).")
+	flag.StringVar(&grafanaToken, "grafana-token", os.Getenv("GRAFANA_TOKEN"), "Grafana service account token with annotations:write.")
+	flag.StringVar(&alertmanagerURL, "alertmanager-url", "", "Alertmanager in which autoscalers with spec.alertSilence silence their target's alerts after every scale (disabled if empty).")
+	flag.Float64Var(&pollJitter, "poll-jitter", 0.1, "Stretch every requeue by a random fraction up to this much of the poll interval, spreading CRs created together (0 disables).")
+	flag.IntVar(&maxConcurrent, "max-concurrent-reconciles", 1, "How many NginxAutoscalers are reconciled in parallel.")
+	flag.DurationVar(&requeueBaseDelay, "requeue-base-delay", 5*time.Millisecond, "First retry delay after a failed reconcile; doubles per consecutive failure.")
+	flag.DurationVar(&requeueMaxDelay, "requeue-max-delay", 1000*time.Second, "Cap on the per-CR retry delay after failures.")
+	flag.Float64Var(&requeueQPS, "requeue-qps", 10, "Overall rate at which the work queue hands out retried items.")
+	flag.IntVar(&requeueBurst, "requeue-burst", 100, "Burst allowed over --requeue-qps.")
+	flag.Float64Var(&writeQPS, "kube-write-qps", 0, "Global cap on writes (Deployments, CRs, status) to the API server per second, across all CRs (0 disables).")
+	flag.IntVar(&writeBurst, "kube-write-burst", 20, "Burst allowed over --kube-write-qps.")
+	flag.Float64Var(&apiQPS, "kube-api-qps", 0, "client-go QPS towards the API server, reads and writes alike (0 keeps controller-runtime's 20).")
+	flag.IntVar(&apiBurst, "kube-api-burst", 0, "client-go burst over --kube-api-qps (0 keeps controller-runtime's 30).")
+	flag.IntVar(&scalesPerMinute, "max-scales-per-minute", 0, "Global cap on targets scaled per minute across all CRs; a scale over it is skipped and decided again (0 disables).")
+	flag.BoolVar(&watchPods, "watch-pods", false, "Recompute a CR as soon as one of its target's pods turns Ready or unready (caches every Pod in the cluster).")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "", "Comma-separated namespaces whose NginxAutoscalers, Deployments and Pods are cached and reconciled (all if empty).")
+	flag.StringVar(&deploymentSelector, "deployment-label-selector", "", "Only cache Deployments matching this label selector, e.g. autoscaler.malisetti.dev/enabled=true (all if empty); targets must match it.")
+	flag.BoolVar(&liveTargetRead, "live-target-read", false, "Re-read the target Deployment from the API server (not the cache) right before scaling it, and decide again if its replicas changed.")
+	flag.StringVar(&role, "role", "all", "all; recommender (publish status.recommendation, never touch targets); or actuator (apply published recommendations).")
+	flag.DurationVar(&statusObservationInterval, "status-observation-interval", time.Minute, "How often status.lastObservation records the latest metrics and per-signal replicas while nothing changes (0 disables).")
+	flag.StringVar(&logLevel, "log-level", "info", "debug, info or error.")
+	flag.StringVar(&logFormat, "log-format", "console", "console or json.")
+	flag.DurationVar(&hysteresisLogInterval, "log-hysteresis-interval", 5*time.Minute, "Log the per-cycle \"within hysteresis; no scale\" message at most this often per CR (0 logs every cycle).")
+	flag.StringVar(&metricNames.CPU, "cpu-metric", metricNames.CPU, "Counter of container CPU seconds the CPU queries rate() (spec.metricNames.cpu overrides it).")
+	flag.StringVar(&metricNames.Memory, "memory-metric", metricNames.Memory, "Gauge of container memory bytes the memory queries sum (spec.metricNames.memory overrides it).")
+	flag.StringVar(&metricNames.NamespaceLabel, "namespace-label", metricNames.NamespaceLabel, "Label holding the pod's namespace on those series.")
+	flag.StringVar(&metricNames.PodLabel, "pod-label", metricNames.PodLabel, "Label holding the pod's name on those series.")
+	flag.StringVar(&metricNames.ImageLabel, "image-label", metricNames.ImageLabel, "Label that is empty on pod-level totals, which the queries skip (empty: no such filter).")
+	flag.DurationVar(&promTransport.DialTimeout, "prom-dial-timeout", 30*time.Second, "Timeout for connecting to Prometheus (HTTP_PROXY, HTTPS_PROXY and NO_PROXY are honored).")
+	flag.Int64Var(&promTransport.MaxResponseBytes, "prom-max-response-bytes", 64<<20, "Fail a Prometheus query whose decompressed answer is larger than this (0 disables the limit).")
+	flag.IntVar(&promMaxConcurrent, "prom-max-concurrent-queries", 32, "Prometheus queries in flight at once across all reconciles; the rest queue (0 disables the limit).")
+	flag.StringVar(&promQueryProxy, "prom-query-proxy", "", "Send Prometheus queries through a \"manager metrics-proxy\" at this URL, e.g. http://localhost:9091 (disabled if empty).")
+	flag.BoolVar(&promGoogleAuth, "prom-google-auth", false, "Authenticate queries to Google Managed Prometheus (monitoring.googleapis.com) with the Workload Identity service account's OAuth2 token.")
+	flag.IntVar(&webhookPort, "webhook-port", 0, "Port serving the NginxAutoscaler webhooks: validation, which warns about per-replica targets beyond the pod's limits, and v1alpha1/v1beta1 conversion (0 disables them).")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "Directory holding the webhook's tls.crt and tls.key.")
+	flag.BoolVar(&printVersion, "version", false, "Print the build's version, git commit and date, and exit.")
 	flag.Parse()
 
+	if printVersion {
+		i := version.Get()
+		fmt.Printf("%s (commit %s, built %s, %s)\n", i.Version, i.GitCommit, i.BuildDate, i.GoVersion)
+		return
+	}
+
 	// Logger
-	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
+	logOpts, err := loggerOptions(logLevel, logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ctrl.SetLogger(zap.New(logOpts...))
+	ctrl.Log.Info("starting nginx-operator-autoscaler", version.Get().KeysAndValues()...)
+	metrics.Registry.MustRegister(version.Collector())
+	metrics.Registry.MustRegister(prom.Collectors()...)
 
-	// Scheme (we only need built-in apps/v1 for Deployment)
+	prom.Configure(promTransport)
+	prom.SetMaxConcurrentQueries(promMaxConcurrent)
+	prom.SetQueryProxy(promQueryProxy)
+	if promGoogleAuth {
+		prom.EnableGoogleAuth()
+	}
+	if faultRate > 0 {
+		ctrl.Log.Info("FAULT INJECTION ENABLED: Prometheus answers will be unreliable", "rate", faultRate)
+		prom.EnableFaultInjection(faultRate)
+	}
+
+	// Scheme (built-in apps/v1 for Deployment, core/v1 for Prometheus discovery,
+	// discovery/v1 for spec.endpoints, authorization/v1 for the RBAC self-check)
 	scheme := runtime.NewScheme()
 	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = discoveryv1.AddToScheme(scheme)
+	_ = authorizationv1.AddToScheme(scheme)
+
+	metricsOpts := server.Options{
+		BindAddress:   metricsAddr,
+		ExtraHandlers: map[string]http.Handler{"/version": version.Handler()},
+	}
+	if metricsSecure {
+		metricsOpts.SecureServing = true
+		metricsOpts.FilterProvider = filters.WithAuthenticationAndAuthorization
+	}
+
+	if err := metricNames.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctrlRole, err := controllers.ParseRole(role)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "--role:", err)
+		os.Exit(1)
+	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	cacheOpts, err := cacheOptions(splitList(watchNamespaces), deploymentSelector)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	restCfg := ctrl.GetConfigOrDie()
+	if apiQPS > 0 {
+		restCfg.QPS = float32(apiQPS)
+	}
+	if apiBurst > 0 {
+		restCfg.Burst = apiBurst
+	}
+	mgr, err := ctrl.NewManager(restCfg, ctrl.Options{
 		Scheme:                 scheme,
-		Metrics:                server.Options{BindAddress: metricsAddr},
+		Cache:                  cacheOpts,
+		Metrics:                metricsOpts,
 		HealthProbeBindAddress: healthAddr,
+		PprofBindAddress:       pprofAddr,
+		WebhookServer:          webhook.NewServer(webhook.Options{Port: webhookPort, CertDir: webhookCertDir}),
 		LeaderElection:         false,
 	})
 	if err != nil {
 		panic(fmt.Errorf("manager: %w", err))
 	}
 
+	// Fail fast with an actionable message instead of Forbidden errors every poll
+	if !skipRBACCheck {
+		reqs, targetReqs := requiredPermissions(ctrlRole)
+		scopes := rbacScopes{
+			watched:    splitList(watchNamespaces),
+			targets:    splitList(allowedTargetNamespaces),
+			reqs:       reqs,
+			targetReqs: targetReqs,
+		}
+		if metricsSecure && metricsAddr != "0" {
+			scopes.cluster = metricsAuthRBAC
+		}
+		if watchPods {
+			scopes.reqs = append(scopes.reqs, podWatchRBAC...)
+		}
+		if err := checkInstalledRBAC(context.Background(), mgr.GetClient(), scopes); err != nil {
+			fmt.Fprintln(os.Stderr, "rbac self-check failed:", err)
+			os.Exit(1)
+		}
+	}
+
 	// Reconciler
-	if err := controllers.SetupNginxAutoscalerController(mgr); err != nil {
+	opts := controllers.Options{
+		AllowedTargetNamespaces: splitList(allowedTargetNamespaces),
+		InstanceName:            instanceName,
+		OpenCostURL:             openCostURL,
+		PollJitter:              pollJitter,
+		MaxConcurrentReconciles: maxConcurrent,
+		RateLimiter: workqueue.NewMaxOfRateLimiter(
+			workqueue.NewItemExponentialFailureRateLimiter(requeueBaseDelay, requeueMaxDelay),
+			&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(requeueQPS), requeueBurst)},
+		),
+		WriteQPS:                  writeQPS,
+		WriteBurst:                writeBurst,
+		ScalesPerMinute:           scalesPerMinute,
+		WatchPods:                 watchPods,
+		LiveTargetRead:            liveTargetRead,
+		Role:                      ctrlRole,
+		StatusObservationInterval: statusObservationInterval,
+		HysteresisLogInterval:     hysteresisLogInterval,
+		MetricNames:               metricNames,
+	}
+	if policyConfigMap != "" {
+		ns, name, ok := strings.Cut(policyConfigMap, "/")
+		if !ok || ns == "" || name == "" {
+			fmt.Fprintln(os.Stderr, "--policy-configmap must be namespace/name")
+			os.Exit(1)
+		}
+		opts.PolicyConfigMap = types.NamespacedName{Namespace: ns, Name: name}
+		loc, err := time.LoadLocation(policyTimeZone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "--policy-timezone: %v\n", err)
+			os.Exit(1)
+		}
+		opts.PolicyTimeZone = loc
+	}
+	if grafanaURL != "" {
+		if grafanaToken == "" {
+			fmt.Fprintln(os.Stderr, "--grafana-url requires --grafana-token (or GRAFANA_TOKEN)")
+			os.Exit(1)
+		}
+		opts.Annotations = events.NewAsync(grafana.New(grafanaURL, grafanaToken), 1000, ctrl.Log.WithName("grafana"))
+		if err := mgr.Add(opts.Annotations); err != nil {
+			panic(fmt.Errorf("grafana annotations: %w", err))
+		}
+	}
+	if alertmanagerURL != "" {
+		opts.Silences = events.NewAsync(alertmanager.New(alertmanagerURL), 1000, ctrl.Log.WithName("alertmanager"))
+		if err := mgr.Add(opts.Silences); err != nil {
+			panic(fmt.Errorf("alertmanager silences: %w", err))
+		}
+	}
+	if decisionStorePath != "" {
+		store, err := decisionstore.Open(decisionStorePath, decisionRetention)
+		if err != nil {
+			panic(fmt.Errorf("decision store: %w", err))
+		}
+		defer store.Close()
+		opts.Decisions = store
+	}
+	if debugAddr != "" || dashboardAddr != "" {
+		opts.Debug = controllers.NewDebugStore()
+	}
+	if debugAddr != "" {
+		routes := map[string]http.Handler{
+			"/debug/autoscalers":     opts.Debug,
+			"/debug/recommendations": recommendationsHandler(mgr.GetClient()),
+			"/version":               version.Handler(),
+		}
+		if opts.Decisions != nil {
+			routes["/debug/decisions"] = decisionsHandler(opts.Decisions)
+			routes["/debug/decisions/backup"] = decisionsBackupHandler(opts.Decisions)
+		}
+		if err := addDebugServer(mgr, debugAddr, debugToken, routes); err != nil {
+			panic(fmt.Errorf("debug server: %w", err))
+		}
+	}
+	if dashboardAddr != "" {
+		if err := addHTTPServer(mgr, dashboardAddr, controllers.NewDashboard(mgr.GetClient(), opts.Debug)); err != nil {
+			panic(fmt.Errorf("dashboard: %w", err))
+		}
+	}
+	if eventSink != "" {
+		sink, err := events.NewSink(eventSink)
+		if err != nil {
+			panic(fmt.Errorf("cloudevents sink: %w", err))
+		}
+		opts.Events = events.NewAsync(sink, 1000, ctrl.Log.WithName("cloudevents"))
+		if err := mgr.Add(opts.Events); err != nil {
+			panic(fmt.Errorf("cloudevents sink: %w", err))
+		}
+	}
+	if eventBus != "" {
+		sink, err := events.NewSink(eventBus)
+		if err != nil {
+			panic(fmt.Errorf("event bus: %w", err))
+		}
+		opts.Bus = events.NewAsync(sink, 1000, ctrl.Log.WithName("event-bus"))
+		if err := mgr.Add(opts.Bus); err != nil {
+			panic(fmt.Errorf("event bus: %w", err))
+		}
+	}
+	if err := controllers.SetupNginxAutoscalerController(mgr, opts); err != nil {
 		panic(fmt.Errorf("setup controller: %w", err))
 	}
+	if webhookPort > 0 {
+		controllers.SetupTargetValidator(mgr)
+		controllers.SetupConversionWebhook(mgr)
+	}
+	if kedaAddr != "" {
+		if err := addKEDAScaler(mgr, kedaAddr, kedaCertDir, kedaClientCA, opts.MetricNames); err != nil {
+			panic(fmt.Errorf("keda scaler: %w", err))
+		}
+	}
 
 	_ = mgr.AddHealthzCheck("ping", healthz.Ping)
 	_ = mgr.AddReadyzCheck("ping", healthz.Ping)
+	if readyPromURL != "" {
+		_ = mgr.AddReadyzCheck("prometheus", func(_ *http.Request) error {
+			return prom.Ping(readyPromURL)
+		})
+	}
 
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		fmt.Fprintln(os.Stderr, "manager stopped:", err)
 		os.Exit(1)
 	}
 }
+
+// splitList parses a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
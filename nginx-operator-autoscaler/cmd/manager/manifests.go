@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/config"
+)
+
+// manifestParams are what a rendering of the embedded manifests varies by.
+type manifestParams struct {
+	Namespace string // of the controller's ServiceAccount and Deployment
+	Image     string // of the manager container; empty keeps config/manager's
+}
+
+// renderManifests decodes the embedded manifests of dirs, in that order, and
+// moves the controller into p.Namespace and onto p.Image. Samples keep their
+// own namespace: they sit next to the Deployments they scale.
+func renderManifests(dirs []string, p manifestParams) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	for _, dir := range dirs {
+		docs, err := decodeManifests(config.Manifests, dir)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, docs...)
+	}
+	for _, obj := range objs {
+		switch obj.GetKind() {
+		case "ServiceAccount":
+			obj.SetNamespace(p.Namespace)
+		case "Deployment":
+			obj.SetNamespace(p.Namespace)
+			if p.Image == "" {
+				continue
+			}
+			containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+			for _, c := range containers {
+				if m, ok := c.(map[string]interface{}); ok && m["name"] == "manager" {
+					m["image"] = p.Image
+				}
+			}
+			_ = unstructured.SetNestedSlice(obj.Object, containers, "spec", "template", "spec", "containers")
+		case "Role":
+			obj.SetNamespace(p.Namespace)
+		case "ClusterRoleBinding", "RoleBinding":
+			if obj.GetKind() == "RoleBinding" {
+				obj.SetNamespace(p.Namespace)
+			}
+			subjects, _, _ := unstructured.NestedSlice(obj.Object, "subjects")
+			for _, s := range subjects {
+				if m, ok := s.(map[string]interface{}); ok && m["kind"] == "ServiceAccount" {
+					m["namespace"] = p.Namespace
+				}
+			}
+			_ = unstructured.SetNestedSlice(obj.Object, subjects, "subjects")
+		}
+	}
+	return objs, nil
+}
+
+// runGenerate is `manager generate manifests`: render the embedded CRDs,
+// RBAC and controller Deployment (and, with --samples, the sample CRs) for
+// an image and namespace, to stdout or as a kustomize base in --output-dir.
+func runGenerate(args []string) error {
+	if len(args) == 0 || args[0] != "manifests" {
+		return fmt.Errorf("usage: manager generate manifests [--image=...] [--namespace=...] [--samples] [--output-dir=...]")
+	}
+	fset := flag.NewFlagSet("generate manifests", flag.ContinueOnError)
+	var p manifestParams
+	fset.StringVar(&p.Namespace, "namespace", "default", "Namespace of the controller's ServiceAccount and Deployment.")
+	fset.StringVar(&p.Image, "image", "", "Controller image (config/manager/deployment.yaml's if empty).")
+	samples := fset.Bool("samples", false, "Include the sample AutoscalerDefaults and NginxAutoscaler.")
+	outDir := fset.String("output-dir", "", "Write one file per kind of manifest plus a kustomization.yaml here instead of to stdout.")
+	if err := fset.Parse(args[1:]); err != nil {
+		return err
+	}
+	dirs := []string{"crd", "rbac", "manager"}
+	if *samples {
+		dirs = append(dirs, "samples")
+	}
+	if *outDir == "" {
+		objs, err := renderManifests(dirs, p)
+		if err != nil {
+			return err
+		}
+		return printObjects(os.Stdout, objs)
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		return err
+	}
+	var resources []string
+	for _, dir := range dirs {
+		objs, err := renderManifests([]string{dir}, p)
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := printObjects(&buf, objs); err != nil {
+			return err
+		}
+		name := dir + ".yaml"
+		if err := os.WriteFile(filepath.Join(*outDir, name), buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		resources = append(resources, name)
+	}
+	kustomization := "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- " +
+		strings.Join(resources, "\n- ") + "\n"
+	return os.WriteFile(filepath.Join(*outDir, "kustomization.yaml"), []byte(kustomization), 0o644)
+}
+
+// optionalManifests widen what the controller may do, or replace rbac.yaml
+// for --role=recommender; install and generate leave them out, to be applied
+// by hand where wanted.
+var optionalManifests = map[string]bool{
+	"rbac/cross_namespace_rbac.yaml": true,
+	"rbac/recommender_rbac.yaml":     true,
+}
+
+// decodeManifests reads every (multi-document) YAML file in dir of fsys,
+// except optionalManifests.
+func decodeManifests(fsys fs.FS, dir string) ([]*unstructured.Unstructured, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var objs []*unstructured.Unstructured
+	for _, name := range files {
+		if optionalManifests[name] {
+			continue
+		}
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		dec := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+		for {
+			obj := &unstructured.Unstructured{}
+			if err := dec.Decode(&obj.Object); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			if len(obj.Object) == 0 {
+				continue // an empty document, e.g. a trailing ---
+			}
+			objs = append(objs, obj)
+		}
+	}
+	return objs, nil
+}
+
+// printObjects writes objs as one multi-document YAML stream.
+func printObjects(w io.Writer, objs []*unstructured.Unstructured) error {
+	for _, obj := range objs {
+		out, err := sigsyaml.Marshal(obj.Object)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestInstallObjects(t *testing.T) {
+	objs, err := installObjects("ops")
+	if err != nil {
+		t.Fatalf("installObjects: %v", err)
+	}
+	kinds := map[string]int{}
+	crdsDone := false
+	for _, obj := range objs {
+		kinds[obj.GetKind()]++
+		if obj.GetKind() != "CustomResourceDefinition" {
+			crdsDone = true
+		} else if crdsDone {
+			t.Fatalf("CRD %s comes after other objects; CRDs must be applied first", obj.GetName())
+		}
+		switch obj.GetKind() {
+		case "ServiceAccount":
+			if obj.GetNamespace() != "ops" {
+				t.Errorf("ServiceAccount %s in namespace %q, want ops", obj.GetName(), obj.GetNamespace())
+			}
+		case "Role", "RoleBinding":
+			if obj.GetNamespace() != "ops" {
+				t.Errorf("%s %s in namespace %q, want ops", obj.GetKind(), obj.GetName(), obj.GetNamespace())
+			}
+		case "ClusterRoleBinding":
+			if obj.GetName() == "nginx-operator-autoscaler-cross-namespace" {
+				t.Errorf("installObjects includes the optional cross-namespace grant")
+			}
+			subjects, _, _ := unstructured.NestedSlice(obj.Object, "subjects")
+			for _, s := range subjects {
+				if ns := s.(map[string]interface{})["namespace"]; ns != "ops" {
+					t.Errorf("%s binds a subject in namespace %v, want ops", obj.GetName(), ns)
+				}
+			}
+		}
+	}
+	if kinds["CustomResourceDefinition"] != 2 || kinds["ServiceAccount"] != 1 || kinds["Role"] != 1 || kinds["RoleBinding"] != 1 || kinds["ClusterRoleBinding"] == 0 {
+		t.Fatalf("installObjects kinds = %v", kinds)
+	}
+}
+
+func TestRenderManifestsSetsImageAndNamespace(t *testing.T) {
+	objs, err := renderManifests([]string{"manager", "samples"}, manifestParams{Namespace: "ops", Image: "example.com/autoscaler:v2"})
+	if err != nil {
+		t.Fatalf("renderManifests: %v", err)
+	}
+	var deployments, samples int
+	for _, obj := range objs {
+		switch obj.GetKind() {
+		case "Deployment":
+			deployments++
+			if obj.GetNamespace() != "ops" {
+				t.Errorf("Deployment in namespace %q, want ops", obj.GetNamespace())
+			}
+			containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+			if image := containers[0].(map[string]interface{})["image"]; image != "example.com/autoscaler:v2" {
+				t.Errorf("manager image = %v", image)
+			}
+		case "NginxAutoscaler", "AutoscalerDefaults":
+			samples++
+			if obj.GetNamespace() != "default" {
+				t.Errorf("sample %s moved to namespace %q; samples keep their own", obj.GetName(), obj.GetNamespace())
+			}
+		}
+	}
+	if deployments != 1 || samples != 2 {
+		t.Fatalf("rendered %d Deployments and %d samples, want 1 and 2", deployments, samples)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/promproxy"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/version"
+)
+
+// runMetricsProxy is `manager metrics-proxy`: serve the Prometheus query API
+// from a cache shared by every controller pointed at it with
+// --prom-query-proxy, until SIGTERM.
+func runMetricsProxy(args []string) error {
+	fset := flag.NewFlagSet("metrics-proxy", flag.ContinueOnError)
+	addr := fset.String("bind-address", "127.0.0.1:9091", "The address the proxy serves queries, /metrics and /healthz on. Unauthenticated: widen it only behind a NetworkPolicy.")
+	upstreams := fset.String("upstream", "", "Comma-separated Prometheus URLs the proxy may query (required); queries for any other are refused.")
+	ttl := fset.Duration("ttl", 15*time.Second, "How old a cached answer may get; refreshed in the background before then.")
+	idle := fset.Duration("idle", 10*time.Minute, "Stop refreshing, and forget, a query nobody asked for in this long.")
+	var transport prom.TransportOptions
+	fset.DurationVar(&transport.DialTimeout, "prom-dial-timeout", 30*time.Second, "Timeout for connecting to Prometheus.")
+	fset.Int64Var(&transport.MaxResponseBytes, "prom-max-response-bytes", 64<<20, "Refuse Prometheus answers larger than this (0 disables the limit).")
+	googleAuth := fset.Bool("prom-google-auth", false, "Authenticate queries to Google Managed Prometheus with the Workload Identity token.")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *ttl <= 0 {
+		return fmt.Errorf("--ttl must be positive")
+	}
+	if len(splitList(*upstreams)) == 0 {
+		return fmt.Errorf("--upstream is required")
+	}
+
+	prom.Configure(transport)
+	if *googleAuth {
+		prom.EnableGoogleAuth()
+	}
+	p := promproxy.New(&http.Client{Transport: prom.Transport(), Timeout: time.Minute}, *ttl, *idle, splitList(*upstreams))
+	p.MaxBodyBytes = transport.MaxResponseBytes
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(promproxy.Collectors()...)
+	reg.MustRegister(version.Collector())
+	mux := http.NewServeMux()
+	mux.Handle("/u/", p)
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	mux.Handle("/version", version.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) })
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx := ctrl.SetupSignalHandler()
+	go func() { _ = p.Run(ctx) }()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	fmt.Printf("metrics proxy listening on %s (ttl %s)\n", *addr, *ttl)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runMigrate is `manager migrate from-hpa`: read the HorizontalPodAutoscalers
+// of a namespace and print an NginxAutoscaler for each, ready to apply once
+// the HPA is deleted. What doesn't translate is reported on stderr.
+func runMigrate(args []string) error {
+	if len(args) == 0 || args[0] != "from-hpa" {
+		return fmt.Errorf("usage: manager migrate from-hpa [--namespace=...] [--prom-url=...]")
+	}
+	fset := flag.NewFlagSet("migrate from-hpa", flag.ContinueOnError)
+	namespace := fset.String("namespace", "default", "Namespace whose HorizontalPodAutoscalers are migrated.")
+	promURL := fset.String("prom-url", "", "spec.promURL of the generated autoscalers (empty lets the controller discover Prometheus).")
+	if err := fset.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	objs, err := migrateHPAs(ctx, c, *namespace, *promURL, os.Stderr)
+	if err != nil {
+		return err
+	}
+	return printObjects(os.Stdout, objs)
+}
+
+// migrateHPAs converts every HPA in namespace whose target can be read,
+// writing a line to notes for each one skipped or converted only in part.
+func migrateHPAs(ctx context.Context, c client.Reader, namespace, promURL string, notes io.Writer) ([]*unstructured.Unstructured, error) {
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := c.List(ctx, &hpas, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("list HorizontalPodAutoscalers: %w", err)
+	}
+	var objs []*unstructured.Unstructured
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		template, err := hpaTemplate(ctx, c, hpa)
+		if err != nil {
+			fmt.Fprintf(notes, "%s: skipped: %v\n", hpa.Name, err)
+			continue
+		}
+		cr, skipped := autoscalerFromHPA(hpa, template, promURL)
+		for _, s := range skipped {
+			fmt.Fprintf(notes, "%s: %s\n", hpa.Name, s)
+		}
+		objs = append(objs, cr)
+	}
+	return objs, nil
+}
+
+// hpaTemplate is the pod template of the HPA's scale target, for the
+// requests its utilization targets are relative to.
+func hpaTemplate(ctx context.Context, c client.Reader, hpa *autoscalingv2.HorizontalPodAutoscaler) (*corev1.PodTemplateSpec, error) {
+	ref := hpa.Spec.ScaleTargetRef
+	key := types.NamespacedName{Namespace: hpa.Namespace, Name: ref.Name}
+	switch {
+	case ref.Kind == "Deployment" && ref.APIVersion == "apps/v1":
+		var dep appsv1.Deployment
+		if err := c.Get(ctx, key, &dep); err != nil {
+			return nil, err
+		}
+		return &dep.Spec.Template, nil
+	case ref.Kind == "DeploymentConfig" && ref.APIVersion == "apps.openshift.io/v1":
+		dc := &unstructured.Unstructured{}
+		dc.SetAPIVersion(ref.APIVersion)
+		dc.SetKind(ref.Kind)
+		if err := c.Get(ctx, key, dc); err != nil {
+			return nil, err
+		}
+		raw, _, _ := unstructured.NestedMap(dc.Object, "spec", "template")
+		var template corev1.PodTemplateSpec
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &template); err != nil {
+			return nil, err
+		}
+		return &template, nil
+	}
+	return nil, fmt.Errorf("scale target %s %s is not a Deployment or DeploymentConfig", ref.APIVersion, ref.Kind)
+}
+
+// autoscalerFromHPA maps hpa onto an NginxAutoscaler of the same name.
+// Utilization targets become per-replica budgets: the percentage of what the
+// pod template requests. It returns what had no equivalent.
+func autoscalerFromHPA(hpa *autoscalingv2.HorizontalPodAutoscaler, template *corev1.PodTemplateSpec, promURL string) (*unstructured.Unstructured, []string) {
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+	ref := hpa.Spec.ScaleTargetRef
+	targetRef := map[string]interface{}{"name": ref.Name}
+	if ref.Kind != "Deployment" {
+		targetRef["apiVersion"] = ref.APIVersion
+		targetRef["kind"] = ref.Kind
+	}
+	spec := map[string]interface{}{
+		"targetRef":   targetRef,
+		"minReplicas": int64(minReplicas),
+		"maxReplicas": int64(hpa.Spec.MaxReplicas),
+	}
+	if promURL != "" {
+		spec["promURL"] = promURL
+	}
+
+	var skipped []string
+	requests := podRequests(template.Spec)
+	for _, m := range hpa.Spec.Metrics {
+		if m.Type != autoscalingv2.ResourceMetricSourceType || m.Resource == nil {
+			skipped = append(skipped, fmt.Sprintf("%s metric not migrated", m.Type))
+			continue
+		}
+		var key string
+		switch m.Resource.Name {
+		case corev1.ResourceCPU:
+			key = "targetCPU"
+		case corev1.ResourceMemory:
+			key = "targetMem"
+		default:
+			skipped = append(skipped, fmt.Sprintf("resource metric %s not migrated", m.Resource.Name))
+			continue
+		}
+		budget, err := perReplicaBudget(m.Resource.Target, requests[m.Resource.Name])
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s target not migrated: %v", m.Resource.Name, err))
+			continue
+		}
+		spec[key] = budget.String()
+	}
+	if hpa.Spec.Behavior != nil {
+		skipped = append(skipped, "behavior not migrated; see cooldown, stepLimit and scalingBudget")
+	}
+
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	cr.SetAPIVersion("autoscaler.malisetti.dev/v1alpha1")
+	cr.SetKind("NginxAutoscaler")
+	cr.SetNamespace(hpa.Namespace)
+	cr.SetName(hpa.Name)
+	return cr, skipped
+}
+
+// perReplicaBudget turns an HPA resource target into what one replica may
+// use: an average value as is, a utilization as that share of request.
+func perReplicaBudget(target autoscalingv2.MetricTarget, request resource.Quantity) (resource.Quantity, error) {
+	switch target.Type {
+	case autoscalingv2.AverageValueMetricType:
+		if target.AverageValue == nil {
+			return resource.Quantity{}, fmt.Errorf("averageValue is empty")
+		}
+		return *target.AverageValue, nil
+	case autoscalingv2.UtilizationMetricType:
+		if target.AverageUtilization == nil {
+			return resource.Quantity{}, fmt.Errorf("averageUtilization is empty")
+		}
+		if request.IsZero() {
+			return resource.Quantity{}, fmt.Errorf("utilization target but the pod template requests none")
+		}
+		milli := request.MilliValue() * int64(*target.AverageUtilization) / 100
+		if request.Format == resource.BinarySI {
+			// bytes, in whole Mi where that's at least one
+			bytes := milli / 1000
+			if bytes >= 1<<20 {
+				bytes = bytes >> 20 << 20
+			}
+			return *resource.NewQuantity(bytes, resource.BinarySI), nil
+		}
+		return *resource.NewMilliQuantity(milli, request.Format), nil
+	}
+	return resource.Quantity{}, fmt.Errorf("target type %s", target.Type)
+}
+
+// podRequests sums the requests of spec's containers, the way the HPA
+// computes utilization.
+func podRequests(spec corev1.PodSpec) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := out[name]
+			sum.Add(q)
+			out[name] = sum
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMigrateHPAs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = autoscalingv2.AddToScheme(scheme)
+
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "nginx", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("400m"), corev1.ResourceMemory: resource.MustParse("256Mi")}}},
+			{Name: "exporter", Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("100m")}}},
+		}}}},
+	}
+	utilization := func(v int32) *int32 { return &v }
+	minReplicas := int32(2)
+	web := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "web"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    12,
+			Metrics: []autoscalingv2.MetricSpec{
+				{Type: autoscalingv2.ResourceMetricSourceType, Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU, Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: utilization(70)}}},
+				{Type: autoscalingv2.ResourceMetricSourceType, Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceMemory, Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: utilization(80)}}},
+				{Type: autoscalingv2.PodsMetricSourceType},
+			},
+		},
+	}
+	db := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "shop", Name: "db"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "db"},
+			MaxReplicas:    3,
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(dep, web, db).Build()
+
+	var notes bytes.Buffer
+	objs, err := migrateHPAs(context.Background(), c, "shop", "http://prom:9090", &notes)
+	if err != nil {
+		t.Fatalf("migrateHPAs: %v", err)
+	}
+	if len(objs) != 1 || objs[0].GetName() != "web" || objs[0].GetNamespace() != "shop" || objs[0].GetKind() != "NginxAutoscaler" {
+		t.Fatalf("migrated %v, want the NginxAutoscaler shop/web only", objs)
+	}
+	want := map[string]interface{}{
+		"targetRef":   map[string]interface{}{"name": "web"},
+		"promURL":     "http://prom:9090",
+		"minReplicas": int64(2),
+		"maxReplicas": int64(12),
+		"targetCPU":   "350m",  // 70% of 500m
+		"targetMem":   "204Mi", // 80% of 256Mi, rounded down
+	}
+	if got := objs[0].Object["spec"]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("spec = %v, want %v", got, want)
+	}
+	for _, note := range []string{"db: skipped", "web: Pods metric not migrated"} {
+		if !strings.Contains(notes.String(), note) {
+			t.Errorf("notes %q lack %q", notes.String(), note)
+		}
+	}
+}
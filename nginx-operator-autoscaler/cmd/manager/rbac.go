@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+)
+
+// rbacRequirement is one permission the controller cannot work without.
+type rbacRequirement struct {
+	group, resource, subresource string
+	verbs                        []string
+}
+
+// requiredRBAC mirrors the Role in config/rbac/rbac.yaml; with --role=all or
+// actuator it must hold in every namespace the manager watches.
+var requiredRBAC = []rbacRequirement{
+	{group: "apps", resource: "deployments", verbs: []string{"get", "list", "watch", "update", "patch"}},
+	{group: "autoscaler.malisetti.dev", resource: "nginxautoscalers", verbs: []string{"get", "list", "watch", "update"}},
+	{group: "autoscaler.malisetti.dev", resource: "nginxautoscalers", subresource: "status", verbs: []string{"update", "patch"}},
+	{group: "autoscaler.malisetti.dev", resource: "autoscalerdefaults", verbs: []string{"get", "list", "watch"}},
+}
+
+// recommenderRBAC mirrors config/rbac/recommender_rbac.yaml: a recommender
+// reads its targets and only writes status.recommendation.
+var recommenderRBAC = []rbacRequirement{
+	{group: "apps", resource: "deployments", verbs: []string{"get", "list", "watch"}},
+	{group: "autoscaler.malisetti.dev", resource: "nginxautoscalers", verbs: []string{"get", "list", "watch"}},
+	{group: "autoscaler.malisetti.dev", resource: "nginxautoscalers", subresource: "status", verbs: []string{"patch"}},
+	{group: "autoscaler.malisetti.dev", resource: "autoscalerdefaults", verbs: []string{"get", "list", "watch"}},
+}
+
+// targetRBAC is what scaling a Deployment in an --allowed-target-namespaces
+// namespace needs (config/rbac/cross_namespace_rbac.yaml grants it).
+var targetRBAC = []rbacRequirement{
+	{group: "apps", resource: "deployments", verbs: []string{"get", "list", "watch", "update", "patch"}},
+}
+
+// recommenderTargetRBAC is what reading a Deployment in an
+// --allowed-target-namespaces namespace needs.
+var recommenderTargetRBAC = []rbacRequirement{
+	{group: "apps", resource: "deployments", verbs: []string{"get", "list", "watch"}},
+}
+
+// requiredPermissions returns what role needs in every watched namespace
+// and in every allowed target namespace.
+func requiredPermissions(role controllers.Role) (reqs, targets []rbacRequirement) {
+	if role == controllers.RoleRecommender {
+		return recommenderRBAC, recommenderTargetRBAC
+	}
+	return requiredRBAC, targetRBAC
+}
+
+// metricsAuthRBAC is what the secure metrics filter needs to authenticate scrapers.
+var metricsAuthRBAC = []rbacRequirement{
+	{group: "authentication.k8s.io", resource: "tokenreviews", verbs: []string{"create"}},
+	{group: "authorization.k8s.io", resource: "subjectaccessreviews", verbs: []string{"create"}},
+}
+
+// podWatchRBAC is what --watch-pods needs for its Pod informer.
+var podWatchRBAC = []rbacRequirement{
+	{resource: "pods", verbs: []string{"list", "watch"}},
+}
+
+// rbacScopes says where the manager needs which permissions.
+type rbacScopes struct {
+	watched    []string          // --watch-namespaces; none means cluster-wide
+	targets    []string          // --allowed-target-namespaces; "*" means cluster-wide
+	reqs       []rbacRequirement // in every watched namespace
+	targetReqs []rbacRequirement // in every target namespace
+	cluster    []rbacRequirement // cluster-scoped resources
+}
+
+// checkInstalledRBAC runs checkRBAC wherever s says permissions must hold,
+// so it verifies whatever RBAC is installed: a Role in the watched namespace
+// passes as well as a ClusterRole.
+func checkInstalledRBAC(ctx context.Context, c client.Client, s rbacScopes) error {
+	watched := s.watched
+	if len(watched) == 0 {
+		watched = []string{""}
+	}
+	var errs []error
+	for _, ns := range watched {
+		errs = append(errs, checkRBAC(ctx, c, ns, s.reqs))
+	}
+	for _, ns := range s.targets {
+		if ns == "*" {
+			ns = ""
+		}
+		errs = append(errs, checkRBAC(ctx, c, ns, s.targetReqs))
+	}
+	if len(s.cluster) > 0 {
+		errs = append(errs, checkRBAC(ctx, c, "", s.cluster))
+	}
+	return errors.Join(errs...)
+}
+
+// checkRBAC asks the API server, via SelfSubjectAccessReview, whether we hold
+// every permission in reqs, and lists all missing ones in the error.
+func checkRBAC(ctx context.Context, c client.Client, namespace string, reqs []rbacRequirement) error {
+	var missing []string
+	for _, req := range reqs {
+		for _, verb := range req.verbs {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace:   namespace,
+						Verb:        verb,
+						Group:       req.group,
+						Resource:    req.resource,
+						Subresource: req.subresource,
+					},
+				},
+			}
+			if err := c.Create(ctx, review); err != nil {
+				return fmt.Errorf("self subject access review: %w", err)
+			}
+			if !review.Status.Allowed {
+				res := req.resource
+				if req.subresource != "" {
+					res += "/" + req.subresource
+				}
+				missing = append(missing, fmt.Sprintf("%s %s.%s", verb, res, req.group))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		scope := "cluster-wide"
+		if namespace != "" {
+			scope = "in namespace " + namespace
+		}
+		return fmt.Errorf("service account lacks RBAC permissions %s: %s (see config/rbac)",
+			scope, strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+)
+
+// fakeAccessReviews answers SelfSubjectAccessReviews with allowed(attributes).
+func fakeAccessReviews(t *testing.T, allowed func(*authorizationv1.ResourceAttributes) bool) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := authorizationv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review := obj.(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = allowed(review.Spec.ResourceAttributes)
+			return nil
+		},
+	}).Build()
+}
+
+func TestCheckInstalledRBAC(t *testing.T) {
+	ctx := context.Background()
+	// A Role in default plus the cluster-scoped reviews, as config/rbac installs
+	c := fakeAccessReviews(t, func(a *authorizationv1.ResourceAttributes) bool {
+		return a.Namespace == "default" || (a.Namespace == "" && strings.HasSuffix(a.Resource, "reviews"))
+	})
+	scopes := rbacScopes{watched: []string{"default"}, reqs: requiredRBAC, targetReqs: targetRBAC, cluster: metricsAuthRBAC}
+	if err := checkInstalledRBAC(ctx, c, scopes); err != nil {
+		t.Fatalf("Role in the watched namespace: %v", err)
+	}
+
+	scopes.watched = nil
+	if err := checkInstalledRBAC(ctx, c, scopes); err == nil || !strings.Contains(err.Error(), "cluster-wide") {
+		t.Fatalf("watching every namespace with a Role: err = %v, want missing cluster-wide permissions", err)
+	}
+
+	scopes.watched, scopes.targets = []string{"default"}, []string{"shop"}
+	if err := checkInstalledRBAC(ctx, c, scopes); err == nil || !strings.Contains(err.Error(), "update deployments.apps") {
+		t.Fatalf("target namespace without RoleBinding: err = %v, want missing update deployments", err)
+	}
+}
+
+func TestRequiredPermissionsRecommender(t *testing.T) {
+	ctx := context.Background()
+	// recommender_rbac.yaml: reads, plus patch on the status subresource
+	c := fakeAccessReviews(t, func(a *authorizationv1.ResourceAttributes) bool {
+		switch a.Verb {
+		case "get", "list", "watch":
+			return a.Subresource == ""
+		case "patch":
+			return a.Subresource == "status"
+		}
+		return false
+	})
+	reqs, targetReqs := requiredPermissions(controllers.RoleRecommender)
+	scopes := rbacScopes{watched: []string{"default"}, targets: []string{"shop"}, reqs: reqs, targetReqs: targetReqs}
+	if err := checkInstalledRBAC(ctx, c, scopes); err != nil {
+		t.Fatalf("recommender with a read-only Role: %v", err)
+	}
+
+	for _, role := range []controllers.Role{controllers.RoleAll, controllers.RoleActuator} {
+		scopes.reqs, scopes.targetReqs = requiredPermissions(role)
+		if err := checkInstalledRBAC(ctx, c, scopes); err == nil || !strings.Contains(err.Error(), "update deployments.apps") {
+			t.Fatalf("%s with a read-only Role: err = %v, want missing update deployments", role, err)
+		}
+	}
+}
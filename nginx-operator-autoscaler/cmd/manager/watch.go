@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+)
+
+// clearScreen moves the cursor home and clears the terminal between frames.
+const clearScreen = "\033[H\033[2J"
+
+// runWatch is `manager watch`: a top-like table of every autoscaler,
+// redrawn every poll interval until interrupted.
+func runWatch(args []string) error {
+	fset := flag.NewFlagSet("watch", flag.ContinueOnError)
+	namespace := fset.String("namespace", "", "Only show autoscalers in this namespace (all if empty).")
+	interval := fset.Duration("interval", 0, "Redraw this often; zero follows the shortest spec.pollInterval shown.")
+	once := fset.Bool("once", false, "Print the table once and exit.")
+	debugURL := fset.String("debug-url", "", "The controller's debug endpoint (e.g. a port-forwarded http://localhost:8082) for live values; status.lastObservation is throttled.")
+	debugToken := fset.String("debug-token", os.Getenv("DEBUG_TOKEN"), "Bearer token of the debug endpoint.")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{})
+	if err != nil {
+		return err
+	}
+	ctx := ctrl.SetupSignalHandler()
+	for {
+		rows, poll, err := watchRows(ctx, c, *namespace)
+		if err != nil {
+			return err
+		}
+		if *debugURL != "" {
+			snaps, err := fetchSnapshots(ctx, *debugURL, *debugToken)
+			if err != nil {
+				return fmt.Errorf("debug endpoint: %w", err)
+			}
+			overlaySnapshots(rows, snaps)
+		}
+		if !*once {
+			fmt.Print(clearScreen)
+		}
+		if err := printWatchTable(os.Stdout, rows, time.Now()); err != nil || *once {
+			return err
+		}
+		every := *interval
+		if every <= 0 {
+			every = poll
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(every):
+		}
+	}
+}
+
+// watchRow is one autoscaler as the watch table shows it.
+type watchRow struct {
+	Name          string // namespace/name
+	Target        string
+	CPUCores      string
+	MemMiB        string
+	Current       int64
+	Desired       int64
+	Reason        string    // skip reason, error, or "scaled"
+	ObservedAt    time.Time // when the values were seen
+	EligibleAt    time.Time // when cooldown after the last scale ends
+	PollInterval  time.Duration
+	cooldown      time.Duration
+	lastScaleTime time.Time
+}
+
+// watchRows reads every autoscaler in namespace (all if empty) and the
+// shortest poll interval among them, 15s if there are none.
+func watchRows(ctx context.Context, c client.Reader, namespace string) ([]watchRow, time.Duration, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetAPIVersion("autoscaler.malisetti.dev/v1alpha1")
+	list.SetKind("NginxAutoscalerList")
+	if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, 0, fmt.Errorf("list NginxAutoscalers: %w", err)
+	}
+	poll := time.Duration(0)
+	rows := make([]watchRow, 0, len(list.Items))
+	for i := range list.Items {
+		row := watchRowFor(&list.Items[i])
+		if poll == 0 || row.PollInterval < poll {
+			poll = row.PollInterval
+		}
+		rows = append(rows, row)
+	}
+	if poll == 0 {
+		poll = 15 * time.Second
+	}
+	return rows, poll, nil
+}
+
+// watchRowFor fills a row from u's spec and status.lastObservation, with the
+// controller's defaults for an unset poll interval and cooldown.
+func watchRowFor(u *unstructured.Unstructured) watchRow {
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	dur := func(key string, def time.Duration) time.Duration {
+		if s, ok := spec[key].(string); ok {
+			if d, err := time.ParseDuration(s); err == nil {
+				return d
+			}
+		}
+		return def
+	}
+	target, _, _ := unstructured.NestedString(spec, "targetRef", "name")
+	if target == "" {
+		target, _, _ = unstructured.NestedString(spec, "targetDeployment")
+	}
+	row := watchRow{
+		Name:         u.GetNamespace() + "/" + u.GetName(),
+		Target:       target,
+		PollInterval: dur("pollInterval", 15*time.Second),
+		cooldown:     dur("cooldown", 60*time.Second),
+	}
+
+	obs, _, _ := unstructured.NestedMap(u.Object, "status", "lastObservation")
+	row.CPUCores, _ = obs["cpuCores"].(string)
+	row.MemMiB, _ = obs["memMiB"].(string)
+	row.Current, _ = obs["current"].(int64)
+	row.Desired, _ = obs["desired"].(int64)
+	row.Reason = observationReason(obs["skipReason"], obs["error"], obs["applied"])
+	at, _ := obs["time"].(string)
+	row.ObservedAt, _ = time.Parse(time.RFC3339, at)
+	if cur, ok, _ := unstructured.NestedInt64(u.Object, "status", "currentReplicas"); ok && row.Current == 0 {
+		row.Current = cur
+	}
+	lastScale, _, _ := unstructured.NestedString(u.Object, "status", "lastScaleTime")
+	if t, err := time.Parse(time.RFC3339, lastScale); err == nil {
+		row.lastScaleTime = t
+		row.EligibleAt = t.Add(row.cooldown)
+	}
+	return row
+}
+
+func observationReason(skip, errMsg, applied interface{}) string {
+	if e, _ := errMsg.(string); e != "" {
+		return "error: " + e
+	}
+	if s, _ := skip.(string); s != "" {
+		return s
+	}
+	if n, _ := applied.(int64); n != 0 {
+		return fmt.Sprintf("scaled to %d", n)
+	}
+	return ""
+}
+
+// fetchSnapshots reads the latest decision of every CR from the debug endpoint.
+func fetchSnapshots(ctx context.Context, baseURL, token string) ([]controllers.DebugSnapshot, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/debug/autoscalers", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var snaps []controllers.DebugSnapshot
+	return snaps, json.NewDecoder(resp.Body).Decode(&snaps)
+}
+
+// overlaySnapshots replaces the status values of rows with the newer ones
+// the controller holds in memory.
+func overlaySnapshots(rows []watchRow, snaps []controllers.DebugSnapshot) {
+	byName := make(map[string]controllers.DebugSnapshot, len(snaps))
+	for _, s := range snaps {
+		byName[s.Autoscaler] = s
+	}
+	for i := range rows {
+		s, ok := byName[rows[i].Name]
+		if !ok || !s.Time.After(rows[i].ObservedAt) {
+			continue
+		}
+		rows[i].CPUCores = fmt.Sprintf("%.3g", s.CPUCores)
+		rows[i].MemMiB = fmt.Sprintf("%.4g", s.MemMiB)
+		rows[i].Current, rows[i].Desired = int64(s.Current), int64(s.Desired)
+		rows[i].Reason = observationReason(s.SkipReason, s.Error, int64(s.Applied))
+		rows[i].ObservedAt = s.Time
+		if t, err := time.Parse(time.RFC3339, s.LastScaleTime); err == nil && t.After(rows[i].lastScaleTime) {
+			rows[i].lastScaleTime = t
+			rows[i].EligibleAt = t.Add(rows[i].cooldown)
+		}
+	}
+}
+
+// printWatchTable writes rows as an aligned table. NEXT is the countdown to
+// the next decision that may scale: the later of the next poll and the end of
+// cooldown after the last scale.
+func printWatchTable(w io.Writer, rows []watchRow, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "AUTOSCALER\tTARGET\tCPU\tMEM(MiB)\tCURRENT\tDESIRED\tNEXT\tSEEN\tREASON")
+	for _, r := range rows {
+		next := now
+		if !r.ObservedAt.IsZero() {
+			next = r.ObservedAt.Add(r.PollInterval)
+		}
+		if r.EligibleAt.After(next) {
+			next = r.EligibleAt
+		}
+		seen := "-"
+		if !r.ObservedAt.IsZero() {
+			seen = now.Sub(r.ObservedAt).Round(time.Second).String() + " ago"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+			r.Name, r.Target, orDash(r.CPUCores), orDash(r.MemMiB), r.Current, r.Desired,
+			countdown(next.Sub(now)), seen, orDash(r.Reason))
+	}
+	return tw.Flush()
+}
+
+func countdown(d time.Duration) string {
+	if d <= 0 {
+		return "now"
+	}
+	return d.Round(time.Second).String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+)
+
+func TestWatchTable(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	autoscaler := func(name string, spec, status map[string]interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec, "status": status}}
+		u.SetAPIVersion("autoscaler.malisetti.dev/v1alpha1")
+		u.SetKind("NginxAutoscaler")
+		u.SetNamespace("shop")
+		u.SetName(name)
+		return u
+	}
+	web := autoscaler("web", map[string]interface{}{"targetRef": map[string]interface{}{"name": "web"}, "pollInterval": "30s", "cooldown": "2m"},
+		map[string]interface{}{
+			"lastScaleTime": now.Add(-time.Minute).Format(time.RFC3339),
+			"lastObservation": map[string]interface{}{
+				"time": now.Add(-10 * time.Second).Format(time.RFC3339), "cpuCores": "1.02", "memMiB": "412",
+				"current": int64(5), "desired": int64(6), "skipReason": "Cooldown",
+			},
+		})
+	api := autoscaler("api", map[string]interface{}{"targetDeployment": "api"}, map[string]interface{}{})
+
+	scheme := runtime.NewScheme()
+	gvk := schema.GroupVersionKind{Group: "autoscaler.malisetti.dev", Version: "v1alpha1", Kind: "NginxAutoscaler"}
+	scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind("NginxAutoscalerList"), &unstructured.UnstructuredList{})
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(web, api).Build()
+
+	rows, poll, err := watchRows(context.Background(), c, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if poll != 15*time.Second || len(rows) != 2 {
+		t.Fatalf("poll %v, %d rows; want the 15s default of api and 2 rows", poll, len(rows))
+	}
+	var out bytes.Buffer
+	if err := printWatchTable(&out, rows, now); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	// web: cooldown ends in 60s, later than the next poll in 20s
+	if len(lines) != 3 || strings.Join(strings.Fields(lines[2]), " ") != "shop/web web 1.02 412 5 6 1m0s 10s ago Cooldown" {
+		t.Fatalf("table:\n%s", out.String())
+	}
+	if strings.Join(strings.Fields(lines[1]), " ") != "shop/api api - - 0 0 now - -" {
+		t.Fatalf("table:\n%s", out.String())
+	}
+
+	// The debug endpoint has a newer decision for web
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]controllers.DebugSnapshot{{
+			Autoscaler: "shop/web", Time: now, CPUCores: 1.5, MemMiB: 400, Current: 5, Desired: 8,
+			SkipReason: "Cooldown", LastScaleTime: now.Add(-time.Minute).Format(time.RFC3339),
+		}})
+	}))
+	defer srv.Close()
+	snaps, err := fetchSnapshots(context.Background(), srv.URL, "tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlaySnapshots(rows, snaps)
+	if r := rows[1]; r.CPUCores != "1.5" || r.Desired != 8 || !r.ObservedAt.Equal(now) {
+		t.Fatalf("overlaid row = %+v", r)
+	}
+}
@@ -0,0 +1,10 @@
+// Package config embeds the deployment manifests in this directory, so the
+// manager binary can install itself (manager install) without a checkout.
+package config
+
+import "embed"
+
+// Manifests holds crd/, rbac/, manager/ and samples/.
+//
+//go:embed crd/*.yaml rbac/*.yaml manager/*.yaml samples/*.yaml
+var Manifests embed.FS
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidatePath is where the NginxAutoscaler validating webhook is served.
+const ValidatePath = "/validate-nginxautoscaler"
+
+// targetValidator denies a NginxAutoscaler whose schedules or blackout
+// windows don't parse (an unknown time zone, say) and otherwise admits it, but
+// warns (kubectl prints it) when its per-replica targets exceed what a pod of
+// its target may use, the same check the TargetsFeasible condition makes at
+// reconcile.
+type targetValidator struct {
+	tc targetCluster
+}
+
+// SetupTargetValidator serves the validating webhook on mgr's webhook server.
+func SetupTargetValidator(mgr manager.Manager) {
+	v := &targetValidator{tc: targetCluster{Client: mgr.GetClient(), reader: mgr.GetAPIReader()}}
+	mgr.GetWebhookServer().Register(ValidatePath, &webhook.Admission{Handler: v})
+}
+
+func (v *targetValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(req.Object.Raw); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	s := parseSpec(spec)
+	if s.ScheduleInvalid != "" {
+		return admission.Denied(s.ScheduleInvalid)
+	}
+	if s.TargetSelector != nil || len(s.Clusters) > 0 || s.KubeconfigSecret != "" {
+		return admission.Allowed("") // no single target in this cluster to check against
+	}
+	ns := req.Namespace
+	if s.TargetNamespace != "" {
+		ns = s.TargetNamespace
+	}
+	var dep appsv1.Deployment
+	if err := v.tc.getTarget(ctx, s.TargetKind, types.NamespacedName{Namespace: ns, Name: s.TargetDeployment}, &dep); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			return admission.Allowed("") // the target may well be created next
+		}
+		return admission.Allowed("").WithWarnings("could not check targets against the pod's limits: " + err.Error())
+	}
+	limits, err := podLimits(ctx, v.tc.reader, &dep)
+	if err != nil {
+		return admission.Allowed("").WithWarnings("could not check targets against the pod's limits: " + err.Error())
+	}
+	if msg := targetsBeyondLimits(s, limits); msg != "" {
+		return admission.Allowed("").WithWarnings(msg + "; replicas never reach the target, so scale-ups come late or not at all")
+	}
+	return admission.Allowed("")
+}
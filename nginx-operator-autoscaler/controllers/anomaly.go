@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+)
+
+// anomalyRef is spec.anomalyFilter: a sample whose modified z-score against
+// the trailing Window exceeds Threshold is a glitch (an exporter restart, a
+// counter reset) and the cycle is sat out rather than scaled on.
+type anomalyRef struct {
+	Window    time.Duration
+	Step      time.Duration // query_range resolution
+	Threshold float64
+}
+
+// anomalous reports whether the latest sample of query is a glitch.
+func anomalous(promURL, query string, ref anomalyRef) (bool, error) {
+	values, err := prom.RangeVector(promURL, query, ref.Window, ref.Step)
+	if err != nil {
+		return false, err
+	}
+	return decision.Anomalous(values, ref.Threshold), nil
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// approveAnnotation on a NginxAutoscaler approves the pending scale whose ID it names.
+const approveAnnotation = "autoscaler.malisetti.dev/approve"
+
+// approvalRef is spec.approval: changes larger than either threshold wait for
+// a human. Zero disables a threshold.
+type approvalRef struct {
+	MaxChangePercent  float64
+	MaxChangeReplicas int32
+}
+
+func (a approvalRef) needed(current, proposed int32) bool {
+	change := proposed - current
+	if change < 0 {
+		change = -change
+	}
+	if a.MaxChangeReplicas > 0 && change > a.MaxChangeReplicas {
+		return true
+	}
+	return a.MaxChangePercent > 0 && current > 0 && float64(change)/float64(current)*100 > a.MaxChangePercent
+}
+
+// gateApproval parks a large change in status.pendingScale until the CR carries
+// approveAnnotation with its ID, then hands back the approved count. It
+// returns the replicas to apply, whether to apply them, and whether status
+// changed. A pending change that no longer needs approval is dropped.
+func gateApproval(u *unstructured.Unstructured, a approvalRef, current, proposed, lo, hi int32, now time.Time) (replicas int32, apply, statusChanged bool) {
+	pending, hasPending, _ := unstructured.NestedMap(u.Object, "status", "pendingScale")
+	pendingID, _ := pending["id"].(string)
+	pendingReplicas, _ := pending["replicas"].(int64)
+
+	if hasPending && pendingID != "" && u.GetAnnotations()[approveAnnotation] == pendingID {
+		unstructured.RemoveNestedField(u.Object, "status", "pendingScale")
+		return clampReplicas(int32(pendingReplicas), lo, hi), true, true
+	}
+	if !a.needed(current, proposed) {
+		if hasPending {
+			unstructured.RemoveNestedField(u.Object, "status", "pendingScale")
+		}
+		return proposed, true, hasPending
+	}
+	if hasPending && pendingReplicas == int64(proposed) {
+		return current, false, false
+	}
+
+	_ = unstructured.SetNestedMap(u.Object, map[string]interface{}{
+		"id":        fmt.Sprintf("%s-%d", now.UTC().Format("20060102t150405"), proposed),
+		"replicas":  int64(proposed),
+		"current":   int64(current),
+		"createdAt": now.Format(time.RFC3339),
+	}, "status", "pendingScale")
+	return current, false, true
+}
+
+func clampReplicas(v, lo, hi int32) int32 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxActuationBackoff caps the wait between attempts to update a target that
+// keeps refusing, unless the poll interval is longer still.
+const maxActuationBackoff = 10 * time.Minute
+
+// actuationBackoff reads status.actuationBackoff: how many updates of the
+// target failed in a row and when the next may be tried. Zero if none failed.
+func actuationBackoff(u *unstructured.Unstructured) (failures int64, retryAt time.Time) {
+	failures, _, _ = unstructured.NestedInt64(u.Object, "status", "actuationBackoff", "failures")
+	at, _, _ := unstructured.NestedString(u.Object, "status", "actuationBackoff", "retryAt")
+	retryAt, _ = time.Parse(time.RFC3339, at)
+	return failures, retryAt
+}
+
+// recordActuationFailure counts a failed update of the target in
+// status.actuationBackoff and returns how long to wait before the next one:
+// the poll interval after the first failure, doubling with each one after.
+// From the second in a row, DegradedActuation=True carries the API server's
+// reason (Forbidden for RBAC and most webhook denials) and the error.
+func recordActuationFailure(u *unstructured.Unstructured, err error, poll time.Duration, now time.Time) time.Duration {
+	failures, _ := actuationBackoff(u)
+	failures++
+	limit := max(maxActuationBackoff, poll)
+	delay := poll
+	for i := int64(1); i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+	_ = unstructured.SetNestedMap(u.Object, map[string]interface{}{
+		"failures": failures,
+		"retryAt":  now.Add(delay).Format(time.RFC3339),
+	}, "status", "actuationBackoff")
+
+	if failures >= 2 {
+		reason := string(apierrors.ReasonForError(err))
+		if reason == "" || reason == string(metav1.StatusReasonUnknown) {
+			reason = "UpdateFailed"
+		}
+		setCondition(u, condDegradedActuation, metav1.ConditionTrue, reason,
+			fmt.Sprintf("%d updates of the target failed in a row; next attempt in %s: %v", failures, delay, err))
+	}
+	return delay
+}
+
+// clearActuationBackoff forgets past failures once the target was updated.
+// Like MetricsAvailable, the condition is only flipped back if it was set.
+func clearActuationBackoff(u *unstructured.Unstructured) {
+	unstructured.RemoveNestedField(u.Object, "status", "actuationBackoff")
+	if meta.FindStatusCondition(getConditions(u), condDegradedActuation) != nil {
+		setCondition(u, condDegradedActuation, metav1.ConditionFalse, "TargetUpdated", "")
+	}
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"math"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// balloonLabel marks (and selects) the balloon pods of the Deployment named by its value.
+const balloonLabel = "autoscaler.malisetti.dev/balloon-for"
+
+// overprovisionRef is spec.overprovisioning: keep a Deployment of paused,
+// low-priority "balloon" pods, each requesting what one target pod does,
+// next to the target. The scheduler preempts them for real pods, so nodes
+// for a scale-up are already there, and cluster-autoscaler adds the next
+// ones while the load is still building.
+type overprovisionRef struct {
+	PriorityClass string // must rank below the target's pods
+	Replicas      int32  // balloons always kept
+	Percent       float64
+	Image         string
+}
+
+// balloonName is dep's balloon Deployment. The dot keeps its pods out of the
+// "<dep>-.*" pod regex the usage, drain and deletion-cost queries match dep's
+// own pods with; "<dep>-balloon" (the name before) fell inside it.
+func balloonName(dep *appsv1.Deployment) string { return dep.Name + ".balloon" }
+
+// legacyBalloonName is the balloon Deployment's name before balloonName.
+func legacyBalloonName(dep *appsv1.Deployment) string { return dep.Name + "-balloon" }
+
+// balloonReplicas sizes the balloons for the scale-up ahead: what the decision
+// wants but the target doesn't run yet (held by cooldown or confirmation),
+// plus Replicas and Percent of the target's replicas on top, never past
+// maxReplicas.
+func (o overprovisionRef) balloonReplicas(current, desired, maxReplicas int32) int32 {
+	n := max(desired-current, 0) + o.Replicas + int32(math.Ceil(float64(current)*o.Percent/100))
+	return max(min(n, maxReplicas-current), 0)
+}
+
+// balloonDeployment is the balloon Deployment for dep: pause containers
+// requesting what one of dep's pods does, scheduled onto the same nodes. It
+// is owned by dep, so it goes when dep does.
+func balloonDeployment(dep *appsv1.Deployment, o overprovisionRef, replicas int32) *appsv1.Deployment {
+	requests := corev1.ResourceList{}
+	for _, c := range dep.Spec.Template.Spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := requests[name]
+			sum.Add(q)
+			requests[name] = sum
+		}
+	}
+	labels := map[string]string{balloonLabel: dep.Name}
+	grace := int64(0)
+	tmpl := dep.Spec.Template.Spec
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: dep.Namespace,
+			Name:      balloonName(dep),
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: dep.Name, UID: dep.UID,
+			}},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					PriorityClassName:             o.PriorityClass,
+					TerminationGracePeriodSeconds: &grace,
+					NodeSelector:                  tmpl.NodeSelector,
+					Affinity:                      tmpl.Affinity,
+					Tolerations:                   tmpl.Tolerations,
+					Containers: []corev1.Container{{
+						Name:      "pause",
+						Image:     o.Image,
+						Resources: corev1.ResourceRequirements{Requests: requests},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// syncBalloons creates or updates dep's balloon Deployment to run replicas pods.
+func (tc targetCluster) syncBalloons(ctx context.Context, dep *appsv1.Deployment, o overprovisionRef, replicas int32) error {
+	want := balloonDeployment(dep, o, replicas)
+	var cur appsv1.Deployment
+	err := tc.Get(ctx, types.NamespacedName{Namespace: want.Namespace, Name: want.Name}, &cur)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err != nil {
+		// First sync since the rename: drop the balloons under the old name
+		if err := tc.deleteBalloon(ctx, dep, legacyBalloonName(dep)); err != nil {
+			return err
+		}
+		return tc.Create(ctx, want)
+	}
+	if equality.Semantic.DeepDerivative(want.Spec, cur.Spec) {
+		return nil
+	}
+	cur.Spec = want.Spec
+	return tc.Update(ctx, &cur)
+}
+
+// deleteBalloons removes dep's balloon Deployment, if there is one of ours.
+func (tc targetCluster) deleteBalloons(ctx context.Context, dep *appsv1.Deployment) error {
+	if err := tc.deleteBalloon(ctx, dep, balloonName(dep)); err != nil {
+		return err
+	}
+	return tc.deleteBalloon(ctx, dep, legacyBalloonName(dep))
+}
+
+func (tc targetCluster) deleteBalloon(ctx context.Context, dep *appsv1.Deployment, name string) error {
+	var cur appsv1.Deployment
+	if err := tc.Get(ctx, types.NamespacedName{Namespace: dep.Namespace, Name: name}, &cur); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if cur.Labels[balloonLabel] != dep.Name {
+		return nil // not ours
+	}
+	return client.IgnoreNotFound(tc.Delete(ctx, &cur))
+}
+
+// overprovision keeps dep's balloons at the size spec.overprovisioning asks
+// for, recorded in status.balloonReplicas, and removes them once o is gone.
+// It reports whether status changed.
+func (tc targetCluster) overprovision(ctx context.Context, u *unstructured.Unstructured, o *overprovisionRef,
+	dep *appsv1.Deployment, current, desired, maxReplicas int32) (bool, error) {
+	prev, had, _ := unstructured.NestedInt64(u.Object, "status", "balloonReplicas")
+	if o == nil {
+		if !had {
+			return false, nil
+		}
+		if err := tc.deleteBalloons(ctx, dep); err != nil {
+			return false, err
+		}
+		unstructured.RemoveNestedField(u.Object, "status", "balloonReplicas")
+		return true, nil
+	}
+	n := o.balloonReplicas(current, desired, maxReplicas)
+	if err := tc.syncBalloons(ctx, dep, *o, n); err != nil {
+		return false, err
+	}
+	if had && prev == int64(n) {
+		return false, nil
+	}
+	_ = unstructured.SetNestedField(u.Object, int64(n), "status", "balloonReplicas")
+	return true, nil
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+)
+
+// scalingBudget reads the token bucket kept in status.scalingBudget. A
+// missing or unreadable bucket reads as full (zero updated time).
+func scalingBudget(u *unstructured.Unstructured) (float64, time.Time) {
+	updatedStr, _, _ := unstructured.NestedString(u.Object, "status", "scalingBudget", "updated")
+	updated, err := time.Parse(time.RFC3339, updatedStr)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	tokensStr, _, _ := unstructured.NestedString(u.Object, "status", "scalingBudget", "tokens")
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return 0, time.Time{}
+	}
+	return tokens, updated
+}
+
+// spendBudget records in status what is left of the budget after scaling from
+// current to applied. The bucket may run dry but never goes negative, so an
+// override past the budget (approval, webhook) is not paid back later.
+func spendBudget(u *unstructured.Unstructured, p decision.Policy, tokens float64, updated time.Time, current, applied int32, now time.Time) {
+	left := p.BudgetAvailable(tokens, updated, now) - math.Abs(float64(applied-current))
+	_ = unstructured.SetNestedField(u.Object, map[string]interface{}{
+		"tokens":  strconv.FormatFloat(math.Max(left, 0), 'f', 2, 64),
+		"updated": now.Format(time.RFC3339),
+	}, "status", "scalingBudget")
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+)
+
+// calibrationRef is spec.calibration: instead of hand-tuned targetCPU and
+// targetMem, learn what one replica uses at its rated spec.targetRPS. Per-
+// replica CPU and memory are regressed against per-replica requests/s over
+// Window, and Utilization of the fit at targetRPS becomes the target. The fit
+// describes what a request costs, not how many replicas ran, so recalibrating
+// after the controller has scaled on it lands on the same targets.
+type calibrationRef struct {
+	Window      time.Duration
+	Step        time.Duration // query_range resolution
+	Utilization float64
+	Refresh     time.Duration // how often the fit is recomputed
+	MinSamples  int           // below this the spec targets still apply
+}
+
+// calibration is status.calibration, one replica's fitted usage at targetRPS.
+type calibration struct {
+	CPU     float64 // cores; 0 when CPU doesn't follow requests
+	MemMiB  float64 // 0 when memory doesn't follow requests
+	Samples int
+	Updated time.Time
+}
+
+// perReplicaQueries are the CPU (cores, a rate over cpuWindow), memory
+// (bytes) and requests/s (rpsQ's) per running pod matching podSel in ns.
+func perReplicaQueries(n MetricNames, ns, podSel, cpuWindow, rpsQ string) (cpuQ, memQ, perRPSQ string) {
+	cpuSel := n.selector(n.CPU, ns, podSel)
+	memSel := n.selector(n.Memory, ns, podSel)
+	pods := fmt.Sprintf(`count(count by (%s) (%s))`, n.PodLabel, cpuSel)
+	cpuQ = fmt.Sprintf(`sum(rate(%s[%s])) / %s`, cpuSel, cpuWindow, pods)
+	memQ = fmt.Sprintf(`sum(%s) / count(count by (%s) (%s))`, memSel, n.PodLabel, memSel)
+	perRPSQ = fmt.Sprintf(`(%s) / %s`, rpsQ, pods)
+	return cpuQ, memQ, perRPSQ
+}
+
+// calibrate fits one replica's usage at targetRPS from the history in
+// Prometheus. Each usage series is paired with the requests series step by
+// step ("and on()" keeps only steps both have, so the two stay aligned).
+func calibrate(promURL string, ref calibrationRef, cpuQ, memQ, perRPSQ string, targetRPS float64, now time.Time) (calibration, error) {
+	fit := func(usageQ string) (float64, int, error) {
+		x, err := prom.RangeVector(promURL, fmt.Sprintf(`(%s) and on() (%s)`, perRPSQ, usageQ), ref.Window, ref.Step)
+		if err != nil {
+			return 0, 0, err
+		}
+		y, err := prom.RangeVector(promURL, fmt.Sprintf(`(%s) and on() (%s)`, usageQ, perRPSQ), ref.Window, ref.Step)
+		if err != nil {
+			return 0, 0, err
+		}
+		intercept, slope, n, ok := decision.LinearFit(x, y)
+		if !ok || slope <= 0 {
+			return 0, n, nil
+		}
+		return intercept + slope*targetRPS, n, nil
+	}
+	cpu, samples, err := fit(cpuQ)
+	if err != nil {
+		return calibration{}, err
+	}
+	mem, _, err := fit(memQ)
+	if err != nil {
+		return calibration{}, err
+	}
+	return calibration{
+		CPU:     math.Max(0, cpu),
+		MemMiB:  math.Max(0, mem) / (1024 * 1024),
+		Samples: samples,
+		Updated: now,
+	}, nil
+}
+
+// targets are the per-replica targets the fit implies, zero where it has
+// nothing to say yet.
+func (c calibration) targets(ref calibrationRef) (cpu, memMiB float64) {
+	if c.Samples < ref.MinSamples {
+		return 0, 0
+	}
+	return c.CPU * ref.Utilization, c.MemMiB * ref.Utilization
+}
+
+// readCalibration loads status.calibration. Floats are stored as strings so
+// a whole number survives the round trip through the API server.
+func readCalibration(u *unstructured.Unstructured) (calibration, bool) {
+	m, ok, _ := unstructured.NestedStringMap(u.Object, "status", "calibration")
+	if !ok {
+		return calibration{}, false
+	}
+	var c calibration
+	c.CPU, _ = strconv.ParseFloat(m["cpuPerReplica"], 64)
+	c.MemMiB, _ = strconv.ParseFloat(m["memMiBPerReplica"], 64)
+	c.Samples, _ = strconv.Atoi(m["samples"])
+	c.Updated, _ = time.Parse(time.RFC3339, m["updated"])
+	return c, true
+}
+
+func writeCalibration(u *unstructured.Unstructured, c calibration) {
+	_ = unstructured.SetNestedStringMap(u.Object, map[string]string{
+		"cpuPerReplica":    strconv.FormatFloat(c.CPU, 'g', 6, 64),
+		"memMiBPerReplica": strconv.FormatFloat(c.MemMiB, 'g', 6, 64),
+		"samples":          strconv.Itoa(c.Samples),
+		"updated":          c.Updated.Format(time.RFC3339),
+	}, "status", "calibration")
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+)
+
+// CloudEvent types we emit.
+const (
+	eventTypeDecision  = "dev.malisetti.autoscaler.decision"  // every decision (--cloudevents-sink)
+	eventTypeScaled    = "dev.malisetti.autoscaler.scaled"    // replicas changed (--event-bus)
+	eventTypeSaturated = "dev.malisetti.autoscaler.saturated" // demand passed maxReplicas (--event-bus)
+	eventTypeVertical  = "dev.malisetti.autoscaler.vertical"  // pods resized, or a resize recommended (--event-bus)
+)
+
+// eventSource is the CloudEvent source of everything one autoscaler emits.
+func eventSource(cr types.NamespacedName) string {
+	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/nginxautoscalers/%s",
+		autoscalerGVK.Group, autoscalerGVK.Version, cr.Namespace, cr.Name)
+}
+
+// decisionEvent carries the full reconcile snapshot: inputs, per-signal
+// sizing, what was applied, or why nothing was. Its ID is the decision ID.
+func decisionEvent(cr types.NamespacedName, snap DebugSnapshot) events.Event {
+	return events.Event{
+		ID:      snap.DecisionID,
+		Source:  eventSource(cr),
+		Type:    eventTypeDecision,
+		Subject: snap.Target,
+		Time:    snap.Time,
+		Data:    snap,
+	}
+}
+
+// The events below name the decision they came out of in data.decisionID.
+
+func scaledEvent(cr types.NamespacedName, target, decisionID string, from, to int32, now time.Time) events.Event {
+	return events.Event{
+		ID:      string(uuid.NewUUID()),
+		Source:  eventSource(cr),
+		Type:    eventTypeScaled,
+		Subject: target,
+		Time:    now,
+		Data:    map[string]interface{}{"from": from, "to": to, "decisionID": decisionID},
+	}
+}
+
+func saturationEvent(cr types.NamespacedName, target, decisionID string, needed, maxReplicas int32, now time.Time) events.Event {
+	return events.Event{
+		ID:      string(uuid.NewUUID()),
+		Source:  eventSource(cr),
+		Type:    eventTypeSaturated,
+		Subject: target,
+		Time:    now,
+		Data:    map[string]interface{}{"neededReplicas": needed, "maxReplicas": maxReplicas, "decisionID": decisionID},
+	}
+}
+
+func verticalEvent(cr types.NamespacedName, target, decisionID string, st verticalState) events.Event {
+	return events.Event{
+		ID:      string(uuid.NewUUID()),
+		Source:  eventSource(cr),
+		Type:    eventTypeVertical,
+		Subject: target,
+		Time:    st.Time,
+		Data: map[string]interface{}{
+			"container":  st.Container,
+			"requests":   map[string]string{"cpu": st.CPU, "memory": st.Memory},
+			"resized":    st.Resized,
+			"decisionID": decisionID,
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// targetCluster is where a CR's target Deployment lives: the local cluster,
+// or a spoke cluster reached through a kubeconfig Secret.
+type targetCluster struct {
+	client.Client               // reads and writes the Deployment
+	reader        client.Reader // uncached reads of Pods and Nodes
+}
+
+// remoteClusters hands out clients for kubeconfig Secrets, rebuilding one only
+// when its Secret changes.
+type remoteClusters struct {
+	reader client.Reader // reads Secrets from the API server; we don't cache them
+	scheme *runtime.Scheme
+
+	mu      sync.Mutex
+	clients map[types.NamespacedName]remoteClient
+}
+
+type remoteClient struct {
+	key, resourceVersion string
+	client               client.Client
+}
+
+// get returns a client for the kubeconfig stored under key in Secret ns/name.
+func (rc *remoteClusters) get(ctx context.Context, ns, name, key string) (client.Client, error) {
+	var secret corev1.Secret
+	ref := types.NamespacedName{Namespace: ns, Name: name}
+	if err := rc.reader.Get(ctx, ref, &secret); err != nil {
+		return nil, fmt.Errorf("kubeconfig secret %s: %w", ref, err)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if cached, ok := rc.clients[ref]; ok && cached.key == key && cached.resourceVersion == secret.ResourceVersion {
+		return cached.client, nil
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig secret %s has no key %q", ref, key)
+	}
+	cfg, err := restConfigFromKubeconfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig secret %s: %w", ref, err)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: rc.scheme})
+	if err != nil {
+		return nil, err
+	}
+	if rc.clients == nil {
+		rc.clients = map[types.NamespacedName]remoteClient{}
+	}
+	rc.clients[ref] = remoteClient{key: key, resourceVersion: secret.ResourceVersion, client: c}
+	return c, nil
+}
+
+// restConfigFromKubeconfig loads a kubeconfig from a tenant's Secret. Only
+// credentials carried inline are accepted: exec and auth-provider plugins
+// would run commands in the manager's pod, and file paths (tokenFile,
+// client-certificate, client-key, certificate-authority) would make it read
+// its own files, such as its ServiceAccount token.
+func restConfigFromKubeconfig(data []byte) (*rest.Config, error) {
+	kc, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, err
+	}
+	for name, user := range kc.AuthInfos {
+		switch {
+		case user.Exec != nil:
+			return nil, fmt.Errorf("user %q: exec plugins are not allowed", name)
+		case user.AuthProvider != nil:
+			return nil, fmt.Errorf("user %q: auth-provider plugins are not allowed", name)
+		case user.TokenFile != "":
+			return nil, fmt.Errorf("user %q: tokenFile is not allowed; use token", name)
+		case user.ClientCertificate != "":
+			return nil, fmt.Errorf("user %q: client-certificate is not allowed; use client-certificate-data", name)
+		case user.ClientKey != "":
+			return nil, fmt.Errorf("user %q: client-key is not allowed; use client-key-data", name)
+		}
+	}
+	for name, cluster := range kc.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %q: certificate-authority is not allowed; use certificate-authority-data", name)
+		}
+	}
+	return clientcmd.NewDefaultClientConfig(*kc, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
+
+// targetCluster resolves the cluster behind a kubeconfig Secret in crNS;
+// an empty secret means the local cluster.
+func (r *reconciler) targetCluster(ctx context.Context, crNS, secret, key string) (targetCluster, error) {
+	if secret == "" {
+		return targetCluster{Client: r.Client, reader: r.apiReader}, nil
+	}
+	c, err := r.clusters.get(ctx, crNS, secret, key)
+	if err != nil {
+		return targetCluster{}, err
+	}
+	return targetCluster{Client: c, reader: c}, nil
+}
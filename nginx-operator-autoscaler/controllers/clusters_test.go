@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: spoke
+  cluster: {server: "https://spoke.example.com:6443"}
+users:
+- name: autoscaler
+  user: {token: abc}
+contexts:
+- name: spoke
+  context: {cluster: spoke, user: autoscaler}
+current-context: spoke
+`
+
+func TestRemoteClustersCachesPerSecretVersion(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "spoke"},
+		Data:       map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+	}
+	r, c := newFakeReconciler(t, Options{}, secret)
+	ctx := context.Background()
+
+	first, err := r.clusters.get(ctx, "default", "spoke", "kubeconfig")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	again, _ := r.clusters.get(ctx, "default", "spoke", "kubeconfig")
+	if first != again {
+		t.Fatal("unchanged Secret should reuse the cached client")
+	}
+
+	secret.Data["kubeconfig"] = []byte(testKubeconfig + "\n")
+	if err := c.Update(ctx, secret); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, _ := r.clusters.get(ctx, "default", "spoke", "kubeconfig")
+	if rebuilt == first {
+		t.Fatal("changed Secret should rebuild the client")
+	}
+
+	if _, err := r.clusters.get(ctx, "default", "spoke", "missing"); err == nil {
+		t.Fatal("missing key: want error")
+	}
+}
+
+func TestRemoteClustersRefuseLocalCredentials(t *testing.T) {
+	for name, user := range map[string]string{
+		"exec plugin":   "exec: {apiVersion: client.authentication.k8s.io/v1, command: sh, args: [-c, 'cat /etc/passwd']}",
+		"auth provider": "auth-provider: {name: oidc, config: {}}",
+		"token file":    "tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token",
+		"client key":    "{client-certificate: /etc/tls/tls.crt, client-key: /etc/tls/tls.key}",
+	} {
+		if strings.HasPrefix(user, "{") {
+			user = "user: " + user
+		} else {
+			user = "user: {" + user + "}"
+		}
+		kubeconfig := strings.Replace(testKubeconfig, "user: {token: abc}", user, 1)
+		if _, err := restConfigFromKubeconfig([]byte(kubeconfig)); !strings.Contains(fmt.Sprint(err), "not allowed") {
+			t.Errorf("%s: err = %v, want the kubeconfig refused", name, err)
+		}
+	}
+
+	ca := strings.Replace(testKubeconfig, `cluster: {server: "https://spoke.example.com:6443"}`,
+		`cluster: {server: "https://spoke.example.com:6443", certificate-authority: /etc/ssl/ca.crt}`, 1)
+	if _, err := restConfigFromKubeconfig([]byte(ca)); !strings.Contains(fmt.Sprint(err), "not allowed") {
+		t.Errorf("certificate-authority path: err = %v, want the kubeconfig refused", err)
+	}
+	if _, err := restConfigFromKubeconfig([]byte(testKubeconfig)); err != nil {
+		t.Errorf("inline token: %v", err)
+	}
+}
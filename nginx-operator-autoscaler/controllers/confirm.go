@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+)
+
+// pendingChange reads status.pendingChange: the direction of the change
+// awaiting spec.confirmationDelay and when it was first proposed.
+func pendingChange(u *unstructured.Unstructured) (string, time.Time) {
+	dir, _, _ := unstructured.NestedString(u.Object, "status", "pendingChange", "direction")
+	since, _, _ := unstructured.NestedString(u.Object, "status", "pendingChange", "since")
+	t, _ := time.Parse(time.RFC3339, since)
+	return dir, t
+}
+
+// recordPendingChange stores d's pending change in status.pendingChange,
+// reporting whether it changed. A poll inside the band clears it.
+func recordPendingChange(u *unstructured.Unstructured, d decision.Result) bool {
+	dir, since := pendingChange(u)
+	if dir == d.PendingDirection && since.Equal(d.PendingSince.Truncate(time.Second)) {
+		return false
+	}
+	if d.PendingDirection == "" {
+		if dir == "" {
+			return false
+		}
+		unstructured.RemoveNestedField(u.Object, "status", "pendingChange")
+		return true
+	}
+	_ = unstructured.SetNestedField(u.Object, map[string]interface{}{
+		"direction": d.PendingDirection,
+		"since":     d.PendingSince.Format(time.RFC3339),
+	}, "status", "pendingChange")
+	return true
+}
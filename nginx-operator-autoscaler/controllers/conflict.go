@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// targetIndexKey indexes NginxAutoscalers by "<namespace>/<deployment>" of their target.
+const targetIndexKey = "spec.targetKey"
+
+// targetKeyOf returns the index key of the Deployment a CR targets.
+func targetKeyOf(u *unstructured.Unstructured) string {
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	return targetKeyFor(u.GetNamespace(), parseSpec(spec))
+}
+
+// targetKeyFor is "<namespace>/<name>" of the target Deployment, suffixed with
+// "@<namespace>/<secret>" for Deployments in a remote cluster.
+func targetKeyFor(crNS string, s autoscalerSpec) string {
+	ns := crNS
+	if s.TargetNamespace != "" {
+		ns = s.TargetNamespace
+	}
+	key := ns + "/" + s.TargetDeployment
+	if s.KubeconfigSecret != "" {
+		key += "@" + crNS + "/" + s.KubeconfigSecret
+	}
+	return key
+}
+
+// targetIndexValues indexes a CR under its target. CRs with a
+// spec.targetSelector target nothing themselves; the CRs they generate do.
+func targetIndexValues(obj client.Object) []string {
+	u := obj.(*unstructured.Unstructured)
+	if _, ok, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "targetSelector"); ok {
+		return nil
+	}
+	return []string{targetKeyOf(u)}
+}
+
+func indexTargetKey(ctx context.Context, mgr ctrl.Manager) error {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	return mgr.GetFieldIndexer().IndexField(ctx, u, targetIndexKey, targetIndexValues)
+}
+
+// conflictOwner returns the CR that wins control of targetKey: the oldest one,
+// with name as a tie-breaker so every reconcile agrees on the same winner.
+func (r *reconciler) conflictOwner(ctx context.Context, targetKey string) (*unstructured.Unstructured, int, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	if err := r.List(ctx, list, client.MatchingFields{targetIndexKey: targetKey}); err != nil {
+		return nil, 0, err
+	}
+
+	var owner *unstructured.Unstructured
+	for i := range list.Items {
+		item := &list.Items[i]
+		if owner == nil || olderThan(item, owner) {
+			owner = item
+		}
+	}
+	return owner, len(list.Items), nil
+}
+
+func olderThan(a, b *unstructured.Unstructured) bool {
+	ta, tb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
+	}
+	return a.GetName() < b.GetName()
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ConvertPath is where the NginxAutoscaler conversion webhook is served.
+const ConvertPath = "/convert"
+
+// The served versions. v1alpha1 stays the storage version and the one the
+// controller reads; v1beta1 regroups the same fields (see toV1beta1), so
+// either converts to the other without loss of meaning.
+const (
+	apiVersionV1alpha1 = "autoscaler.malisetti.dev/v1alpha1"
+	apiVersionV1beta1  = "autoscaler.malisetti.dev/v1beta1"
+)
+
+// metricSources maps each entry type of v1beta1 spec.metrics to the v1alpha1
+// field it stands for. A target entry carries that field's value as target;
+// the others carry the field's block under the same key. Their order is the
+// order conversion lists them in.
+var metricSources = []struct {
+	Type, Field string
+	Target      bool
+}{
+	{"CPU", "targetCPU", true},
+	{"Memory", "targetMem", true},
+	{"RequestsPerSecond", "targetRPS", true},
+	{"LatencyMs", "targetLatencyMs", true},
+	{"PodMetric", "podMetric", false},
+	{"SQL", "sql", false},
+	{"PubSub", "pubsub", false},
+	{"NATS", "nats", false},
+	{"Endpoints", "endpoints", false},
+	{"SLO", "slo", false},
+}
+
+// behaviorFields are the v1alpha1 fields v1beta1 groups under spec.behavior,
+// under the same names.
+var behaviorFields = []string{
+	"cooldown", "hysteresisPct", "stepLimit", "rounding", "minChange", "requiredSamples",
+	"confirmationDelay", "scaleDownDelayAfterRollout", "initialDelay", "scalingBudget", "flapDetection",
+}
+
+// convertAutoscaler returns u in apiVersion, a served version. Only the spec
+// differs between versions; everything else is copied as is.
+func convertAutoscaler(u *unstructured.Unstructured, apiVersion string) (*unstructured.Unstructured, error) {
+	out := u.DeepCopy()
+	from := u.GetAPIVersion()
+	if from == apiVersion {
+		return out, nil
+	}
+	spec, _, _ := unstructured.NestedMap(out.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	switch {
+	case from == apiVersionV1alpha1 && apiVersion == apiVersionV1beta1:
+		toV1beta1(spec)
+	case from == apiVersionV1beta1 && apiVersion == apiVersionV1alpha1:
+		toV1alpha1(spec)
+	default:
+		return nil, fmt.Errorf("cannot convert %s to %s", from, apiVersion)
+	}
+	if _, had := u.Object["spec"]; had {
+		out.Object["spec"] = spec
+	}
+	out.SetAPIVersion(apiVersion)
+	return out, nil
+}
+
+// toV1beta1 rewrites a v1alpha1 spec in place: targetDeployment becomes
+// targetRef.name, the per-replica targets and demand sources become
+// spec.metrics entries, and the pacing fields move under spec.behavior.
+// spec.shadow, which overlays the same fields, is rewritten the same way.
+func toV1beta1(spec map[string]interface{}) {
+	if shadow, ok := spec["shadow"].(map[string]interface{}); ok {
+		toV1beta1(shadow)
+	}
+	if name, ok := spec["targetDeployment"]; ok {
+		ref, _ := spec["targetRef"].(map[string]interface{})
+		if ref == nil {
+			ref = map[string]interface{}{}
+		}
+		// targetRef.name wins over targetDeployment in v1alpha1 too
+		if n, _ := ref["name"].(string); n == "" {
+			ref["name"] = name
+		}
+		spec["targetRef"] = ref
+		delete(spec, "targetDeployment")
+	}
+
+	var metrics []interface{}
+	for _, m := range metricSources {
+		v, ok := spec[m.Field]
+		if !ok {
+			continue
+		}
+		entry := map[string]interface{}{"type": m.Type}
+		if m.Target {
+			entry["target"] = v
+		} else {
+			entry[m.Field] = v
+		}
+		metrics = append(metrics, entry)
+		delete(spec, m.Field)
+	}
+	if metrics != nil {
+		spec["metrics"] = metrics
+	}
+
+	behavior := map[string]interface{}{}
+	for _, f := range behaviorFields {
+		if v, ok := spec[f]; ok {
+			behavior[f] = v
+			delete(spec, f)
+		}
+	}
+	if len(behavior) > 0 {
+		spec["behavior"] = behavior
+	}
+}
+
+// toV1alpha1 undoes toV1beta1. targetRef is kept as is; v1alpha1 reads the
+// target's name from it. Metrics entries of an unknown type are dropped (the
+// v1beta1 schema admits none).
+func toV1alpha1(spec map[string]interface{}) {
+	if shadow, ok := spec["shadow"].(map[string]interface{}); ok {
+		toV1alpha1(shadow)
+	}
+	metrics, _ := spec["metrics"].([]interface{})
+	for _, item := range metrics {
+		entry, _ := item.(map[string]interface{})
+		t, _ := entry["type"].(string)
+		for _, m := range metricSources {
+			if m.Type != t {
+				continue
+			}
+			if m.Target {
+				spec[m.Field] = entry["target"]
+			} else {
+				spec[m.Field] = entry[m.Field]
+			}
+		}
+	}
+	delete(spec, "metrics")
+
+	behavior, _ := spec["behavior"].(map[string]interface{})
+	for _, f := range behaviorFields {
+		if v, ok := behavior[f]; ok {
+			spec[f] = v
+		}
+	}
+	delete(spec, "behavior")
+}
+
+// SetupConversionWebhook serves the conversion webhook on mgr's webhook server.
+func SetupConversionWebhook(mgr manager.Manager) {
+	mgr.GetWebhookServer().Register(ConvertPath, http.HandlerFunc(serveConversion))
+}
+
+// serveConversion answers an apiextensions.k8s.io/v1 ConversionReview.
+func serveConversion(w http.ResponseWriter, req *http.Request) {
+	var review apiextensionsv1.ConversionReview
+	if err := json.NewDecoder(req.Body).Decode(&review); err != nil || review.Request == nil {
+		http.Error(w, "expected a ConversionReview request", http.StatusBadRequest)
+		return
+	}
+	resp := &apiextensionsv1.ConversionResponse{UID: review.Request.UID, Result: metav1.Status{Status: metav1.StatusSuccess}}
+	for _, obj := range review.Request.Objects {
+		raw, err := convertRaw(obj.Raw, review.Request.DesiredAPIVersion)
+		if err != nil {
+			resp.ConvertedObjects, resp.Result = nil, metav1.Status{Status: metav1.StatusFailure, Message: err.Error()}
+			break
+		}
+		resp.ConvertedObjects = append(resp.ConvertedObjects, runtime.RawExtension{Raw: raw})
+	}
+	review.Request, review.Response = nil, resp
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(review)
+}
+
+func convertRaw(raw []byte, apiVersion string) ([]byte, error) {
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	converted, err := convertAutoscaler(u, apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	return converted.MarshalJSON()
+}
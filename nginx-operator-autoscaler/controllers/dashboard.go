@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//go:embed dashboard.html
+var dashboardHTML string
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+// dashboardDecisions is how many of the latest decisions a row lists.
+const dashboardDecisions = 8
+
+// Sparkline size, in SVG user units.
+const sparkWidth, sparkHeight = 240, 40
+
+type dashboardRow struct {
+	Name       string
+	Target     string
+	Current    int32
+	Desired    int32
+	Min, Max   int32
+	Demand     string // SVG polyline points: desired replicas per decision
+	Replicas   string // and the replicas running at the time
+	Decisions  []dashboardDecision
+	Conditions []metav1.Condition
+}
+
+type dashboardDecision struct {
+	Time    string
+	ID      string
+	Current int32
+	Desired int32
+	Outcome string
+}
+
+// NewDashboard serves a read-only HTML page of every autoscaler: replicas
+// wanted against replicas run over the last decisions, those decisions, and
+// the CR's conditions. It lists CRs through reader and takes decisions from
+// debug, so CRs this controller has not reconciled yet show no history.
+func NewDashboard(reader client.Reader, debug *DebugStore) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/" {
+			http.NotFound(w, req)
+			return
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+		if err := reader.List(req.Context(), list); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rows := make([]dashboardRow, 0, len(list.Items))
+		for i := range list.Items {
+			rows = append(rows, dashboardRowFor(&list.Items[i], debug))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = dashboardTemplate.Execute(w, rows)
+	})
+}
+
+func dashboardRowFor(u *unstructured.Unstructured, debug *DebugStore) dashboardRow {
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	s := parseSpec(spec)
+	key := u.GetNamespace() + "/" + u.GetName()
+	row := dashboardRow{
+		Name:       key,
+		Target:     s.TargetDeployment,
+		Min:        s.MinReplicas,
+		Max:        s.MaxReplicas,
+		Conditions: getConditions(u),
+	}
+	if cur, ok, _ := unstructured.NestedInt64(u.Object, "status", "currentReplicas"); ok {
+		row.Current = int32(cur)
+	}
+	if des, ok, _ := unstructured.NestedInt64(u.Object, "status", "desiredReplicas"); ok {
+		row.Desired = int32(des)
+	}
+
+	history := debug.History(key)
+	demand := make([]int32, len(history))
+	replicas := make([]int32, len(history))
+	for i, snap := range history {
+		demand[i], replicas[i] = snap.Desired, snap.Current
+	}
+	top := max(row.Max, 1)
+	for _, v := range demand {
+		top = max(top, v)
+	}
+	row.Demand, row.Replicas = sparkPoints(demand, top), sparkPoints(replicas, top)
+	if n := len(history); n > 0 {
+		last := history[n-1]
+		if last.Target != "" {
+			row.Target = last.Target
+		}
+		row.Current, row.Desired = last.Current, last.Desired
+	}
+	for i := len(history) - 1; i >= 0 && len(row.Decisions) < dashboardDecisions; i-- {
+		snap := history[i]
+		row.Decisions = append(row.Decisions, dashboardDecision{
+			Time:    snap.Time.UTC().Format("15:04:05"),
+			ID:      snap.DecisionID,
+			Current: snap.Current,
+			Desired: snap.Desired,
+			Outcome: decisionOutcome(snap),
+		})
+	}
+	return row
+}
+
+// sparkPoints scales values (one per decision, oldest first) into the
+// points of a sparkWidth x sparkHeight polyline whose top is top.
+func sparkPoints(values []int32, top int32) string {
+	if len(values) == 0 {
+		return ""
+	}
+	step := 0.0
+	if len(values) > 1 {
+		step = float64(sparkWidth) / float64(len(values)-1)
+	}
+	var b strings.Builder
+	for i, v := range values {
+		y := float64(sparkHeight) * (1 - float64(v)/float64(top))
+		fmt.Fprintf(&b, "%.1f,%.1f ", float64(i)*step, y)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// decisionOutcome is what came of a decision, in a few words.
+func decisionOutcome(snap DebugSnapshot) string {
+	switch {
+	case snap.Error != "":
+		return "error: " + snap.Error
+	case snap.Applied > 0:
+		return fmt.Sprintf("scaled to %d", snap.Applied)
+	case snap.SkipReason != "":
+		return snap.SkipReason
+	}
+	return "no change"
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DebugSnapshot is what one reconcile of one CR computed, kept for /debug.
+type DebugSnapshot struct {
+	Autoscaler        string             `json:"autoscaler"`
+	DecisionID        string             `json:"decisionID,omitempty"` // also on its CloudEvents, log record and status
+	Target            string             `json:"target,omitempty"`
+	Revision          string             `json:"revision,omitempty"`     // the target's deployment.kubernetes.io/revision
+	TemplateHash      string             `json:"templateHash,omitempty"` // and its pod-template-hash
+	Time              time.Time          `json:"time"`
+	CPUCores          float64            `json:"cpuCores"`
+	CPUSlope          float64            `json:"cpuSlope,omitempty"`
+	MemMiB            float64            `json:"memMiB"`
+	RPS               float64            `json:"rps,omitempty"`
+	LatencyMs         float64            `json:"latencyMs,omitempty"`
+	ExternalMetrics   map[string]float64 `json:"externalMetrics,omitempty"` // by source: podMetric, sql
+	External          float64            `json:"external,omitempty"`        // their demand, in replicas
+	ErrorRatio        float64            `json:"errorRatio,omitempty"`
+	DrainLoad         float64            `json:"drainLoad,omitempty"`
+	BurnRate          float64            `json:"burnRate,omitempty"`
+	CPUReplicas       int32              `json:"cpuReplicas"`
+	MemReplicas       int32              `json:"memReplicas"`
+	RPSReplicas       int32              `json:"rpsReplicas,omitempty"`
+	LatencyReplicas   int32              `json:"latencyReplicas,omitempty"`
+	SLOReplicas       int32              `json:"sloReplicas,omitempty"`
+	TrendReplicas     int32              `json:"trendReplicas,omitempty"`
+	ExternalReplicas  int32              `json:"externalReplicas,omitempty"`
+	Current           int32              `json:"current"`
+	Desired           int32              `json:"desired"`
+	ShadowDesired     int32              `json:"shadowDesired,omitempty"`
+	ShadowReplicas    int32              `json:"shadowReplicas,omitempty"`
+	ShadowReason      string             `json:"shadowReason,omitempty"`
+	ReplicaHourlyCost float64            `json:"replicaHourlyCost,omitempty"`
+	SpotFraction      float64            `json:"spotFraction,omitempty"`
+	Zones             int32              `json:"zones,omitempty"`
+	LimitedBy         string             `json:"limitedBy,omitempty"`
+	Applied           int32              `json:"applied,omitempty"`
+	SkipReason        string             `json:"skipReason,omitempty"`
+	LastScaleTime     string             `json:"lastScaleTime,omitempty"`
+	CooldownRemaining string             `json:"cooldownRemaining,omitempty"`
+	FlapDamped        bool               `json:"flapDamped,omitempty"` // band and cooldown widened by flapDetection
+	Error             string             `json:"error,omitempty"`
+}
+
+// decisionLogValues flattens snap into key/value pairs named as in its JSON,
+// for the one structured log record written per decision.
+func decisionLogValues(snap DebugSnapshot) []interface{} {
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		return []interface{}{"decisionID", snap.DecisionID}
+	}
+	var m map[string]interface{}
+	_ = json.Unmarshal(raw, &m)
+	delete(m, "autoscaler") // already on the logger
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kv := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		kv = append(kv, k, m[k])
+	}
+	return kv
+}
+
+// maxDebugHistory bounds the snapshots kept per CR for the dashboard.
+const maxDebugHistory = 60
+
+// DebugStore keeps the latest DebugSnapshot per CR, and the few before it for
+// the dashboard. A nil store records nothing.
+type DebugStore struct {
+	mu      sync.RWMutex
+	snaps   map[string]DebugSnapshot
+	history map[string][]DebugSnapshot
+}
+
+func NewDebugStore() *DebugStore {
+	return &DebugStore{snaps: map[string]DebugSnapshot{}, history: map[string][]DebugSnapshot{}}
+}
+
+func (d *DebugStore) record(s DebugSnapshot) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.snaps[s.Autoscaler] = s
+	h := append(d.history[s.Autoscaler], s)
+	if len(h) > maxDebugHistory {
+		h = h[len(h)-maxDebugHistory:]
+	}
+	d.history[s.Autoscaler] = h
+}
+
+func (d *DebugStore) forget(key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.snaps, key)
+	delete(d.history, key)
+}
+
+// History returns the last snapshots of the CR key ("namespace/name"),
+// oldest first.
+func (d *DebugStore) History(key string) []DebugSnapshot {
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return append([]DebugSnapshot(nil), d.history[key]...)
+}
+
+// Snapshots returns all snapshots ordered by CR key.
+func (d *DebugStore) Snapshots() []DebugSnapshot {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]DebugSnapshot, 0, len(d.snaps))
+	for _, s := range d.snaps {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Autoscaler < out[j].Autoscaler })
+	return out
+}
+
+// ServeHTTP dumps every snapshot as JSON.
+func (d *DebugStore) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(d.Snapshots())
+}
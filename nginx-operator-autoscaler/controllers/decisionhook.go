@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decisionhook"
+)
+
+// webhookRef is spec.decisionWebhook.
+type webhookRef struct {
+	URL      string
+	Timeout  time.Duration
+	FailOpen bool // failurePolicy: Ignore applies the proposal when the webhook can't be reached
+}
+
+// reviewScale lets spec.decisionWebhook approve, veto or override a proposed
+// change. It returns the replica count to apply, or a skip reason when
+// nothing should be applied this cycle.
+func reviewScale(ctx context.Context, s autoscalerSpec, req decisionhook.Request) (int32, string) {
+	logger := log.FromContext(ctx)
+	// JSON cannot carry NaN (a failed error-guard query); leave such metrics out
+	for k, v := range req.Metrics {
+		if math.IsNaN(v) {
+			delete(req.Metrics, k)
+		}
+	}
+
+	resp, err := decisionhook.Review(ctx, s.DecisionWebhook.URL, s.DecisionWebhook.Timeout, req)
+	switch {
+	case err != nil && s.DecisionWebhook.FailOpen:
+		logger.Error(err, "decision webhook failed; applying proposal (failurePolicy Ignore)")
+		return req.Proposed, ""
+	case err != nil:
+		logger.Error(err, "decision webhook failed; holding replicas")
+		return req.Current, "WebhookFailed"
+	case !resp.Allowed:
+		logger.Info("decision webhook vetoed scale", "proposed", req.Proposed, "reason", resp.Reason)
+		return req.Current, "WebhookVetoed"
+	case resp.Replicas == nil:
+		return req.Proposed, ""
+	}
+
+	// Overrides still respect the CR's bounds
+	replicas := clampReplicas(*resp.Replicas, s.MinReplicas, s.MaxReplicas)
+	logger.Info("decision webhook overrode replicas", "proposed", req.Proposed, "override", replicas, "reason", resp.Reason)
+	if replicas == req.Current {
+		return req.Current, "WebhookVetoed"
+	}
+	return replicas, ""
+}
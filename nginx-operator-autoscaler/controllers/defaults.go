@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	defaultsGVK = schema.GroupVersionKind{
+		Group:   "autoscaler.malisetti.dev",
+		Version: "v1alpha1",
+		Kind:    "AutoscalerDefaults",
+	}
+)
+
+// defaultsName is the well-known name of the per-namespace AutoscalerDefaults object.
+const defaultsName = "default"
+
+// namespaceDefaults is the AutoscalerDefaults object of one namespace.
+// Values are layered under every NginxAutoscaler spec in that namespace,
+// and limits bound what those specs may ask for.
+type namespaceDefaults struct {
+	Values map[string]interface{}
+	Limits map[string]interface{}
+}
+
+// loadNamespaceDefaults fetches the AutoscalerDefaults named "default" in ns.
+// A missing object (or a cluster without the CRD) yields empty defaults.
+func loadNamespaceDefaults(ctx context.Context, c client.Reader, ns string) (namespaceDefaults, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(defaultsGVK)
+	if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: defaultsName}, u); err != nil {
+		if client.IgnoreNotFound(err) == nil || meta.IsNoMatchError(err) {
+			return namespaceDefaults{}, nil
+		}
+		return namespaceDefaults{}, err
+	}
+
+	values, _, _ := unstructured.NestedMap(u.Object, "spec")
+	limits, _, _ := unstructured.NestedMap(u.Object, "spec", "limits")
+	delete(values, "limits")
+	return namespaceDefaults{Values: values, Limits: limits}, nil
+}
+
+// mergeSpec returns spec layered over the namespace defaults: keys set on the
+// CR win, anything unset falls through to the defaults object.
+func (d namespaceDefaults) mergeSpec(spec map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(d.Values)+len(spec))
+	for k, v := range d.Values {
+		out[k] = v
+	}
+	for k, v := range spec {
+		out[k] = v
+	}
+	return out
+}
+
+// applyLimits clamps a parsed spec into the namespace admin's bounds.
+func (d namespaceDefaults) applyLimits(s autoscalerSpec) autoscalerSpec {
+	if v, ok := limitI32(d.Limits, "maxReplicas"); ok && s.MaxReplicas > v {
+		s.MaxReplicas = v
+	}
+	if v, ok := limitI32(d.Limits, "minReplicas"); ok && s.MinReplicas < v {
+		s.MinReplicas = v
+	}
+	if v, ok := d.Limits["minCooldown"].(string); ok {
+		if min := parseDur(v, 0); s.Cooldown < min {
+			s.Cooldown = min
+		}
+	}
+	if v, ok := d.Limits["minPollInterval"].(string); ok {
+		if min := parseDur(v, 0); s.PollInterval < min {
+			s.PollInterval = min
+		}
+	}
+	if s.MinReplicas > s.MaxReplicas {
+		s.MinReplicas = s.MaxReplicas
+	}
+	if s.Shadow != nil {
+		shadow := d.applyLimits(*s.Shadow)
+		s.Shadow = &shadow
+	}
+	return s
+}
+
+func limitI32(m map[string]interface{}, key string) (int32, bool) {
+	switch v := m[key].(type) {
+	case int64:
+		return int32(v), true
+	case float64:
+		return int32(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+)
+
+// podDeletionCostAnnotation ranks pods for the ReplicaSet controller on
+// scale-down: the lowest cost goes first, and unannotated pods count as 0.
+const podDeletionCostAnnotation = "controller.kubernetes.io/pod-deletion-cost"
+
+// podCPUQuery is the CPU (cores, a rate over window) of each pod matching
+// podSel in ns, by pod name in the "pod" label whatever n calls it.
+func podCPUQuery(n MetricNames, ns, podSel, window string) string {
+	q := fmt.Sprintf(`sum by (%s) (rate(%s[%s]))`, n.PodLabel, n.selector(n.CPU, ns, podSel), window)
+	if n.PodLabel != "pod" {
+		q = fmt.Sprintf(`label_replace(%s, "pod", "$1", "%s", "(.*)")`, q, n.PodLabel)
+	}
+	return q
+}
+
+// markLeastLoaded gives the n running pods of dep with the lowest load (a
+// per-pod query such as podCPUQuery) a negative pod-deletion-cost, the idlest
+// lowest, so the coming scale-down removes them instead of the newest pods.
+// Pods Prometheus has no sample for yet count as idle. A negative cost left
+// on any other pod by an earlier, abandoned scale-down is removed.
+func (tc targetCluster) markLeastLoaded(ctx context.Context, dep *appsv1.Deployment, promURL, loadQuery string, n int) error {
+	load, err := prom.VectorByLabel(promURL, loadQuery, "pod")
+	if err != nil {
+		return err
+	}
+	pods, err := tc.runningPods(ctx, dep)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(pods, func(i, j int) bool { return load[pods[i].Name] < load[pods[j].Name] })
+
+	for i := range pods {
+		pod := &pods[i]
+		cost := ""
+		if i < n {
+			cost = strconv.Itoa(i - n)
+		}
+		prev, has := pod.Annotations[podDeletionCostAnnotation]
+		if prev == cost || (cost == "" && (!has || !isNegative(prev))) {
+			continue
+		}
+		patch := client.MergeFrom(pod.DeepCopy())
+		if cost == "" {
+			delete(pod.Annotations, podDeletionCostAnnotation)
+		} else {
+			if pod.Annotations == nil {
+				pod.Annotations = map[string]string{}
+			}
+			pod.Annotations[podDeletionCostAnnotation] = cost
+		}
+		if err := tc.Patch(ctx, pod, patch); err != nil {
+			return fmt.Errorf("annotate pod %s: %w", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+func isNegative(cost string) bool {
+	v, err := strconv.Atoi(cost)
+	return err == nil && v < 0
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// spec.targetRef.apiVersion/kind naming an OpenShift DeploymentConfig.
+const (
+	deploymentConfigAPIVersion = "apps.openshift.io/v1"
+	deploymentConfigKind       = "DeploymentConfig"
+)
+
+var deploymentConfigGVK = schema.GroupVersionKind{Group: "apps.openshift.io", Version: "v1", Kind: "DeploymentConfig"}
+
+// deploymentConfig reads the DeploymentConfig at key, uncached, into dep as
+// a Deployment: same metadata, replicas, template and status counts, its map
+// selector as matchLabels and status.latestVersion as the revision. dep
+// keeps the DC's apiVersion and kind so writes can find their way back.
+func (tc targetCluster) deploymentConfig(ctx context.Context, key types.NamespacedName, dep *appsv1.Deployment) error {
+	dc := &unstructured.Unstructured{}
+	dc.SetGroupVersionKind(deploymentConfigGVK)
+	if err := tc.reader.Get(ctx, key, dc); err != nil {
+		return err
+	}
+	var cfg struct {
+		Spec struct {
+			Replicas *int32                 `json:"replicas"`
+			Selector map[string]string      `json:"selector"`
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+		Status struct {
+			LatestVersion     int64 `json:"latestVersion"`
+			Replicas          int32 `json:"replicas"`
+			UpdatedReplicas   int32 `json:"updatedReplicas"`
+			ReadyReplicas     int32 `json:"readyReplicas"`
+			AvailableReplicas int32 `json:"availableReplicas"`
+		} `json:"status"`
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(dc.Object, &cfg); err != nil {
+		return fmt.Errorf("deploymentconfig %s: %w", key, err)
+	}
+	if len(cfg.Spec.Selector) == 0 {
+		return fmt.Errorf("deploymentconfig %s has no selector", key)
+	}
+	annotations := dc.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[revisionAnnotation] = strconv.FormatInt(cfg.Status.LatestVersion, 10)
+	*dep = appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: deploymentConfigAPIVersion, Kind: deploymentConfigKind},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         dc.GetNamespace(),
+			Name:              dc.GetName(),
+			UID:               dc.GetUID(),
+			ResourceVersion:   dc.GetResourceVersion(),
+			Generation:        dc.GetGeneration(),
+			CreationTimestamp: dc.GetCreationTimestamp(),
+			Labels:            dc.GetLabels(),
+			Annotations:       annotations,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: cfg.Spec.Replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: cfg.Spec.Selector},
+			Template: cfg.Spec.Template,
+		},
+		Status: appsv1.DeploymentStatus{
+			Replicas:          cfg.Status.Replicas,
+			UpdatedReplicas:   cfg.Status.UpdatedReplicas,
+			ReadyReplicas:     cfg.Status.ReadyReplicas,
+			AvailableReplicas: cfg.Status.AvailableReplicas,
+		},
+	}
+	return nil
+}
+
+// isDeploymentConfig reports whether dep is a DeploymentConfig read by
+// deploymentConfig.
+func isDeploymentConfig(dep *appsv1.Deployment) bool {
+	return dep.APIVersion == deploymentConfigAPIVersion && dep.Kind == deploymentConfigKind
+}
+
+// dcObject is an empty DeploymentConfig with dep's name, to patch.
+func dcObject(dep *appsv1.Deployment) *unstructured.Unstructured {
+	dc := &unstructured.Unstructured{}
+	dc.SetGroupVersionKind(deploymentConfigGVK)
+	dc.SetNamespace(dep.Namespace)
+	dc.SetName(dep.Name)
+	return dc
+}
+
+// patchDeploymentConfig sends patch, a diff of metadata or the pod template
+// computed against dep, to the DeploymentConfig dep stands for; both kinds
+// keep those at the same paths.
+func (tc targetCluster) patchDeploymentConfig(ctx context.Context, dep *appsv1.Deployment, patch client.Patch) error {
+	data, err := patch.Data(dep)
+	if err != nil {
+		return err
+	}
+	return tc.Patch(ctx, dcObject(dep), client.RawPatch(types.MergePatchType, data))
+}
+
+// scaleDeploymentConfig sets the DeploymentConfig's replicas through its
+// scale subresource, as oc scale does.
+func (tc targetCluster) scaleDeploymentConfig(ctx context.Context, dep *appsv1.Deployment, replicas int32) error {
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{"replicas": replicas}})
+	if err != nil {
+		return err
+	}
+	if err := tc.SubResource("scale").Patch(ctx, dcObject(dep), client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return err
+	}
+	dep.Spec.Replicas = &replicas
+	return nil
+}
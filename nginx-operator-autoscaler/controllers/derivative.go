@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+)
+
+// derivativeRef scales ahead of a CPU ramp: when usage climbs faster than
+// CPUPerMinute, replicas are sized for the usage expected Lookahead from now
+// (roughly how long a new pod takes to become ready).
+type derivativeRef struct {
+	CPUPerMinute float64 // cores per minute
+	Lookahead    time.Duration
+	Window       string // PromQL duration the slope is fitted over
+}
+
+// cpuSlopeQuery is the per-minute slope of cpuQ, fitted by least squares over
+// window so a single noisy scrape doesn't read as a ramp.
+func cpuSlopeQuery(cpuQ, window string) string {
+	return fmt.Sprintf(`deriv((%s)[%s:30s]) * 60`, cpuQ, window)
+}
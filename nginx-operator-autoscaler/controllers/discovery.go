@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// wellKnownPromServices are tried in order when a CR leaves promURL unset.
+var wellKnownPromServices = []struct {
+	namespace, name string
+	port            int
+}{
+	{"monitoring", "kube-prometheus-stack-prometheus", 9090},
+	{"monitoring", "prometheus-k8s", 9090},
+	{"monitoring", "prometheus-operated", 9090},
+	{"prometheus", "prometheus-server", 80},
+	{"monitoring", "prometheus-server", 80},
+}
+
+var prometheusListGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "PrometheusList",
+}
+
+// discoveryTTL bounds how long a discovered endpoint is reused before re-checking.
+const discoveryTTL = 5 * time.Minute
+
+// promDiscoverer finds a Prometheus endpoint in the cluster. It reads through
+// the API server rather than the cache so we don't keep every Service in memory.
+type promDiscoverer struct {
+	reader client.Reader
+	clock  clock.PassiveClock
+
+	mu  sync.Mutex
+	url string
+	at  time.Time
+}
+
+func (d *promDiscoverer) discover(ctx context.Context) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.url != "" && d.clock.Since(d.at) < discoveryTTL {
+		return d.url, nil
+	}
+
+	url, err := d.lookup(ctx)
+	if err != nil {
+		return "", err
+	}
+	d.url, d.at = url, d.clock.Now()
+	return url, nil
+}
+
+func (d *promDiscoverer) lookup(ctx context.Context) (string, error) {
+	// 1) Well-known Service names from the common Helm charts
+	for _, c := range wellKnownPromServices {
+		var svc corev1.Service
+		err := d.reader.Get(ctx, types.NamespacedName{Namespace: c.namespace, Name: c.name}, &svc)
+		if err == nil {
+			return fmt.Sprintf("http://%s.%s.svc:%d", c.name, c.namespace, c.port), nil
+		}
+		if client.IgnoreNotFound(err) != nil {
+			return "", err
+		}
+	}
+
+	// 2) Prometheus-operator Prometheus CRs, served by the prometheus-operated Service
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(prometheusListGVK)
+	if err := d.reader.List(ctx, list); err != nil && !meta.IsNoMatchError(err) {
+		return "", err
+	}
+	if len(list.Items) > 0 {
+		return fmt.Sprintf("http://prometheus-operated.%s.svc:9090", list.Items[0].GetNamespace()), nil
+	}
+
+	return "", fmt.Errorf("no Prometheus found; set spec.promURL")
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+)
+
+// drainRef is spec.drain: scale-down waits until some pod's drain metric (by
+// default nginx active connections) is at or under MaxPerPod, and then
+// removes that pod alone.
+type drainRef struct {
+	Query     string // per-pod PromQL, labelled by pod; empty means nginx_connections_active
+	MaxPerPod float64
+}
+
+// drainQuery is the drain metric of each of the target's pods.
+func drainQuery(d drainRef, ns, podSel string) string {
+	if d.Query != "" {
+		return d.Query
+	}
+	return fmt.Sprintf(`sum by (pod) (nginx_connections_active{namespace="%s",pod=~"%s"})`, ns, podSel)
+}
+
+// lowestLoad is the smallest per-pod value, or NaN without any.
+func lowestLoad(byPod map[string]float64) float64 {
+	low := math.NaN()
+	for _, v := range byPod {
+		if math.IsNaN(low) || v < low {
+			low = v
+		}
+	}
+	return low
+}
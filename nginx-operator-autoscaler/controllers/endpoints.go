@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// endpointsRef is spec.endpoints: the target follows another tier, one
+// replica per TargetPerReplica ready endpoints of Service (in the target's
+// namespace), e.g. one cache pod per two app pods.
+type endpointsRef struct {
+	Service          string
+	TargetPerReplica float64
+}
+
+// readyEndpoints counts the ready endpoints of service across its
+// EndpointSlices. A pod is counted once even when it appears in several
+// slices (one per address family on dual-stack Services).
+func (tc targetCluster) readyEndpoints(ctx context.Context, ns, service string) (float64, error) {
+	var slices discoveryv1.EndpointSliceList
+	if err := tc.reader.List(ctx, &slices, client.InNamespace(ns), client.MatchingLabels{discoveryv1.LabelServiceName: service}); err != nil {
+		return 0, fmt.Errorf("spec.endpoints: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, s := range slices.Items {
+		for _, ep := range s.Endpoints {
+			if ep.Conditions.Ready != nil && !*ep.Conditions.Ready {
+				continue
+			}
+			key := ""
+			switch {
+			case ep.TargetRef != nil:
+				key = ep.TargetRef.Kind + "/" + ep.TargetRef.Name
+			case len(ep.Addresses) > 0:
+				key = ep.Addresses[0]
+			default:
+				continue
+			}
+			seen[key] = true
+		}
+	}
+	return float64(len(seen)), nil
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WorkloadRecommendation is one autoscaler's line in a recommendations
+// export: how it is configured, the peak demand it saw and what the
+// controller would change, for capacity-planning and FinOps tooling.
+// Suggestions are omitted until the feature producing them (spec.learning,
+// spec.calibration, spec.tuning) has enough history.
+type WorkloadRecommendation struct {
+	Autoscaler string          `json:"autoscaler"`
+	Target     string          `json:"target"`
+	Replicas   int32           `json:"replicas"`
+	Config     WorkloadConfig  `json:"config"`
+	PeakDemand *PeakDemand     `json:"peakDemand,omitempty"`
+	Suggested  SuggestedConfig `json:"suggested"`
+}
+
+// WorkloadConfig is the effective sizing configuration of an autoscaler.
+type WorkloadConfig struct {
+	MinReplicas   int32   `json:"minReplicas"`
+	MaxReplicas   int32   `json:"maxReplicas"`
+	TargetCPU     float64 `json:"targetCPU,omitempty"` // cores per replica
+	TargetMemMiB  float64 `json:"targetMemMiB,omitempty"`
+	HysteresisPct float64 `json:"hysteresisPct"`
+	Cooldown      string  `json:"cooldown"`
+	StepLimit     int32   `json:"stepLimit"`
+}
+
+// PeakDemand is status.peakDemand: the most replicas any signal asked for
+// over the last week, and the usage behind it.
+type PeakDemand struct {
+	Replicas int32     `json:"replicas"`
+	CPUCores float64   `json:"cpuCores"`
+	MemMiB   float64   `json:"memMiB"`
+	Time     time.Time `json:"time"`
+}
+
+// SuggestedConfig collects the settings the controller suggests.
+type SuggestedConfig struct {
+	MinReplicas   int32   `json:"minReplicas,omitempty"`   // spec.learning
+	MaxReplicas   int32   `json:"maxReplicas,omitempty"`   // spec.learning
+	TargetCPU     float64 `json:"targetCPU,omitempty"`     // spec.calibration
+	TargetMemMiB  float64 `json:"targetMemMiB,omitempty"`  // spec.calibration
+	HysteresisPct float64 `json:"hysteresisPct,omitempty"` // spec.tuning
+	Cooldown      string  `json:"cooldown,omitempty"`      // spec.tuning
+	StepLimit     int32   `json:"stepLimit,omitempty"`     // spec.tuning
+}
+
+// ExportRecommendations reads every autoscaler in namespace (all if empty)
+// and returns its recommendation, ordered as listed.
+func ExportRecommendations(ctx context.Context, reader client.Reader, namespace string) ([]WorkloadRecommendation, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	if err := reader.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	out := make([]WorkloadRecommendation, 0, len(list.Items))
+	for i := range list.Items {
+		out = append(out, workloadRecommendation(&list.Items[i]))
+	}
+	return out, nil
+}
+
+func workloadRecommendation(u *unstructured.Unstructured) WorkloadRecommendation {
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	s := parseSpec(spec)
+	rec := WorkloadRecommendation{
+		Autoscaler: u.GetNamespace() + "/" + u.GetName(),
+		Target:     s.TargetDeployment,
+		Config: WorkloadConfig{
+			MinReplicas:   s.MinReplicas,
+			MaxReplicas:   s.MaxReplicas,
+			TargetCPU:     s.TargetCPU,
+			TargetMemMiB:  s.TargetMem,
+			HysteresisPct: s.HysteresisPct,
+			Cooldown:      s.Cooldown.String(),
+			StepLimit:     s.StepLimit,
+		},
+	}
+	if cur, ok, _ := unstructured.NestedInt64(u.Object, "status", "currentReplicas"); ok {
+		rec.Replicas = int32(cur)
+	}
+	num := func(m map[string]interface{}, key string) float64 {
+		str, _ := m[key].(string)
+		v, _ := strconv.ParseFloat(str, 64)
+		return v
+	}
+
+	if peak, ok, _ := unstructured.NestedMap(u.Object, "status", "peakDemand"); ok {
+		replicas, _ := peak["replicas"].(int64)
+		at, _ := peak["time"].(string)
+		t, _ := time.Parse(time.RFC3339, at)
+		rec.PeakDemand = &PeakDemand{Replicas: int32(replicas), CPUCores: num(peak, "cpuCores"), MemMiB: num(peak, "memMiB"), Time: t}
+	}
+	if l, ok := readLearning(u); ok && !l.Completed.IsZero() {
+		rec.Suggested.MinReplicas, rec.Suggested.MaxReplicas = l.MinReplicas, l.MaxReplicas
+	}
+	if cal, ok, _ := unstructured.NestedMap(u.Object, "status", "calibration"); ok {
+		rec.Suggested.TargetCPU, rec.Suggested.TargetMemMiB = num(cal, "cpuPerReplica"), num(cal, "memMiBPerReplica")
+	}
+	if t, ok, _ := unstructured.NestedMap(u.Object, "status", "recommendations"); ok {
+		rec.Suggested.HysteresisPct = num(t, "hysteresisPct")
+		rec.Suggested.Cooldown, _ = t["cooldown"].(string)
+		rec.Suggested.StepLimit = int32(num(t, "stepLimit"))
+	}
+	return rec
+}
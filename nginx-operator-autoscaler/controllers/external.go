@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// hasExternal reports whether the spec scales on any external source.
+func (s autoscalerSpec) hasExternal() bool {
+	return s.PodMetric != nil || s.SQL != nil || s.PubSub != nil || s.NATS != nil || s.Endpoints != nil
+}
+
+// externalMetrics reads every external source the spec sets, keyed by its
+// spec field. The first failure fails the lot: sizing on the sources that
+// happened to answer could scale down a backlog nobody is reading.
+func (r *reconciler) externalMetrics(ctx context.Context, tc targetCluster, dep *appsv1.Deployment, crNS string, s autoscalerSpec) (map[string]float64, error) {
+	values := map[string]float64{}
+	if s.PodMetric != nil {
+		v, err := tc.podMetric(ctx, dep, *s.PodMetric)
+		if err != nil {
+			return nil, err
+		}
+		values["podMetric"] = v
+	}
+	if s.SQL != nil {
+		v, err := r.sqlMetric(ctx, crNS, *s.SQL)
+		if err != nil {
+			return nil, err
+		}
+		values["sql"] = v
+	}
+	if s.PubSub != nil {
+		v, err := pubsubMetric(ctx, *s.PubSub)
+		if err != nil {
+			return nil, err
+		}
+		values["pubsub"] = v
+	}
+	if s.NATS != nil {
+		v, err := natsMetric(ctx, *s.NATS)
+		if err != nil {
+			return nil, err
+		}
+		values["nats"] = v
+	}
+	if s.Endpoints != nil {
+		v, err := tc.readyEndpoints(ctx, dep.Namespace, s.Endpoints.Service)
+		if err != nil {
+			return nil, err
+		}
+		values["endpoints"] = v
+	}
+	return values, nil
+}
+
+// externalDemand is the replicas the external sources ask for: each value
+// over its source's per-replica target, the largest winning. Decide sizes
+// it at one per replica, with headroom like any other signal.
+func (s autoscalerSpec) externalDemand(values map[string]float64) float64 {
+	var demand float64
+	if s.PodMetric != nil {
+		demand = max(demand, values["podMetric"]/s.PodMetric.TargetPerReplica)
+	}
+	if s.SQL != nil {
+		demand = max(demand, values["sql"]/s.SQL.TargetPerReplica)
+	}
+	if s.PubSub != nil {
+		demand = max(demand, values["pubsub"]/s.PubSub.TargetPerReplica)
+	}
+	if s.NATS != nil {
+		demand = max(demand, values["nats"]/s.NATS.TargetPerReplica)
+	}
+	if s.Endpoints != nil {
+		demand = max(demand, values["endpoints"]/s.Endpoints.TargetPerReplica)
+	}
+	return demand
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// flapRef is spec.flapDetection: once the target has changed direction
+// Reversals times within Window, the hysteresis band and cooldown are
+// widened by their factors for Duration.
+type flapRef struct {
+	Reversals        int32
+	Window           time.Duration
+	HysteresisFactor float64
+	CooldownFactor   float64
+	Duration         time.Duration
+}
+
+// flapDamping reads status.flapDamping: when the last episode was detected
+// and when its wider band and cooldown end. Zero times when there was none.
+func flapDamping(u *unstructured.Unstructured) (detected, until time.Time) {
+	detectedStr, _, _ := unstructured.NestedString(u.Object, "status", "flapDamping", "detected")
+	untilStr, _, _ := unstructured.NestedString(u.Object, "status", "flapDamping", "until")
+	detected, _ = time.Parse(time.RFC3339, detectedStr)
+	until, _ = time.Parse(time.RFC3339, untilStr)
+	return detected, until
+}
+
+// flapReversals counts the scales in status.scaleHistory that went the other
+// way from the one before, made after since and within window of now.
+func flapReversals(u *unstructured.Unstructured, since time.Time, window time.Duration, now time.Time) int {
+	history, _, _ := unstructured.NestedSlice(u.Object, "status", "scaleHistory")
+	reversals, prev := 0, 0
+	// newest first; walk it oldest first
+	for i := len(history) - 1; i >= 0; i-- {
+		entry, ok := history[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		from, _, _ := unstructured.NestedInt64(entry, "from")
+		to, _, _ := unstructured.NestedInt64(entry, "to")
+		dir := 0
+		switch {
+		case to > from:
+			dir = 1
+		case to < from:
+			dir = -1
+		default:
+			continue
+		}
+		timeStr, _, _ := unstructured.NestedString(entry, "time")
+		t, err := time.Parse(time.RFC3339, timeStr)
+		if err == nil && prev != 0 && dir != prev && t.After(since) && !t.Before(now.Add(-window)) {
+			reversals++
+		}
+		prev = dir
+	}
+	return reversals
+}
+
+// dampFlapping returns s with its hysteresis band and cooldown widened while
+// a flapping episode lasts, and whether one does. A new episode starts, and is
+// recorded in status.flapDamping, when enough reversals happened since the
+// last one was detected; started reports that.
+func dampFlapping(u *unstructured.Unstructured, s autoscalerSpec, now time.Time) (damped autoscalerSpec, active, started bool) {
+	f := s.Flap
+	damp := func() autoscalerSpec {
+		s.HysteresisPct *= f.HysteresisFactor
+		s.Cooldown = time.Duration(float64(s.Cooldown) * f.CooldownFactor)
+		return s
+	}
+	detected, until := flapDamping(u)
+	if now.Before(until) {
+		return damp(), true, false
+	}
+	reversals := flapReversals(u, detected, f.Window, now)
+	if reversals < int(f.Reversals) {
+		return s, false, false
+	}
+	damped = damp()
+	_ = unstructured.SetNestedField(u.Object, map[string]interface{}{
+		"detected":      now.Format(time.RFC3339),
+		"until":         now.Add(f.Duration).Format(time.RFC3339),
+		"reversals":     int64(reversals),
+		"hysteresisPct": strconv.FormatFloat(damped.HysteresisPct, 'f', -1, 64),
+		"cooldown":      damped.Cooldown.String(),
+	}, "status", "flapDamping")
+	return damped, true, true
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/gitops"
+)
+
+// gitopsRef is spec.gitops: replicas are committed to a manifest in Git
+// instead of written to the Deployment.
+type gitopsRef struct {
+	Repo       string
+	Branch     string
+	PushBranch string // commit here and open a pull request instead of committing to Branch
+	Path       string
+	Field      string
+	SecretName string // in the CR's namespace: identity+known_hosts, token, or username/password
+	// Invalid names what is missing when spec.gitops is set but incomplete;
+	// such a CR is never actuated, rather than writing the Deployment
+	Invalid string
+}
+
+// commitReplicas writes replicas to the manifest behind ref. It reports
+// whether a commit was pushed; an unchanged manifest is not an error.
+func (r *reconciler) commitReplicas(ctx context.Context, crNS string, ref gitopsRef, target string, from, to int32) (bool, error) {
+	var secret corev1.Secret
+	if err := r.apiReader.Get(ctx, types.NamespacedName{Namespace: crNS, Name: ref.SecretName}, &secret); err != nil {
+		return false, fmt.Errorf("gitops secret %s/%s: %w", crNS, ref.SecretName, err)
+	}
+	auth, err := gitops.Credentials(secret.Data)
+	if err != nil {
+		return false, fmt.Errorf("gitops secret %s/%s: %w", crNS, ref.SecretName, err)
+	}
+
+	msg := fmt.Sprintf("Scale %s from %d to %d replicas", target, from, to)
+	pushed, err := gitops.SetReplicas(ctx, gitops.Target{
+		Repo:       ref.Repo,
+		Branch:     ref.Branch,
+		PushBranch: ref.PushBranch,
+		Path:       ref.Path,
+		Field:      ref.Field,
+		Auth:       auth,
+	}, to, msg, gitops.Author{Name: r.opts.InstanceName, Email: r.opts.InstanceName + "@autoscaler.malisetti.dev"})
+	if err != nil || !pushed || ref.PushBranch == "" {
+		return pushed, err
+	}
+	if token := secret.Data["token"]; len(token) > 0 {
+		if err := gitops.OpenPullRequest(ctx, ref.Repo, string(token), ref.PushBranch, ref.Branch, msg); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// ingressRef selects the ingress-nginx traffic that drives request-rate scaling.
+// Host and Path are optional and narrow the match to one rule of the Ingress.
+type ingressRef struct {
+	Name      string
+	Namespace string // empty means the target's namespace
+	Host      string
+	Path      string
+}
+
+// ingressRequestsQuery is the per-second request rate ingress-nginx served
+// for ref over window, from its nginx_ingress_controller_requests counter.
+func ingressRequestsQuery(ref ingressRef, window string) string {
+	return fmt.Sprintf(`sum(rate(nginx_ingress_controller_requests{%s}[%s]))`, ref.matchers(), window)
+}
+
+// requestsQuery is the request rate spec.targetRPS sizes dep on, from
+// ingress-nginx or else the Istio mesh; empty when nothing drives it.
+func (s autoscalerSpec) requestsQuery(dep *appsv1.Deployment) string {
+	switch {
+	case s.TargetRPS <= 0:
+		return ""
+	case s.Ingress != nil:
+		ref := *s.Ingress
+		if ref.Namespace == "" {
+			ref.Namespace = dep.Namespace
+		}
+		return ingressRequestsQuery(ref, s.RateWindows.Requests)
+	case s.Istio != nil:
+		return istioRequestsQuery(s.istioFor(dep), s.RateWindows.Requests)
+	}
+	return ""
+}
+
+// ingressErrorRatioQuery is the share of ref's requests answered with a 5xx.
+// The denominator is clamped so an idle service reads 0, not 0/0 = NaN.
+func ingressErrorRatioQuery(ref ingressRef, window string) string {
+	m := ref.matchers()
+	return fmt.Sprintf(`sum(rate(nginx_ingress_controller_requests{%s,status=~"5.."}[%s])) / clamp_min(sum(rate(nginx_ingress_controller_requests{%s}[%s])), 1e-9)`, m, window, m, window)
+}
+
+func (ref ingressRef) matchers() string {
+	matchers := []string{
+		fmt.Sprintf(`namespace=%q`, ref.Namespace),
+		fmt.Sprintf(`ingress=%q`, ref.Name),
+	}
+	if ref.Host != "" {
+		matchers = append(matchers, fmt.Sprintf(`host=%q`, ref.Host))
+	}
+	if ref.Path != "" {
+		matchers = append(matchers, fmt.Sprintf(`path=%q`, ref.Path))
+	}
+	return strings.Join(matchers, ",")
+}
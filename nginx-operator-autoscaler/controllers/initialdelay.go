@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+)
+
+// firstSeen remembers when this controller process first reconciled each CR,
+// for spec.initialDelay. It is in memory on purpose: a restart starts every
+// delay over, so the first decision after one is not made on a cold sample.
+type firstSeen struct {
+	mu sync.Mutex
+	at map[string]time.Time
+}
+
+func newFirstSeen() *firstSeen {
+	return &firstSeen{at: map[string]time.Time{}}
+}
+
+// since returns when key was first seen, recording now if never.
+func (f *firstSeen) since(key string, now time.Time) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	at, ok := f.at[key]
+	if !ok {
+		at = now
+		f.at[key] = at
+	}
+	return at
+}
+
+func (f *firstSeen) forget(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.at, key)
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// inPlaceRef is spec.inPlaceResize: answer load by resizing the CPU requests
+// of the running pods first (in-place pod resize, Kubernetes 1.27+), and only
+// add pods once they are at MaxCPU, or remove pods once they are back at the
+// template's request.
+type inPlaceRef struct {
+	Container string  // empty means the first container
+	MaxCPU    float64 // cores per pod
+}
+
+// podCPURequest is the CPU request (cores) of the named container in pod.
+func podCPURequest(pod *corev1.Pod, container string) (float64, bool) {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			q, ok := c.Resources.Requests[corev1.ResourceCPU]
+			return q.AsApproximateFloat64(), ok
+		}
+	}
+	return 0, false
+}
+
+// inPlacePods is what one cycle knows about the pods spec.inPlaceResize sizes.
+type inPlacePods struct {
+	pods      []corev1.Pod
+	container string
+	tmpl      float64 // the template's CPU request, cores
+	scale     float64 // how much bigger the pods' requests run, on average
+}
+
+// inPlacePods lists the target's running pods for in-place resizing, or
+// returns nil when the container has no CPU request to scale.
+func (tc targetCluster) inPlacePods(ctx context.Context, dep *appsv1.Deployment, ref inPlaceRef) (*inPlacePods, error) {
+	c := fallbackContainer(dep, ref.Container)
+	if c == nil {
+		return nil, nil
+	}
+	q, ok := c.Resources.Requests[corev1.ResourceCPU]
+	if !ok || q.IsZero() {
+		return nil, nil
+	}
+	pods, err := tc.runningPods(ctx, dep)
+	if err != nil {
+		return nil, err
+	}
+	ip := &inPlacePods{pods: pods, container: c.Name, tmpl: q.AsApproximateFloat64(), scale: 1}
+	if len(pods) > 0 {
+		var sum float64
+		for i := range pods {
+			cpu, ok := podCPURequest(&pods[i], c.Name)
+			if !ok {
+				cpu = ip.tmpl
+			}
+			sum += cpu / ip.tmpl
+		}
+		ip.scale = sum / float64(len(pods))
+	}
+	return ip, nil
+}
+
+// target is the CPU request every pod should run so the current replicas
+// carry what cpuNeed replicas of today's size would, within the template's
+// request and maxCPU. ok is false when resizing can't go that way any
+// further (or the change is not CPU's), and replicas must change instead.
+func (ip *inPlacePods) target(maxCPU float64, cpuNeed, current int32, up bool) (cores float64, ok bool) {
+	if current <= 0 || len(ip.pods) == 0 {
+		return 0, false
+	}
+	factor := float64(cpuNeed) / float64(current)
+	if up != (factor > 1) || factor == 1 {
+		return 0, false
+	}
+	now := ip.scale * ip.tmpl
+	want := math.Max(ip.tmpl, math.Min(maxCPU, now*factor))
+	if up && want <= now || !up && want >= now {
+		return 0, false
+	}
+	return want, true
+}
+
+// resizePods sets the CPU request of container on every pod to cores, with a
+// CPU limit kept at the same ratio to it. Pods are resized through their
+// resize subresource (Kubernetes 1.33+) or, where the API server has none,
+// by patching the pod spec (1.27 to 1.32, with InPlacePodVerticalScaling).
+func (tc targetCluster) resizePods(ctx context.Context, pods []corev1.Pod, container string, cores float64) error {
+	to := milliCPU(cores)
+	for i := range pods {
+		pod := &pods[i]
+		patch := client.MergeFrom(pod.DeepCopy())
+		for j := range pod.Spec.Containers {
+			c := &pod.Spec.Containers[j]
+			if c.Name != container {
+				continue
+			}
+			from := c.Resources.Requests[corev1.ResourceCPU]
+			if from.Cmp(to) == 0 {
+				continue
+			}
+			scaleResources(c, corev1.ResourceCPU, from, to)
+		}
+		err := tc.SubResource("resize").Patch(ctx, pod, patch)
+		if apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err) {
+			err = tc.Patch(ctx, pod, patch)
+		}
+		if err != nil {
+			return fmt.Errorf("resize pod %s: %w", pod.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// istioRef selects the Istio/Envoy telemetry of a meshed workload.
+type istioRef struct {
+	Reporter        string  // "destination" (server-side Envoy) or "source"
+	Workload        string  // destination_workload; empty means the target Deployment
+	Namespace       string  // destination_workload_namespace; empty means the target's
+	LatencyQuantile float64 // e.g. 0.95 for p95
+}
+
+// istioFor is spec.istio with its defaults filled in from dep; zero without spec.istio.
+func (s autoscalerSpec) istioFor(dep *appsv1.Deployment) istioRef {
+	if s.Istio == nil {
+		return istioRef{}
+	}
+	ref := *s.Istio
+	if ref.Workload == "" {
+		ref.Workload = dep.Name
+	}
+	if ref.Namespace == "" {
+		ref.Namespace = dep.Namespace
+	}
+	return ref
+}
+
+func (ref istioRef) matchers() string {
+	return strings.Join([]string{
+		fmt.Sprintf(`reporter=%q`, ref.Reporter),
+		fmt.Sprintf(`destination_workload_namespace=%q`, ref.Namespace),
+		fmt.Sprintf(`destination_workload=%q`, ref.Workload),
+	}, ",")
+}
+
+// istioRequestsQuery is the workload's per-second request rate from istio_requests_total.
+func istioRequestsQuery(ref istioRef, window string) string {
+	return fmt.Sprintf(`sum(rate(istio_requests_total{%s}[%s]))`, ref.matchers(), window)
+}
+
+// istioErrorRatioQuery is the share of the workload's requests answered with
+// a 5xx; like ingressErrorRatioQuery it reads 0 while the workload is idle.
+func istioErrorRatioQuery(ref istioRef, window string) string {
+	m := ref.matchers()
+	return fmt.Sprintf(`sum(rate(istio_requests_total{%s,response_code=~"5.."}[%s])) / clamp_min(sum(rate(istio_requests_total{%s}[%s])), 1e-9)`, m, window, m, window)
+}
+
+// istioLatencyQuery is the workload's request latency quantile in milliseconds.
+func istioLatencyQuery(ref istioRef, window string) string {
+	return fmt.Sprintf(`histogram_quantile(%g, sum by (le) (rate(istio_request_duration_milliseconds_bucket{%s}[%s])))`,
+		ref.LatencyQuantile, ref.matchers(), window)
+}
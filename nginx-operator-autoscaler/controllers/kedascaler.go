@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/externalscaler"
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+)
+
+// kedaMetricName is the single metric we report to KEDA. Its value is the
+// replica count the decision engine wants, against a target of 1 per replica,
+// so the HPA that KEDA manages lands on exactly that count.
+const kedaMetricName = "nginx-autoscaler-desired-replicas"
+
+// KEDAScaler answers KEDA external-scaler calls with the same CPU/memory
+// budget model the NginxAutoscaler controller uses. Trigger metadata takes
+// the NginxAutoscaler spec keys (targetDeployment, targetCPU, minReplicas, ...)
+// layered over the namespace's AutoscalerDefaults; the ScaledObject's
+// namespace is the target namespace. Cooldown, step limits and hysteresis are
+// left to the HPA's own behavior settings.
+type KEDAScaler struct {
+	client    client.Reader
+	reader    client.Reader // uncached Pod reads
+	discovery *promDiscoverer
+	names     MetricNames
+}
+
+// NewKEDAScaler builds a scaler reading Deployments and defaults through mgr,
+// querying the series names (see Options.MetricNames) unless a trigger
+// overrides them.
+func NewKEDAScaler(mgr ctrl.Manager, names MetricNames) *KEDAScaler {
+	return &KEDAScaler{
+		client:    mgr.GetClient(),
+		reader:    mgr.GetAPIReader(),
+		discovery: &promDiscoverer{reader: mgr.GetAPIReader(), clock: clock.RealClock{}},
+		names:     names.orDefault(),
+	}
+}
+
+var _ externalscaler.Scaler = (*KEDAScaler)(nil)
+
+// kedaActivationKey is the trigger metadata key holding a PromQL demand
+// signal for IsActive.
+const kedaActivationKey = "activationQuery"
+
+// IsActive reports whether the target should run at all, which only matters
+// when minReplicas is 0 and KEDA owns scaling to and from zero. With
+// activationQuery set (e.g. the ingress request rate) the target is active
+// while that query reads above 0, which still works at zero replicas. Without
+// it the answer comes from the target's own CPU and memory, which a
+// Deployment at zero doesn't have: it can scale to zero but never back.
+func (k *KEDAScaler) IsActive(ctx context.Context, ref *externalscaler.ScaledObjectRef) (bool, error) {
+	if q := ref.GetScalerMetadata()[kedaActivationKey]; q != "" {
+		s, err := k.spec(ctx, ref)
+		if err != nil {
+			return false, err
+		}
+		v, err := prom.InstantVector(s.PromURL, q)
+		if err != nil {
+			return false, err
+		}
+		if !decision.Usable(v) {
+			return false, fmt.Errorf("%s returned %v", kedaActivationKey, v)
+		}
+		return v > 0, nil
+	}
+	d, err := k.decide(ctx, ref)
+	if err != nil {
+		return false, err
+	}
+	return d.Desired > 0, nil
+}
+
+func (k *KEDAScaler) GetMetricSpec(context.Context, *externalscaler.ScaledObjectRef) ([]*externalscaler.MetricSpec, error) {
+	return []*externalscaler.MetricSpec{{MetricName: kedaMetricName, TargetSize: 1}}, nil
+}
+
+func (k *KEDAScaler) GetMetrics(ctx context.Context, ref *externalscaler.ScaledObjectRef, _ string) ([]*externalscaler.MetricValue, error) {
+	d, err := k.decide(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return []*externalscaler.MetricValue{{MetricName: kedaMetricName, MetricValue: int64(d.Desired)}}, nil
+}
+
+// spec is the trigger's metadata layered over the namespace's defaults, with
+// the Prometheus URL discovered when the metadata doesn't set one.
+func (k *KEDAScaler) spec(ctx context.Context, ref *externalscaler.ScaledObjectRef) (autoscalerSpec, error) {
+	nsDefaults, err := loadNamespaceDefaults(ctx, k.client, ref.GetNamespace())
+	if err != nil {
+		return autoscalerSpec{}, err
+	}
+	md := make(map[string]string, len(ref.GetScalerMetadata()))
+	for key, v := range ref.GetScalerMetadata() {
+		if key != kedaActivationKey {
+			md[key] = v
+		}
+	}
+	s := nsDefaults.applyLimits(parseSpec(nsDefaults.mergeSpec(metadataSpec(md))))
+	if s.TargetDeployment == "" {
+		return autoscalerSpec{}, fmt.Errorf("trigger metadata must set targetDeployment")
+	}
+	if s.PromURL == "" {
+		if s.PromURL, err = k.discovery.discover(ctx); err != nil {
+			return autoscalerSpec{}, err
+		}
+	}
+	return s, nil
+}
+
+func (k *KEDAScaler) decide(ctx context.Context, ref *externalscaler.ScaledObjectRef) (decision.Result, error) {
+	s, err := k.spec(ctx, ref)
+	if err != nil {
+		return decision.Result{}, err
+	}
+
+	var dep appsv1.Deployment
+	if err := k.client.Get(ctx, types.NamespacedName{Namespace: ref.GetNamespace(), Name: s.TargetDeployment}, &dep); err != nil {
+		return decision.Result{}, err
+	}
+	current := int32(1)
+	if dep.Spec.Replicas != nil {
+		current = *dep.Spec.Replicas
+	}
+
+	cpuQ, memQ := usageQueries(k.names.with(s.MetricNames), dep.Namespace, dep.Name+"-.*", s.RateWindows.CPU)
+	cpu, cpuFound, err := usage(s.PromURL, cpuQ, s.Percentile)
+	if err != nil {
+		return decision.Result{}, err
+	}
+	mem, memFound, err := usage(s.PromURL, memQ, s.Percentile)
+	if err != nil {
+		return decision.Result{}, err
+	}
+	// An error makes KEDA fall back rather than scale to minReplicas
+	tc := targetCluster{reader: k.reader}
+	if err := tc.checkUsagePresent(ctx, &dep, cpuFound, memFound); err != nil {
+		return decision.Result{}, err
+	}
+
+	return decision.Decide(s.policy(), decision.Input{
+		Current:  current,
+		CPUCores: cpu,
+		MemMiB:   mem / (1024 * 1024),
+	}), nil
+}
+
+// metadataSpec turns KEDA's string-only trigger metadata into the typed map
+// parseSpec expects: integers, then floats, then booleans, else strings.
+func metadataSpec(md map[string]string) map[string]interface{} {
+	spec := make(map[string]interface{}, len(md))
+	for k, v := range md {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			spec[k] = i
+		} else if f, err := strconv.ParseFloat(v, 64); err == nil {
+			spec[k] = f
+		} else if b, err := strconv.ParseBool(v); err == nil {
+			spec[k] = b
+		} else {
+			spec[k] = v
+		}
+	}
+	return spec
+}
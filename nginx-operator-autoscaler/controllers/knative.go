@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// spec.targetRef.apiVersion/kind naming a Knative Service.
+const (
+	knativeServingAPIVersion = "serving.knative.dev/v1"
+	knativeServiceKind       = "Service"
+)
+
+var knativeServiceGVK = schema.GroupVersionKind{Group: "serving.knative.dev", Version: "v1", Kind: "Service"}
+var knativeRevisionGVK = schema.GroupVersionKind{Group: "serving.knative.dev", Version: "v1", Kind: "Revision"}
+
+// Knative's autoscaler keeps a Revision between these bounds; setting both
+// to the same count pins it there.
+const (
+	knativeRevisionLabel = "serving.knative.dev/revision"
+	knativeMinScale      = "autoscaling.knative.dev/min-scale"
+	knativeMaxScale      = "autoscaling.knative.dev/max-scale"
+)
+
+// knativeDeployment resolves the Knative Service at key to the Deployment
+// of its latest ready Revision, which Knative names <revision>-deployment.
+// Metrics, pods and the current replica count are all read from it.
+func (tc targetCluster) knativeDeployment(ctx context.Context, key types.NamespacedName) (types.NamespacedName, error) {
+	ksvc := &unstructured.Unstructured{}
+	ksvc.SetGroupVersionKind(knativeServiceGVK)
+	if err := tc.reader.Get(ctx, key, ksvc); err != nil {
+		return types.NamespacedName{}, err
+	}
+	rev, _, _ := unstructured.NestedString(ksvc.Object, "status", "latestReadyRevisionName")
+	if rev == "" {
+		// No Deployment to scale yet; reads as not found, like a missing Deployment
+		return types.NamespacedName{}, apierrors.NewNotFound(schema.GroupResource{Group: "serving.knative.dev", Resource: "revisions"}, key.Name+" (latest ready)")
+	}
+	return types.NamespacedName{Namespace: key.Namespace, Name: rev + "-deployment"}, nil
+}
+
+// pinKnativeScale sizes a Knative Revision by setting its min-scale and
+// max-scale annotations to replicas; writing its Deployment would be undone
+// by Knative's autoscaler on its next tick. Zero clears max-scale (0 means
+// unbounded to Knative) and leaves scale-to-zero to Knative.
+func (tc targetCluster) pinKnativeScale(ctx context.Context, dep *appsv1.Deployment, replicas int32) error {
+	rev := dep.Labels[knativeRevisionLabel]
+	if rev == "" {
+		return fmt.Errorf("deployment %s/%s has no %s label", dep.Namespace, dep.Name, knativeRevisionLabel)
+	}
+	annotations := map[string]interface{}{
+		knativeMinScale: strconv.Itoa(int(replicas)),
+		knativeMaxScale: strconv.Itoa(int(replicas)),
+	}
+	if replicas == 0 {
+		annotations[knativeMaxScale] = nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"annotations": annotations}})
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(knativeRevisionGVK)
+	obj.SetNamespace(dep.Namespace)
+	obj.SetName(rev)
+	return tc.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch))
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+)
+
+// learningRef is spec.learning: watch demand for Period without scaling,
+// then suggest the MinQuantile and MaxQuantile of it, in replicas, as
+// minReplicas and maxReplicas.
+type learningRef struct {
+	Period      time.Duration
+	Step        time.Duration // query_range resolution
+	MinQuantile float64
+	MaxQuantile float64
+}
+
+// learning is status.learning: when the period started and, once it is
+// over, the bounds it suggests.
+type learning struct {
+	Started     time.Time
+	Completed   time.Time // zero while still learning
+	MinReplicas int32
+	MaxReplicas int32
+	Samples     int
+}
+
+// learnBounds sizes the demand seen since started, in replicas of targetCPU
+// and targetMem (whichever needs more per sample), and takes its quantiles.
+func learnBounds(promURL string, ref learningRef, cpuQ, memQ string, targetCPU, targetMem float64, started, now time.Time) (learning, error) {
+	window := now.Sub(started)
+	cpu, err := prom.RangeVector(promURL, cpuQ, window, ref.Step)
+	if err != nil {
+		return learning{}, err
+	}
+	var mem []float64
+	if targetMem > 0 {
+		if mem, err = prom.RangeVector(promURL, memQ, window, ref.Step); err != nil {
+			return learning{}, err
+		}
+	}
+	// Both series end now; line them up from the end
+	demand := make([]float64, 0, len(cpu))
+	samples := 0
+	for i := range cpu {
+		d := cpu[i] / targetCPU
+		if j := len(mem) - len(cpu) + i; j >= 0 && j < len(mem) {
+			d = math.Max(d, mem[j]/(1024*1024)/targetMem)
+		}
+		if !math.IsNaN(d) {
+			samples++
+		}
+		demand = append(demand, d)
+	}
+	if samples == 0 {
+		return learning{}, fmt.Errorf("no demand samples in the %s learning period", window.Round(time.Second))
+	}
+	lo := int32(math.Max(1, math.Ceil(decision.Quantile(demand, ref.MinQuantile))))
+	hi := int32(math.Ceil(decision.Quantile(demand, ref.MaxQuantile)))
+	return learning{
+		Started:     started,
+		Completed:   now,
+		MinReplicas: lo,
+		MaxReplicas: max(lo, hi),
+		Samples:     samples,
+	}, nil
+}
+
+// readLearning loads status.learning.
+func readLearning(u *unstructured.Unstructured) (learning, bool) {
+	m, ok, _ := unstructured.NestedMap(u.Object, "status", "learning")
+	if !ok {
+		return learning{}, false
+	}
+	var l learning
+	startedStr, _, _ := unstructured.NestedString(m, "started")
+	completedStr, _, _ := unstructured.NestedString(m, "completed")
+	l.Started, _ = time.Parse(time.RFC3339, startedStr)
+	l.Completed, _ = time.Parse(time.RFC3339, completedStr)
+	lo, _, _ := unstructured.NestedInt64(m, "suggestedMinReplicas")
+	hi, _, _ := unstructured.NestedInt64(m, "suggestedMaxReplicas")
+	samples, _, _ := unstructured.NestedInt64(m, "samples")
+	l.MinReplicas, l.MaxReplicas, l.Samples = int32(lo), int32(hi), int(samples)
+	return l, true
+}
+
+func writeLearning(u *unstructured.Unstructured, l learning) {
+	m := map[string]interface{}{"started": l.Started.Format(time.RFC3339)}
+	if !l.Completed.IsZero() {
+		m["completed"] = l.Completed.Format(time.RFC3339)
+		m["suggestedMinReplicas"] = int64(l.MinReplicas)
+		m["suggestedMaxReplicas"] = int64(l.MaxReplicas)
+		m["samples"] = int64(l.Samples)
+	}
+	_ = unstructured.SetNestedMap(u.Object, m, "status", "learning")
+}
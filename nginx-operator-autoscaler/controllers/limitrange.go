@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podLimits is the most CPU and memory one pod of dep can use: its
+// containers' limits (or the namespace LimitRange's default limit when a
+// container sets none), capped by the LimitRange's container and pod max.
+// A resource is missing from the result when any container is unbounded.
+func podLimits(ctx context.Context, reader client.Reader, dep *appsv1.Deployment) (corev1.ResourceList, error) {
+	var ranges corev1.LimitRangeList
+	if err := reader.List(ctx, &ranges, client.InNamespace(dep.Namespace)); err != nil {
+		return nil, err
+	}
+	out := corev1.ResourceList{}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		var total resource.Quantity
+		bounded := len(dep.Spec.Template.Spec.Containers) > 0
+		for _, c := range dep.Spec.Template.Spec.Containers {
+			limit, ok := c.Resources.Limits[name]
+			for _, lr := range ranges.Items {
+				for _, item := range lr.Spec.Limits {
+					if item.Type != corev1.LimitTypeContainer {
+						continue
+					}
+					if d, has := item.Default[name]; has && !ok {
+						limit, ok = d, true
+					}
+					if m, has := item.Max[name]; has && (!ok || m.Cmp(limit) < 0) {
+						limit, ok = m, true
+					}
+				}
+			}
+			if !ok {
+				bounded = false
+				break
+			}
+			total.Add(limit)
+		}
+		for _, lr := range ranges.Items {
+			for _, item := range lr.Spec.Limits {
+				if m, has := item.Max[name]; has && item.Type == corev1.LimitTypePod && (!bounded || m.Cmp(total) < 0) {
+					total, bounded = m, true
+				}
+			}
+		}
+		if bounded {
+			out[name] = total
+		}
+	}
+	return out, nil
+}
+
+// targetsBeyondLimits explains, one sentence per resource, where the CR's
+// per-replica targets ask for more than a pod can ever use: such a replica
+// is throttled or OOM-killed before it reaches the target, so the
+// autoscaler scales up too late or never. Empty when the targets fit.
+func targetsBeyondLimits(s autoscalerSpec, limits corev1.ResourceList) string {
+	var msgs []string
+	if l, ok := limits[corev1.ResourceCPU]; ok && s.TargetCPU > l.AsApproximateFloat64() {
+		msgs = append(msgs, fmt.Sprintf("targetCPU %g cores per replica exceeds the %s a pod may use", s.TargetCPU, l.String()))
+	}
+	if l, ok := limits[corev1.ResourceMemory]; ok && s.TargetMem > l.AsApproximateFloat64()/(1<<20) {
+		msgs = append(msgs, fmt.Sprintf("targetMem %gMi per replica exceeds the %s a pod may use", s.TargetMem, l.String()))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// setTargetsFeasible records targetsBeyondLimits' verdict in the
+// TargetsFeasible condition, which only appears once a target was found
+// infeasible. It reports whether the status changed.
+func setTargetsFeasible(u *unstructured.Unstructured, msg string) bool {
+	if msg != "" {
+		return setCondition(u, condTargetsFeasible, metav1.ConditionFalse, "ExceedsPodLimits", msg)
+	}
+	if meta.FindStatusCondition(getConditions(u), condTargetsFeasible) == nil {
+		return false
+	}
+	return setCondition(u, condTargetsFeasible, metav1.ConditionTrue, "WithinPodLimits", "")
+}
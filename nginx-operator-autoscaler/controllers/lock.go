@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// managedByAnnotation on a Deployment names the autoscaler that owns its replica count.
+	managedByAnnotation = "autoscaler.malisetti.dev/managed-by"
+	// lockFinalizer lets us release managedByAnnotation when a CR is deleted.
+	lockFinalizer = "autoscaler.malisetti.dev/release-lock"
+)
+
+// lockValue identifies this CR (and controller instance) in managedByAnnotation.
+func (r *reconciler) lockValue(u *unstructured.Unstructured) string {
+	return r.opts.InstanceName + ":" + u.GetNamespace() + "/" + u.GetName()
+}
+
+// acquireLock stamps managedByAnnotation on dep unless someone else holds it.
+// forceAdopt overwrites a foreign claim. It returns the current holder when refused.
+func (r *reconciler) acquireLock(ctx context.Context, tc targetCluster, u *unstructured.Unstructured, dep *appsv1.Deployment, forceAdopt bool) (bool, string, error) {
+	want := r.lockValue(u)
+	holder := dep.Annotations[managedByAnnotation]
+	if holder == want {
+		return true, holder, nil
+	}
+	if holder != "" && !forceAdopt {
+		return false, holder, nil
+	}
+
+	patch := client.MergeFrom(dep.DeepCopy())
+	if dep.Annotations == nil {
+		dep.Annotations = map[string]string{}
+	}
+	dep.Annotations[managedByAnnotation] = want
+	if err := tc.patchTarget(ctx, dep, patch); err != nil {
+		return false, holder, err
+	}
+	return true, want, nil
+}
+
+// releaseLock removes our claim from the target Deployment (in every member
+// cluster, for multi-cluster CRs), if we still hold it, and the balloon pods
+// spec.overprovisioning kept next to it.
+func (r *reconciler) releaseLock(ctx context.Context, u *unstructured.Unstructured) error {
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	s := parseSpec(spec)
+	ns := u.GetNamespace()
+	if s.TargetNamespace != "" {
+		ns = s.TargetNamespace
+	}
+
+	secrets := [][2]string{{s.KubeconfigSecret, s.KubeconfigKey}}
+	if len(s.Clusters) > 0 {
+		secrets = secrets[:0]
+		for _, m := range s.Clusters {
+			secrets = append(secrets, [2]string{m.KubeconfigSecret, m.KubeconfigKey})
+		}
+	}
+	for _, sec := range secrets {
+		tc, err := r.targetCluster(ctx, u.GetNamespace(), sec[0], sec[1])
+		if err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue // kubeconfig Secret gone: nothing we can release
+			}
+			return err
+		}
+		var dep appsv1.Deployment
+		if err := tc.getTarget(ctx, s.TargetKind, types.NamespacedName{Namespace: ns, Name: s.TargetDeployment}, &dep); err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue
+			}
+			return err
+		}
+		if _, had, _ := unstructured.NestedInt64(u.Object, "status", "balloonReplicas"); had {
+			if err := tc.deleteBalloons(ctx, &dep); err != nil {
+				return err
+			}
+		}
+		if dep.Annotations[managedByAnnotation] != r.lockValue(u) {
+			continue
+		}
+		patch := client.MergeFrom(dep.DeepCopy())
+		delete(dep.Annotations, managedByAnnotation)
+		if err := tc.patchTarget(ctx, &dep, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleFinalizer adds our finalizer to live CRs and, for CRs being deleted,
+// releases the Deployment lock and lets the deletion proceed. done reports
+// whether the CR is going away and reconcile should stop.
+func (r *reconciler) handleFinalizer(ctx context.Context, u *unstructured.Unstructured) (done bool, err error) {
+	if u.GetDeletionTimestamp() != nil {
+		if !controllerutil.ContainsFinalizer(u, lockFinalizer) {
+			return true, nil
+		}
+		if err := r.releaseLock(ctx, u); err != nil {
+			return true, err
+		}
+		controllerutil.RemoveFinalizer(u, lockFinalizer)
+		return true, r.Update(ctx, u)
+	}
+	if controllerutil.AddFinalizer(u, lockFinalizer) {
+		return false, r.Update(ctx, u)
+	}
+	return false, nil
+}
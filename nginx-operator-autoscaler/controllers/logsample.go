@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+)
+
+// logSampler lets a message that would otherwise repeat every cycle through
+// at most once per interval per CR. A zero interval lets everything through.
+type logSampler struct {
+	every time.Duration
+	mu    sync.Mutex
+	last  map[string]time.Time
+}
+
+func newLogSampler(every time.Duration) *logSampler {
+	return &logSampler{every: every, last: map[string]time.Time{}}
+}
+
+// allow reports whether key's message should be logged at now.
+func (s *logSampler) allow(key string, now time.Time) bool {
+	if s.every <= 0 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.last[key]; ok && now.Sub(last) < s.every {
+		return false
+	}
+	s.last[key] = now
+	return true
+}
+
+func (s *logSampler) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.last, key)
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// MetricNames are the cAdvisor series and label keys the built-in CPU and
+// memory queries use. Stacks that rename or relabel them (a recording-rule
+// prefix, pod_name instead of pod, no image label) set them on the
+// controller, or per CR in spec.metricNames.
+type MetricNames struct {
+	CPU            string // a counter of CPU seconds
+	Memory         string // a gauge of bytes
+	NamespaceLabel string
+	PodLabel       string
+	ImageLabel     string // series without it are pod-level totals; empty: no such filter
+}
+
+// DefaultMetricNames are what cAdvisor exports through the kubelet.
+var DefaultMetricNames = MetricNames{
+	CPU:            "container_cpu_usage_seconds_total",
+	Memory:         "container_memory_working_set_bytes",
+	NamespaceLabel: "namespace",
+	PodLabel:       "pod",
+	ImageLabel:     "image",
+}
+
+var (
+	promMetricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	promLabelName  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// metricNameOverrides is spec.metricNames: the keys a CR sets, already
+// checked to be valid PromQL names.
+type metricNameOverrides map[string]string
+
+// parseMetricNames reads spec.metricNames {cpu, memory, namespaceLabel,
+// podLabel, imageLabel}. Anything that is not a PromQL name is ignored
+// rather than spliced into a query; imageLabel may be "" to drop the filter.
+func parseMetricNames(spec map[string]interface{}) metricNameOverrides {
+	m, _ := spec["metricNames"].(map[string]interface{})
+	if len(m) == 0 {
+		return nil
+	}
+	out := metricNameOverrides{}
+	for key, re := range map[string]*regexp.Regexp{
+		"cpu": promMetricName, "memory": promMetricName,
+		"namespaceLabel": promLabelName, "podLabel": promLabelName, "imageLabel": promLabelName,
+	} {
+		v, ok := m[key].(string)
+		if ok && (re.MatchString(v) || key == "imageLabel" && v == "") {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// with is n with a CR's overrides applied.
+func (n MetricNames) with(o metricNameOverrides) MetricNames {
+	for key, field := range map[string]*string{
+		"cpu": &n.CPU, "memory": &n.Memory,
+		"namespaceLabel": &n.NamespaceLabel, "podLabel": &n.PodLabel, "imageLabel": &n.ImageLabel,
+	} {
+		if v, ok := o[key]; ok {
+			*field = v
+		}
+	}
+	return n
+}
+
+// orDefault is DefaultMetricNames for the zero value, and otherwise n with
+// empty series and namespace/pod labels filled from it. An empty ImageLabel
+// is kept: it turns the filter off.
+func (n MetricNames) orDefault() MetricNames {
+	d := DefaultMetricNames
+	if n == (MetricNames{}) {
+		return d
+	}
+	if n.CPU == "" {
+		n.CPU = d.CPU
+	}
+	if n.Memory == "" {
+		n.Memory = d.Memory
+	}
+	if n.NamespaceLabel == "" {
+		n.NamespaceLabel = d.NamespaceLabel
+	}
+	if n.PodLabel == "" {
+		n.PodLabel = d.PodLabel
+	}
+	return n
+}
+
+// selector is metric's series for the pods matching podSel in ns.
+func (n MetricNames) selector(metric, ns, podSel string) string {
+	sel := fmt.Sprintf(`%s{%s="%s",%s=~"%s"`, metric, n.NamespaceLabel, ns, n.PodLabel, podSel)
+	if n.ImageLabel != "" {
+		sel += fmt.Sprintf(`,%s!=""`, n.ImageLabel)
+	}
+	return sel + "}"
+}
+
+// Validate reports a series or label name that is not valid PromQL.
+func (n MetricNames) Validate() error {
+	for _, f := range []struct{ name, value string }{
+		{"cpu metric", n.CPU}, {"memory metric", n.Memory},
+	} {
+		if f.value != "" && !promMetricName.MatchString(f.value) {
+			return fmt.Errorf("%s %q is not a Prometheus metric name", f.name, f.value)
+		}
+	}
+	for _, f := range []struct{ name, value string }{
+		{"namespace label", n.NamespaceLabel}, {"pod label", n.PodLabel}, {"image label", n.ImageLabel},
+	} {
+		if f.value != "" && !promLabelName.MatchString(f.value) {
+			return fmt.Errorf("%s %q is not a Prometheus label name", f.name, f.value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// discardedSamples counts samples spec.anomalyFilter threw away, served on
+// the manager's /metrics next to controller-runtime's own.
+var discardedSamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "nginx_autoscaler_discarded_samples_total",
+	Help: "Metric samples discarded as anomalies by spec.anomalyFilter.",
+}, []string{"namespace", "name", "metric"})
+
+// recommendedReplicas is the replica count each policy settled on last cycle:
+// "active" is the one applied, "shadow" the spec.shadow policy it is compared
+// against.
+var recommendedReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "nginx_autoscaler_recommended_replicas",
+	Help: "Replicas the active and shadow policies decided on in the last cycle.",
+}, []string{"namespace", "name", "policy"})
+
+func init() {
+	metrics.Registry.MustRegister(discardedSamples, recommendedReplicas)
+}
+
+// forgetMetrics drops the series of a CR that is gone.
+func forgetMetrics(req ctrl.Request) {
+	labels := prometheus.Labels{"namespace": req.Namespace, "name": req.Name}
+	discardedSamples.DeletePartialMatch(labels)
+	recommendedReplicas.DeletePartialMatch(labels)
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// errMetricsUnavailable means the usage queries came back empty while the
+// target has running pods: a broken scrape job or relabeling, not idle pods.
+var errMetricsUnavailable = errors.New("usage metrics missing for running pods")
+
+// checkUsagePresent cross-checks empty usage results against the pod list
+// from the API server. Empty is only believed (as zero load) when the target
+// really has no running pods; otherwise it returns errMetricsUnavailable,
+// wrapped with the pod count. A failed pod list counts as unavailable too.
+func (tc targetCluster) checkUsagePresent(ctx context.Context, dep *appsv1.Deployment, cpuFound, memFound bool) error {
+	if cpuFound && memFound {
+		return nil
+	}
+	pods, err := tc.runningPods(ctx, dep)
+	if err != nil {
+		return fmt.Errorf("%w (cannot list pods: %v)", errMetricsUnavailable, err)
+	}
+	if len(pods) == 0 {
+		return nil
+	}
+	var missing string
+	switch {
+	case !cpuFound && !memFound:
+		missing = "cpu and memory"
+	case !cpuFound:
+		missing = "cpu"
+	default:
+		missing = "memory"
+	}
+	return fmt.Errorf("%w: no %s samples for %d running pod(s)", errMetricsUnavailable, missing, len(pods))
+}
+
+// setMetricsAvailable records the outcome of checkUsagePresent in the
+// MetricsAvailable condition, reporting whether status changed. The condition
+// only appears once metrics went missing, so healthy CRs carry no extra noise.
+func setMetricsAvailable(u *unstructured.Unstructured, err error) bool {
+	if err != nil {
+		return setCondition(u, condMetricsAvailable, metav1.ConditionFalse, "MetricsUnavailable", err.Error())
+	}
+	if meta.FindStatusCondition(getConditions(u), condMetricsAvailable) == nil {
+		return false
+	}
+	return setCondition(u, condMetricsAvailable, metav1.ConditionTrue, "MetricsFound", "")
+}
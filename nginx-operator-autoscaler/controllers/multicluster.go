@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+)
+
+// clusterMember is one cluster a multi-cluster service runs in. Each member
+// runs a Deployment with the same namespace and name as the CR's target.
+type clusterMember struct {
+	Name             string
+	PromURL          string // this cluster's Prometheus
+	KubeconfigSecret string // empty means the local cluster
+	KubeconfigKey    string
+	Weight           float64 // share of the total replicas
+}
+
+// memberState is what one reconcile observed in one member cluster.
+type memberState struct {
+	clusterMember
+	tc  targetCluster
+	dep appsv1.Deployment
+}
+
+// reconcileClusters scales a service spread over several clusters: demand is
+// summed over every member's Prometheus, one decision is made for the total,
+// and the result goes through the same gates as a single target (webhook,
+// policy, approval, backoff, scale limiter) before being split across
+// members by weight.
+func (r *reconciler) reconcileClusters(ctx context.Context, req ctrl.Request, u *unstructured.Unstructured, s autoscalerSpec,
+	targetNS, targetKey string, snap *DebugSnapshot) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("nginxautoscaler", client.ObjectKeyFromObject(u))
+	requeue := ctrl.Result{RequeueAfter: s.PollInterval}
+	fail := func(err error, msg string) (ctrl.Result, error) {
+		logger.Error(err, msg)
+		snap.Error = err.Error()
+		return requeue, nil
+	}
+	if s.GitOps != nil {
+		// One manifest can't hold a count per cluster
+		logger.Info("spec.gitops can't be combined with spec.clusters")
+		snap.SkipReason = "GitOpsWithClusters"
+		return requeue, nil
+	}
+
+	members := make([]memberState, 0, len(s.Clusters))
+	var current int32
+	var cpuCores, memMiB float64
+	for _, m := range s.Clusters {
+		if m.PromURL == "" {
+			return fail(fmt.Errorf("cluster %q has no promURL", m.Name), "invalid cluster member")
+		}
+		tc, err := r.targetCluster(ctx, u.GetNamespace(), m.KubeconfigSecret, m.KubeconfigKey)
+		if err != nil {
+			return fail(err, "failed to build a client for cluster "+m.Name)
+		}
+		ms := memberState{clusterMember: m, tc: tc}
+		if err := tc.Get(ctx, types.NamespacedName{Namespace: targetNS, Name: s.TargetDeployment}, &ms.dep); err != nil {
+			return fail(err, "failed to get target Deployment in cluster "+m.Name)
+		}
+		locked, holder, err := r.acquireLock(ctx, tc, u, &ms.dep, s.ForceAdopt)
+		if err != nil {
+			return fail(err, "failed to stamp managed-by annotation in cluster "+m.Name)
+		}
+		if !locked {
+			msg := fmt.Sprintf("Deployment in cluster %s is managed by %q; set spec.forceAdopt to take over", m.Name, holder)
+			snap.SkipReason = "ClaimedElsewhere"
+			if setCondition(u, condTargetAdopted, metav1.ConditionFalse, "ClaimedElsewhere", msg) {
+				if err := r.patchStatus(ctx, u); err != nil {
+					logger.Error(err, "failed to update status (will retry later)")
+				}
+			}
+			return requeue, nil
+		}
+		if ms.dep.Spec.Replicas != nil {
+			current += *ms.dep.Spec.Replicas
+		}
+
+		cpuQ, memQ := usageQueries(r.opts.MetricNames.with(s.MetricNames), targetNS, ms.dep.Name+"-.*", s.RateWindows.CPU)
+		cpu, cpuFound, err := usage(m.PromURL, cpuQ, s.Percentile)
+		if err != nil {
+			return fail(err, "prometheus cpu query failed in cluster "+m.Name)
+		}
+		mem, memFound, err := usage(m.PromURL, memQ, s.Percentile)
+		if err != nil {
+			return fail(err, "prometheus mem query failed in cluster "+m.Name)
+		}
+		if err := ms.tc.checkUsagePresent(ctx, &ms.dep, cpuFound, memFound); err != nil {
+			return fail(err, "usage metrics unavailable in cluster "+m.Name)
+		}
+		cpuCores += cpu
+		memMiB += mem / (1024 * 1024)
+		members = append(members, ms)
+	}
+	setCondition(u, condTargetAdopted, metav1.ConditionTrue, "Adopted", "")
+	snap.Current, snap.CPUCores, snap.MemMiB = current, cpuCores, memMiB
+
+	now := r.clock.Now()
+	var lastScale time.Time
+	if str, _, _ := unstructured.NestedString(u.Object, "status", "lastScaleTime"); str != "" {
+		lastScale, _ = time.Parse(time.RFC3339, str)
+	}
+	budgetTokens, budgetUpdated := scalingBudget(u)
+	prevDirection, prevSamples := sampleStreak(u, now, s.PollInterval)
+	pendingDirection, pendingSince := pendingChange(u)
+	d := decision.Decide(s.policy(), decision.Input{
+		Current:          current,
+		CPUCores:         cpuCores,
+		MemMiB:           memMiB,
+		LastScale:        lastScale,
+		BudgetTokens:     budgetTokens,
+		BudgetUpdated:    budgetUpdated,
+		PrevDirection:    prevDirection,
+		PrevSamples:      prevSamples,
+		PendingDirection: pendingDirection,
+		PendingSince:     pendingSince,
+		Now:              now,
+	})
+	snap.DecisionID = string(uuid.NewUUID())
+	snap.CPUReplicas, snap.MemReplicas, snap.Desired = d.CPUReplicas, d.MemReplicas, d.Desired
+	statusChanged := recordSampleStreak(u, d, now)
+	if recordPendingChange(u, d) {
+		statusChanged = true
+	}
+
+	weights := make([]float64, len(members))
+	for i, m := range members {
+		weights[i] = m.Weight
+	}
+	// Rebalance even without a total change, so weight edits take effect
+	total := current
+	if d.Reason == decision.ReasonScale {
+		total = d.New
+	}
+	if total == current && balanced(members, decision.Distribute(current, weights)) {
+		if d.Reason != decision.ReasonScale {
+			snap.SkipReason = d.Reason
+			if d.CooldownRemaining > 0 {
+				snap.CooldownRemaining = d.CooldownRemaining.Round(time.Second).String()
+			}
+		}
+		if statusChanged {
+			if err := r.patchStatus(ctx, u); err != nil {
+				logger.Error(err, "failed to update status (will retry later)")
+			}
+		}
+		return requeue, nil
+	}
+
+	return r.apply(ctx, req, u, s, targetCluster{}, nil, targetKey, proposal{
+		Current:   current,
+		Desired:   d.Desired,
+		Replicas:  total,
+		LimitedBy: d.LimitedBy,
+		Metrics:   map[string]float64{"cpuCores": cpuCores, "memMiB": memMiB},
+		Now:       now,
+		Scale: func(ctx context.Context, replicas int32) error {
+			return r.scaleMembers(ctx, u, members, decision.Distribute(replicas, weights))
+		},
+	}, snap)
+}
+
+// balanced reports whether every member already runs its share of split.
+func balanced(members []memberState, split []int32) bool {
+	for i := range members {
+		if replicasOrOne(&members[i].dep) != split[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scaleMembers sets each member's Deployment to its share of split and lists
+// the shares in status.clusters.
+func (r *reconciler) scaleMembers(ctx context.Context, u *unstructured.Unstructured, members []memberState, split []int32) error {
+	statusClusters := make([]interface{}, 0, len(members))
+	for i := range members {
+		ms := &members[i]
+		want := split[i]
+		statusClusters = append(statusClusters, map[string]interface{}{"name": ms.Name, "replicas": int64(want)})
+		if ms.dep.Spec.Replicas != nil && *ms.dep.Spec.Replicas == want {
+			continue
+		}
+		ms.dep.Spec.Replicas = &want
+		if err := ms.tc.Update(ctx, &ms.dep); err != nil {
+			return fmt.Errorf("cluster %s: %w", ms.Name, err)
+		}
+	}
+	return unstructured.SetNestedSlice(u.Object, statusClusters, "status", "clusters")
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/jetstream"
+)
+
+// natsRef is spec.nats: a JetStream consumer whose pending messages stream
+// consumer replicas are sized on, TargetPerReplica each.
+type natsRef struct {
+	MonitoringURL     string // a server's HTTP monitoring port, e.g. http://nats.nats:8222
+	Account           string // optional; any account by default
+	Stream            string
+	Consumer          string
+	IncludeAckPending bool // count delivered-but-unacked messages too; default true
+	TargetPerReplica  float64
+	Timeout           time.Duration
+}
+
+// natsMetric reads ref's consumer from the server's /jsz endpoint.
+func natsMetric(ctx context.Context, ref natsRef) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ref.Timeout)
+	defer cancel()
+	c, err := jetstream.Pending(ctx, ref.MonitoringURL, ref.Account, ref.Stream, ref.Consumer)
+	if err != nil {
+		return 0, fmt.Errorf("spec.nats: %w", err)
+	}
+	v := float64(c.NumPending)
+	if ref.IncludeAckPending {
+		v += float64(c.NumAckPending)
+	}
+	return v, nil
+}
@@ -4,17 +4,34 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decisionhook"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decisionstore"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/opencost"
 	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
 )
 
@@ -26,21 +43,146 @@ var (
 	}
 )
 
+// Options tunes controller-wide behavior that is not part of any CR spec.
+type Options struct {
+	// AllowedTargetNamespaces lists namespaces a CR may target via
+	// spec.targetRef.namespace besides its own. "*" allows any namespace.
+	AllowedTargetNamespaces []string
+	// InstanceName identifies this controller in the managed-by annotation it
+	// stamps on Deployments, so separate installations don't fight over one.
+	InstanceName string
+	// Debug, when set, receives a snapshot of every reconcile for /debug.
+	Debug *DebugStore
+	// Events, when set, receives every scaling decision as a CloudEvent.
+	Events *events.Async
+	// Bus, when set, receives scale events and saturation alerts only.
+	Bus *events.Async
+	// Annotations, when set, marks every scale on Grafana dashboards.
+	Annotations *events.Async
+	// Silences, when set, receives the scales of CRs with spec.alertSilence
+	// and silences their alerts in Alertmanager.
+	Silences *events.Async
+	// Decisions, when set, persists every decision for offline analysis.
+	Decisions *decisionstore.Store
+	// OpenCostURL is the OpenCost/Kubecost API used by spec.costCap when the
+	// CR does not name its own.
+	OpenCostURL string
+	// PolicyConfigMap holds an org-wide Rego policy (key policy.rego) that may
+	// deny or clamp every scaling action; empty disables it.
+	PolicyConfigMap types.NamespacedName
+	// PolicyTimeZone is the zone of the policy's input.time.local; nil means UTC.
+	PolicyTimeZone *time.Location
+	// PollJitter stretches every requeue by a random 0..PollJitter fraction
+	// of it, so CRs created together (a GitOps sync) drift apart instead of
+	// querying Prometheus and writing status in the same second forever.
+	PollJitter float64
+	// MaxConcurrentReconciles is how many CRs are reconciled in parallel;
+	// zero means one at a time.
+	MaxConcurrentReconciles int
+	// RateLimiter paces requeues after errors; nil means controller-runtime's default.
+	RateLimiter workqueue.RateLimiter
+	// WriteQPS and WriteBurst bound all Deployment, CR and status writes to
+	// the local API server, across every CR; WriteQPS zero disables the limit.
+	WriteQPS   float64
+	WriteBurst int
+	// ScalesPerMinute caps how many targets are scaled per minute across
+	// every CR; a scale over it is skipped until the next poll. Zero disables.
+	ScalesPerMinute int
+	// WatchPods recomputes a CR as soon as a target pod turns Ready or
+	// unready, instead of on the next poll. It caches every Pod in the
+	// cluster, so it is off by default.
+	WatchPods bool
+	// LiveTargetRead re-reads the target Deployment from the API server
+	// right before updating it, and skips the cycle if its replica count is
+	// no longer the one the decision was based on (the cache can lag a scale
+	// by another actor by a second or two).
+	LiveTargetRead bool
+	// Role selects recommending, actuating or both (the zero value); see Role.
+	Role Role
+	// MetricNames are the cAdvisor series and labels the CPU and memory
+	// queries use, unless a CR overrides them; zero means DefaultMetricNames.
+	MetricNames MetricNames
+	// HysteresisLogInterval logs the "within hysteresis; no scale" message,
+	// which every steady CR would otherwise repeat each poll, at most once
+	// per this interval per CR. Zero logs it every cycle.
+	HysteresisLogInterval time.Duration
+	// StatusObservationInterval is how often status.lastObservation is
+	// rewritten with the latest metrics, per-signal replicas and skip reason
+	// while they hold steady; a new skip reason or error is written at once.
+	// Zero leaves it out of status.
+	StatusObservationInterval time.Duration
+	// Clock drives cooldown and other time-window logic; nil means the real clock.
+	Clock clock.PassiveClock
+}
+
 type reconciler struct {
 	client.Client
+	opts      Options
+	clock     clock.PassiveClock
+	apiReader client.Reader
+	discovery *promDiscoverer
+	clusters  *remoteClusters
+	policy    *policyGate
+	scales    *scaleLimiter
+	// hysteresisLog samples the "within hysteresis" message, logged every
+	// cycle a CR holds steady
+	hysteresisLog *logSampler
+	// firstSeen starts spec.initialDelay for each CR
+	firstSeen *firstSeen
 }
 
-func SetupNginxAutoscalerController(mgr ctrl.Manager) error {
-	r := &reconciler{Client: mgr.GetClient()}
-	// Watch the CRD using an unstructured object (no codegen needed)
+func SetupNginxAutoscalerController(mgr ctrl.Manager, opts Options) error {
+	r := newReconciler(limitWrites(mgr.GetClient(), opts.WriteQPS, opts.WriteBurst), mgr.GetAPIReader(), opts)
+	if err := indexTargetKey(context.Background(), mgr); err != nil {
+		return err
+	}
+	// Watch the CRD using an unstructured object (no codegen needed), and the
+	// targets so replica drift from humans or CI is corrected right away
 	u := &unstructured.Unstructured{}
 	u.SetGroupVersionKind(autoscalerGVK)
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(u).
-		Complete(r)
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(r.autoscalersForDeployment),
+			builder.WithPredicates(replicasChanged))
+	if opts.WatchPods {
+		b = b.Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.autoscalersForPod),
+			builder.WithPredicates(readinessChanged))
+	}
+	return b.WithOptions(controller.Options{
+		MaxConcurrentReconciles: opts.MaxConcurrentReconciles,
+		RateLimiter:             opts.RateLimiter,
+	}).Complete(r)
+}
+
+func newReconciler(c client.Client, apiReader client.Reader, opts Options) *reconciler {
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.RealClock{}
+	}
+	opts.MetricNames = opts.MetricNames.orDefault()
+	return &reconciler{
+		Client:        c,
+		opts:          opts,
+		clock:         clk,
+		apiReader:     apiReader,
+		discovery:     &promDiscoverer{reader: apiReader, clock: clk},
+		clusters:      &remoteClusters{reader: apiReader, scheme: c.Scheme()},
+		policy:        newPolicyGate(apiReader, opts.PolicyConfigMap, opts.PolicyTimeZone),
+		scales:        newScaleLimiter(opts.ScalesPerMinute),
+		hysteresisLog: newLogSampler(opts.HysteresisLogInterval),
+		firstSeen:     newFirstSeen(),
+	}
 }
 
 func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	res, err := r.reconcile(ctx, req)
+	if res.RequeueAfter > 0 && r.opts.PollJitter > 0 {
+		res.RequeueAfter = wait.Jitter(res.RequeueAfter, r.opts.PollJitter)
+	}
+	return res, err
+}
+
+func (r *reconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx).WithValues("nginxautoscaler", req.NamespacedName)
 
 	// 1) Load CR
@@ -48,61 +190,168 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	u.SetGroupVersionKind(autoscalerGVK)
 	if err := r.Get(ctx, req.NamespacedName, u); err != nil {
 		// gone? nothing to do.
+		r.opts.Debug.forget(req.String())
+		r.hysteresisLog.forget(req.String())
+		r.firstSeen.forget(req.String())
+		forgetMetrics(req)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	if r.opts.Role == RoleRecommender {
+		// Recommenders never claim targets; the finalizer is the actuator's
+		if u.GetDeletionTimestamp() != nil {
+			r.opts.Debug.forget(req.String())
+			r.hysteresisLog.forget(req.String())
+			r.firstSeen.forget(req.String())
+			forgetMetrics(req)
+			return ctrl.Result{}, nil
+		}
+	} else if done, err := r.handleFinalizer(ctx, u); done || err != nil {
+		r.opts.Debug.forget(req.String())
+		r.hysteresisLog.forget(req.String())
+		r.firstSeen.forget(req.String())
+		forgetMetrics(req)
+		return ctrl.Result{}, err
+	}
 
-	spec := u.Object["spec"].(map[string]interface{})
+	// Status writes are patches against the status as loaded here
+	ctx = withStatusBase(ctx, u)
 
-	// Extract spec
-	getStr := func(key, def string) string {
-		if v, ok := spec[key].(string); ok && v != "" {
-			return v
+	// Whatever path we exit through, leave a snapshot behind for /debug and
+	// (throttled) in status.lastObservation, and let clients see this
+	// generation of the spec was processed
+	snap := DebugSnapshot{Autoscaler: req.String(), Time: r.clock.Now()}
+	defer func() {
+		observed := r.opts.StatusObservationInterval > 0 && recordObservation(u, snap, r.opts.StatusObservationInterval)
+		peaked := snap.DecisionID != "" && snap.Error == "" && recordPeak(u, snap)
+		if g, _, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration"); observed || peaked || g != u.GetGeneration() {
+			if err := r.patchStatus(ctx, u); err != nil {
+				logger.Error(err, "failed to record observation (will retry later)")
+			}
 		}
-		return def
+		r.opts.Debug.record(snap)
+		if snap.DecisionID != "" {
+			logger.Info("decision", decisionLogValues(snap)...)
+			r.opts.Events.Emit(decisionEvent(req.NamespacedName, snap))
+			if r.opts.Decisions != nil {
+				if err := r.opts.Decisions.Record(snap.Autoscaler, snap.Time, snap); err != nil {
+					logger.Error(err, "failed to persist decision")
+				}
+			}
+		}
+	}()
+
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+
+	// Layer the CR over its namespace's AutoscalerDefaults, then clamp to the admin limits
+	nsDefaults, err := loadNamespaceDefaults(ctx, r, req.Namespace)
+	if err != nil {
+		logger.Error(err, "failed to load namespace defaults; using CR spec only")
+	}
+	s := nsDefaults.applyLimits(parseSpec(nsDefaults.mergeSpec(spec)))
+	s.MinReplicas = scheduledMin(s, r.clock.Now())
+
+	// 2) Load Deployment (cross-namespace targets must be allowlisted on the controller)
+	targetNS := req.Namespace
+	if s.TargetNamespace != "" {
+		targetNS = s.TargetNamespace
 	}
-	getF64 := func(key string, def float64) float64 {
-		if v, ok := spec[key].(float64); ok {
-			return v
+	if !r.targetNamespaceAllowed(req.Namespace, targetNS) {
+		msg := fmt.Sprintf("namespace %q is not in --allowed-target-namespaces", targetNS)
+		logger.Info("refusing cross-namespace target", "targetNamespace", targetNS)
+		snap.SkipReason = "NamespaceNotAllowed"
+		if setCondition(u, condTargetAllowed, metav1.ConditionFalse, "NamespaceNotAllowed", msg) {
+			if err := r.patchStatus(ctx, u); err != nil {
+				logger.Error(err, "failed to update status (will retry later)")
+			}
 		}
-		return def
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 	}
-	getI32 := func(key string, def int32) int32 {
-		if v, ok := spec[key].(int64); ok {
-			return int32(v)
+	setCondition(u, condTargetAllowed, metav1.ConditionTrue, "Allowed", "")
+
+	// A selector fans out into one generated CR per matching Deployment
+	if s.TargetSelector != nil {
+		if len(s.Clusters) > 0 {
+			logger.Info("spec.targetSelector can't be combined with spec.clusters")
+			snap.SkipReason = "SelectorWithClusters"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 		}
-		if v, ok := spec[key].(float64); ok {
-			return int32(v)
+		if r.opts.Role == RoleRecommender {
+			// The generated CRs are the actuator's to write; recommend for them instead
+			snap.SkipReason = "SelectorParent"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 		}
-		return def
+		return r.reconcileSelector(ctx, u, s, targetNS, &snap)
+	}
+
+	// Only the oldest CR targeting a Deployment may scale it; the rest stand down
+	targetKey := targetKeyFor(req.Namespace, s)
+	snap.Target = targetKey
+	owner, claimants, err := r.conflictOwner(ctx, targetKey)
+	if err != nil {
+		logger.Error(err, "failed to list autoscalers sharing the target")
+		snap.Error = err.Error()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 	}
-	parseDur := func(s string, def time.Duration) time.Duration {
-		if s == "" {
-			return def
+	if owner != nil && owner.GetUID() != u.GetUID() {
+		msg := fmt.Sprintf("%s is also managed by %s/%s", targetKey, owner.GetNamespace(), owner.GetName())
+		logger.Info("target claimed by an older autoscaler; standing down", "owner", owner.GetNamespace()+"/"+owner.GetName())
+		snap.SkipReason = "Conflicted"
+		if setCondition(u, condConflicted, metav1.ConditionTrue, "TargetClaimed", msg) {
+			if err := r.patchStatus(ctx, u); err != nil {
+				logger.Error(err, "failed to update status (will retry later)")
+			}
 		}
-		d, err := time.ParseDuration(s)
-		if err != nil {
-			return def
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	setCondition(u, condConflicted, metav1.ConditionFalse, "SoleOwner", fmt.Sprintf("%d autoscaler(s) target %s", claimants, targetKey))
+
+	// A service spread over several clusters is sized as a whole, then split by weight
+	if len(s.Clusters) > 0 {
+		if r.opts.Role == RoleRecommender || r.opts.Role == RoleActuator {
+			logger.Info("spec.clusters is only supported with --role=all", "role", r.opts.Role)
+			snap.SkipReason = "RoleUnsupported"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 		}
-		return d
+		return r.reconcileClusters(ctx, req, u, s, targetNS, targetKey, &snap)
 	}
 
-	targetDeployment := getStr("targetDeployment", "nginx-sample-deployment-2")
-	promURL := getStr("promURL", "http://kube-prometheus-stack-prometheus.monitoring.svc:9090")
-	pollInterval := parseDur(getStr("pollInterval", "15s"), 15*time.Second)
-	cooldown := parseDur(getStr("cooldown", "60s"), 60*time.Second)
-	minReplicas := getI32("minReplicas", 2)
-	maxReplicas := getI32("maxReplicas", 20)
-	targetCPU := getF64("targetCPU", 0.2)   // cores per replica
-	targetMem := getF64("targetMem", 300.0) // MiB per replica
-	hysteresisPct := getF64("hysteresisPct", 10.0)
-	stepLimit := getI32("stepLimit", 5)
+	// The target may live in a spoke cluster reached through a kubeconfig Secret
+	tc, err := r.targetCluster(ctx, req.Namespace, s.KubeconfigSecret, s.KubeconfigKey)
+	if err != nil {
+		logger.Error(err, "failed to build a client for the target cluster")
+		snap.Error = err.Error()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
 
-	// 2) Load Deployment
 	var dep appsv1.Deployment
-	key := types.NamespacedName{Namespace: req.Namespace, Name: targetDeployment}
-	if err := r.Get(ctx, key, &dep); err != nil {
-		logger.Error(err, "failed to get target Deployment", "name", targetDeployment)
-		return ctrl.Result{RequeueAfter: pollInterval}, client.IgnoreNotFound(err)
+	key := types.NamespacedName{Namespace: targetNS, Name: s.TargetDeployment}
+	if err := tc.getTarget(ctx, s.TargetKind, key, &dep); err != nil {
+		logger.Error(err, "failed to get target Deployment", "name", s.TargetDeployment)
+		snap.Error = err.Error()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, client.IgnoreNotFound(err)
+	}
+
+	// Claim the Deployment; refuse if another autoscaler or system already holds
+	// it. A recommender only reads the target, so it claims nothing.
+	if r.opts.Role != RoleRecommender {
+		locked, holder, err := r.acquireLock(ctx, tc, u, &dep, s.ForceAdopt)
+		if err != nil {
+			logger.Error(err, "failed to stamp managed-by annotation")
+			snap.Error = err.Error()
+			return ctrl.Result{RequeueAfter: s.PollInterval}, err
+		}
+		if !locked {
+			msg := fmt.Sprintf("Deployment is managed by %q; set spec.forceAdopt to take over", holder)
+			logger.Info("target claimed by another manager; not scaling", "holder", holder)
+			snap.SkipReason = "ClaimedElsewhere"
+			if setCondition(u, condTargetAdopted, metav1.ConditionFalse, "ClaimedElsewhere", msg) {
+				if err := r.patchStatus(ctx, u); err != nil {
+					logger.Error(err, "failed to update status (will retry later)")
+				}
+			}
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		setCondition(u, condTargetAdopted, metav1.ConditionTrue, "Adopted", "")
 	}
 
 	if dep.Spec.Replicas == nil {
@@ -110,113 +359,968 @@ func (r *reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		dep.Spec.Replicas = &r1
 	}
 	current := *dep.Spec.Replicas
+	snap.Current = current
+	snap.Revision = dep.Annotations[revisionAnnotation]
+	snap.TemplateHash, _, _ = unstructured.NestedString(u.Object, "status", "observedTemplateHash")
+
+	// Per-replica targets a pod can never reach would make every scale-up late
+	if limits, err := podLimits(ctx, tc.reader, &dep); err != nil {
+		logger.Error(err, "failed to read LimitRanges")
+	} else if msg := targetsBeyondLimits(s, limits); setTargetsFeasible(u, msg) {
+		if msg != "" {
+			logger.Info("per-replica targets exceed what a pod may use", "detail", msg)
+		}
+		if err := r.patchStatus(ctx, u); err != nil {
+			logger.Error(err, "failed to update status (will retry later)")
+		}
+	}
+
+	// An actuator takes its numbers from the recommender instead of Prometheus
+	if r.opts.Role == RoleActuator {
+		return r.actuateRecommendation(ctx, req, u, s, tc, &dep, targetKey, &snap)
+	}
+
+	// Resolve Prometheus: explicit spec/defaults value, else auto-discovered
+	if s.PromURL == "" && s.KubeconfigSecret != "" {
+		err := fmt.Errorf("spec.promURL is required for targets in another cluster")
+		logger.Error(err, "cannot discover Prometheus for a remote target")
+		snap.Error = err.Error()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	if s.PromURL == "" {
+		url, err := r.discovery.discover(ctx)
+		if err != nil {
+			logger.Error(err, "prometheus discovery failed")
+			snap.Error = err.Error()
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		s.PromURL = url
+	}
+	statusChanged := false
+	if recorded, _, _ := unstructured.NestedString(u.Object, "status", "promURL"); recorded != s.PromURL {
+		_ = unstructured.SetNestedField(u.Object, s.PromURL, "status", "promURL")
+		statusChanged = true
+	}
+	names := r.opts.MetricNames.with(s.MetricNames)
+	// Learned per-replica capacity replaces the hand-tuned targets once there is enough history
+	if rpsQ := s.requestsQuery(&dep); s.Calibration != nil && rpsQ == "" {
+		logger.Info("calibration needs targetRPS with spec.ingress or spec.istio; calibration inactive")
+	} else if s.Calibration != nil {
+		cal, ok := readCalibration(u)
+		if now := r.clock.Now(); !ok || now.Sub(cal.Updated) >= s.Calibration.Refresh {
+			cpuQ, memQ, perRPSQ := perReplicaQueries(names, dep.Namespace, dep.Name+"-.*", s.RateWindows.CPU, rpsQ)
+			if fresh, err := calibrate(s.PromURL, *s.Calibration, cpuQ, memQ, perRPSQ, s.TargetRPS, now); err != nil {
+				logger.Error(err, "capacity calibration failed; keeping the previous fit")
+			} else {
+				// Size on the fit as stored, so this poll and the next agree
+				writeCalibration(u, fresh)
+				cal, ok = readCalibration(u)
+				statusChanged = true
+			}
+		}
+		cpu, mem := cal.targets(*s.Calibration)
+		if cpu > 0 {
+			s.TargetCPU = cpu
+		}
+		if mem > 0 {
+			s.TargetMem = mem
+		}
+	}
+	// Suggest a band, cooldown and step limit from the demand history; only an operator applies them
+	if s.Tuning != nil && s.TargetCPU > 0 {
+		if now := r.clock.Now(); now.Sub(recommendationsUpdated(u)) >= s.Tuning.Refresh {
+			cpuQ, _ := usageQueries(names, dep.Namespace, dep.Name+"-.*", s.RateWindows.CPU)
+			if t, samples, ok, err := tune(s.PromURL, *s.Tuning, cpuQ, s.TargetCPU); err != nil {
+				logger.Error(err, "tuning fit failed; keeping the previous recommendations")
+			} else {
+				writeRecommendations(u, t, samples, ok, now)
+				statusChanged = true
+			}
+		}
+	}
+	// Learning mode watches demand for a period without scaling, then suggests bounds
+	inLearning := false
+	if s.Learning != nil {
+		now := r.clock.Now()
+		l, ok := readLearning(u)
+		switch {
+		case !ok:
+			l = learning{Started: now}
+			writeLearning(u, l)
+			statusChanged = true
+			inLearning = true
+		case !l.Completed.IsZero():
+		case now.Sub(l.Started) < s.Learning.Period:
+			inLearning = true
+		default:
+			cpuQ, memQ := usageQueries(names, dep.Namespace, dep.Name+"-.*", s.RateWindows.CPU)
+			if fit, err := learnBounds(s.PromURL, *s.Learning, cpuQ, memQ, s.TargetCPU, s.TargetMem, l.Started, now); err != nil {
+				logger.Error(err, "failed to fit learned bounds; still learning")
+				inLearning = true
+			} else {
+				logger.Info("learning complete", "suggestedMinReplicas", fit.MinReplicas,
+					"suggestedMaxReplicas", fit.MaxReplicas, "samples", fit.Samples)
+				writeLearning(u, fit)
+				statusChanged = true
+			}
+		}
+	} else if _, ok := readLearning(u); ok {
+		// Dropping spec.learning forgets the last run, so adding it back starts a new one
+		unstructured.RemoveNestedField(u.Object, "status", "learning")
+		statusChanged = true
+	}
+	// Pods the vertical fallback resized carry a bigger share of the load each
+	if s.Vertical != nil {
+		if st, ok := readVertical(u); ok {
+			cpuScale, memScale := st.scales(fallbackContainer(&dep, s.Vertical.Container))
+			s.TargetCPU *= cpuScale
+			s.TargetMem *= memScale
+		}
+	}
+	// So do pods resized in place, on average over the running ones
+	var resizable *inPlacePods
+	if s.InPlace != nil {
+		if ip, err := tc.inPlacePods(ctx, &dep, *s.InPlace); err != nil {
+			logger.Error(err, "failed to list pods; in-place resize inactive")
+		} else if ip != nil {
+			resizable = ip
+			s.TargetCPU *= ip.scale
+		}
+	}
+	// Remember when the target last rolled out, for the post-rollout scale-down window
+	lastRollout, rolled := observeRollout(u, &dep, r.clock.Now())
+	if rolled {
+		if hash, err := tc.templateHash(ctx, &dep); err != nil {
+			logger.Error(err, "failed to look up the target's pod-template-hash")
+		} else {
+			_ = unstructured.SetNestedField(u.Object, hash, "status", "observedTemplateHash")
+		}
+	}
+	if rolled || statusChanged {
+		if err := r.patchStatus(ctx, u); err != nil {
+			logger.Error(err, "failed to update status (will retry later)")
+		}
+	}
 
 	// 3) Query Prometheus (sum across pods of this deployment – by pod prefix)
-	prefix := dep.Name + "-"
-	cpuQ := fmt.Sprintf(`sum(rate(container_cpu_usage_seconds_total{namespace="%s",pod=~"%s.*",image!=""}[2m]))`, dep.Namespace, prefix)
-	memQ := fmt.Sprintf(`sum(container_memory_working_set_bytes{namespace="%s",pod=~"%s.*",image!=""})`, dep.Namespace, prefix)
+	podSel := dep.Name + "-.*"
+	// Pods still warming up (JIT, cache fill) look idle; measure only warm ones
+	// and extrapolate their average to every running pod.
+	extrapolate := 1.0
+	if s.WarmUp > 0 {
+		warm, running, err := r.warmPods(ctx, tc, &dep, s.WarmUp)
+		switch {
+		case err != nil:
+			logger.Error(err, "failed to list pods for warm-up exclusion; using all pods")
+		case len(warm) > 0 && len(warm) < running:
+			podSel = podRegex(warm)
+			extrapolate = float64(running) / float64(len(warm))
+		}
+	}
+	cpuQ, memQ := usageQueries(names, dep.Namespace, podSel, s.RateWindows.CPU)
 
-	cpu, err := prom.InstantVector(promURL, cpuQ)
+	cpu, cpuFound, err := usage(s.PromURL, cpuQ, s.Percentile)
 	if err != nil {
 		logger.Error(err, "prometheus cpu query failed")
-		return ctrl.Result{RequeueAfter: pollInterval}, nil
+		snap.Error = err.Error()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 	}
-	mem, err := prom.InstantVector(promURL, memQ)
+	mem, memFound, err := usage(s.PromURL, memQ, s.Percentile)
 	if err != nil {
 		logger.Error(err, "prometheus mem query failed")
-		return ctrl.Result{RequeueAfter: pollInterval}, nil
+		snap.Error = err.Error()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	// Empty results only mean zero load when there are no pods to measure
+	missing := tc.checkUsagePresent(ctx, &dep, cpuFound, memFound)
+	if setMetricsAvailable(u, missing) {
+		if err := r.patchStatus(ctx, u); err != nil {
+			logger.Error(err, "failed to update status (will retry later)")
+		}
+	}
+	if missing != nil {
+		logger.Info("usage metrics unavailable; holding replicas", "reason", missing.Error())
+		snap.SkipReason = "MetricsUnavailable"
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	if !decision.Usable(cpu) || !decision.Usable(mem) {
+		// NaN/±Inf, or a negative rate from a counter reset: no data, not zero load
+		logger.Info("discarding invalid usage sample", "cpu", cpu, "mem", mem)
+		snap.SkipReason = "InvalidSample"
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	totalCPUcores := cpu * extrapolate // seconds/sec → cores
+	totalMemMiB := mem * extrapolate / (1024 * 1024)
+	snap.CPUCores, snap.MemMiB = totalCPUcores, totalMemMiB
+
+	// CPU trend; without it the derivative term just sits this cycle out
+	var cpuSlope float64
+	derivative := s.Derivative
+	if derivative == nil && s.Shadow != nil {
+		derivative = s.Shadow.Derivative
+	}
+	if derivative != nil {
+		if slope, err := prom.InstantVector(s.PromURL, cpuSlopeQuery(cpuQ, derivative.Window)); err != nil {
+			logger.Error(err, "prometheus cpu slope query failed; derivative term inactive")
+		} else if math.IsNaN(slope) || math.IsInf(slope, 0) {
+			logger.Info("invalid cpu slope sample; derivative term inactive", "slope", slope)
+		} else {
+			cpuSlope = slope * extrapolate
+			snap.CPUSlope = cpuSlope
+		}
+	}
+
+	// Optional traffic signals: capacity follows requests (ingress-nginx, else
+	// the Istio mesh) and mesh latency, not lagging pod CPU
+	var rps, latencyMs float64
+	istio := s.istioFor(&dep)
+	rpsQ := s.requestsQuery(&dep)
+	if rpsQ != "" {
+		rps, err = prom.InstantVector(s.PromURL, rpsQ)
+		if err != nil {
+			logger.Error(err, "prometheus requests query failed")
+			snap.Error = err.Error()
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		if !decision.Usable(rps) {
+			logger.Info("discarding invalid requests sample", "rps", rps)
+			snap.SkipReason = "InvalidSample"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		snap.RPS = rps
+	}
+	latencyQ := ""
+	if s.Istio != nil && s.TargetLatencyMs > 0 {
+		latencyQ = istioLatencyQuery(istio, s.RateWindows.Latency)
+		latencyMs, err = prom.InstantVector(s.PromURL, latencyQ)
+		if err != nil {
+			logger.Error(err, "prometheus latency query failed")
+			snap.Error = err.Error()
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		if !decision.Usable(latencyMs) {
+			logger.Info("discarding invalid latency sample", "latencyMs", latencyMs)
+			snap.SkipReason = "InvalidSample"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		snap.LatencyMs = latencyMs
 	}
-	totalCPUcores := cpu // seconds/sec → cores
-	totalMemMiB := mem / (1024 * 1024)
 
-	// 4) Compute desired replicas
-	cpuReplicas := int32(math.Ceil(totalCPUcores / targetCPU))
-	memReplicas := int32(math.Ceil(totalMemMiB / targetMem))
-	desired := max32(cpuReplicas, memReplicas)
-	if desired < minReplicas {
-		desired = minReplicas
+	// Demand from outside Prometheus: pod JSON endpoints, a database backlog
+	var external float64
+	if s.hasExternal() {
+		values, err := r.externalMetrics(ctx, tc, &dep, u.GetNamespace(), s)
+		if err != nil {
+			logger.Error(err, "external metric failed")
+			snap.Error = err.Error()
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		for source, v := range values {
+			if !decision.Usable(v) {
+				logger.Info("discarding invalid external sample", "source", source, "value", v)
+				snap.SkipReason = "InvalidSample"
+				return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+			}
+		}
+		external = s.externalDemand(values)
+		snap.ExternalMetrics, snap.External = values, external
 	}
-	if desired > maxReplicas {
-		desired = maxReplicas
+
+	// Exporter restarts and counter resets show up as one wild sample; sit the cycle out
+	if s.Anomaly != nil {
+		signals := map[string]string{"cpu": cpuQ, "memory": memQ, "requests": rpsQ, "latency": latencyQ}
+		var discarded []string
+		for metric, q := range signals {
+			if q == "" {
+				continue
+			}
+			bad, err := anomalous(s.PromURL, q, *s.Anomaly)
+			if err != nil {
+				logger.Error(err, "anomaly check failed; trusting the sample", "metric", metric)
+				continue
+			}
+			if bad {
+				discardedSamples.WithLabelValues(req.Namespace, req.Name, metric).Inc()
+				discarded = append(discarded, metric)
+			}
+		}
+		if len(discarded) > 0 {
+			sort.Strings(discarded)
+			logger.Info("discarding anomalous samples", "metrics", discarded)
+			snap.SkipReason = "AnomalousSample"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
 	}
 
-	// 5) Hysteresis band
-	if !outsideBand(current, desired, hysteresisPct) {
-		logger.Info("within hysteresis; no scale",
-			"current", current, "desired", desired,
-			"cpu_cores", fmt.Sprintf("%.3f", totalCPUcores),
-			"mem_mib", fmt.Sprintf("%.1f", totalMemMiB))
-		return ctrl.Result{RequeueAfter: pollInterval}, nil
+	// SLO burn rate over the short window
+	var goodRate, totalRate float64
+	if s.SLO != nil {
+		goodQ, totalQ := sloQueries(*s.SLO)
+		if goodRate, err = prom.InstantVector(s.PromURL, goodQ); err == nil {
+			totalRate, err = prom.InstantVector(s.PromURL, totalQ)
+		}
+		if err != nil {
+			logger.Error(err, "prometheus SLO query failed")
+			snap.Error = err.Error()
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		if !decision.Usable(goodRate) || !decision.Usable(totalRate) {
+			logger.Info("discarding invalid SLO sample", "goodRate", goodRate, "totalRate", totalRate)
+			snap.SkipReason = "InvalidSample"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
 	}
 
-	// 6) Cooldown: store lastScaleTime in status
-	now := time.Now().Format(time.RFC3339)
+	// Error-rate guard: a failed guard query must not let a scale-down through
+	var errorRatio float64
+	if s.MaxErrorRatio > 0 {
+		errQ := s.ErrorQuery
+		switch {
+		case errQ != "":
+		case s.Ingress != nil:
+			ref := *s.Ingress
+			if ref.Namespace == "" {
+				ref.Namespace = dep.Namespace
+			}
+			errQ = ingressErrorRatioQuery(ref, s.RateWindows.Errors)
+		case s.Istio != nil:
+			errQ = istioErrorRatioQuery(istio, s.RateWindows.Errors)
+		}
+		if errQ == "" {
+			logger.Info("errorGuard needs errorGuard.query, spec.ingress or spec.istio; guard inactive")
+		} else if errorRatio, err = prom.InstantVector(s.PromURL, errQ); err != nil {
+			logger.Error(err, "prometheus error-ratio query failed; holding scale-down")
+			errorRatio = math.NaN()
+		} else if math.IsNaN(errorRatio) {
+			// 0/0 from a query without a clamped denominator: no traffic, so no errors
+			errorRatio = 0
+		} else if !decision.Usable(errorRatio) {
+			logger.Info("invalid error-ratio sample; holding scale-down", "errorRatio", errorRatio)
+			errorRatio = math.NaN()
+		} else {
+			snap.ErrorRatio = errorRatio
+		}
+	}
+
+	// Share of pods exposed to spot interruptions
+	var spotFraction float64
+	if s.SpotFactor > 1 {
+		if spotFraction, err = tc.spotFraction(ctx, &dep, s.SpotNodeLabels); err != nil {
+			logger.Error(err, "failed to inspect pod nodes for spot capacity; spot factor inactive")
+		}
+		snap.SpotFraction = spotFraction
+	}
+
+	// Zones in use, for zone-balanced rounding
+	var zones int32
+	if s.ZoneBalanced {
+		if zones, err = tc.zoneCount(ctx, &dep); err != nil {
+			logger.Error(err, "failed to count topology zones; zone rounding inactive")
+		}
+		snap.Zones = zones
+	}
+
+	// Connections on the least-busy pod; unknown holds scale-down
+	drainLoad := math.NaN()
+	if s.Drain != nil {
+		if byPod, err := prom.VectorByLabel(s.PromURL, drainQuery(*s.Drain, dep.Namespace, dep.Name+"-.*"), "pod"); err != nil {
+			logger.Error(err, "prometheus drain query failed; holding scale-down")
+		} else if drainLoad = lowestLoad(byPod); !math.IsNaN(drainLoad) {
+			snap.DrainLoad = drainLoad
+		}
+	}
+
+	// Price one replica for the cost ceiling; without a price the cap is skipped
+	var replicaCost float64
+	if s.MaxHourlyCost > 0 {
+		costURL := s.OpenCostURL
+		if costURL == "" {
+			costURL = r.opts.OpenCostURL
+		}
+		if costURL == "" {
+			logger.Info("costCap set but no OpenCost URL configured; cap inactive")
+		} else if replicaCost, err = opencost.ReplicaHourlyCost(costURL, dep.Namespace, dep.Name, current); err != nil {
+			logger.Error(err, "opencost query failed; cost cap inactive this cycle")
+		}
+		snap.ReplicaHourlyCost = replicaCost
+	}
+
+	// 4) Decide: per-metric sizing, clamp, hysteresis band, rollout window, cooldown, step limit
+	now := r.clock.Now()
+	// A target that keeps reversing gets a wider band and a longer cooldown for a while
+	if s.Flap != nil {
+		var started bool
+		s, snap.FlapDamped, started = dampFlapping(u, s, now)
+		if started {
+			reversals, _, _ := unstructured.NestedInt64(u.Object, "status", "flapDamping", "reversals")
+			logger.Info("flapping; widening hysteresis band and cooldown", "reversals", reversals,
+				"hysteresisPct", s.HysteresisPct, "cooldown", s.Cooldown, "for", s.Flap.Duration)
+			if err := r.patchStatus(ctx, u); err != nil {
+				logger.Error(err, "failed to record flap damping in status (will retry later)")
+			}
+		}
+	}
 	lastScaleStr, _, _ := unstructured.NestedString(u.Object, "status", "lastScaleTime")
+	snap.LastScaleTime = lastScaleStr
+	var lastScale time.Time
 	if lastScaleStr != "" {
 		if t, err := time.Parse(time.RFC3339, lastScaleStr); err == nil {
-			if time.Since(t) < cooldown {
-				logger.Info("cooldown active; skipping", "cooldown", cooldown)
-				return ctrl.Result{RequeueAfter: pollInterval}, nil
+			lastScale = t
+		}
+	}
+	budgetTokens, budgetUpdated := scalingBudget(u)
+	prevDirection, prevSamples := sampleStreak(u, now, s.PollInterval)
+	pendingDirection, pendingSince := pendingChange(u)
+	in := decision.Input{
+		Current:           current,
+		CPUCores:          totalCPUcores,
+		CPUSlope:          cpuSlope,
+		MemMiB:            totalMemMiB,
+		RPS:               rps,
+		LatencyMs:         latencyMs,
+		External:          external,
+		ErrorRatio:        errorRatio,
+		GoodRate:          goodRate,
+		TotalRate:         totalRate,
+		ReplicaHourlyCost: replicaCost,
+		SpotFraction:      spotFraction,
+		Zones:             zones,
+		LastScale:         lastScale,
+		LastRollout:       lastRollout,
+		BudgetTokens:      budgetTokens,
+		BudgetUpdated:     budgetUpdated,
+		PrevDirection:     prevDirection,
+		PrevSamples:       prevSamples,
+		PendingDirection:  pendingDirection,
+		PendingSince:      pendingSince,
+		DrainLoad:         drainLoad,
+		Now:               now,
+	}
+	d := decision.Decide(s.policy(), in)
+	snap.DecisionID = string(uuid.NewUUID())
+	recommendedReplicas.WithLabelValues(req.Namespace, req.Name, "active").Set(float64(d.New))
+	// The shadow policy sees the same signals and history; it is only reported
+	if s.Shadow != nil {
+		sd := decision.Decide(s.Shadow.policy(), in)
+		snap.ShadowDesired, snap.ShadowReplicas, snap.ShadowReason = sd.Desired, sd.New, sd.Reason
+		recommendedReplicas.WithLabelValues(req.Namespace, req.Name, "shadow").Set(float64(sd.New))
+		if sd.New != d.New {
+			logger.Info("shadow policy disagrees", "replicas", d.New, "shadowReplicas", sd.New,
+				"reason", d.Reason, "shadowReason", sd.Reason)
+		}
+	} else {
+		recommendedReplicas.DeleteLabelValues(req.Namespace, req.Name, "shadow")
+	}
+	desired, newReplicas := d.Desired, d.New
+	// Pods past the namespace's ResourceQuota would be rejected at creation; ask only for what fits
+	var quotaMsg string
+	if newReplicas > current {
+		if room, limiting, err := tc.quotaRoom(ctx, &dep); err != nil {
+			logger.Error(err, "failed to read ResourceQuotas; scaling without the quota check")
+		} else if int64(current)+int64(room) < int64(newReplicas) {
+			quotaMsg = fmt.Sprintf("ResourceQuota %s admits %d more pod(s), %d wanted", limiting, room, newReplicas-current)
+			newReplicas = current + room
+			d.New, d.Scale, d.LimitedBy = newReplicas, newReplicas != current, limitQuotaExceeded
+		}
+	}
+	snap.CPUReplicas, snap.MemReplicas, snap.Desired = d.CPUReplicas, d.MemReplicas, d.Desired
+	snap.RPSReplicas, snap.LatencyReplicas, snap.SLOReplicas = d.RPSReplicas, d.LatencyReplicas, d.SLOReplicas
+	snap.BurnRate, snap.TrendReplicas, snap.ExternalReplicas = d.BurnRate, d.TrendReplicas, d.ExternalReplicas
+
+	snap.LimitedBy = d.LimitedBy
+
+	var limitChanged bool
+	switch d.LimitedBy {
+	case decision.LimitCostCap:
+		msg := fmt.Sprintf("budget %.2f/h at %.2f/replica affords %d replicas", s.MaxHourlyCost, replicaCost, desired)
+		limitChanged = setCondition(u, condLimited, metav1.ConditionTrue, decision.LimitCostCap, msg)
+	case limitQuotaExceeded:
+		limitChanged = setCondition(u, condLimited, metav1.ConditionTrue, limitQuotaExceeded, quotaMsg)
+	default:
+		limitChanged = setCondition(u, condLimited, metav1.ConditionFalse, "WithinLimits", "")
+	}
+	// Demand beyond maxReplicas is worth an alert on the event bus, once per episode
+	need := max(d.CPUReplicas, d.MemReplicas, d.RPSReplicas, d.LatencyReplicas, d.SLOReplicas, d.TrendReplicas, d.ExternalReplicas)
+	if need > s.MaxReplicas {
+		msg := fmt.Sprintf("demand needs %d replicas, maxReplicas is %d", need, s.MaxReplicas)
+		if setCondition(u, condSaturated, metav1.ConditionTrue, "AtMaxReplicas", msg) {
+			limitChanged = true
+			r.opts.Bus.Emit(saturationEvent(req.NamespacedName, targetKey, snap.DecisionID, need, s.MaxReplicas, now))
+		}
+	} else if setCondition(u, condSaturated, metav1.ConditionFalse, "BelowMaxReplicas", "") {
+		limitChanged = true
+	}
+	// Past maxReplicas for long enough, grow the pods instead (or say how much)
+	if s.Vertical != nil {
+		resize := s.Vertical.Resize && r.opts.Role != RoleRecommender && s.GitOps == nil && !inLearning
+		cpuNeed := max(d.CPUReplicas, d.RPSReplicas, d.LatencyReplicas, d.SLOReplicas, d.TrendReplicas)
+		if changed, err := tc.verticalFallback(ctx, u, *s.Vertical, &dep, cpuNeed, d.MemReplicas, s.MaxReplicas, resize, now); err != nil {
+			logger.Error(err, "vertical fallback failed")
+		} else if changed {
+			limitChanged = true
+			if st, ok := readVertical(u); ok {
+				logger.Info("vertical fallback", "container", st.Container, "cpu", st.CPU, "memory", st.Memory, "resized", st.Resized)
+				r.opts.Bus.Emit(verticalEvent(req.NamespacedName, targetKey, snap.DecisionID, st))
 			}
 		}
 	}
+	if recordSampleStreak(u, d, now) {
+		limitChanged = true
+	}
+	if recordPendingChange(u, d) {
+		limitChanged = true
+	}
+	// Balloon pods hold nodes for the scale-up ahead; recommenders and learners leave targets alone
+	if r.opts.Role != RoleRecommender && !inLearning {
+		if changed, err := tc.overprovision(ctx, u, s.Overprovision, &dep, current, desired, s.MaxReplicas); err != nil {
+			logger.Error(err, "failed to size balloon pods")
+		} else if changed {
+			limitChanged = true
+		}
+	}
+	metrics := map[string]float64{
+		"cpuCores":   totalCPUcores,
+		"memMiB":     totalMemMiB,
+		"rps":        rps,
+		"latencyMs":  latencyMs,
+		"errorRatio": errorRatio,
+		"burnRate":   d.BurnRate,
+	}
+	if r.opts.Role == RoleRecommender {
+		rec := recommendation{Replicas: current, Desired: desired, Current: current,
+			Reason: d.Reason, LimitedBy: d.LimitedBy, Metrics: metrics, Time: now}
+		if d.Reason == decision.ReasonScale {
+			rec.Replicas = newReplicas
+		}
+		if publishRecommendation(u, rec, s.PollInterval) {
+			limitChanged = true
+		}
+		if rec.Replicas != current {
+			logger.Info("recommending", "current", current, "recommended", rec.Replicas, "desired_raw", desired)
+		}
+		snap.SkipReason = "Recommended"
+		if d.Reason != decision.ReasonScale {
+			snap.SkipReason = d.Reason
+		}
+		if d.CooldownRemaining > 0 {
+			snap.CooldownRemaining = d.CooldownRemaining.Round(time.Second).String()
+		}
+	}
+	if limitChanged {
+		if err := r.patchStatus(ctx, u); err != nil {
+			logger.Error(err, "failed to update status (will retry later)")
+		}
+	}
+	if r.opts.Role == RoleRecommender {
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	if inLearning {
+		snap.SkipReason = "Learning"
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	// Metrics are collected and decided on from the start; acting waits for initialDelay
+	if s.InitialDelay > 0 {
+		if remaining := r.firstSeen.since(req.String(), now).Add(s.InitialDelay).Sub(now); remaining > 0 {
+			logger.Info("within initial delay; not scaling yet",
+				"current", current, "desired", desired, "remaining", remaining.Round(time.Second))
+			snap.SkipReason = "InitialDelay"
+			snap.CooldownRemaining = remaining.Round(time.Second).String()
+			return ctrl.Result{RequeueAfter: min(remaining, s.PollInterval)}, nil
+		}
+	}
 
-	// 7) Rate limit step
-	diff := int32(0)
-	if desired > current {
-		diff = min32(desired-current, stepLimit)
-	} else if desired < current {
-		diff = -min32(current-desired, stepLimit)
+	switch d.Reason {
+	case decision.ReasonWithinHysteresis:
+		if r.hysteresisLog.allow(req.String(), now) {
+			logger.Info("within hysteresis; no scale",
+				"current", current, "desired", desired,
+				"cpu_cores", fmt.Sprintf("%.3f", totalCPUcores),
+				"mem_mib", fmt.Sprintf("%.1f", totalMemMiB))
+		}
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonBelowMinChange:
+		// as frequent as the hysteresis message on a large target, so sampled the same way
+		if r.hysteresisLog.allow(req.String(), now) {
+			logger.Info("change below the minimum; no scale",
+				"current", current, "desired", desired, "minChange", s.MinChange)
+		}
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonStepNotAllowed:
+		logger.Info("no allowed replica count within the step limit; holding",
+			"current", current, "desired", desired, "stepLimit", s.StepLimit)
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonCooldown:
+		logger.Info("cooldown active; skipping", "cooldown", s.Cooldown)
+		snap.SkipReason = d.Reason
+		snap.CooldownRemaining = d.CooldownRemaining.Round(time.Second).String()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonErrorRateHigh:
+		logger.Info("error rate above guard; holding scale-down",
+			"current", current, "desired", desired, "errorRatio", errorRatio, "maxRatio", s.MaxErrorRatio)
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonGuardVeto:
+		logger.Info("guard metric vetoes scale-down",
+			"current", current, "desired", desired, "primary", s.PrimaryMetric, "guard", d.GuardReplicas)
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonRecentRollout:
+		logger.Info("target rolled out recently; holding scale-down",
+			"current", current, "desired", desired, "remaining", d.CooldownRemaining.Round(time.Second))
+		snap.SkipReason = d.Reason
+		snap.CooldownRemaining = d.CooldownRemaining.Round(time.Second).String()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonUnconfirmed:
+		logger.Info("waiting for consecutive samples to agree",
+			"current", current, "desired", desired, "direction", d.Direction,
+			"samples", fmt.Sprintf("%d/%d", d.Samples, s.RequiredSamples))
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonPending:
+		logger.Info("change pending confirmation",
+			"current", current, "desired", desired, "direction", d.PendingDirection,
+			"remaining", d.CooldownRemaining.Round(time.Second))
+		snap.SkipReason = d.Reason
+		snap.CooldownRemaining = d.CooldownRemaining.Round(time.Second).String()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonInvalidInput:
+		logger.Info("invalid metric input; holding replicas", "current", current)
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonDraining:
+		logger.Info("waiting for a pod to drain before scaling down",
+			"current", current, "desired", desired, "lowestLoad", drainLoad, "maxPerPod", s.Drain.MaxPerPod)
+		snap.SkipReason = d.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	case decision.ReasonBudgetExhausted:
+		logger.Info("scaling budget spent; holding",
+			"current", current, "desired", desired, "budget", fmt.Sprintf("%d/%s", s.BudgetReplicas, s.BudgetWindow),
+			"remaining", d.CooldownRemaining.Round(time.Second))
+		snap.SkipReason = d.Reason
+		snap.CooldownRemaining = d.CooldownRemaining.Round(time.Second).String()
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 	}
-	newReplicas := current + diff
-	if newReplicas < minReplicas {
-		newReplicas = minReplicas
+
+	if newReplicas == current && d.LimitedBy == limitQuotaExceeded {
+		logger.Info("namespace quota leaves no room; not scaling up", "current", current, "desired", desired, "quota", quotaMsg)
+		snap.SkipReason = limitQuotaExceeded
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 	}
-	if newReplicas > maxReplicas {
-		newReplicas = maxReplicas
+
+	// Resize the running pods first; replicas change once they can't go further.
+	// A resize has no replica count for the gates to review, so with any gate
+	// configured (or the target in backoff) replicas change instead.
+	if s.InPlace != nil {
+		status, reason, msg := metav1.ConditionTrue, "Active", ""
+		if gates := r.scalingGates(s); len(gates) > 0 {
+			status, reason = metav1.ConditionFalse, "GatesConfigured"
+			msg = fmt.Sprintf("%s review every scale; replicas change instead", strings.Join(gates, ", "))
+			resizable = nil
+		}
+		if setCondition(u, condInPlaceResize, status, reason, msg) {
+			if err := r.patchStatus(ctx, u); err != nil {
+				logger.Error(err, "failed to update status (will retry later)")
+			}
+		}
 	}
+	if _, retryAt := actuationBackoff(u); now.Before(retryAt) {
+		resizable = nil
+	}
+	if resizable != nil {
+		cpuNeed := max(d.CPUReplicas, d.RPSReplicas, d.LatencyReplicas, d.SLOReplicas, d.TrendReplicas)
+		if cores, ok := resizable.target(s.InPlace.MaxCPU, cpuNeed, current, newReplicas > current); ok {
+			if err := tc.resizePods(ctx, resizable.pods, resizable.container, cores); err != nil {
+				logger.Error(err, "in-place resize failed; changing replicas instead")
+			} else {
+				logger.Info("resized pods in place", "pods", len(resizable.pods), "cpu", fmt.Sprintf("%.3f", cores),
+					"current", current, "desired", desired)
+				snap.SkipReason = "ResizedInPlace"
+				return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+			}
+		}
+	}
+
+	return r.apply(ctx, req, u, s, tc, &dep, targetKey, proposal{
+		Current:   current,
+		Desired:   desired,
+		Replicas:  newReplicas,
+		LimitedBy: d.LimitedBy,
+		Metrics:   metrics,
+		Now:       now,
+	}, &snap)
+}
+
+// scalingGates names the gates s's scales go through before reaching the
+// target, empty when a decision is applied as is.
+func (r *reconciler) scalingGates(s autoscalerSpec) []string {
+	var gates []string
+	if s.DecisionWebhook != nil {
+		gates = append(gates, "spec.decisionWebhook")
+	}
+	if r.policy != nil {
+		gates = append(gates, "--policy-configmap")
+	}
+	if s.Approval != nil {
+		gates = append(gates, "spec.approval")
+	}
+	if s.GitOps != nil {
+		gates = append(gates, "spec.gitops")
+	}
+	if r.scales != nil {
+		gates = append(gates, "--max-scales-per-minute")
+	}
+	return gates
+}
+
+// proposal is a decided scale on its way through the gates to the target.
+type proposal struct {
+	Current, Desired, Replicas int32
+	LimitedBy                  string
+	Metrics                    map[string]float64
+	Now                        time.Time
+	// Scale, when set, writes the approved replicas in place of scaling
+	// apply's single target: spec.clusters splits them across members.
+	Scale func(ctx context.Context, replicas int32) error
+}
+
+// apply runs p through the decision webhook, the policy and approval gates,
+// then scales the target (or commits to Git) and records it in status.
+func (r *reconciler) apply(ctx context.Context, req ctrl.Request, u *unstructured.Unstructured, s autoscalerSpec,
+	tc targetCluster, dep *appsv1.Deployment, targetKey string, p proposal, snap *DebugSnapshot) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("nginxautoscaler", req.NamespacedName)
+	current, desired, newReplicas, now := p.Current, p.Desired, p.Replicas, p.Now
 
-	// 8) Patch Deployment
-	dep.Spec.Replicas = &newReplicas
-	if err := r.Update(ctx, &dep); err != nil {
-		logger.Error(err, "failed to update replicas")
-		return ctrl.Result{RequeueAfter: pollInterval}, err
+	if s.ScheduleInvalid != "" {
+		logger.Info("not scaling; fix the schedule", "problem", s.ScheduleInvalid, "current", current, "proposed", newReplicas)
+		snap.SkipReason = "ScheduleInvalid"
+		snap.Error = s.ScheduleInvalid
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	if inBlackout(s, now) {
+		logger.Info("not scaling inside a blackout window", "current", current, "proposed", newReplicas)
+		snap.SkipReason = "BlackoutWindow"
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+
+	// Custom business logic gets the last word before anything is applied
+	if s.DecisionWebhook != nil {
+		var skip string
+		newReplicas, skip = reviewScale(ctx, s, decisionhook.Request{
+			Autoscaler: req.String(),
+			Target:     targetKey,
+			Current:    current,
+			Desired:    desired,
+			Proposed:   newReplicas,
+			LimitedBy:  p.LimitedBy,
+			Metrics:    p.Metrics,
+		})
+		if skip != "" {
+			snap.SkipReason = skip
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+	}
+
+	// Org-wide governance bounds whatever is left
+	if r.policy != nil {
+		var skip string
+		newReplicas, skip = r.policy.review(ctx, policyProposal{
+			Autoscaler: req.NamespacedName,
+			Target:     targetKey,
+			Current:    current,
+			Desired:    desired,
+			Proposed:   newReplicas,
+			Metrics:    p.Metrics,
+			Now:        now,
+		})
+		if skip != "" {
+			snap.SkipReason = skip
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+	}
+
+	// Large changes wait for a human to approve the parked recommendation
+	if s.Approval != nil {
+		var apply, changed bool
+		newReplicas, apply, changed = gateApproval(u, *s.Approval, current, newReplicas, s.MinReplicas, s.MaxReplicas, now)
+		if !apply {
+			id, _, _ := unstructured.NestedString(u.Object, "status", "pendingScale", "id")
+			logger.Info("change needs approval; annotate the autoscaler to apply it",
+				"current", current, "proposed", p.Replicas, "annotation", approveAnnotation+"="+id)
+			snap.SkipReason = "AwaitingApproval"
+			if changed {
+				if err := r.patchStatus(ctx, u); err != nil {
+					logger.Error(err, "failed to update status (will retry later)")
+				}
+			}
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+		if newReplicas == current {
+			snap.SkipReason = "ApprovedNoChange"
+			if changed {
+				if err := r.patchStatus(ctx, u); err != nil {
+					logger.Error(err, "failed to update status (will retry later)")
+				}
+			}
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+	}
+
+	if s.GitOps != nil && s.GitOps.Invalid != "" {
+		logger.Info("not scaling; fix spec.gitops", "problem", s.GitOps.Invalid, "current", current, "proposed", newReplicas)
+		snap.SkipReason = "GitOpsInvalid"
+		snap.Error = s.GitOps.Invalid
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+
+	// A target that keeps refusing updates (webhook denials, RBAC) is retried
+	// less and less often instead of every poll
+	if _, retryAt := actuationBackoff(u); now.Before(retryAt) {
+		remaining := retryAt.Sub(now)
+		logger.Info("target updates keep failing; backing off",
+			"current", current, "proposed", newReplicas, "retryIn", remaining.Round(time.Second))
+		snap.SkipReason = "ActuationBackoff"
+		snap.CooldownRemaining = remaining.Round(time.Second).String()
+		return ctrl.Result{RequeueAfter: min(remaining, s.PollInterval)}, nil
+	}
+	backOff := func(err error) ctrl.Result {
+		delay := recordActuationFailure(u, err, s.PollInterval, now)
+		if err := r.patchStatus(ctx, u); err != nil {
+			logger.Error(err, "failed to update status (will retry later)")
+		}
+		return ctrl.Result{RequeueAfter: delay}
+	}
+
+	// 8) Patch Deployment, or commit the count for Argo CD/Flux to sync
+	if s.GitOps != nil {
+		pushed, err := r.commitReplicas(ctx, req.Namespace, *s.GitOps, targetKey, current, newReplicas)
+		if err != nil {
+			logger.Error(err, "failed to commit replicas to git", "repo", s.GitOps.Repo)
+			snap.Error = err.Error()
+			return backOff(err), nil
+		}
+		if !pushed {
+			// Already committed; waiting for the GitOps tool to sync it
+			snap.SkipReason = "GitOpsPending"
+			return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+		}
+	} else {
+		// After a Prometheus outage every CR wants to scale at once; let them in a few at a time
+		if ok, retry := r.scales.allow(now); !ok {
+			logger.Info("controller-wide scale rate reached; deciding again later",
+				"current", current, "proposed", newReplicas, "retryIn", retry.Round(time.Second))
+			snap.SkipReason = "ScaleRateLimited"
+			return ctrl.Result{RequeueAfter: min(retry, s.PollInterval)}, nil
+		}
+		if p.Scale != nil {
+			if err := p.Scale(ctx, newReplicas); err != nil {
+				logger.Error(err, "failed to update replicas")
+				snap.Error = err.Error()
+				return backOff(err), nil
+			}
+		} else {
+			if r.opts.LiveTargetRead && s.TargetKind != targetKindDeploymentConfig { // DCs are always read live
+				var live appsv1.Deployment
+				if err := tc.reader.Get(ctx, client.ObjectKeyFromObject(dep), &live); err != nil {
+					logger.Error(err, "failed to re-read target before scaling")
+					snap.Error = err.Error()
+					return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+				}
+				if got := replicasOrOne(&live); got != current {
+					logger.Info("target was rescaled since it was read; deciding again", "cached", current, "live", got)
+					snap.SkipReason = "TargetChanged"
+					return ctrl.Result{Requeue: true}, nil
+				}
+				*dep = live
+			}
+			if newReplicas < current && (s.DeletionCost || s.Drain != nil) {
+				// An actuator never resolves Prometheus itself; use what the recommender found
+				promURL := s.PromURL
+				if promURL == "" {
+					promURL, _, _ = unstructured.NestedString(u.Object, "status", "promURL")
+				}
+				loadQ := podCPUQuery(r.opts.MetricNames.with(s.MetricNames), dep.Namespace, dep.Name+"-.*", s.RateWindows.CPU)
+				if s.Drain != nil {
+					// The pod the drain gate found idle is the one to remove
+					loadQ = drainQuery(*s.Drain, dep.Namespace, dep.Name+"-.*")
+				}
+				if err := tc.markLeastLoaded(ctx, dep, promURL, loadQ, int(current-newReplicas)); err != nil {
+					logger.Error(err, "failed to set pod-deletion-cost hints; scaling down without them")
+				}
+			}
+			if err := tc.scaleTarget(ctx, s.TargetKind, dep, newReplicas); err != nil {
+				logger.Error(err, "failed to update replicas")
+				snap.Error = err.Error()
+				if apierrors.IsConflict(err) {
+					// a stale read, not a refusal; the retry will see the new version
+					return ctrl.Result{RequeueAfter: s.PollInterval}, err
+				}
+				return backOff(err), nil
+			}
+		}
+	}
+
+	snap.Applied = newReplicas
+	scaled := scaledEvent(req.NamespacedName, targetKey, snap.DecisionID, current, newReplicas, now)
+	r.opts.Bus.Emit(scaled)
+	r.opts.Annotations.Emit(scaled)
+	if s.Silence != nil {
+		var pods string
+		if dep != nil { // nil for spec.clusters
+			var err error
+			if pods, err = tc.podPattern(ctx, dep); err != nil {
+				logger.Error(err, "failed to list the target's ReplicaSets; not silencing its pod alerts")
+			}
+		}
+		r.opts.Silences.Emit(silenceEvent(scaled, *s.Silence, pods))
 	}
 
 	// 9) Update CR status
-	_ = unstructured.SetNestedField(u.Object, now, "status", "lastScaleTime")
+	_ = unstructured.SetNestedField(u.Object, now.Format(time.RFC3339), "status", "lastScaleTime")
 	_ = unstructured.SetNestedField(u.Object, int64(newReplicas), "status", "currentReplicas")
 	_ = unstructured.SetNestedField(u.Object, int64(desired), "status", "desiredReplicas")
-	if err := r.Status().Update(ctx, u); err != nil {
+	hash, _, _ := unstructured.NestedString(u.Object, "status", "observedTemplateHash")
+	var revision string
+	if dep != nil { // nil for spec.clusters
+		revision = dep.Annotations[revisionAnnotation]
+	}
+	recordScale(u, current, newReplicas, revision, hash, snap.DecisionID, now)
+	clearActuationBackoff(u)
+	if s.BudgetReplicas > 0 {
+		budgetTokens, budgetUpdated := scalingBudget(u)
+		spendBudget(u, s.policy(), budgetTokens, budgetUpdated, current, newReplicas, now)
+	}
+	if err := r.patchStatus(ctx, u); err != nil {
 		logger.Error(err, "failed to update status (will retry later)")
 	}
 
-	logger.Info("scaled",
+	logger.Info("scaled", "decisionID", snap.DecisionID,
 		"from", current, "to", newReplicas, "desired_raw", desired,
-		"cpu_cores", fmt.Sprintf("%.3f", totalCPUcores),
-		"mem_mib", fmt.Sprintf("%.1f", totalMemMiB))
+		"cpu_cores", fmt.Sprintf("%.3f", p.Metrics["cpuCores"]),
+		"mem_mib", fmt.Sprintf("%.1f", p.Metrics["memMiB"]))
 
-	return ctrl.Result{RequeueAfter: pollInterval}, nil
+	return ctrl.Result{RequeueAfter: s.PollInterval}, nil
 }
 
-func outsideBand(current, desired int32, hysteresisPct float64) bool {
-	if current == desired {
-		return false
-	}
-	low := float64(current) * (1.0 - hysteresisPct/100.0)
-	high := float64(current) * (1.0 + hysteresisPct/100.0)
-	return float64(desired) < low || float64(desired) > high
+// usageQueries are the CPU (cores, a rate over cpuWindow) and memory (bytes)
+// used by pods matching podSel in ns.
+func usageQueries(n MetricNames, ns, podSel, cpuWindow string) (cpuQ, memQ string) {
+	cpuQ = fmt.Sprintf(`sum(rate(%s[%s]))`, n.selector(n.CPU, ns, podSel), cpuWindow)
+	memQ = fmt.Sprintf(`sum(%s)`, n.selector(n.Memory, ns, podSel))
+	return cpuQ, memQ
 }
 
-func min32(a, b int32) int32 {
-	if a < b {
-		return a
+// targetNamespaceAllowed reports whether a CR in crNS may scale a Deployment in targetNS.
+func (r *reconciler) targetNamespaceAllowed(crNS, targetNS string) bool {
+	if crNS == targetNS {
+		return true
 	}
-	return b
-}
-func max32(a, b int32) int32 {
-	if a > b {
-		return a
+	for _, ns := range r.opts.AllowedTargetNamespaces {
+		if ns == "*" || ns == targetNS {
+			return true
+		}
 	}
-	return b
+	return false
 }
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// recordObservation copies what this cycle saw and decided from snap into
+// status.lastObservation, so `kubectl get -o yaml` explains why nothing
+// happened. To spare etcd it only does so once every interval, unless the
+// skip reason or error changed; it reports whether status changed. Floats
+// are stored as strings so a whole number survives the round trip.
+func recordObservation(u *unstructured.Unstructured, snap DebugSnapshot, interval time.Duration) bool {
+	prev, found, _ := unstructured.NestedMap(u.Object, "status", "lastObservation")
+	if found {
+		at, _ := prev["time"].(string)
+		last, _ := time.Parse(time.RFC3339, at)
+		reason, _ := prev["skipReason"].(string)
+		errMsg, _ := prev["error"].(string)
+		if snap.Time.Sub(last) < interval && reason == snap.SkipReason && errMsg == snap.Error {
+			return false
+		}
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', 6, 64) }
+	obs := map[string]interface{}{
+		"time":       snap.Time.Format(time.RFC3339),
+		"decisionID": snap.DecisionID,
+		"cpuCores":   f(snap.CPUCores),
+		"memMiB":     f(snap.MemMiB),
+		"current":    int64(snap.Current),
+		"desired":    int64(snap.Desired),
+		"skipReason": snap.SkipReason,
+		"error":      snap.Error,
+		"desiredBySignal": map[string]interface{}{
+			"cpu":      int64(snap.CPUReplicas),
+			"memory":   int64(snap.MemReplicas),
+			"rps":      int64(snap.RPSReplicas),
+			"latency":  int64(snap.LatencyReplicas),
+			"slo":      int64(snap.SLOReplicas),
+			"trend":    int64(snap.TrendReplicas),
+			"external": int64(snap.ExternalReplicas),
+		},
+	}
+	if snap.RPS != 0 {
+		obs["rps"] = f(snap.RPS)
+	}
+	if snap.LatencyMs != 0 {
+		obs["latencyMs"] = f(snap.LatencyMs)
+	}
+	if len(snap.ExternalMetrics) > 0 {
+		ext := map[string]interface{}{}
+		for source, v := range snap.ExternalMetrics {
+			ext[source] = f(v)
+		}
+		obs["externalMetrics"] = ext
+	}
+	if snap.Applied != 0 {
+		obs["applied"] = int64(snap.Applied)
+	}
+	if snap.CooldownRemaining != "" {
+		obs["cooldownRemaining"] = snap.CooldownRemaining
+	}
+	_ = unstructured.SetNestedMap(u.Object, obs, "status", "lastObservation")
+	return true
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// peakWindow is how long status.peakDemand holds a peak before a lower
+// demand may replace it.
+const peakWindow = 7 * 24 * time.Hour
+
+// demandReplicas is what the busiest signal of snap asked for, before
+// min/max, step limits and the other policies cut it down.
+func demandReplicas(snap DebugSnapshot) int32 {
+	return max(snap.CPUReplicas, snap.MemReplicas, snap.RPSReplicas, snap.LatencyReplicas,
+		snap.SLOReplicas, snap.TrendReplicas, snap.ExternalReplicas)
+}
+
+// recordPeak keeps the highest demand of the last peakWindow in
+// status.peakDemand, with the usage behind it, for capacity planning. It
+// reports whether status changed.
+func recordPeak(u *unstructured.Unstructured, snap DebugSnapshot) bool {
+	demand := demandReplicas(snap)
+	if demand <= 0 {
+		return false
+	}
+	prev, found, _ := unstructured.NestedMap(u.Object, "status", "peakDemand")
+	if found {
+		replicas, _ := prev["replicas"].(int64)
+		at, _ := prev["time"].(string)
+		t, err := time.Parse(time.RFC3339, at)
+		if err == nil && snap.Time.Sub(t) < peakWindow && int64(demand) <= replicas {
+			return false
+		}
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'g', 6, 64) }
+	_ = unstructured.SetNestedMap(u.Object, map[string]interface{}{
+		"replicas": int64(demand),
+		"cpuCores": f(snap.CPUCores),
+		"memMiB":   f(snap.MemMiB),
+		"time":     snap.Time.Format(time.RFC3339),
+	}, "status", "peakDemand")
+	return true
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+)
+
+// percentileRef replaces the instantaneous CPU and memory usage with a
+// quantile of it over a trailing window, so sawtooth load (batch jobs, GC
+// cycles) is sized for its peaks rather than wherever the last scrape landed.
+type percentileRef struct {
+	Quantile float64 // e.g. 0.9
+	Window   time.Duration
+	Step     time.Duration // query_range resolution
+}
+
+// usage evaluates a usage query: its instant value, or with pct set the
+// pct.Quantile of its samples over pct.Window. found is false when
+// Prometheus had no samples at all, which is returned as 0.
+func usage(promURL, query string, pct *percentileRef) (v float64, found bool, err error) {
+	if pct == nil {
+		return prom.Instant(promURL, query)
+	}
+	values, err := prom.RangeVector(promURL, query, pct.Window, pct.Step)
+	if err != nil {
+		return 0, false, err
+	}
+	return decision.Quantile(values, pct.Quantile), len(values) > 0, nil
+}
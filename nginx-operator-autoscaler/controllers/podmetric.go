@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// podMetricRef is spec.podMetric: a number read from a JSON endpoint on every
+// target pod (queue depth, open sessions) for apps without a Prometheus
+// exporter, aggregated into demand and sized at TargetPerReplica per pod.
+type podMetricRef struct {
+	Port             int32
+	Path             string // default "/"
+	Scheme           string // http (default) or https
+	JSONPath         string // e.g. {.queue.depth}; every match in a pod is summed
+	Aggregation      string // sum (default), avg or max over the pods
+	TargetPerReplica float64
+	Timeout          time.Duration
+}
+
+const (
+	aggregationSum = "sum"
+	aggregationAvg = "avg"
+	aggregationMax = "max"
+)
+
+// podMetricClient scrapes pods; each request carries its own timeout.
+var podMetricClient = &http.Client{}
+
+// podMetricParallelism bounds concurrent scrapes of one target's pods.
+const podMetricParallelism = 16
+
+// podMetric scrapes ref from every running pod of dep and returns the total
+// demand: the sum, or avg/max times the running pods. Pods that fail to
+// answer are left out and the result extrapolated from those that did; it
+// is an error if none did.
+func (tc targetCluster) podMetric(ctx context.Context, dep *appsv1.Deployment, ref podMetricRef) (float64, error) {
+	path := ref.JSONPath
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	jp := jsonpath.New("podMetric")
+	if err := jp.Parse(path); err != nil {
+		return 0, fmt.Errorf("spec.podMetric.jsonPath: %w", err)
+	}
+	pods, err := tc.runningPods(ctx, dep)
+	if err != nil {
+		return 0, err
+	}
+	if len(pods) == 0 {
+		return 0, nil
+	}
+
+	values := make([]float64, len(pods))
+	errs := make([]error, len(pods))
+	sem := make(chan struct{}, podMetricParallelism)
+	var wg sync.WaitGroup
+	for i := range pods {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			values[i], errs[i] = scrapePod(ctx, &pods[i], ref, jp)
+		}(i)
+	}
+	wg.Wait()
+
+	var sum, peak float64
+	var answered int
+	var firstErr error
+	for i := range pods {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("pod %s: %w", pods[i].Name, errs[i])
+			}
+			continue
+		}
+		answered++
+		sum += values[i]
+		peak = max(peak, values[i])
+	}
+	if answered == 0 {
+		return 0, firstErr
+	}
+	running := float64(len(pods))
+	switch ref.Aggregation {
+	case aggregationAvg:
+		return sum / float64(answered) * running, nil
+	case aggregationMax:
+		return peak * running, nil
+	default:
+		return sum * running / float64(answered), nil
+	}
+}
+
+// scrapePod GETs ref's endpoint on pod and sums what jp finds in the answer.
+func scrapePod(ctx context.Context, pod *corev1.Pod, ref podMetricRef, jp *jsonpath.JSONPath) (float64, error) {
+	if pod.Status.PodIP == "" {
+		return 0, fmt.Errorf("no pod IP")
+	}
+	ctx, cancel := context.WithTimeout(ctx, ref.Timeout)
+	defer cancel()
+	u := fmt.Sprintf("%s://%s%s", ref.Scheme, net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(ref.Port))), ref.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	r, err := podMetricClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("GET %s: %s", u, r.Status)
+	}
+	var doc interface{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&doc); err != nil {
+		return 0, fmt.Errorf("GET %s: %w", u, err)
+	}
+	results, err := jp.FindResults(doc)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	var found bool
+	for _, set := range results {
+		for _, v := range set {
+			f, err := jsonNumber(v.Interface())
+			if err != nil {
+				return 0, err
+			}
+			total += f
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("%s matched nothing", ref.JSONPath)
+	}
+	return total, nil
+}
+
+// jsonNumber reads a decoded JSON number, or a string holding one.
+func jsonNumber(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// runningPods lists the target's Running, non-terminating pods. Pods are read
+// through the API server so we don't cache every Pod in the cluster.
+func (tc targetCluster) runningPods(ctx context.Context, dep *appsv1.Deployment) ([]corev1.Pod, error) {
+	sel, err := metav1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	var pods corev1.PodList
+	if err := tc.reader.List(ctx, &pods, client.InNamespace(dep.Namespace), client.MatchingLabelsSelector{Selector: sel}); err != nil {
+		return nil, err
+	}
+	out := pods.Items[:0]
+	for _, p := range pods.Items {
+		if p.Status.Phase == corev1.PodRunning && p.DeletionTimestamp == nil {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// podNodes returns the target's running pods and the Nodes they run on, keyed by name.
+func (tc targetCluster) podNodes(ctx context.Context, dep *appsv1.Deployment) ([]corev1.Pod, map[string]*corev1.Node, error) {
+	pods, err := tc.runningPods(ctx, dep)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes := map[string]*corev1.Node{}
+	for _, p := range pods {
+		if _, seen := nodes[p.Spec.NodeName]; seen {
+			continue
+		}
+		node := &corev1.Node{}
+		if err := tc.reader.Get(ctx, types.NamespacedName{Name: p.Spec.NodeName}, node); err != nil {
+			return nil, nil, err
+		}
+		nodes[p.Spec.NodeName] = node
+	}
+	return pods, nodes, nil
+}
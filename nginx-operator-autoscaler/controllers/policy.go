@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/policy"
+)
+
+// policyKey is the ConfigMap key holding the Rego module.
+const policyKey = "policy.rego"
+
+// policyGate evaluates the org-wide Rego policy from a ConfigMap, recompiling
+// only when the ConfigMap changes.
+type policyGate struct {
+	reader client.Reader // reads the ConfigMap and Namespaces from the API server
+	ref    types.NamespacedName
+	loc    *time.Location // of input.time.local
+
+	mu              sync.Mutex
+	resourceVersion string
+	engine          *policy.Engine
+}
+
+// newPolicyGate returns nil, disabling the gate, when ref is empty. loc is
+// the zone business hours are written in; nil means UTC.
+func newPolicyGate(reader client.Reader, ref types.NamespacedName, loc *time.Location) *policyGate {
+	if ref.Name == "" {
+		return nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &policyGate{reader: reader, ref: ref, loc: loc}
+}
+
+func (g *policyGate) load(ctx context.Context) (*policy.Engine, error) {
+	var cm corev1.ConfigMap
+	if err := g.reader.Get(ctx, g.ref, &cm); err != nil {
+		return nil, fmt.Errorf("policy ConfigMap %s: %w", g.ref, err)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.engine != nil && g.resourceVersion == cm.ResourceVersion {
+		return g.engine, nil
+	}
+	module, ok := cm.Data[policyKey]
+	if !ok {
+		return nil, fmt.Errorf("policy ConfigMap %s has no key %q", g.ref, policyKey)
+	}
+	engine, err := policy.Compile(ctx, module)
+	if err != nil {
+		return nil, fmt.Errorf("policy ConfigMap %s: %w", g.ref, err)
+	}
+	g.engine, g.resourceVersion = engine, cm.ResourceVersion
+	return engine, nil
+}
+
+// policyProposal is the context a policy sees about one proposed change.
+type policyProposal struct {
+	Autoscaler types.NamespacedName
+	Target     string
+	Current    int32
+	Desired    int32
+	Proposed   int32
+	Metrics    map[string]float64
+	Now        time.Time
+}
+
+// review returns the replica count the policy allows, or a skip reason when
+// nothing should be applied. A policy that can't be loaded or evaluated holds
+// replicas: governance fails closed.
+func (g *policyGate) review(ctx context.Context, p policyProposal) (int32, string) {
+	logger := log.FromContext(ctx)
+	engine, err := g.load(ctx)
+	if err != nil {
+		logger.Error(err, "scaling policy unavailable; holding replicas")
+		return p.Current, "PolicyError"
+	}
+
+	var ns corev1.Namespace
+	if err := g.reader.Get(ctx, types.NamespacedName{Name: p.Autoscaler.Namespace}, &ns); err != nil {
+		logger.Error(err, "failed to read namespace labels for the scaling policy; holding replicas")
+		return p.Current, "PolicyError"
+	}
+	labels := map[string]interface{}{}
+	for k, v := range ns.Labels {
+		labels[k] = v
+	}
+	metrics := map[string]interface{}{}
+	for k, v := range p.Metrics {
+		if !math.IsNaN(v) {
+			metrics[k] = v
+		}
+	}
+	changePct := 0.0
+	if p.Current > 0 {
+		changePct = float64(p.Proposed-p.Current) / float64(p.Current) * 100
+	}
+
+	v, err := engine.Evaluate(ctx, map[string]interface{}{
+		"autoscaler": map[string]interface{}{"namespace": p.Autoscaler.Namespace, "name": p.Autoscaler.Name},
+		"namespace":  map[string]interface{}{"name": ns.Name, "labels": labels},
+		"target":     p.Target,
+		"current":    p.Current,
+		"desired":    p.Desired,
+		"proposed":   p.Proposed,
+		"change":     p.Proposed - p.Current,
+		"changePct":  changePct,
+		"metrics":    metrics,
+		"time":       policyTime(p.Now, g.loc),
+	})
+	if err != nil {
+		logger.Error(err, "scaling policy evaluation failed; holding replicas")
+		return p.Current, "PolicyError"
+	}
+	if len(v.Deny) > 0 {
+		logger.Info("scaling policy denied change", "proposed", p.Proposed, "reasons", v.Deny)
+		return p.Current, "PolicyDenied"
+	}
+
+	replicas := p.Proposed
+	if v.Max != nil && replicas > *v.Max {
+		replicas = *v.Max
+	}
+	if v.Min != nil && replicas < *v.Min {
+		replicas = *v.Min
+	}
+	if replicas != p.Proposed {
+		logger.Info("scaling policy clamped change", "proposed", p.Proposed, "allowed", replicas)
+	}
+	if replicas == p.Current {
+		return p.Current, "PolicyClamped"
+	}
+	return replicas, ""
+}
+
+// policyTime is input.time: now in UTC, plus the same in loc under local so
+// business-hours and blackout rules can be written in the org's own zone.
+func policyTime(now time.Time, loc *time.Location) map[string]interface{} {
+	at := func(t time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"rfc3339": t.Format(time.RFC3339),
+			"hour":    t.Hour(),
+			"weekday": t.Weekday().String(),
+		}
+	}
+	in := at(now.UTC())
+	local := at(now.In(loc))
+	local["zone"] = loc.String()
+	in["local"] = local
+	return in
+}
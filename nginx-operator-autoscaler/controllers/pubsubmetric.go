@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/pubsub"
+)
+
+// pubsubRef is spec.pubsub: a Pub/Sub subscription whose undelivered
+// messages subscriber replicas are sized on, TargetPerReplica each.
+type pubsubRef struct {
+	Project          string
+	Subscription     string
+	TargetPerReplica float64
+	Timeout          time.Duration
+}
+
+// pubsubMetric reads ref's backlog from Cloud Monitoring as the manager's
+// service account, which needs roles/monitoring.viewer in ref.Project.
+func pubsubMetric(ctx context.Context, ref pubsubRef) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ref.Timeout)
+	defer cancel()
+	v, err := pubsub.Backlog(ctx, ref.Project, ref.Subscription)
+	if err != nil {
+		return 0, fmt.Errorf("spec.pubsub: %w", err)
+	}
+	return v, nil
+}
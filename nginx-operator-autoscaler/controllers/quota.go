@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// limitQuotaExceeded is status.lastObservation.limitedBy and the
+// ScalingLimited reason when a ResourceQuota caps a scale-up.
+const limitQuotaExceeded = "QuotaExceeded"
+
+// quotaRoom is how many more pods of dep's template the ResourceQuotas in
+// its namespace still admit, and which quota and resource run out first;
+// room is math.MaxInt32 when nothing bounds it. Quotas with scopes are
+// skipped: whether they cover the pods depends on more than the template.
+func (tc targetCluster) quotaRoom(ctx context.Context, dep *appsv1.Deployment) (room int32, limiting string, err error) {
+	var quotas corev1.ResourceQuotaList
+	if err := tc.reader.List(ctx, &quotas, client.InNamespace(dep.Namespace)); err != nil {
+		return 0, "", err
+	}
+	requests, limits := podResources(&dep.Spec.Template.Spec)
+	perPod := corev1.ResourceList{
+		corev1.ResourcePods:           resource.MustParse("1"),
+		"count/pods":                  resource.MustParse("1"),
+		corev1.ResourceCPU:            requests[corev1.ResourceCPU],
+		corev1.ResourceMemory:         requests[corev1.ResourceMemory],
+		corev1.ResourceRequestsCPU:    requests[corev1.ResourceCPU],
+		corev1.ResourceRequestsMemory: requests[corev1.ResourceMemory],
+		corev1.ResourceLimitsCPU:      limits[corev1.ResourceCPU],
+		corev1.ResourceLimitsMemory:   limits[corev1.ResourceMemory],
+	}
+	room = math.MaxInt32
+	for _, q := range quotas.Items {
+		if len(q.Spec.Scopes) > 0 || q.Spec.ScopeSelector != nil {
+			continue
+		}
+		for name, hard := range q.Status.Hard {
+			need, ok := perPod[name]
+			if !ok || need.IsZero() {
+				continue
+			}
+			left := hard.DeepCopy()
+			left.Sub(q.Status.Used[name])
+			n := int32(0)
+			if left.Sign() > 0 {
+				n = int32(min(float64(left.MilliValue())/float64(need.MilliValue()), math.MaxInt32))
+			}
+			if n < room {
+				room, limiting = n, fmt.Sprintf("%s (%s)", q.Name, name)
+			}
+		}
+	}
+	return room, limiting, nil
+}
+
+// podResources sums the requests and limits of spec's containers (a limit
+// standing in for a missing request, as API defaulting does); an init
+// container asking for more than all of them together sets the figure, as
+// the scheduler and quota admission count it.
+func podResources(spec *corev1.PodSpec) (requests, limits corev1.ResourceList) {
+	requests, limits = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := requests[name]
+			sum.Add(q)
+			requests[name] = sum
+		}
+		for name, q := range c.Resources.Limits {
+			sum := limits[name]
+			sum.Add(q)
+			limits[name] = sum
+			if _, ok := c.Resources.Requests[name]; !ok {
+				// A limit without a request is defaulted into one
+				sum := requests[name]
+				sum.Add(q)
+				requests[name] = sum
+			}
+		}
+	}
+	for _, c := range spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if cur := requests[name]; q.Cmp(cur) > 0 {
+				requests[name] = q
+			}
+		}
+		for name, q := range c.Resources.Limits {
+			if cur := limits[name]; q.Cmp(cur) > 0 {
+				limits[name] = q
+			}
+		}
+	}
+	return requests, limits
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import "regexp"
+
+// rateWindows are the rate() ranges of the built-in queries. Short-lived,
+// bursty pods want ~30s so a spike is not averaged away; steady services are
+// smoother on 5m.
+type rateWindows struct {
+	CPU      string // container_cpu_usage_seconds_total
+	Requests string // ingress/Istio request rate
+	Errors   string // ingress/Istio 5xx ratio
+	Latency  string // Istio latency histogram
+}
+
+// promDuration matches a PromQL duration such as 30s, 5m or 1h30m.
+var promDuration = regexp.MustCompile(`^([0-9]+(ms|s|m|h|d|w|y))+$`)
+
+// parseRateWindows reads spec.rateWindow, the default for every query, and
+// spec.rateWindows, per-metric overrides of it. Anything that is not a PromQL
+// duration is ignored rather than spliced into a query.
+func parseRateWindows(spec map[string]interface{}) rateWindows {
+	def := "2m"
+	if v, ok := spec["rateWindow"].(string); ok && promDuration.MatchString(v) {
+		def = v
+	}
+	per, _ := spec["rateWindows"].(map[string]interface{})
+	get := func(key string) string {
+		if v, ok := per[key].(string); ok && promDuration.MatchString(v) {
+			return v
+		}
+		return def
+	}
+	return rateWindows{
+		CPU:      get("cpu"),
+		Requests: get("requests"),
+		Errors:   get("errors"),
+		Latency:  get("latency"),
+	}
+}
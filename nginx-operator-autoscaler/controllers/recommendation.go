@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Role is the half of the autoscaler a controller runs. A recommender
+// computes replicas and publishes them in status.recommendation without
+// touching targets; an actuator applies published recommendations through
+// the webhook, policy and approval gates. Running both (RoleAll) is the
+// classic single controller.
+type Role string
+
+const (
+	RoleAll         Role = "all"
+	RoleRecommender Role = "recommender"
+	RoleActuator    Role = "actuator"
+)
+
+// ParseRole validates a --role value.
+func ParseRole(s string) (Role, error) {
+	switch r := Role(s); r {
+	case RoleAll, RoleRecommender, RoleActuator:
+		return r, nil
+	case "":
+		return RoleAll, nil
+	}
+	return "", fmt.Errorf("unknown role %q (want all, recommender or actuator)", s)
+}
+
+// recommendation is status.recommendation, the hand-off from recommender to
+// actuator. Metrics ride along for the actuator's webhook and policy gates.
+type recommendation struct {
+	Replicas  int32 // what the target should run; Current when no change is due
+	Desired   int32
+	Current   int32 // the target's replicas it was computed against
+	Reason    string
+	LimitedBy string
+	Metrics   map[string]float64
+	Time      time.Time
+}
+
+// stale reports why the actuator must not apply rec to a target now running
+// current replicas, or "" if it may. A recommender republishes at least every
+// poll, so one older than three polls means it has stopped.
+func (rec recommendation) stale(current int32, poll time.Duration, now time.Time) string {
+	if rec.Current != current {
+		return fmt.Sprintf("computed for %d replicas, target runs %d", rec.Current, current)
+	}
+	if age := now.Sub(rec.Time); age > 3*poll {
+		return fmt.Sprintf("published %s ago", age.Round(time.Second))
+	}
+	return ""
+}
+
+// readRecommendation loads status.recommendation.
+func readRecommendation(u *unstructured.Unstructured) (recommendation, bool) {
+	m, ok, _ := unstructured.NestedMap(u.Object, "status", "recommendation")
+	if !ok {
+		return recommendation{}, false
+	}
+	rec := recommendation{Metrics: map[string]float64{}}
+	replicas, _ := m["replicas"].(int64)
+	desired, _ := m["desired"].(int64)
+	current, _ := m["current"].(int64)
+	rec.Replicas, rec.Desired, rec.Current = int32(replicas), int32(desired), int32(current)
+	rec.Reason, _ = m["reason"].(string)
+	rec.LimitedBy, _ = m["limitedBy"].(string)
+	if at, _ := m["time"].(string); at != "" {
+		rec.Time, _ = time.Parse(time.RFC3339, at)
+	}
+	metrics, _ := m["metrics"].(map[string]interface{})
+	for k, v := range metrics {
+		s, _ := v.(string)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			rec.Metrics[k] = f
+		}
+	}
+	return rec, true
+}
+
+// publishRecommendation writes rec to status.recommendation and reports
+// whether status changed. Metrics are stored as strings (JSON has no NaN, and
+// a whole float would come back as an integer). Fresh metrics or a newer time
+// alone are not worth a write, or every write would trigger the next
+// reconcile; the time is refreshed once it is a poll old, as a heartbeat.
+func publishRecommendation(u *unstructured.Unstructured, rec recommendation, poll time.Duration) bool {
+	if prev, ok := readRecommendation(u); ok &&
+		prev.Replicas == rec.Replicas && prev.Desired == rec.Desired && prev.Current == rec.Current &&
+		prev.Reason == rec.Reason && prev.LimitedBy == rec.LimitedBy &&
+		rec.Time.Sub(prev.Time) < poll {
+		return false
+	}
+	metrics := map[string]interface{}{}
+	for k, v := range rec.Metrics {
+		metrics[k] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	_ = unstructured.SetNestedMap(u.Object, map[string]interface{}{
+		"replicas":  int64(rec.Replicas),
+		"desired":   int64(rec.Desired),
+		"current":   int64(rec.Current),
+		"reason":    rec.Reason,
+		"limitedBy": rec.LimitedBy,
+		"metrics":   metrics,
+		"time":      rec.Time.Format(time.RFC3339),
+	}, "status", "recommendation")
+	return true
+}
+
+// actuateRecommendation is the actuator's cycle: the published recommendation
+// goes through the same gates a decision would, as long as it is still fresh
+// and was computed against the replicas the target runs now.
+func (r *reconciler) actuateRecommendation(ctx context.Context, req ctrl.Request, u *unstructured.Unstructured, s autoscalerSpec,
+	tc targetCluster, dep *appsv1.Deployment, targetKey string, snap *DebugSnapshot) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("nginxautoscaler", req.NamespacedName)
+	current := snap.Current
+	now := r.clock.Now()
+
+	rec, ok := readRecommendation(u)
+	if !ok {
+		snap.SkipReason = "NoRecommendation"
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	if why := rec.stale(current, s.PollInterval, now); why != "" {
+		logger.Info("ignoring stale recommendation", "why", why)
+		snap.SkipReason = "RecommendationStale"
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	snap.Desired, snap.LimitedBy = rec.Desired, rec.LimitedBy
+	if rec.Replicas == current {
+		snap.SkipReason = rec.Reason
+		return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+	}
+	return r.apply(ctx, req, u, s, tc, dep, targetKey, proposal{
+		Current:   current,
+		Desired:   rec.Desired,
+		Replicas:  clampReplicas(rec.Replicas, s.MinReplicas, s.MaxReplicas),
+		LimitedBy: rec.LimitedBy,
+		Metrics:   rec.Metrics,
+		Now:       now,
+	}, snap)
+}
@@ -0,0 +1,3014 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decisionhook"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/sqltest"
+	"github.com/malisettirammurthy/promtest"
+)
+
+// fakeScheme knows the built-in types; our CRDs are handled as unstructured.
+func fakeScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = discoveryv1.AddToScheme(scheme)
+	return scheme
+}
+
+// newFakeReconciler wires a reconciler to a fake client holding objs.
+func newFakeReconciler(t *testing.T, opts Options, objs ...client.Object) (*reconciler, client.Client) {
+	t.Helper()
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(autoscalerGVK)
+	c := fake.NewClientBuilder().
+		WithScheme(fakeScheme()).
+		WithObjects(objs...).
+		WithStatusSubresource(cr).
+		WithIndex(cr, targetIndexKey, targetIndexValues).
+		Build()
+	return newReconciler(c, c, opts), c
+}
+
+func replicasOf(t *testing.T, c client.Client, ns, name string) int32 {
+	t.Helper()
+	var dep appsv1.Deployment
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: ns, Name: name}, &dep); err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	return *dep.Spec.Replicas
+}
+
+func TestCooldownWithFakeClock(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "60s",
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+	})
+	// The fake client drops status writes made after a plain Update that
+	// introduced the finalizer, so start with it already in place.
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("after first reconcile replicas = %d, want 5", got)
+	}
+
+	// Demand doubles, but we are 10s into a 60s cooldown
+	prom.SetInstant("container_cpu_usage_seconds_total", 2.0)
+	clk.SetTime(clk.Now().Add(10 * time.Second))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("during cooldown replicas = %d, want 5", got)
+	}
+
+	// Cooldown expires without anyone sleeping
+	clk.SetTime(clk.Now().Add(51 * time.Second))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 10 {
+		t.Fatalf("after cooldown replicas = %d, want 10", got)
+	}
+}
+
+func TestScalingBudget(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"minReplicas":      int64(1),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+		"scalingBudget":    map[string]interface{}{"replicas": int64(4), "per": "1h"},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	step := func(want int32, msg string) {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != want {
+			t.Fatalf("%s: replicas = %d, want %d", msg, got, want)
+		}
+	}
+
+	step(5, "2->5 spends 3 of 4")
+	prom.SetInstant("container_cpu_usage_seconds_total", 2.0) // 10 replicas
+	step(6, "one token left")
+	clk.SetTime(clk.Now().Add(5 * time.Minute))
+	step(6, "budget spent")
+	clk.SetTime(clk.Now().Add(25 * time.Minute))
+	step(8, "half an hour refills 2")
+}
+
+func TestRequiredSamples(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"minReplicas":      int64(1),
+		"targetCPU":        0.2,
+		"requiredSamples":  int64(2),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	step := func(want int32, msg string) {
+		t.Helper()
+		clk.SetTime(clk.Now().Add(15 * time.Second)) // the default poll interval
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != want {
+			t.Fatalf("%s: replicas = %d, want %d", msg, got, want)
+		}
+	}
+
+	step(2, "first sample up")
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.2) // wants 1: down
+	step(2, "direction flipped")
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0)
+	step(2, "streak restarted")
+	step(5, "second consecutive sample up")
+}
+
+// TestRequiredSamplesIgnoreEventReconciles covers the reconciles a status
+// patch or target event triggers between polls: they must not count as
+// samples of their own.
+func TestRequiredSamplesIgnoreEventReconciles(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"pollInterval":     "30s",
+		"targetCPU":        0.2,
+		"requiredSamples":  int64(3),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	reconcile := func(after time.Duration) {
+		t.Helper()
+		clk.SetTime(clk.Now().Add(after))
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		reconcile(time.Second) // back to back, as after each status patch
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("back-to-back reconciles: replicas = %d, want 2", got)
+	}
+	u := newAutoscaler("default", "web", nil)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatal(err)
+	}
+	if n, _, _ := unstructured.NestedInt64(u.Object, "status", "samples", "count"); n != 1 {
+		t.Fatalf("status.samples.count = %d, want 1", n)
+	}
+
+	reconcile(30 * time.Second)
+	reconcile(time.Second)
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("two polls: replicas = %d, want 2", got)
+	}
+	reconcile(30 * time.Second)
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("three polls: replicas = %d, want 5", got)
+	}
+}
+
+func TestPercentileInput(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	// Sawtooth: the latest scrape sits in a trough, p90 sees the peaks
+	prom.SetRange("container_cpu_usage_seconds_total", 0.2, 1.0, 0.2, 1.0, 0.2)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"targetCPU":        0.2,
+		"percentile":       map[string]interface{}{"quantile": 0.9, "window": "10m"},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("replicas = %d, want 5 (p90 of 1.0 cores)", got)
+	}
+}
+
+func TestPollJitter(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.4) // 2 replicas: nothing to do
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"pollInterval":     "10s",
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, _ := newFakeReconciler(t, Options{InstanceName: "test", PollJitter: 0.5}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 10; i++ {
+		res, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if res.RequeueAfter < 10*time.Second || res.RequeueAfter > 15*time.Second {
+			t.Fatalf("RequeueAfter = %v, want within [10s, 15s]", res.RequeueAfter)
+		}
+		seen[res.RequeueAfter] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("RequeueAfter never varied: %v", seen)
+	}
+}
+
+func TestWriteLimitedClient(t *testing.T) {
+	dep := newDeployment("default", "web", 2)
+	c := limitWrites(fake.NewClientBuilder().WithScheme(fakeScheme()).WithObjects(dep).Build(), 0.001, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The burst of one goes to the first write; the next would wait ~17 minutes
+	if err := c.Update(ctx, dep); err != nil {
+		t.Fatalf("first write: %v", err)
+	}
+	if err := c.Status().Update(ctx, dep); err == nil {
+		t.Fatal("second write: want rate-limit error before the deadline")
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, dep); err != nil {
+		t.Fatalf("reads must not be limited: %v", err)
+	}
+}
+
+func TestScalesPerMinute(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	var objs []client.Object
+	for _, name := range []string{"web", "api"} {
+		cr := newAutoscaler("default", name, map[string]interface{}{
+			"targetDeployment": name,
+			"promURL":          prom.URL,
+			"targetCPU":        0.2,
+			"pollInterval":     "2m",
+		})
+		cr.SetFinalizers([]string{lockFinalizer})
+		objs = append(objs, cr, newDeployment("default", name, 2))
+	}
+	debug := NewDebugStore()
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk, Debug: debug, ScalesPerMinute: 1}, objs...)
+	reconcile := func(name string) ctrl.Result {
+		t.Helper()
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+		if err != nil {
+			t.Fatalf("reconcile %s: %v", name, err)
+		}
+		return res
+	}
+
+	// Both want 5 at once; only the first gets the minute's one scale
+	reconcile("web")
+	res := reconcile("api")
+	if web, api := replicasOf(t, c, "default", "web"), replicasOf(t, c, "default", "api"); web != 5 || api != 2 {
+		t.Fatalf("replicas web=%d api=%d, want 5 and 2", web, api)
+	}
+	limited := 0
+	for _, snap := range debug.Snapshots() {
+		if snap.SkipReason == "ScaleRateLimited" {
+			limited++
+		}
+	}
+	if limited != 1 {
+		t.Fatalf("%d snapshots skipped as ScaleRateLimited, want 1", limited)
+	}
+	// Retried when the slot frees, not a whole poll later
+	if res.RequeueAfter > time.Minute+time.Minute/10 {
+		t.Fatalf("requeue after %s, want about a minute", res.RequeueAfter)
+	}
+
+	clk.SetTime(clk.Now().Add(time.Minute))
+	reconcile("api")
+	if got := replicasOf(t, c, "default", "api"); got != 5 {
+		t.Fatalf("a minute later api replicas = %d, want 5", got)
+	}
+}
+
+func TestDeploymentMapsToAutoscalers(t *testing.T) {
+	web := newAutoscaler("default", "web", map[string]interface{}{"targetDeployment": "web"})
+	_ = unstructured.SetNestedField(web.Object, int64(5), "status", "currentReplicas")
+	other := newAutoscaler("default", "api", map[string]interface{}{"targetDeployment": "api"})
+	r, _ := newFakeReconciler(t, Options{InstanceName: "test"}, web, other)
+	ctx := context.Background()
+
+	got := r.autoscalersForDeployment(ctx, newDeployment("default", "web", 3))
+	if len(got) != 1 || got[0].Name != "web" {
+		t.Fatalf("drifted to 3: got %v, want [default/web]", got)
+	}
+	if got := r.autoscalersForDeployment(ctx, newDeployment("default", "web", 5)); len(got) != 0 {
+		t.Fatalf("our own write to 5: got %v, want none", got)
+	}
+	if got := r.autoscalersForDeployment(ctx, newDeployment("default", "api", 1)); len(got) != 1 || got[0].Name != "api" {
+		t.Fatalf("never scaled: got %v, want [default/api]", got)
+	}
+}
+
+func TestPodMapsToAutoscalers(t *testing.T) {
+	web := newAutoscaler("default", "web", map[string]interface{}{"targetDeployment": "web"})
+	r, _ := newFakeReconciler(t, Options{InstanceName: "test"}, web)
+	ctx := context.Background()
+
+	yes := true
+	pod := runningPod("default", "web-7d9c8-abcde", time.Now())
+	pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = "7d9c8"
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-7d9c8", Controller: &yes}}
+	if got := r.autoscalersForPod(ctx, pod); len(got) != 1 || got[0].Name != "web" {
+		t.Fatalf("got %v, want [default/web]", got)
+	}
+	pod.OwnerReferences[0].Name = "api-7d9c8"
+	if got := r.autoscalersForPod(ctx, pod); len(got) != 0 {
+		t.Fatalf("pod of another Deployment: got %v, want none", got)
+	}
+
+	ready := pod.DeepCopy()
+	ready.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	if !readinessChanged.Update(event.UpdateEvent{ObjectOld: pod, ObjectNew: ready}) {
+		t.Fatal("turning Ready must trigger")
+	}
+	if readinessChanged.Update(event.UpdateEvent{ObjectOld: ready, ObjectNew: ready.DeepCopy()}) {
+		t.Fatal("staying Ready must not trigger")
+	}
+	if readinessChanged.Create(event.CreateEvent{Object: pod}) {
+		t.Fatal("an unready new pod must not trigger")
+	}
+}
+
+func TestLiveTargetRead(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	_, cached := newFakeReconciler(t, Options{}, newDeployment("default", "web", 2), cr)
+	// The API server already has the Deployment at 4; the cache still says 2
+	live := fake.NewClientBuilder().WithScheme(fakeScheme()).WithObjects(newDeployment("default", "web", 4)).Build()
+	r := newReconciler(cached, live, Options{InstanceName: "test", LiveTargetRead: true})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	res, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if !res.Requeue {
+		t.Fatalf("result = %+v, want an immediate requeue", res)
+	}
+	if got := replicasOf(t, cached, "default", "web"); got != 2 {
+		t.Fatalf("replicas = %d, want 2 (no write based on a stale count)", got)
+	}
+}
+
+func TestRecommenderActuatorSplit(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	recommender, c := newFakeReconciler(t, Options{InstanceName: "test", Role: RoleRecommender, Clock: clk},
+		newDeployment("default", "web", 2), cr)
+	actuator := newReconciler(c, c, Options{InstanceName: "test", Role: RoleActuator, Clock: clk})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := recommender.Reconcile(ctx, req); err != nil {
+		t.Fatalf("recommend: %v", err)
+	}
+	var dep appsv1.Deployment
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, &dep); err != nil {
+		t.Fatal(err)
+	}
+	if *dep.Spec.Replicas != 2 {
+		t.Fatalf("recommender scaled the target to %d", *dep.Spec.Replicas)
+	}
+	if holder := dep.Annotations[managedByAnnotation]; holder != "" {
+		t.Fatalf("recommender claimed the target: %q", holder)
+	}
+	u := newAutoscaler("default", "web", nil)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatal(err)
+	}
+	rec, ok := readRecommendation(u)
+	if !ok || rec.Replicas != 5 || rec.Current != 2 || rec.Metrics["cpuCores"] != 1 {
+		t.Fatalf("recommendation = %+v, %v", rec, ok)
+	}
+
+	if _, err := actuator.Reconcile(ctx, req); err != nil {
+		t.Fatalf("actuate: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("after actuation replicas = %d, want 5", got)
+	}
+
+	// The recommendation was computed for 2 replicas; someone scales to 3 by
+	// hand and the actuator must not reapply it
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, &dep); err != nil {
+		t.Fatal(err)
+	}
+	three := int32(3)
+	dep.Spec.Replicas = &three
+	if err := c.Update(ctx, &dep); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := actuator.Reconcile(ctx, req); err != nil {
+		t.Fatalf("actuate: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 3 {
+		t.Fatalf("stale recommendation applied: replicas = %d, want 3", got)
+	}
+
+	// A recommender that stopped publishing is not trusted either
+	if _, err := recommender.Reconcile(ctx, req); err != nil {
+		t.Fatalf("recommend: %v", err)
+	}
+	clk.SetTime(clk.Now().Add(time.Hour))
+	if _, err := actuator.Reconcile(ctx, req); err != nil {
+		t.Fatalf("actuate: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 3 {
+		t.Fatalf("expired recommendation applied: replicas = %d, want 3", got)
+	}
+}
+
+func TestConfirmationDelay(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment":  "web",
+		"promURL":           prom.URL,
+		"cooldown":          "0s",
+		"confirmationDelay": "30s",
+		"targetCPU":         0.2,
+		"stepLimit":         int64(20),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	step := func(cpu float64, after time.Duration, want int32) {
+		t.Helper()
+		prom.SetInstant("container_cpu_usage_seconds_total", cpu)
+		clk.SetTime(clk.Now().Add(after))
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != want {
+			t.Fatalf("cpu %.1f: replicas = %d, want %d", cpu, got, want)
+		}
+	}
+
+	step(1.0, 0, 2)               // spike recorded as pending
+	step(0.4, 15*time.Second, 2)  // gone before the delay: dropped
+	step(1.0, 15*time.Second, 2)  // pending again, from scratch
+	step(1.0, 20*time.Second, 2)  // 20s of 30s
+	step(1.0, 15*time.Second, 5)  // still wanted after 35s: applied
+	step(2.0, 15*time.Second, 10) // the ramp goes on without waiting again
+}
+
+func runningPod(ns, name string, started time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: map[string]string{"app": "web"}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning, StartTime: &metav1.Time{Time: started}},
+	}
+}
+
+func TestWarmUpExcludesYoungPods(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	// Two warm pods use 0.8 cores between them; the two fresh ones are idle.
+	// Counting all four would size for 0.8 cores (4 replicas, no change);
+	// extrapolating the warm average sizes for 1.6 cores (8 replicas).
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.8)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"warmUp":           "2m",
+		"targetCPU":        0.2,
+		"stepLimit":        int64(10),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk},
+		newDeployment("default", "web", 4), cr,
+		runningPod("default", "web-a", clk.Now().Add(-time.Hour)),
+		runningPod("default", "web-b", clk.Now().Add(-time.Hour)),
+		runningPod("default", "web-c", clk.Now().Add(-30*time.Second)),
+		runningPod("default", "web-d", clk.Now().Add(-30*time.Second)),
+	)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("replicas = %d, want 8", got)
+	}
+	for _, q := range prom.Queries() {
+		if strings.Contains(q, "web-c") || strings.Contains(q, "web-d") {
+			t.Errorf("query includes a warming pod: %s", q)
+		}
+	}
+}
+
+func TestSpotFraction(t *testing.T) {
+	spotNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "spot-1", Labels: map[string]string{"karpenter.sh/capacity-type": "spot"}}}
+	customNode := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cheap-1", Labels: map[string]string{"pool": "cheap"}}}
+	onDemand := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "od-1"}}
+
+	objs := []client.Object{spotNode, customNode, onDemand}
+	for i, node := range []string{"spot-1", "cheap-1", "od-1", "od-1"} {
+		p := runningPod("default", "web-"+string(rune('a'+i)), time.Now())
+		p.Spec.NodeName = node
+		objs = append(objs, p)
+	}
+	r, _ := newFakeReconciler(t, Options{}, objs...)
+	tc := targetCluster{Client: r.Client, reader: r.apiReader}
+	dep := newDeployment("default", "web", 4)
+
+	got, err := tc.spotFraction(context.Background(), dep, nil)
+	if err != nil || got != 0.25 {
+		t.Fatalf("spotFraction = %v, %v; want 0.25", got, err)
+	}
+	got, err = tc.spotFraction(context.Background(), dep, map[string]string{"pool": "cheap"})
+	if err != nil || got != 0.5 {
+		t.Fatalf("spotFraction with extra labels = %v, %v; want 0.5", got, err)
+	}
+}
+
+func TestDecisionWebhook(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // proposes 5 replicas
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	answer := `{"allowed":false,"reason":"change freeze"}`
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req decisionhook.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Current != 2 || req.Proposed != 5 {
+			t.Errorf("unexpected webhook request %+v (%v)", req, err)
+		}
+		w.Write([]byte(answer))
+	}))
+	defer hook.Close()
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+		"minReplicas":      int64(1),
+		"decisionWebhook":  map[string]interface{}{"url": hook.URL},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("vetoed: replicas = %d, want 2", got)
+	}
+
+	answer = `{"allowed":true,"replicas":3}`
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 3 {
+		t.Fatalf("overridden: replicas = %d, want 3", got)
+	}
+}
+
+func TestScalingPolicy(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // proposes 5 replicas
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "scaling-policy"},
+		Data: map[string]string{policyKey: `
+package nginxautoscaler
+
+deny[msg] {
+	input.namespace.labels.freeze == "true"
+	msg := "frozen"
+}
+
+max_replicas := 4 { input.change > 1 }
+`},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"freeze": "true"}}}
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+		"minReplicas":      int64(1),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	opts := Options{InstanceName: "test", PolicyConfigMap: types.NamespacedName{Namespace: "ops", Name: "scaling-policy"}}
+	r, c := newFakeReconciler(t, opts, newDeployment("default", "web", 2), cr, cm, ns)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("denied: replicas = %d, want 2", got)
+	}
+
+	ns.Labels = nil
+	if err := c.Update(ctx, ns); err != nil {
+		t.Fatalf("update namespace: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 4 {
+		t.Fatalf("clamped: replicas = %d, want 4", got)
+	}
+}
+
+func TestScalingPolicyTimeZone(t *testing.T) {
+	ctx := context.Background()
+	// Friday 15:30 UTC is already 16:30 in Berlin
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 3, 15, 30, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // proposes 5 replicas
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ops", Name: "scaling-policy"},
+		Data: map[string]string{policyKey: `
+package nginxautoscaler
+
+deny[msg] {
+	input.time.local.weekday == "Friday"
+	input.time.local.hour >= 16
+	msg := "weekend freeze"
+}
+`},
+	}
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	for _, tc := range []struct {
+		loc  *time.Location
+		want int32
+	}{
+		{nil, 5}, // UTC: 15:30, before the freeze
+		{time.FixedZone("CET", 3600), 2},
+	} {
+		opts := Options{InstanceName: "test", Clock: clk, PolicyTimeZone: tc.loc,
+			PolicyConfigMap: types.NamespacedName{Namespace: "ops", Name: "scaling-policy"}}
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		r, c := newFakeReconciler(t, opts, newDeployment("default", "web", 2), cr.DeepCopy(), cm.DeepCopy(), ns)
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != tc.want {
+			t.Fatalf("zone %v: replicas = %d, want %d", tc.loc, got, tc.want)
+		}
+	}
+}
+
+func TestInvalidGitOpsHoldsTarget(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // proposes 5 replicas
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+		"gitops": map[string]interface{}{
+			"repo":      "git@github.com:acme/deploy.git",
+			"path":      "",
+			"secretRef": map[string]interface{}{"name": "deploy-key"},
+		},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	debug := NewDebugStore()
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Debug: debug}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("replicas = %d, want 2 (Argo CD/Flux own the count)", got)
+	}
+	if snaps := debug.Snapshots(); len(snaps) != 1 || snaps[0].SkipReason != "GitOpsInvalid" || snaps[0].Error != "spec.gitops.path is empty" {
+		t.Fatalf("debug snapshots = %+v, want one skipped as GitOpsInvalid", snaps)
+	}
+}
+
+func TestManualApproval(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 2.0) // proposes 10 replicas
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+		"approval":         map[string]interface{}{"maxChangePercent": int64(50)},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 4), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 4 {
+		t.Fatalf("unapproved: replicas = %d, want 4", got)
+	}
+	u := newAutoscaler("default", "web", nil)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatal(err)
+	}
+	id, _, _ := unstructured.NestedString(u.Object, "status", "pendingScale", "id")
+	if id == "" {
+		t.Fatalf("no pendingScale in status: %v", u.Object["status"])
+	}
+
+	u.SetAnnotations(map[string]string{approveAnnotation: id})
+	if err := c.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 10 {
+		t.Fatalf("approved: replicas = %d, want 10", got)
+	}
+}
+
+func TestClustersGoThroughApproval(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 2.0) // proposes 10 replicas
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+		"approval":         map[string]interface{}{"maxChangePercent": int64(50)},
+		"clusters":         []interface{}{map[string]interface{}{"name": "a", "promURL": prom.URL}},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 4), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 4 {
+		t.Fatalf("unapproved: replicas = %d, want 4", got)
+	}
+	u := newAutoscaler("default", "web", nil)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatal(err)
+	}
+	id, _, _ := unstructured.NestedString(u.Object, "status", "pendingScale", "id")
+	if id == "" {
+		t.Fatalf("no pendingScale in status: %v", u.Object["status"])
+	}
+
+	u.SetAnnotations(map[string]string{approveAnnotation: id})
+	if err := c.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 10 {
+		t.Fatalf("approved: replicas = %d, want 10", got)
+	}
+}
+
+func TestDeletionCostHints(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	// Registered first so the per-pod query doesn't match the total below
+	prom.SetSeries("by (pod)", map[string]float64{"web-a": 0.3, "web-b": 0.05, "web-c": 0.5, "web-d": 0.6}, "pod")
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.45) // 2 replicas at 1 core each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment":  "web",
+		"promURL":           prom.URL,
+		"targetCPU":         1.0,
+		"stepLimit":         int64(10),
+		"deletionCostHints": true,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	// web-d still carries a hint from a scale-down that never happened
+	stale := runningPod("default", "web-d", time.Now())
+	stale.Annotations = map[string]string{podDeletionCostAnnotation: "-1"}
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 4), cr,
+		runningPod("default", "web-a", time.Now()), runningPod("default", "web-b", time.Now()),
+		runningPod("default", "web-c", time.Now()), stale)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("replicas = %d, want 2", got)
+	}
+	want := map[string]string{"web-a": "-1", "web-b": "-2", "web-c": "", "web-d": ""}
+	for name, cost := range want {
+		var pod corev1.Pod
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &pod); err != nil {
+			t.Fatal(err)
+		}
+		if got := pod.Annotations[podDeletionCostAnnotation]; got != cost {
+			t.Errorf("%s pod-deletion-cost = %q, want %q", name, got, cost)
+		}
+	}
+}
+
+func TestDrainAwareScaleDown(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.2) // 1 replica at 0.2 cores
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+	prom.SetSeries("nginx_connections_active", map[string]float64{"web-a": 30, "web-b": 12, "web-c": 40}, "pod")
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"minReplicas":      int64(1),
+		"targetCPU":        0.2,
+		"drain":            map[string]interface{}{"maxPerPod": int64(5)},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 3), cr,
+		runningPod("default", "web-a", time.Now()), runningPod("default", "web-b", time.Now()),
+		runningPod("default", "web-c", time.Now()))
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 3 {
+		t.Fatalf("no pod drained: replicas = %d, want 3", got)
+	}
+
+	// web-b winds down; it alone goes
+	prom.SetSeries("nginx_connections_active", map[string]float64{"web-a": 30, "web-b": 2, "web-c": 40}, "pod")
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("web-b drained: replicas = %d, want 2", got)
+	}
+	var pod corev1.Pod
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web-b"}, &pod); err != nil {
+		t.Fatal(err)
+	}
+	if got := pod.Annotations[podDeletionCostAnnotation]; got != "-1" {
+		t.Fatalf("web-b pod-deletion-cost = %q, want -1", got)
+	}
+}
+
+func TestErrorGuardLetsIdleServiceScaleDown(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.2) // 1 replica at 0.2 cores
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	for _, guard := range []map[string]interface{}{
+		{"maxRatio": 0.05},
+		// A hand-written ratio without a clamped denominator answers 0/0
+		{"maxRatio": 0.05, "query": `sum(rate(http_errors_total[2m])) / sum(rate(http_requests_total[2m]))`},
+	} {
+		// No requests at all: the ingress ratio reads empty, the custom one NaN
+		prom.SetEmpty("nginx_ingress_controller_requests")
+		prom.SetInstant("http_errors_total", math.NaN())
+		cr := newAutoscaler("default", "web", map[string]interface{}{
+			"targetDeployment": "web",
+			"promURL":          prom.URL,
+			"cooldown":         "0s",
+			"minReplicas":      int64(1),
+			"targetCPU":        0.2,
+			"stepLimit":        int64(20),
+			"ingress":          map[string]interface{}{"name": "web"},
+			"errorGuard":       guard,
+		})
+		cr.SetFinalizers([]string{lockFinalizer})
+		r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 4), cr)
+		req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != 1 {
+			t.Fatalf("idle service with errorGuard %v: replicas = %d, want 1", guard, got)
+		}
+	}
+}
+
+func TestCapacityCalibration(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	// history registers the per-replica series, each half of a pair told
+	// apart by what follows "and on()", ahead of the totals
+	history := func(rps, cpu []float64) {
+		prom.SetRange("and on() (sum(rate(container_cpu", rps...)
+		prom.SetRange("and on() (sum(container_memory", rps...)
+		prom.SetRange("(sum(container_memory_working_set_bytes", 200<<20, 200<<20, 200<<20, 200<<20)
+		prom.SetRange("and on() ((sum(rate(nginx_ingress", cpu...)
+		prom.SetInstant("container_cpu_usage_seconds_total", 2.0)
+		prom.SetInstant("container_memory_working_set_bytes", 0)
+		prom.SetInstant("nginx_ingress_controller_requests", 400)
+	}
+	// One replica costs 0.05 cores idle plus 0.002 per request/s: 0.25 at 100 rps.
+	// At 4 replicas each one saw 50-125 rps.
+	history([]float64{50, 75, 100, 125}, []float64{0.15, 0.2, 0.25, 0.3})
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2, // would ask for 10 replicas
+		"targetRPS":        100.0,
+		"ingress":          map[string]interface{}{"name": "web"},
+		"stepLimit":        int64(20),
+		"calibration":      map[string]interface{}{"minSamples": int64(4)},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 4), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	calibrated := func() calibration {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		u := newAutoscaler("default", "web", nil)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatal(err)
+		}
+		cal, ok := readCalibration(u)
+		if !ok || !cal.Updated.Equal(clk.Now()) {
+			t.Fatalf("status.calibration = %+v, %v", cal, ok)
+		}
+		return cal
+	}
+
+	cal := calibrated()
+	// Memory stays flat as requests grow, so it isn't calibrated
+	if math.Abs(cal.CPU-0.25) > 1e-6 || cal.MemMiB != 0 || cal.Samples != 4 {
+		t.Fatalf("status.calibration = %+v, want 0.25 cores, no memory, 4 samples", cal)
+	}
+	// 2 cores at 0.25 each
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("replicas = %d, want 8", got)
+	}
+
+	// At 8 replicas each one sees half the traffic and half the CPU, along
+	// the same cost line: recalibrating must not lower the target
+	history([]float64{25, 37.5, 50, 62.5}, []float64{0.1, 0.125, 0.15, 0.175})
+	clk.SetTime(clk.Now().Add(time.Hour))
+	if again := calibrated(); math.Abs(again.CPU-cal.CPU) > 1e-6 {
+		t.Fatalf("recalibrated cpuPerReplica = %v, want %v", again.CPU, cal.CPU)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("after recalibrating: replicas = %d, want 8", got)
+	}
+}
+
+func TestAnomalyFilter(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	steady := []float64{1.0, 1.1, 0.9, 1.0, 1.05, 0.95, 1.0, 1.0}
+	// An exporter restart: the latest CPU sample reads 9 cores (45 replicas)
+	prom.SetRange("container_cpu_usage_seconds_total", append(steady, 9.0)...)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"maxReplicas":      int64(50),
+		"stepLimit":        int64(50),
+		"anomalyFilter":    map[string]interface{}{},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 5), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	discarded := testutil.ToFloat64(discardedSamples.WithLabelValues("default", "web", "cpu"))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("scaled on a glitch: replicas = %d, want 5", got)
+	}
+	if got := testutil.ToFloat64(discardedSamples.WithLabelValues("default", "web", "cpu")) - discarded; got != 1 {
+		t.Fatalf("discarded cpu samples = %v, want 1", got)
+	}
+
+	// Load really doubled: two samples in a row at the new level
+	prom.SetRange("container_cpu_usage_seconds_total", append(steady, 2.0, 2.0)...)
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 10 {
+		t.Fatalf("level shift: replicas = %d, want 10", got)
+	}
+}
+
+func TestShadowPolicy(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 2.0)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"maxReplicas":      int64(50),
+		"stepLimit":        int64(50),
+		"shadow":           map[string]interface{}{"targetCPU": 0.5},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	debug := NewDebugStore()
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Debug: debug}, newDeployment("default", "web", 5), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	// The active policy is applied; the shadow is only reported
+	if got := replicasOf(t, c, "default", "web"); got != 10 {
+		t.Fatalf("replicas = %d, want 10", got)
+	}
+	snaps := debug.Snapshots()
+	if len(snaps) != 1 || snaps[0].ShadowReplicas != 4 || snaps[0].ShadowDesired != 4 {
+		t.Fatalf("snapshot = %+v, want shadow at 4 replicas", snaps)
+	}
+	if got := testutil.ToFloat64(recommendedReplicas.WithLabelValues("default", "web", "shadow")); got != 4 {
+		t.Fatalf("shadow gauge = %v, want 4", got)
+	}
+	if got := testutil.ToFloat64(recommendedReplicas.WithLabelValues("default", "web", "active")); got != 10 {
+		t.Fatalf("active gauge = %v, want 10", got)
+	}
+}
+
+func TestVerticalFallback(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 4.0) // 20 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	dep := newDeployment("default", "web", 10)
+	dep.Spec.Template.Spec.Containers[0].Resources = corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("400m")},
+	}
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"maxReplicas":      int64(10),
+		"verticalFallback": map[string]interface{}{"after": "10m"},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	// At maxReplicas for an hour already
+	_ = unstructured.SetNestedSlice(cr.Object, []interface{}{map[string]interface{}{
+		"type": condSaturated, "status": "True", "reason": "AtMaxReplicas", "message": "",
+		"lastTransitionTime": time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	}}, "status", "conditions")
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, dep, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	requests := func() (cpu, cpuLimit string) {
+		var got appsv1.Deployment
+		if err := c.Get(ctx, req.NamespacedName, &got); err != nil {
+			t.Fatalf("get deployment: %v", err)
+		}
+		res := got.Spec.Template.Spec.Containers[0].Resources
+		return res.Requests.Cpu().String(), res.Limits.Cpu().String()
+	}
+	status := func() map[string]interface{} {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(autoscalerGVK)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatalf("get autoscaler: %v", err)
+		}
+		m, _, _ := unstructured.NestedMap(u.Object, "status", "verticalFallback")
+		return m
+	}
+
+	// Recommend only: twice the CPU, nothing written to the Deployment
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if st := status(); st["cpu"] != "400m" || st["resized"] != false {
+		t.Fatalf("status.verticalFallback = %v, want a 400m recommendation", st)
+	}
+	if cpu, _ := requests(); cpu != "200m" {
+		t.Fatalf("recommend mode resized the Deployment to %s", cpu)
+	}
+
+	// Resize, bounded by maxCPU; the limit keeps its ratio to the request
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	_ = unstructured.SetNestedMap(u.Object, map[string]interface{}{"after": "10m", "mode": "Resize", "maxCPU": "300m"},
+		"spec", "verticalFallback")
+	if err := c.Update(ctx, u); err != nil {
+		t.Fatalf("update autoscaler: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if cpu, limit := requests(); cpu != "300m" || limit != "600m" {
+		t.Fatalf("resized to request %s, limit %s; want 300m, 600m", cpu, limit)
+	}
+	if st := status(); st["resized"] != true || st["cpuScale"] != "1.5" {
+		t.Fatalf("status.verticalFallback = %v, want resized at scale 1.5", st)
+	}
+
+	// Each replica now takes 0.3 cores, so 14 are needed; the resize is left to settle
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if cpu, _ := requests(); cpu != "300m" {
+		t.Fatalf("resized again within after: %s", cpu)
+	}
+}
+
+func TestInPlaceResize(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.6) // 3 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cpu := func(v string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(v)}}
+	}
+	dep := newDeployment("default", "web", 2)
+	dep.Spec.Template.Spec.Containers[0].Resources = cpu("200m")
+	objs := []client.Object{dep}
+	for _, name := range []string{"web-a", "web-b"} {
+		pod := runningPod("default", name, time.Now().Add(-time.Hour))
+		pod.Spec.Containers = []corev1.Container{{Name: "nginx", Image: "nginx", Resources: cpu("200m")}}
+		objs = append(objs, pod)
+	}
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"minReplicas":      int64(1),
+		"inPlaceResize":    map[string]interface{}{"maxCPU": "500m"},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	objs = append(objs, cr)
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, objs...)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	podCPU := func() string {
+		var pod corev1.Pod
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web-a"}, &pod); err != nil {
+			t.Fatalf("get pod: %v", err)
+		}
+		return pod.Spec.Containers[0].Resources.Requests.Cpu().String()
+	}
+	step := func(wantCPU string, wantReplicas int32) {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := podCPU(); got != wantCPU {
+			t.Fatalf("pod cpu request = %s, want %s", got, wantCPU)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != wantReplicas {
+			t.Fatalf("replicas = %d, want %d", got, wantReplicas)
+		}
+	}
+
+	// 1.5x the load: the two pods grow to 300m instead of a third pod
+	step("300m", 2)
+	// Sized right now; nothing to do
+	step("300m", 2)
+	// Load climbs past what 500m pods can carry: first up to the ceiling...
+	prom.SetInstant("container_cpu_usage_seconds_total", 2.0)
+	step("500m", 2)
+	// ...then more replicas, at 0.5 cores each
+	step("500m", 4)
+}
+
+func TestInPlaceResizeRefusedWithGates(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.6) // 3 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cpu := corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")}}
+	dep := newDeployment("default", "web", 2)
+	dep.Spec.Template.Spec.Containers[0].Resources = cpu
+	pod := runningPod("default", "web-a", time.Now().Add(-time.Hour))
+	pod.Spec.Containers = []corev1.Container{{Name: "nginx", Image: "nginx", Resources: cpu}}
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"inPlaceResize":    map[string]interface{}{"maxCPU": "500m"},
+		"approval":         map[string]interface{}{"maxChangePercent": int64(10)},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, dep, pod, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	// Neither the pods nor the replicas move until someone approves
+	if err := c.Get(ctx, client.ObjectKeyFromObject(pod), pod); err != nil {
+		t.Fatal(err)
+	}
+	if got := pod.Spec.Containers[0].Resources.Requests.Cpu().String(); got != "200m" {
+		t.Fatalf("pod cpu request = %s, want 200m", got)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("replicas = %d, want 2", got)
+	}
+	u := newAutoscaler("default", "web", nil)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatal(err)
+	}
+	if id, _, _ := unstructured.NestedString(u.Object, "status", "pendingScale", "id"); id == "" {
+		t.Fatalf("no pendingScale in status: %v", u.Object["status"])
+	}
+	cond := meta.FindStatusCondition(getConditions(u), condInPlaceResize)
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "GatesConfigured" || !strings.Contains(cond.Message, "spec.approval") {
+		t.Fatalf("InPlaceResize condition = %+v", cond)
+	}
+}
+
+func TestOverprovisioningBalloons(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.8) // 4 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	dep := newDeployment("default", "web", 4)
+	dep.Spec.Template.Spec.Containers[0].Resources = corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+	}
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+		"overprovisioning": map[string]interface{}{"priorityClassName": "overprovisioning", "replicas": int64(1), "percent": int64(25)},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	// Balloons under the name they had before, when their pods counted as web's
+	legacy := newDeployment("default", "web-balloon", 1)
+	legacy.Labels = map[string]string{balloonLabel: "web"}
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, dep, legacy, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	balloonKey := types.NamespacedName{Namespace: "default", Name: "web.balloon"}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(legacy), legacy); !apierrors.IsNotFound(err) {
+		t.Fatalf("web-balloon still there after the rename: %v", err)
+	}
+	// Balloon pods stay out of the pod regex web's usage is summed over
+	checked := 0
+	for _, q := range prom.Queries() {
+		m := regexp.MustCompile(`pod=~"([^"]*)"`).FindStringSubmatch(q)
+		if m == nil {
+			continue
+		}
+		checked++
+		pods := regexp.MustCompile("^(?:" + m[1] + ")$")
+		if !pods.MatchString("web-7c9d8b5f4-abcde") || pods.MatchString(balloonKey.Name+"-6b7f9c8d5-x2x9k") {
+			t.Fatalf("pod matcher %q: web's pods in %v, balloons in %v", m[1],
+				pods.MatchString("web-7c9d8b5f4-abcde"), pods.MatchString(balloonKey.Name+"-6b7f9c8d5-x2x9k"))
+		}
+	}
+	if checked == 0 {
+		t.Fatalf("no pod matcher among the queries: %v", prom.Queries())
+	}
+	// 1 standing balloon plus 25% of 4 replicas, each the size of a web pod
+	var balloons appsv1.Deployment
+	if err := c.Get(ctx, balloonKey, &balloons); err != nil {
+		t.Fatalf("get balloons: %v", err)
+	}
+	pod := balloons.Spec.Template.Spec
+	if *balloons.Spec.Replicas != 2 || pod.PriorityClassName != "overprovisioning" ||
+		pod.Containers[0].Resources.Requests.Cpu().String() != "200m" {
+		t.Fatalf("balloons = %d x %s at priority %q, want 2 x 200m at overprovisioning",
+			*balloons.Spec.Replicas, pod.Containers[0].Resources.Requests.Cpu(), pod.PriorityClassName)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 4 {
+		t.Fatalf("replicas = %d, want 4", got)
+	}
+
+	// Dropping spec.overprovisioning removes them
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	unstructured.RemoveNestedField(u.Object, "spec", "overprovisioning")
+	if err := c.Update(ctx, u); err != nil {
+		t.Fatalf("update autoscaler: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(ctx, balloonKey, &balloons); err == nil {
+		t.Fatalf("balloons still there after overprovisioning was removed")
+	}
+}
+
+func TestTargetSelector(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	teamDep := func(name, team string) *appsv1.Deployment {
+		dep := newDeployment("default", name, 2)
+		dep.Labels = map[string]string{"team": team}
+		return dep
+	}
+	blanket := newAutoscaler("default", "team-a", map[string]interface{}{
+		"targetSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"team": "a"}},
+		"promURL":        prom.URL,
+		"targetCPU":      0.2,
+	})
+	blanket.SetFinalizers([]string{lockFinalizer})
+	blanket.SetUID("team-a-uid")
+	// api has its own CR, which wins over the blanket policy
+	explicit := newAutoscaler("default", "api", map[string]interface{}{"targetDeployment": "api", "promURL": prom.URL})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"},
+		teamDep("web", "a"), teamDep("api", "a"), teamDep("db", "b"), blanket, explicit)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "team-a"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	generated := generatedAutoscalers(t, c, blanket)
+	if len(generated) != 1 || generated[0].GetName() != selectorChildName("team-a", "web") {
+		t.Fatalf("generated %d autoscalers, want just the one for web", len(generated))
+	}
+	if got := targetKeyOf(&generated[0]); got != "default/web" {
+		t.Fatalf("generated autoscaler targets %s, want default/web", got)
+	}
+	if got := generated[0].GetAnnotations()[selectorParentAnnotation]; got != "team-a" {
+		t.Fatalf("generated autoscaler's parent annotation = %q, want team-a", got)
+	}
+
+	// The generated CR scales its Deployment like any other
+	child := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(&generated[0])}
+	if _, err := r.Reconcile(ctx, child); err != nil {
+		t.Fatalf("reconcile generated: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, child); err != nil {
+		t.Fatalf("reconcile generated: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("web replicas = %d, want 5", got)
+	}
+
+	// web leaves the team: its generated CR goes
+	var web appsv1.Deployment
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, &web); err != nil {
+		t.Fatalf("get web: %v", err)
+	}
+	web.Labels["team"] = "b"
+	if err := c.Update(ctx, &web); err != nil {
+		t.Fatalf("relabel web: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	// (once it has released web's lock)
+	if _, err := r.Reconcile(ctx, child); err != nil {
+		t.Fatalf("reconcile generated: %v", err)
+	}
+	if left := generatedAutoscalers(t, c, blanket); len(left) != 0 {
+		t.Fatalf("%d generated autoscalers left, want 0", len(left))
+	}
+}
+
+// generatedAutoscalers lists the NginxAutoscalers parent controls.
+func generatedAutoscalers(t *testing.T, c client.Client, parent *unstructured.Unstructured) []unstructured.Unstructured {
+	t.Helper()
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	if err := c.List(context.Background(), list, client.InNamespace(parent.GetNamespace())); err != nil {
+		t.Fatalf("list autoscalers: %v", err)
+	}
+	var out []unstructured.Unstructured
+	for _, item := range list.Items {
+		if generatedBy(&item, parent) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func TestTargetSelectorChildNames(t *testing.T) {
+	ctx := context.Background()
+	long := strings.Repeat("p", 253)
+	selectorCR := func(name string, uid types.UID, team string) *unstructured.Unstructured {
+		u := newAutoscaler("default", name, map[string]interface{}{
+			"targetSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"team": team}},
+			"promURL":        "http://prometheus.invalid",
+		})
+		u.SetUID(uid)
+		u.SetFinalizers([]string{lockFinalizer})
+		return u
+	}
+	teamDep := func(name, team string) *appsv1.Deployment {
+		dep := newDeployment("default", name, 2)
+		dep.Labels = map[string]string{"team": team}
+		return dep
+	}
+	// "a-b" selecting c and "a" selecting b-c both used to generate a-b-c
+	ab, a, longCR := selectorCR("a-b", "ab-uid", "x"), selectorCR("a", "a-uid", "y"), selectorCR(long, "long-uid", "z")
+	// A hand-made CR already holding the name long would generate for d
+	squatter := newAutoscaler("default", selectorChildName(long, "d"), map[string]interface{}{"targetDeployment": "other"})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"},
+		teamDep("c", "x"), teamDep("b-c", "y"), teamDep("d", "z"), ab, a, longCR, squatter)
+
+	for _, parent := range []*unstructured.Unstructured{ab, a, longCR} {
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(parent)}); err != nil {
+			t.Fatalf("reconcile %s: %v", parent.GetName(), err)
+		}
+	}
+	for _, tc := range []struct {
+		parent *unstructured.Unstructured
+		target string
+	}{{ab, "default/c"}, {a, "default/b-c"}} {
+		generated := generatedAutoscalers(t, c, tc.parent)
+		if len(generated) != 1 || targetKeyOf(&generated[0]) != tc.target {
+			t.Fatalf("%s generated %d autoscalers, want one for %s", tc.parent.GetName(), len(generated), tc.target)
+		}
+	}
+	if name := selectorChildName(long, "d"); len(name) > 253 {
+		t.Fatalf("child name is %d characters, want at most 253", len(name))
+	}
+
+	// The squatter is neither adopted nor overwritten, and the parent says why
+	if generated := generatedAutoscalers(t, c, longCR); len(generated) != 0 {
+		t.Fatalf("long-named parent adopted %d autoscalers, want 0", len(generated))
+	}
+	got := &unstructured.Unstructured{}
+	got.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(squatter), got); err != nil {
+		t.Fatalf("get squatter: %v", err)
+	}
+	if targetKeyOf(got) != "default/other" {
+		t.Fatalf("squatter now targets %s, want default/other", targetKeyOf(got))
+	}
+	parent := &unstructured.Unstructured{}
+	parent.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(longCR), parent); err != nil {
+		t.Fatalf("get parent: %v", err)
+	}
+	if cond := meta.FindStatusCondition(getConditions(parent), condTargetAdopted); cond == nil || cond.Reason != "SelectorFailed" {
+		t.Fatalf("TargetAdopted = %+v, want reason SelectorFailed", cond)
+	}
+}
+
+func TestRecommenderLeavesSelectorToActuator(t *testing.T) {
+	ctx := context.Background()
+	dep := newDeployment("default", "web", 2)
+	dep.Labels = map[string]string{"team": "a"}
+	blanket := newAutoscaler("default", "team-a", map[string]interface{}{
+		"targetSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"team": "a"}},
+		"promURL":        "http://prometheus.invalid",
+	})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Role: RoleRecommender}, dep, blanket)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "team-a"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	if err := c.List(ctx, list); err != nil {
+		t.Fatalf("list autoscalers: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("recommender left %d autoscalers, want only the selector CR", len(list.Items))
+	}
+}
+
+func TestSilencePodPatternExcludesSiblings(t *testing.T) {
+	controller := true
+	rs := func(owner, uid, hash string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            owner + "-" + hash,
+			Labels:          map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: owner, UID: types.UID(uid), Controller: &controller}},
+		}}
+	}
+	web := newDeployment("default", "web", 2)
+	web.UID = "web-uid"
+	// web-canary shares web's labels, so only ownership tells its ReplicaSet apart
+	r, _ := newFakeReconciler(t, Options{InstanceName: "test"}, web,
+		rs("web", "web-uid", "7d9c8"), rs("web", "web-uid", "5f6b4"), rs("web-canary", "canary-uid", "6c7d8"))
+	tc := targetCluster{Client: r.Client, reader: r.apiReader}
+
+	pattern, err := tc.podPattern(context.Background(), web)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile("^(?:" + pattern + ")$")
+	for pod, want := range map[string]bool{
+		"web-7d9c8-x2k4p":        true,
+		"web-5f6b4-bq7zt":        true,
+		"web-canary-6c7d8-m9d2w": false,
+		"web-canary-7d9c8-m9d2w": false,
+	} {
+		if re.MatchString(pod) != want {
+			t.Errorf("pattern %q matches %s = %v, want %v", pattern, pod, !want, want)
+		}
+	}
+
+	r, _ = newFakeReconciler(t, Options{InstanceName: "test"}, web)
+	tc = targetCluster{Client: r.Client, reader: r.apiReader}
+	if pattern, err := tc.podPattern(context.Background(), web); err != nil || pattern != "" {
+		t.Fatalf("without ReplicaSets: %q, %v", pattern, err)
+	}
+}
+
+func TestScaleHistoryRecordsRevision(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	dep := newDeployment("default", "web", 2)
+	dep.UID = "web-uid"
+	dep.Annotations = map[string]string{revisionAnnotation: "3"}
+	controller := true
+	rs := func(name, rev, hash string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "default",
+			Name:            name,
+			Labels:          map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+			Annotations:     map[string]string{revisionAnnotation: rev},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "web-uid", Controller: &controller}},
+		}}
+	}
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, dep, rs("web-5d8f7", "2", "5d8f7"), rs("web-7c9b4", "3", "7c9b4"), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("replicas = %d, want 5", got)
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	history, _, _ := unstructured.NestedSlice(u.Object, "status", "scaleHistory")
+	if len(history) != 1 {
+		t.Fatalf("scaleHistory = %v, want one entry", history)
+	}
+	entry := history[0].(map[string]interface{})
+	if entry["revision"] != "3" || entry["templateHash"] != "7c9b4" || entry["from"] != int64(2) || entry["to"] != int64(5) {
+		t.Fatalf("scaleHistory[0] = %v, want 2->5 at revision 3 (7c9b4)", entry)
+	}
+}
+
+func TestStatusPatchSurvivesConcurrentEdit(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	cr.SetGeneration(3)
+	// Someone edits the CR between our read and every status write
+	edits := 0
+	c := fake.NewClientBuilder().
+		WithScheme(fakeScheme()).
+		WithObjects(newDeployment("default", "web", 2), cr).
+		WithStatusSubresource(cr).
+		WithIndex(cr, targetIndexKey, targetIndexValues).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, sub string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				edited := obj.DeepCopyObject().(client.Object)
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), edited); err != nil {
+					return err
+				}
+				edited.SetLabels(map[string]string{"edit": strconv.Itoa(edits)})
+				edits++
+				if err := c.Update(ctx, edited); err != nil {
+					return err
+				}
+				return c.SubResource(sub).Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+	r := newReconciler(c, c, Options{InstanceName: "test"})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if edits == 0 {
+		t.Fatalf("no status write went through the interceptor")
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	if got, _, _ := unstructured.NestedInt64(u.Object, "status", "currentReplicas"); got != 5 {
+		t.Fatalf("status.currentReplicas = %d, want 5", got)
+	}
+	if got, _, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration"); got != u.GetGeneration() {
+		t.Fatalf("status.observedGeneration = %d, want %d", got, u.GetGeneration())
+	}
+}
+
+func TestLastObservationIsThrottled(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.4) // 2 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 64*1024*1024)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk, StatusObservationInterval: time.Minute},
+		newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	observation := func() map[string]interface{} {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(autoscalerGVK)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatalf("get autoscaler: %v", err)
+		}
+		obs, ok, _ := unstructured.NestedMap(u.Object, "status", "lastObservation")
+		if !ok {
+			t.Fatalf("status.lastObservation missing")
+		}
+		return obs
+	}
+
+	obs := observation()
+	if replicasOf(t, c, "default", "web") != 2 {
+		t.Fatalf("replicas changed; the test wants a steady target")
+	}
+	first := obs["time"]
+	if obs["cpuCores"] != "0.4" || obs["memMiB"] != "64" || obs["current"] != int64(2) || obs["desired"] != int64(2) {
+		t.Fatalf("lastObservation = %v", obs)
+	}
+	if by, _ := obs["desiredBySignal"].(map[string]interface{}); by["cpu"] != int64(2) {
+		t.Fatalf("lastObservation.desiredBySignal = %v, want cpu 2", obs["desiredBySignal"])
+	}
+
+	// Within the interval and nothing new: status is left alone
+	clk.SetTime(clk.Now().Add(30 * time.Second))
+	if obs := observation(); obs["time"] != first {
+		t.Fatalf("lastObservation rewritten after 30s: %v", obs["time"])
+	}
+
+	// Scaling changes the skip reason, which is written at once
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.6)
+	clk.SetTime(clk.Now().Add(time.Second))
+	if obs := observation(); obs["time"] == first || obs["cpuCores"] != "0.6" {
+		t.Fatalf("lastObservation not rewritten on change: %v", obs)
+	}
+}
+
+// chanSink hands every event it is sent to the test.
+type chanSink chan events.Event
+
+func (c chanSink) Send(_ context.Context, e events.Event) error { c <- e; return nil }
+func (c chanSink) Close() error                                 { return nil }
+
+func TestDecisionIDCorrelatesEventsAndStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	decisions, scales := make(chanSink, 4), make(chanSink, 4)
+	opts := Options{
+		InstanceName:              "test",
+		Events:                    events.NewAsync(decisions, 4, logr.Discard()),
+		Bus:                       events.NewAsync(scales, 4, logr.Discard()),
+		StatusObservationInterval: time.Minute,
+	}
+	go opts.Events.Start(ctx)
+	go opts.Bus.Start(ctx)
+	r, c := newFakeReconciler(t, opts, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("replicas = %d, want 5", got)
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	history, _, _ := unstructured.NestedSlice(u.Object, "status", "scaleHistory")
+	if len(history) != 1 {
+		t.Fatalf("status.scaleHistory = %v, want one scale", history)
+	}
+	id, _ := history[0].(map[string]interface{})["decisionID"].(string)
+	if id == "" {
+		t.Fatalf("scaleHistory[0] has no decisionID: %v", history[0])
+	}
+	if got, _, _ := unstructured.NestedString(u.Object, "status", "lastObservation", "decisionID"); got != id {
+		t.Fatalf("status.lastObservation.decisionID = %q, want %q", got, id)
+	}
+
+	select {
+	case e := <-decisions:
+		if e.ID != id || e.Data.(DebugSnapshot).DecisionID != id {
+			t.Fatalf("decision event %s carries decision %q, want %q", e.ID, e.Data.(DebugSnapshot).DecisionID, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no decision event")
+	}
+	select {
+	case e := <-scales:
+		if got := e.Data.(map[string]interface{})["decisionID"]; got != id {
+			t.Fatalf("scaled event decisionID = %v, want %q", got, id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no scaled event")
+	}
+}
+
+func TestHysteresisLogIsSampled(t *testing.T) {
+	var lines []string
+	ctx := log.IntoContext(context.Background(), funcr.New(func(_, args string) { lines = append(lines, args) }, funcr.Options{}))
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.4) // 2 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, _ := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk, HysteresisLogInterval: 5 * time.Minute},
+		newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	logged := func() int {
+		n := 0
+		for _, l := range lines {
+			if strings.Contains(l, "within hysteresis") {
+				n++
+			}
+		}
+		return n
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		clk.SetTime(clk.Now().Add(time.Minute))
+	}
+	if got := logged(); got != 1 {
+		t.Fatalf("logged %d hysteresis messages in 4 minutes, want 1", got)
+	}
+	clk.SetTime(clk.Now().Add(time.Minute))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := logged(); got != 2 {
+		t.Fatalf("logged %d hysteresis messages after 5 minutes, want 2", got)
+	}
+}
+
+func TestInvalidSamplesHoldReplicas(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 4), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	// A counter reset (negative rate), NaN and ±Inf must not read as zero
+	// load and drop the Deployment to minReplicas, nor as infinite load.
+	for _, v := range []float64{-2, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		prom.SetInstant("container_cpu_usage_seconds_total", v)
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile(%v): %v", v, err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != 4 {
+			t.Fatalf("cpu sample %v: replicas = %d, want 4", v, got)
+		}
+	}
+
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0)
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("after a valid sample replicas = %d, want 5", got)
+	}
+}
+
+func TestEmptyMetricsWithRunningPods(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetEmpty("container_cpu_usage_seconds_total")
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 4),
+		runningPod("default", "web-1", time.Now()), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	condition := func() *metav1.Condition {
+		u := newAutoscaler("default", "web", nil)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatal(err)
+		}
+		return meta.FindStatusCondition(getConditions(u), condMetricsAvailable)
+	}
+
+	// A pod is running, so no CPU series means a broken scrape, not zero load
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 4 {
+		t.Fatalf("with metrics missing replicas = %d, want 4", got)
+	}
+	if cond := condition(); cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "MetricsUnavailable" {
+		t.Fatalf("MetricsAvailable = %+v; want False/MetricsUnavailable", cond)
+	}
+
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0)
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("after metrics returned replicas = %d, want 5", got)
+	}
+	if cond := condition(); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("MetricsAvailable = %+v; want True", cond)
+	}
+}
+
+func TestPodMetricScaling(t *testing.T) {
+	ctx := context.Background()
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stats" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"queue": {"depth": 200, "name": "jobs"}}`))
+	}))
+	defer app.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(app.URL, "http://"))
+	portNum, _ := strconv.Atoi(port)
+
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.2)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+		"podMetric": map[string]interface{}{
+			"port":             int64(portNum),
+			"path":             "/stats",
+			"jsonPath":         ".queue.depth",
+			"targetPerReplica": int64(50),
+		},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	pods := []client.Object{newDeployment("default", "web", 2), cr}
+	for _, name := range []string{"web-1", "web-2"} {
+		p := runningPod("default", name, time.Now())
+		p.Status.PodIP = host
+		pods = append(pods, p)
+	}
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, pods...)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	// Two pods with 200 queued each at 50 per replica need 8 replicas; CPU needs 1
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("replicas = %d, want 8", got)
+	}
+}
+
+func TestSQLMetricScaling(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.New(t)
+	db.Set(int64(300))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.2)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+		"sql": map[string]interface{}{
+			"driver":           sqltest.Driver,
+			"query":            "SELECT count(*) FROM jobs WHERE state = 'queued'",
+			"secretRef":        map[string]interface{}{"name": "jobs-db"},
+			"targetPerReplica": int64(50),
+		},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "jobs-db"},
+		Data:       map[string][]byte{"dsn": []byte(db.DSN)},
+	}
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 2), secret, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	// 300 queued jobs at 50 per worker need 6 replicas; CPU needs 1
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 6 {
+		t.Fatalf("replicas = %d, want 6", got)
+	}
+
+	// A failing query holds replicas rather than dropping to what CPU wants
+	db.SetError(errors.New("connection refused"))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 6 {
+		t.Fatalf("after a failed query replicas = %d, want 6", got)
+	}
+}
+
+func TestNATSMetricScaling(t *testing.T) {
+	ctx := context.Background()
+	body := `{"account_details":[{"name":"$G","stream_detail":[{"name":"ORDERS","consumer_detail":[{"name":"worker","num_pending":300,"num_ack_pending":100}]}]}]}`
+	jsz := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer jsz.Close()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.2)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+		"nats": map[string]interface{}{
+			"monitoringURL":    jsz.URL,
+			"stream":           "ORDERS",
+			"consumer":         "worker",
+			"targetPerReplica": int64(50),
+		},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	// 300 pending + 100 ack-pending at 50 per consumer need 8 replicas
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("replicas = %d, want 8", got)
+	}
+
+	// The consumer disappearing holds replicas rather than scaling to CPU
+	body = `{"account_details":[]}`
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("after a missing consumer replicas = %d, want 8", got)
+	}
+}
+
+func TestEndpointsScaling(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.2)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "cache", map[string]interface{}{
+		"targetDeployment": "cache",
+		"promURL":          prom.URL,
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+		"endpoints":        map[string]interface{}{"service": "app", "targetPerReplica": int64(2)},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	ready, notReady := true, false
+	endpoint := func(pod, addr string, isReady *bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{addr},
+			Conditions: discoveryv1.EndpointConditions{Ready: isReady},
+			TargetRef:  &corev1.ObjectReference{Kind: "Pod", Name: pod},
+		}
+	}
+	slice := func(name string, family discoveryv1.AddressType, eps ...discoveryv1.Endpoint) *discoveryv1.EndpointSlice {
+		return &discoveryv1.EndpointSlice{
+			ObjectMeta:  metav1.ObjectMeta{Namespace: "default", Name: name, Labels: map[string]string{discoveryv1.LabelServiceName: "app"}},
+			AddressType: family,
+			Endpoints:   eps,
+		}
+	}
+	// 6 ready app pods (dual-stack, so each appears twice), 1 not ready, and
+	// an unrelated Service's slice
+	v4 := slice("app-v4", discoveryv1.AddressTypeIPv4)
+	v6 := slice("app-v6", discoveryv1.AddressTypeIPv6)
+	for i := 0; i < 6; i++ {
+		pod := "app-" + strconv.Itoa(i)
+		v4.Endpoints = append(v4.Endpoints, endpoint(pod, "10.0.0."+strconv.Itoa(i), &ready))
+		v6.Endpoints = append(v6.Endpoints, endpoint(pod, "fd00::"+strconv.Itoa(i), nil))
+	}
+	v4.Endpoints = append(v4.Endpoints, endpoint("app-starting", "10.0.0.99", &notReady))
+	other := slice("other", discoveryv1.AddressTypeIPv4, endpoint("x", "10.0.1.1", &ready), endpoint("y", "10.0.1.2", &ready))
+	other.Labels[discoveryv1.LabelServiceName] = "other"
+
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, newDeployment("default", "cache", 1), v4, v6, other, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "cache"}}
+
+	// 6 ready app endpoints at 2 per cache pod need 3 replicas
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "cache"); got != 3 {
+		t.Fatalf("replicas = %d, want 3", got)
+	}
+}
+
+func TestKnativeServiceTarget(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetRef": map[string]interface{}{
+			"apiVersion": "serving.knative.dev/v1",
+			"kind":       "Service",
+			"name":       "web",
+		},
+		"promURL":     prom.URL,
+		"minReplicas": int64(1),
+		"maxReplicas": int64(20),
+		"targetCPU":   0.2,
+		"stepLimit":   int64(20),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	ksvc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"latestReadyRevisionName": "web-00002"},
+	}}
+	ksvc.SetGroupVersionKind(knativeServiceGVK)
+	ksvc.SetNamespace("default")
+	ksvc.SetName("web")
+	rev := &unstructured.Unstructured{}
+	rev.SetGroupVersionKind(knativeRevisionGVK)
+	rev.SetNamespace("default")
+	rev.SetName("web-00002")
+	dep := newDeployment("default", "web-00002-deployment", 2)
+	dep.Labels = map[string]string{knativeRevisionLabel: "web-00002"}
+
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, ksvc, rev, dep, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	// The Revision is pinned at 5; the Deployment is left to Knative
+	if err := c.Get(ctx, client.ObjectKeyFromObject(rev), rev); err != nil {
+		t.Fatalf("get revision: %v", err)
+	}
+	if a := rev.GetAnnotations(); a[knativeMinScale] != "5" || a[knativeMaxScale] != "5" {
+		t.Fatalf("revision annotations = %v, want min-scale and max-scale 5", a)
+	}
+	if got := replicasOf(t, c, "default", "web-00002-deployment"); got != 2 {
+		t.Fatalf("deployment replicas = %d, want it untouched at 2", got)
+	}
+	if q := prom.Queries(); len(q) == 0 || !strings.Contains(q[0], "web-00002-deployment") {
+		t.Fatalf("queries = %q, want them against the revision's pods", q)
+	}
+}
+
+func TestDeploymentConfigTarget(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetRef": map[string]interface{}{
+			"apiVersion": "apps.openshift.io/v1",
+			"kind":       "DeploymentConfig",
+			"name":       "web",
+		},
+		"promURL":     prom.URL,
+		"minReplicas": int64(1),
+		"maxReplicas": int64(20),
+		"targetCPU":   0.2,
+		"stepLimit":   int64(20),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	dc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"selector": map[string]interface{}{"app": "web"},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}},
+				"spec": map[string]interface{}{"containers": []interface{}{
+					map[string]interface{}{"name": "nginx", "image": "nginx"},
+				}},
+			},
+		},
+		"status": map[string]interface{}{"latestVersion": int64(3)},
+	}}
+	dc.SetGroupVersionKind(deploymentConfigGVK)
+	dc.SetNamespace("default")
+	dc.SetName("web")
+
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, dc, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(dc), dc); err != nil {
+		t.Fatalf("get deploymentconfig: %v", err)
+	}
+	if got, _, _ := unstructured.NestedInt64(dc.Object, "spec", "replicas"); got != 5 {
+		t.Fatalf("deploymentconfig replicas = %d, want 5", got)
+	}
+	a := dc.GetAnnotations()
+	if a[managedByAnnotation] != "test:default/web" {
+		t.Fatalf("annotations = %v, want the managed-by lock", a)
+	}
+	if _, ok := a[revisionAnnotation]; ok {
+		t.Fatalf("annotations = %v: the synthesized revision leaked into the DeploymentConfig", a)
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	if h, _, _ := unstructured.NestedSlice(u.Object, "status", "scaleHistory"); len(h) != 1 || h[0].(map[string]interface{})["revision"] != "3" {
+		t.Fatalf("scaleHistory = %v, want one entry at revision 3", h)
+	}
+
+	// Deleting the autoscaler releases the lock on the DeploymentConfig
+	if err := c.Delete(ctx, u); err != nil {
+		t.Fatalf("delete autoscaler: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(dc), dc); err != nil {
+		t.Fatalf("get deploymentconfig: %v", err)
+	}
+	if _, ok := dc.GetAnnotations()[managedByAnnotation]; ok {
+		t.Fatalf("annotations = %v, want the lock released", dc.GetAnnotations())
+	}
+}
+
+func TestResourceQuotaClampsScaleUp(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 2.0) // 10 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(20),
+		"targetCPU":        0.2,
+		"stepLimit":        int64(20),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	dep := newDeployment("default", "web", 2)
+	dep.Spec.Template.Spec.Containers[0].Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}
+	// 2 cores allowed, 1 used: room for 4 more pods at 250m
+	quota := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2"), corev1.ResourcePods: resource.MustParse("50")},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("1"), corev1.ResourcePods: resource.MustParse("4")},
+		},
+	}
+	debug := NewDebugStore()
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Debug: debug}, dep, quota, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	limited := func() *metav1.Condition {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(autoscalerGVK)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatalf("get autoscaler: %v", err)
+		}
+		return meta.FindStatusCondition(getConditions(u), condLimited)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 6 {
+		t.Fatalf("replicas = %d, want 6 (2 + the 4 the quota admits)", got)
+	}
+	if cond := limited(); cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "QuotaExceeded" {
+		t.Fatalf("ScalingLimited = %+v, want True/QuotaExceeded", cond)
+	}
+
+	// Quota used up: hold rather than create pods that would be rejected
+	quota.Status.Used[corev1.ResourceRequestsCPU] = resource.MustParse("2")
+	if err := c.Update(ctx, quota); err != nil {
+		t.Fatalf("update quota: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 6 {
+		t.Fatalf("replicas = %d, want 6 held at the quota", got)
+	}
+	if snaps := debug.Snapshots(); len(snaps) != 1 || snaps[0].SkipReason != "QuotaExceeded" {
+		t.Fatalf("snapshot = %+v, want skip reason QuotaExceeded", snaps)
+	}
+}
+
+func TestSchedulesAndBlackoutWindows(t *testing.T) {
+	ctx := context.Background()
+	// Wednesday 14:30 UTC: 09:30 in New York, 15:30 in Berlin
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.4) // 2 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	spec := map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+		"cooldown":         "0s",
+		"stepLimit":        int64(10),
+		"schedules": []interface{}{map[string]interface{}{
+			"days": []interface{}{"Mon", "Tue", "Wed", "Thu", "Fri"}, "start": "09:00", "end": "17:00",
+			"timezone": "America/New_York", "minReplicas": int64(8),
+		}},
+		"blackoutWindows": []interface{}{map[string]interface{}{
+			"start": "23:00", "end": "00:30", "timezone": "Europe/Berlin",
+		}},
+	}
+	cr := newAutoscaler("default", "web", spec)
+	cr.SetFinalizers([]string{lockFinalizer})
+	debug := NewDebugStore()
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk, Debug: debug}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("business hours: replicas = %d, want the schedule's floor of 8", got)
+	}
+
+	// 17:30 in New York closes the floor, but it is 23:30 in Berlin: a blackout holds replicas
+	clk.SetTime(time.Date(2025, 1, 15, 22, 30, 0, 0, time.UTC))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 8 {
+		t.Fatalf("blackout: replicas = %d, want 8 held", got)
+	}
+	if snaps := debug.Snapshots(); len(snaps) == 0 || snaps[len(snaps)-1].SkipReason != "BlackoutWindow" {
+		t.Fatalf("snapshots = %+v, want skip reason BlackoutWindow", snaps)
+	}
+	clk.SetTime(time.Date(2025, 1, 15, 23, 45, 0, 0, time.UTC)) // 00:45 in Berlin
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("after the blackout: replicas = %d, want 2", got)
+	}
+
+	// An unknown zone is refused at admission
+	spec["blackoutWindows"] = []interface{}{map[string]interface{}{"start": "17:00", "end": "18:00", "timezone": "Europe/Atlantis"}}
+	v := &targetValidator{tc: targetCluster{Client: c, reader: c}}
+	raw, _ := json.Marshal(newAutoscaler("default", "web", spec).Object)
+	resp := v.Handle(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: raw},
+	}})
+	if resp.Allowed || !strings.Contains(resp.Result.Message, "spec.blackoutWindows[0].timezone") {
+		t.Fatalf("admission = allowed %v, %+v; want denied naming the timezone", resp.Allowed, resp.Result)
+	}
+}
+
+func TestTargetsBeyondPodLimits(t *testing.T) {
+	ctx := context.Background()
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.4)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	spec := map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        "1500m",
+		"targetMem":        "256Mi",
+	}
+	cr := newAutoscaler("default", "web", spec)
+	cr.SetFinalizers([]string{lockFinalizer})
+	// One container limited to 1 CPU, the other unset but defaulted to 250m by the LimitRange
+	dep := newDeployment("default", "web", 2)
+	dep.Spec.Template.Spec.Containers[0].Resources.Limits = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}
+	dep.Spec.Template.Spec.Containers = append(dep.Spec.Template.Spec.Containers, corev1.Container{Name: "sidecar", Image: "envoy"})
+	lr := &corev1.LimitRange{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "limits"},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type:    corev1.LimitTypeContainer,
+			Default: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+		}}},
+	}
+	r, c := newFakeReconciler(t, Options{InstanceName: "test"}, dep, lr, cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	feasible := func() *metav1.Condition {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(autoscalerGVK)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatalf("get autoscaler: %v", err)
+		}
+		return meta.FindStatusCondition(getConditions(u), condTargetsFeasible)
+	}
+
+	// 1.5 cores per replica, but a pod may use at most 1.25
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	cond := feasible()
+	if cond == nil || cond.Status != metav1.ConditionFalse || cond.Reason != "ExceedsPodLimits" || !strings.Contains(cond.Message, "1250m") {
+		t.Fatalf("TargetsFeasible = %+v, want False/ExceedsPodLimits naming 1250m", cond)
+	}
+
+	// The webhook admits the CR but warns the same way
+	v := &targetValidator{tc: targetCluster{Client: c, reader: c}}
+	raw, _ := json.Marshal(cr.Object)
+	resp := v.Handle(ctx, admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: raw},
+	}})
+	if !resp.Allowed || len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "targetCPU") {
+		t.Fatalf("admission = allowed %v, warnings %q; want allowed with a targetCPU warning", resp.Allowed, resp.Warnings)
+	}
+
+	// Lowering the target clears the condition
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	_ = unstructured.SetNestedField(u.Object, "1", "spec", "targetCPU")
+	if err := c.Update(ctx, u); err != nil {
+		t.Fatalf("update autoscaler: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if cond := feasible(); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Fatalf("TargetsFeasible = %+v, want True", cond)
+	}
+}
+
+func TestFlapDetectionWidensBand(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	prom := promtest.New(t)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "60s",
+		"minReplicas":      int64(1),
+		"targetCPU":        0.2,
+		"hysteresisPct":    15.0,
+		"flapDetection":    map[string]interface{}{"reversals": int64(3), "window": "30m", "duration": "10m"},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 4), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	step := func(cpu float64, want int32, msg string) {
+		t.Helper()
+		clk.SetTime(clk.Now().Add(2 * time.Minute))
+		prom.SetInstant("container_cpu_usage_seconds_total", cpu)
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != want {
+			t.Fatalf("%s: replicas = %d, want %d", msg, got, want)
+		}
+	}
+
+	// 4 -> 5 is outside the 15% band, but not the 30% one
+	step(1.0, 5, "up")
+	step(0.8, 4, "first reversal")
+	step(1.0, 5, "second reversal")
+	step(0.8, 4, "third reversal")
+	step(1.0, 4, "band widened")
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(autoscalerGVK)
+	if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+		t.Fatalf("get autoscaler: %v", err)
+	}
+	damping, _, _ := unstructured.NestedMap(u.Object, "status", "flapDamping")
+	if damping["reversals"] != int64(3) || damping["hysteresisPct"] != "30" || damping["cooldown"] != "2m0s" {
+		t.Fatalf("status.flapDamping = %v, want 3 reversals, 30%% and 2m0s", damping)
+	}
+
+	// The episode ends; the reversals it was detected on don't start another
+	clk.SetTime(clk.Now().Add(10 * time.Minute))
+	step(1.0, 5, "band restored")
+}
+
+func TestTuningRecommendations(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	// Two hours of 4.5 replicas' worth of CPU, ±10%; instant queries get the last sample
+	var history []float64
+	for i := 0; i < 6; i++ {
+		history = append(history, 0.9, 0.99, 0.81, 0.9)
+	}
+	prom.SetRange("container_cpu_usage_seconds_total", history...)
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"targetCPU":        0.2,
+		"hysteresisPct":    2.0,
+		"tuning":           map[string]interface{}{"window": "2h", "refresh": "1h"},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 5), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	recommendations := func() map[string]string {
+		t.Helper()
+		u := newAutoscaler("default", "web", nil)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatal(err)
+		}
+		m, _, _ := unstructured.NestedStringMap(u.Object, "status", "recommendations")
+		return m
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	got := recommendations()
+	want := map[string]string{"hysteresisPct": "10", "cooldown": "5m0s", "stepLimit": "1", "samples": "24",
+		"updated": clk.Now().Format(time.RFC3339)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("status.recommendations = %v, want %v", got, want)
+	}
+
+	// Not refitted before the refresh interval
+	prom.SetRange("container_cpu_usage_seconds_total", 0.9, 0.9)
+	clk.SetTime(clk.Now().Add(30 * time.Minute))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := recommendations(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("status.recommendations refitted early: %v", got)
+	}
+
+	// Past it, too short a history keeps only the sample count
+	clk.SetTime(clk.Now().Add(time.Hour))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := recommendations(); got["samples"] != "2" || got["hysteresisPct"] != "" {
+		t.Fatalf("status.recommendations = %v, want 2 samples and no suggestions", got)
+	}
+}
+
+func TestLearningModeSuggestsBounds(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"learning":         map[string]interface{}{"period": "1h", "minQuantile": 0.0, "maxQuantile": 1.0},
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	debug := NewDebugStore()
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk, Debug: debug}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	status := func() learning {
+		t.Helper()
+		u := newAutoscaler("default", "web", nil)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatal(err)
+		}
+		l, ok := readLearning(u)
+		if !ok {
+			t.Fatal("status.learning missing")
+		}
+		return l
+	}
+
+	// Demand for 5 replicas, but the period has just begun
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 2 {
+		t.Fatalf("while learning replicas = %d, want 2", got)
+	}
+	if snaps := debug.Snapshots(); len(snaps) != 1 || snaps[0].SkipReason != "Learning" {
+		t.Fatalf("debug snapshots = %+v, want one with skipReason Learning", snaps)
+	}
+	if l := status(); !l.Started.Equal(clk.Now()) || !l.Completed.IsZero() {
+		t.Fatalf("status.learning = %+v, want started now and not completed", l)
+	}
+
+	// An hour of demand between 1 and 7 replicas ends the period, and scaling starts
+	prom.SetRange("container_cpu_usage_seconds_total", 0.2, 0.6, 1.4, 1.0)
+	clk.SetTime(clk.Now().Add(time.Hour))
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	l := status()
+	if l.MinReplicas != 1 || l.MaxReplicas != 7 || l.Samples != 4 || !l.Completed.Equal(clk.Now()) {
+		t.Fatalf("status.learning = %+v, want 1-7 from 4 samples", l)
+	}
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("after learning replicas = %d, want 5", got)
+	}
+}
+
+func TestInitialDelayHoldsFirstScale(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"initialDelay":     "5m",
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	debug := NewDebugStore()
+	newController := func() (*reconciler, client.Client) {
+		return newFakeReconciler(t, Options{InstanceName: "test", Clock: clk, Debug: debug}, newDeployment("default", "web", 2), cr.DeepCopy())
+	}
+	r, c := newController()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	reconcile := func(want int32) {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if got := replicasOf(t, c, "default", "web"); got != want {
+			t.Fatalf("replicas = %d, want %d", got, want)
+		}
+	}
+
+	// Demand for 5 replicas, but the CR was only just seen
+	reconcile(2)
+	if snaps := debug.Snapshots(); len(snaps) != 1 || snaps[0].SkipReason != "InitialDelay" || snaps[0].CooldownRemaining != "5m0s" {
+		t.Fatalf("debug snapshots = %+v, want one held by InitialDelay with 5m0s left", snaps)
+	}
+	clk.SetTime(clk.Now().Add(4 * time.Minute))
+	reconcile(2)
+	clk.SetTime(clk.Now().Add(time.Minute))
+	reconcile(5)
+
+	// A restarted controller waits again before acting on its first sample
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.4)
+	r, c = newController()
+	reconcile(2)
+	clk.SetTime(clk.Now().Add(5 * time.Minute))
+	reconcile(7)
+}
+
+func TestDashboard(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "10m",
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	debug := NewDebugStore()
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk, Debug: debug}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		clk.SetTime(clk.Now().Add(time.Minute))
+	}
+	if h := debug.History("default/web"); len(h) != 2 {
+		t.Fatalf("history has %d snapshots, want 2", len(h))
+	}
+
+	rec := httptest.NewRecorder()
+	NewDashboard(c, debug).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dashboard returned %d: %s", rec.Code, body)
+	}
+	for _, want := range []string{"default/web", "scaled to 5", "WithinHysteresis", `class="demand" points="0.0,`, condSaturated} {
+		if !strings.Contains(body, want) {
+			t.Errorf("dashboard lacks %q:\n%s", want, body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	NewDashboard(c, debug).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown path returned %d, want 404", rec.Code)
+	}
+}
+
+func TestExportRecommendations(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 3.0) // 15 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+		"maxReplicas":      int64(10),
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	r, c := newFakeReconciler(t, Options{InstanceName: "test", Clock: clk}, newDeployment("default", "web", 2), cr)
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	reconcileAt := func(cores float64, at time.Time) {
+		t.Helper()
+		prom.SetInstant("container_cpu_usage_seconds_total", cores)
+		clk.SetTime(at)
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+	}
+	peak := func() *PeakDemand {
+		t.Helper()
+		recs, err := ExportRecommendations(ctx, c, "default")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recs) != 1 {
+			t.Fatalf("exported %d recommendations, want 1", len(recs))
+		}
+		return recs[0].PeakDemand
+	}
+
+	// Demand for 15 replicas is capped at 10, but the peak keeps the 15
+	start := clk.Now()
+	reconcileAt(3.0, start)
+	recs, err := ExportRecommendations(ctx, c, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := recs[0]
+	if rec.Autoscaler != "default/web" || rec.Target != "web" || rec.Replicas != 7 ||
+		rec.Config.MaxReplicas != 10 || rec.Config.TargetCPU != 0.2 || rec.Config.Cooldown != "0s" {
+		t.Fatalf("recommendation = %+v", rec)
+	}
+	if p := rec.PeakDemand; p == nil || p.Replicas != 15 || p.CPUCores != 3 || !p.Time.Equal(start) {
+		t.Fatalf("peak demand = %+v, want 15 replicas at 3 cores", p)
+	}
+
+	// A lower demand leaves the peak alone until it is a week old
+	reconcileAt(1.0, start.Add(time.Hour))
+	if p := peak(); p.Replicas != 15 {
+		t.Fatalf("peak demand = %+v, want 15 still", p)
+	}
+	reconcileAt(1.0, start.Add(peakWindow))
+	if p := peak(); p.Replicas != 5 || !p.Time.Equal(start.Add(peakWindow)) {
+		t.Fatalf("peak demand = %+v, want 5 once the old peak expired", p)
+	}
+}
+
+func TestActuationBackoff(t *testing.T) {
+	ctx := context.Background()
+	clk := clocktesting.NewFakePassiveClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.0) // 5 replicas at 0.2 cores each
+	prom.SetInstant("container_memory_working_set_bytes", 0)
+
+	cr := newAutoscaler("default", "web", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          prom.URL,
+		"cooldown":         "0s",
+		"targetCPU":        0.2,
+	})
+	cr.SetFinalizers([]string{lockFinalizer})
+	// An admission webhook denies every change of the Deployment until fixed
+	denied, attempts := true, 0
+	c := fake.NewClientBuilder().
+		WithScheme(fakeScheme()).
+		WithObjects(newDeployment("default", "web", 2), cr).
+		WithStatusSubresource(cr).
+		WithIndex(cr, targetIndexKey, targetIndexValues).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if _, ok := obj.(*appsv1.Deployment); ok {
+					attempts++
+					if denied {
+						return apierrors.NewForbidden(appsv1.Resource("deployments"), "web", errors.New("denied by policy"))
+					}
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	r := newReconciler(c, c, Options{InstanceName: "test", Clock: clk})
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}}
+	reconcile := func(after time.Duration, wantAttempts int, wantRequeue time.Duration) *unstructured.Unstructured {
+		t.Helper()
+		clk.SetTime(clk.Now().Add(after))
+		res, err := r.Reconcile(ctx, req)
+		if err != nil {
+			t.Fatalf("reconcile: %v", err)
+		}
+		if attempts != wantAttempts || res.RequeueAfter != wantRequeue {
+			t.Fatalf("attempts = %d, requeue = %s; want %d, %s", attempts, res.RequeueAfter, wantAttempts, wantRequeue)
+		}
+		u := newAutoscaler("default", "web", nil)
+		if err := c.Get(ctx, req.NamespacedName, u); err != nil {
+			t.Fatalf("get autoscaler: %v", err)
+		}
+		return u
+	}
+	degraded := func(u *unstructured.Unstructured) *metav1.Condition {
+		return meta.FindStatusCondition(getConditions(u), condDegradedActuation)
+	}
+
+	// One failure is retried next poll, without a condition
+	if u := reconcile(0, 1, 15*time.Second); degraded(u) != nil {
+		t.Fatalf("DegradedActuation after one failure: %+v", degraded(u))
+	}
+	u := reconcile(15*time.Second, 2, 30*time.Second)
+	if c := degraded(u); c == nil || c.Status != metav1.ConditionTrue || c.Reason != "Forbidden" {
+		t.Fatalf("DegradedActuation = %+v, want True/Forbidden", c)
+	}
+	// The poll in between leaves the target alone
+	reconcile(15*time.Second, 2, 15*time.Second)
+	u = reconcile(15*time.Second, 3, time.Minute)
+	if failures, retryAt := actuationBackoff(u); failures != 3 || !retryAt.Equal(clk.Now().Add(time.Minute)) {
+		t.Fatalf("status.actuationBackoff = %d failures, retry at %s; want 3, a minute from now", failures, retryAt)
+	}
+
+	denied = false
+	reconcile(59*time.Second, 3, time.Second)
+	u = reconcile(time.Second, 4, 15*time.Second)
+	if got := replicasOf(t, c, "default", "web"); got != 5 {
+		t.Fatalf("replicas = %d, want 5", got)
+	}
+	if c := degraded(u); c == nil || c.Status != metav1.ConditionFalse {
+		t.Fatalf("DegradedActuation = %+v, want False", c)
+	}
+	if _, ok, _ := unstructured.NestedMap(u.Object, "status", "actuationBackoff"); ok {
+		t.Fatalf("status.actuationBackoff kept after a successful update")
+	}
+}
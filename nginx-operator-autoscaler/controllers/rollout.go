@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// revisionAnnotation is maintained by the Deployment controller and bumped on every rollout.
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// observeRollout compares the Deployment's revision with the one recorded in
+// status and returns when the current revision was first seen. A revision
+// change stamps status.rolloutTime with now; the first revision an autoscaler
+// ever sees is not treated as a rollout. changed reports a status edit.
+func observeRollout(u *unstructured.Unstructured, dep *appsv1.Deployment, now time.Time) (rolloutAt time.Time, changed bool) {
+	rev := dep.Annotations[revisionAnnotation]
+	recorded, found, _ := unstructured.NestedString(u.Object, "status", "observedRevision")
+	if rev != "" && rev != recorded {
+		_ = unstructured.SetNestedField(u.Object, rev, "status", "observedRevision")
+		if found {
+			_ = unstructured.SetNestedField(u.Object, now.Format(time.RFC3339), "status", "rolloutTime")
+		}
+		changed = true
+	}
+
+	if s, _, _ := unstructured.NestedString(u.Object, "status", "rolloutTime"); s != "" {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			rolloutAt = t
+		}
+	}
+	return rolloutAt, changed
+}
+
+// templateHash is the pod-template-hash of dep's current revision: the label
+// its ReplicaSet for that revision (and every pod of it) carries. It is ""
+// while that ReplicaSet doesn't exist yet.
+func (tc targetCluster) templateHash(ctx context.Context, dep *appsv1.Deployment) (string, error) {
+	sets, err := tc.replicaSets(ctx, dep)
+	if err != nil {
+		return "", err
+	}
+	rev := dep.Annotations[revisionAnnotation]
+	for _, rs := range sets {
+		if rs.Annotations[revisionAnnotation] == rev {
+			return rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey], nil
+		}
+	}
+	return "", nil
+}
+
+// replicaSets lists the ReplicaSets dep controls, of every revision.
+func (tc targetCluster) replicaSets(ctx context.Context, dep *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	sel, err := metav1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	var sets appsv1.ReplicaSetList
+	if err := tc.reader.List(ctx, &sets, client.InNamespace(dep.Namespace), client.MatchingLabelsSelector{Selector: sel}); err != nil {
+		return nil, err
+	}
+	var owned []appsv1.ReplicaSet
+	for _, rs := range sets.Items {
+		if metav1.IsControlledBy(&rs, dep) {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// maxScaleHistory bounds status.scaleHistory.
+const maxScaleHistory = 10
+
+// recordScale prepends a scale of the target, with the revision and template
+// hash it ran when it was scaled and the decision that scaled it, to
+// status.scaleHistory, so replica growth can be traced back to the deploy
+// that brought it.
+func recordScale(u *unstructured.Unstructured, from, to int32, revision, hash, decisionID string, now time.Time) {
+	history, _, _ := unstructured.NestedSlice(u.Object, "status", "scaleHistory")
+	entry := map[string]interface{}{
+		"time":         now.Format(time.RFC3339),
+		"from":         int64(from),
+		"to":           int64(to),
+		"revision":     revision,
+		"templateHash": hash,
+		"decisionID":   decisionID,
+	}
+	history = append([]interface{}{entry}, history...)
+	if len(history) > maxScaleHistory {
+		history = history[:maxScaleHistory]
+	}
+	_ = unstructured.SetNestedSlice(u.Object, history, "status", "scaleHistory")
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+)
+
+// sampleStreak reads status.samples: the direction the last polls wanted to
+// scale in and how many in a row did. Every status patch and target event
+// reconciles again between polls, so a reconcile less than interval after
+// the last counted sample gets one fewer back: it re-evaluates that sample
+// rather than adding another.
+func sampleStreak(u *unstructured.Unstructured, now time.Time, interval time.Duration) (string, int32) {
+	dir, _, _ := unstructured.NestedString(u.Object, "status", "samples", "direction")
+	n, _, _ := unstructured.NestedInt64(u.Object, "status", "samples", "count")
+	at, _, _ := unstructured.NestedString(u.Object, "status", "samples", "at")
+	if t, err := time.Parse(time.RFC3339, at); err == nil && n > 0 && now.Sub(t) < interval {
+		n--
+	}
+	return dir, int32(n)
+}
+
+// recordSampleStreak stores d's streak in status.samples, reporting whether
+// it changed. A poll inside the band clears it.
+func recordSampleStreak(u *unstructured.Unstructured, d decision.Result, now time.Time) bool {
+	dir, _, _ := unstructured.NestedString(u.Object, "status", "samples", "direction")
+	n, _, _ := unstructured.NestedInt64(u.Object, "status", "samples", "count")
+	if dir == d.Direction && int32(n) == d.Samples {
+		return false
+	}
+	if d.Direction == "" {
+		unstructured.RemoveNestedField(u.Object, "status", "samples")
+		return true
+	}
+	_ = unstructured.SetNestedField(u.Object, map[string]interface{}{
+		"direction": d.Direction,
+		"count":     int64(d.Samples),
+		"at":        now.UTC().Format(time.RFC3339),
+	}, "status", "samples")
+	return true
+}
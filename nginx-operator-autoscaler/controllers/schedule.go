@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// window is a weekly recurring span of local time in its own IANA zone, so
+// a region's business hours follow that region's clock across DST.
+type window struct {
+	Days       []time.Weekday // empty means every day
+	Start, End time.Duration  // since local midnight; End <= Start runs past midnight
+	Location   *time.Location
+}
+
+// scheduleRef is one spec.schedules entry: MinReplicas is the floor while
+// the window is open.
+type scheduleRef struct {
+	window
+	MinReplicas int32
+}
+
+// contains reports whether now falls in w. An overnight window belongs to
+// the day it opens on.
+func (w window) contains(now time.Time) bool {
+	local := now.In(w.Location)
+	at := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute + time.Duration(local.Second())*time.Second
+	if w.Start < w.End {
+		return w.on(local.Weekday()) && at >= w.Start && at < w.End
+	}
+	return (w.on(local.Weekday()) && at >= w.Start) || (w.on((local.Weekday()+6)%7) && at < w.End)
+}
+
+func (w window) on(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduledMin is minReplicas raised to the floor of every open schedule,
+// within maxReplicas.
+func scheduledMin(s autoscalerSpec, now time.Time) int32 {
+	floor := s.MinReplicas
+	for _, sch := range s.Schedules {
+		if sch.contains(now) && sch.MinReplicas > floor {
+			floor = sch.MinReplicas
+		}
+	}
+	return min(floor, max(s.MaxReplicas, s.MinReplicas))
+}
+
+// inBlackout reports whether now falls in any of spec.blackoutWindows.
+func inBlackout(s autoscalerSpec, now time.Time) bool {
+	for _, w := range s.BlackoutWindows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWindow reads days, start, end and timezone of the entry at field
+// (e.g. "spec.schedules[0]"), naming the first thing wrong with it.
+func parseWindow(field string, m map[string]interface{}) (window, string) {
+	w := window{Location: time.UTC}
+	if tz, _ := m["timezone"].(string); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return w, fmt.Sprintf("%s.timezone: %v", field, err)
+		}
+		w.Location = loc
+	}
+	days, _ := m["days"].([]interface{})
+	for _, d := range days {
+		name, _ := d.(string)
+		day, ok := weekdays[strings.ToLower(name)[:min(3, len(name))]]
+		if !ok {
+			return w, fmt.Sprintf("%s.days: unknown day %q", field, name)
+		}
+		w.Days = append(w.Days, day)
+	}
+	for _, f := range []struct {
+		name string
+		into *time.Duration
+	}{{"start", &w.Start}, {"end", &w.End}} {
+		v, _ := m[f.name].(string)
+		var hh, mm int
+		if n, err := fmt.Sscanf(v, "%d:%d", &hh, &mm); err != nil || n != 2 || hh < 0 || hh > 24 || mm < 0 || mm > 59 || (hh == 24 && mm != 0) {
+			return w, fmt.Sprintf("%s.%s: %q is not HH:MM", field, f.name, v)
+		}
+		*f.into = time.Duration(hh)*time.Hour + time.Duration(mm)*time.Minute
+	}
+	if w.Start == w.End || w.Start == 24*time.Hour {
+		return w, fmt.Sprintf("%s: start and end must differ, and start come before 24:00", field)
+	}
+	return w, ""
+}
+
+// parseSchedules reads spec.schedules and spec.blackoutWindows. invalid names
+// the first bad entry; the controller holds replicas until it is fixed rather
+// than run with a floor or blackout silently dropped.
+func parseSchedules(spec map[string]interface{}) (schedules []scheduleRef, blackouts []window, invalid string) {
+	entries, _ := spec["schedules"].([]interface{})
+	for i, e := range entries {
+		m, _ := e.(map[string]interface{})
+		field := fmt.Sprintf("spec.schedules[%d]", i)
+		w, problem := parseWindow(field, m)
+		if problem != "" {
+			return nil, nil, problem
+		}
+		sch := scheduleRef{window: w}
+		switch v := m["minReplicas"].(type) {
+		case int64:
+			sch.MinReplicas = int32(v)
+		case float64:
+			sch.MinReplicas = int32(v)
+		}
+		schedules = append(schedules, sch)
+	}
+	entries, _ = spec["blackoutWindows"].([]interface{})
+	for i, e := range entries {
+		m, _ := e.(map[string]interface{})
+		w, problem := parseWindow(fmt.Sprintf("spec.blackoutWindows[%d]", i), m)
+		if problem != "" {
+			return nil, nil, problem
+		}
+		blackouts = append(blackouts, w)
+	}
+	return schedules, blackouts, ""
+}
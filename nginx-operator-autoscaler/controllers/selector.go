@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// selectorParentAnnotation on a generated NginxAutoscaler names the
+// spec.targetSelector CR it was generated from, in the same namespace. It is
+// for people; the controller owner reference is what the controller trusts.
+const selectorParentAnnotation = "autoscaler.malisetti.dev/selector-parent"
+
+// selectorChildName is <parent>-<dep>, cut short to fit, plus a hash of both
+// names so that e.g. "a-b"+"c" and "a"+"b-c" don't collide.
+func selectorChildName(parent, dep string) string {
+	sum := sha256.Sum256([]byte(parent + "/" + dep))
+	suffix := "-" + hex.EncodeToString(sum[:5])
+	name := parent + "-" + dep
+	if limit := 253 - len(suffix); len(name) > limit {
+		name = strings.TrimRight(name[:limit], "-.")
+	}
+	return name + suffix
+}
+
+// generatedBy reports whether child is controlled by parent.
+func generatedBy(child, parent metav1.Object) bool {
+	ref := metav1.GetControllerOf(child)
+	return ref != nil && ref.UID == parent.GetUID()
+}
+
+// selectorChild is the NginxAutoscaler parent generates for dep: parent's
+// spec with the selector swapped for dep's name, owned by parent so it goes
+// when parent does.
+func selectorChild(parent *unstructured.Unstructured, dep *appsv1.Deployment) *unstructured.Unstructured {
+	spec, _, _ := unstructured.NestedMap(parent.Object, "spec")
+	delete(spec, "targetSelector")
+	delete(spec, "targetDeployment")
+	targetRef, _ := spec["targetRef"].(map[string]interface{})
+	if targetRef == nil {
+		targetRef = map[string]interface{}{}
+	}
+	targetRef["name"] = dep.Name
+	spec["targetRef"] = targetRef
+
+	child := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	child.SetGroupVersionKind(autoscalerGVK)
+	child.SetNamespace(parent.GetNamespace())
+	child.SetName(selectorChildName(parent.GetName(), dep.Name))
+	child.SetAnnotations(map[string]string{selectorParentAnnotation: parent.GetName()})
+	controller := true
+	child.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: autoscalerGVK.GroupVersion().String(),
+		Kind:       autoscalerGVK.Kind,
+		Name:       parent.GetName(),
+		UID:        parent.GetUID(),
+		Controller: &controller,
+	}})
+	return child
+}
+
+// reconcileSelector keeps one generated NginxAutoscaler per Deployment
+// matching spec.targetSelector, each scaled on its own, and deletes those
+// whose Deployment no longer matches. A Deployment another CR already
+// targets is left to that CR.
+func (r *reconciler) reconcileSelector(ctx context.Context, u *unstructured.Unstructured, s autoscalerSpec, targetNS string, snap *DebugSnapshot) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("nginxautoscaler", client.ObjectKeyFromObject(u))
+	var ls metav1.LabelSelector
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(s.TargetSelector, &ls); err != nil {
+		return r.selectorFailed(ctx, u, snap, s, fmt.Errorf("spec.targetSelector: %w", err))
+	}
+	sel, err := metav1.LabelSelectorAsSelector(&ls)
+	if err != nil {
+		return r.selectorFailed(ctx, u, snap, s, fmt.Errorf("spec.targetSelector: %w", err))
+	}
+	snap.Target = targetNS + "/" + sel.String()
+
+	tc, err := r.targetCluster(ctx, u.GetNamespace(), s.KubeconfigSecret, s.KubeconfigKey)
+	if err != nil {
+		return r.selectorFailed(ctx, u, snap, s, err)
+	}
+	var deps appsv1.DeploymentList
+	if err := tc.List(ctx, &deps, client.InNamespace(targetNS), client.MatchingLabelsSelector{Selector: sel}); err != nil {
+		return r.selectorFailed(ctx, u, snap, s, err)
+	}
+	children := &unstructured.UnstructuredList{}
+	children.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	if err := r.List(ctx, children, client.InNamespace(u.GetNamespace())); err != nil {
+		return r.selectorFailed(ctx, u, snap, s, err)
+	}
+	existing := map[string]*unstructured.Unstructured{}
+	for i := range children.Items {
+		if generatedBy(&children.Items[i], u) {
+			existing[children.Items[i].GetName()] = &children.Items[i]
+		}
+	}
+
+	var selected []string
+	for i := range deps.Items {
+		dep := &deps.Items[i]
+		if _, balloon := dep.Labels[balloonLabel]; balloon {
+			continue
+		}
+		want := selectorChild(u, dep)
+		claimed, err := r.claimedElsewhere(ctx, u, want)
+		if err != nil {
+			return r.selectorFailed(ctx, u, snap, s, err)
+		}
+		if claimed {
+			continue
+		}
+		selected = append(selected, dep.Name)
+		cur, ok := existing[want.GetName()]
+		delete(existing, want.GetName())
+		switch {
+		case !ok:
+			if err := r.Create(ctx, want); apierrors.IsAlreadyExists(err) {
+				// Ours if the cache hadn't caught up with our last Create
+				other := &unstructured.Unstructured{}
+				other.SetGroupVersionKind(autoscalerGVK)
+				if err := r.Get(ctx, client.ObjectKeyFromObject(want), other); apierrors.IsNotFound(err) {
+					continue
+				} else if err != nil {
+					return r.selectorFailed(ctx, u, snap, s, err)
+				}
+				if !generatedBy(other, u) {
+					return r.selectorFailed(ctx, u, snap, s, fmt.Errorf("NginxAutoscaler %s already exists and was not generated by this CR", want.GetName()))
+				}
+				continue
+			} else if err != nil {
+				return r.selectorFailed(ctx, u, snap, s, err)
+			}
+			logger.Info("autoscaling selected Deployment", "deployment", dep.Name, "autoscaler", want.GetName())
+		case !equality.Semantic.DeepEqual(cur.Object["spec"], want.Object["spec"]):
+			cur.Object["spec"] = want.Object["spec"]
+			if err := r.Update(ctx, cur); err != nil {
+				return r.selectorFailed(ctx, u, snap, s, err)
+			}
+		}
+	}
+	for _, stale := range existing {
+		if err := r.Delete(ctx, stale); client.IgnoreNotFound(err) != nil {
+			return r.selectorFailed(ctx, u, snap, s, err)
+		}
+		logger.Info("Deployment no longer selected", "autoscaler", stale.GetName())
+	}
+
+	sort.Strings(selected)
+	snap.SkipReason = "TargetSelector"
+	prev, _, _ := unstructured.NestedStringSlice(u.Object, "status", "selectedTargets")
+	changed := strings.Join(prev, ",") != strings.Join(selected, ",")
+	if changed {
+		_ = unstructured.SetNestedStringSlice(u.Object, selected, "status", "selectedTargets")
+	}
+	msg := fmt.Sprintf("%d Deployment(s) selected", len(selected))
+	if setCondition(u, condTargetAdopted, metav1.ConditionTrue, "Selected", msg) || changed {
+		if err := r.patchStatus(ctx, u); err != nil {
+			logger.Error(err, "failed to update status (will retry later)")
+		}
+	}
+	return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+}
+
+// claimedElsewhere reports whether a CR other than one parent generated
+// already targets the child's Deployment.
+func (r *reconciler) claimedElsewhere(ctx context.Context, parent, child *unstructured.Unstructured) (bool, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	if err := r.List(ctx, list, client.MatchingFields{targetIndexKey: targetKeyOf(child)}); err != nil {
+		return false, err
+	}
+	for i := range list.Items {
+		if list.Items[i].GetNamespace() != child.GetNamespace() || !generatedBy(&list.Items[i], parent) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *reconciler) selectorFailed(ctx context.Context, u *unstructured.Unstructured, snap *DebugSnapshot, s autoscalerSpec, err error) (ctrl.Result, error) {
+	log.FromContext(ctx).Error(err, "failed to resolve spec.targetSelector", "nginxautoscaler", client.ObjectKeyFromObject(u))
+	snap.Error = err.Error()
+	if setCondition(u, condTargetAdopted, metav1.ConditionFalse, "SelectorFailed", err.Error()) {
+		if err := r.patchStatus(ctx, u); err != nil {
+			log.FromContext(ctx).Error(err, "failed to update status (will retry later)")
+		}
+	}
+	return ctrl.Result{RequeueAfter: s.PollInterval}, nil
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+)
+
+// silenceRef is spec.alertSilence: for Duration after each scale, silence the
+// target's alerts in Alertmanager, only those named in AlertNames if any.
+type silenceRef struct {
+	Duration   time.Duration
+	AlertNames []string
+}
+
+// silenceEvent is scaled with what to silence added to its data, for the
+// Alertmanager silencer: pods is a regex of the target's pod names, "" to
+// leave pod-level alerts alone.
+func silenceEvent(scaled events.Event, ref silenceRef, pods string) events.Event {
+	data := map[string]interface{}{"duration": ref.Duration.String(), "alertNames": ref.AlertNames, "pods": pods}
+	for k, v := range scaled.Data.(map[string]interface{}) {
+		data[k] = v
+	}
+	scaled.Data = data
+	return scaled
+}
+
+// podPattern is a regex matching the names of dep's pods, and no one else's:
+// <deployment>-<pod-template-hash>-<suffix> for the hash of each ReplicaSet
+// dep controls. A bare <deployment>-.* would also match a sibling such as
+// web-canary. It is "" when dep has no ReplicaSets (e.g. a DeploymentConfig,
+// whose pods belong to ReplicationControllers).
+func (tc targetCluster) podPattern(ctx context.Context, dep *appsv1.Deployment) (string, error) {
+	sets, err := tc.replicaSets(ctx, dep)
+	if err != nil {
+		return "", err
+	}
+	var hashes []string
+	for _, rs := range sets {
+		if h := rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; h != "" {
+			hashes = append(hashes, regexp.QuoteMeta(h))
+		}
+	}
+	if len(hashes) == 0 {
+		return "", nil
+	}
+	sort.Strings(hashes)
+	return regexp.QuoteMeta(dep.Name) + "-(" + strings.Join(hashes, "|") + ")-[a-z0-9]+", nil
+}
@@ -0,0 +1,18 @@
+package controllers
+
+import "fmt"
+
+// sloRef is an SLO expressed as a pair of event counters, e.g.
+// good: http_requests_total{code!~"5.."} and total: http_requests_total.
+type sloRef struct {
+	Good      string  // counter selector of good events
+	Total     string  // counter selector of all events
+	Objective float64 // e.g. 0.999
+	Window    string  // short burn-rate window, PromQL duration
+}
+
+// sloQueries returns the good and total event-rate queries over the burn window.
+func sloQueries(ref sloRef) (good, total string) {
+	return fmt.Sprintf(`sum(rate(%s[%s]))`, ref.Good, ref.Window),
+		fmt.Sprintf(`sum(rate(%s[%s]))`, ref.Total, ref.Window)
+}
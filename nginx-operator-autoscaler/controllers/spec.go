@@ -0,0 +1,864 @@
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+)
+
+// autoscalerSpec is the parsed, defaulted view of a NginxAutoscaler spec.
+type autoscalerSpec struct {
+	TargetDeployment string
+	TargetSelector   map[string]interface{} // a LabelSelector; set, it replaces TargetDeployment
+	TargetNamespace  string                 // empty means the CR's own namespace
+	TargetKind       targetKind             // what TargetDeployment names; a Deployment by default
+	KubeconfigSecret string                 // Secret in the CR's namespace holding a remote cluster's kubeconfig
+	KubeconfigKey    string
+	Clusters         []clusterMember // multi-cluster service; replaces the single target
+	PromURL          string
+	PollInterval     time.Duration
+	Cooldown         time.Duration
+	ScaleDownDelay   time.Duration // no scale-down this long after a rollout
+	WarmUp           time.Duration // pods younger than this are left out of usage
+	ConfirmDelay     time.Duration // a change waits this long as pending, then is re-checked
+	InitialDelay     time.Duration // no actuation this long after the controller first sees the CR
+	MinReplicas      int32
+	MaxReplicas      int32
+	TargetCPU        float64           // cores per replica
+	TargetMem        float64           // MiB per replica
+	TargetRPS        float64           // requests/s per replica, with Ingress or Istio set
+	TargetLatencyMs  float64           // with Istio set
+	ErrorQuery       string            // PromQL error ratio; empty derives it from Ingress or Istio
+	MaxErrorRatio    float64           // block scale-down above this ratio; zero disables
+	PrimaryMetric    string            // the signal that sets the target, the others guarding it; empty: max of all
+	SpotFactor       float64           // resilience factor for pods on spot nodes; zero disables
+	SpotNodeLabels   map[string]string // extra labels marking spot nodes
+	ZoneBalanced     bool              // round replicas to a multiple of the zones in use
+	MultipleOf       int32             // replicas must be a multiple of this
+	Parity           string            // "odd" or "even" from allowedReplicaCounts
+	AllowedReplicas  []int32           // explicit list from allowedReplicaCounts
+	MaxHourlyCost    float64           // cost ceiling per hour; zero disables
+	OpenCostURL      string            // empty means the controller's --opencost-url
+	SLO              *sloRef
+	Derivative       *derivativeRef // scale ahead of a steep CPU ramp
+	Percentile       *percentileRef // size on a quantile of usage over a window
+	Ingress          *ingressRef
+	Istio            *istioRef
+	RateWindows      rateWindows
+	MetricNames      metricNameOverrides // over Options.MetricNames
+	DecisionWebhook  *webhookRef         // reviews every scale before it is applied
+	Approval         *approvalRef        // large changes wait for a human
+	GitOps           *gitopsRef          // commit replicas to Git instead of writing the Deployment
+	HysteresisPct    float64
+	Rounding         string // decision.Round*; empty rounds up
+	MinChange        int32  // changes smaller than this are held above MinChangeAbove
+	MinChangeAbove   int32
+	StepLimit        int32
+	BudgetReplicas   int32 // replica changes allowed per BudgetWindow; zero disables
+	BudgetWindow     time.Duration
+	Flap             *flapRef        // widen the band and cooldown while the target flaps
+	Silence          *silenceRef     // silence the target's alerts while a scale settles
+	RequiredSamples  int32           // consecutive polls that must agree before scaling
+	HeadroomPct      float64         // spare capacity on top of measured demand
+	HeadroomReplicas int32           // fixed idle replicas on top of that
+	ForceAdopt       bool            // take over a Deployment claimed by someone else
+	DeletionCost     bool            // steer scale-down to the least-loaded pods
+	Drain            *drainRef       // scale down one drained pod at a time
+	PodMetric        *podMetricRef   // demand scraped from a JSON endpoint on each pod
+	SQL              *sqlRef         // demand counted by a database query
+	PubSub           *pubsubRef      // demand from a Pub/Sub subscription's backlog
+	NATS             *natsRef        // demand from a JetStream consumer's pending messages
+	Endpoints        *endpointsRef   // demand from another Service's ready endpoints
+	Calibration      *calibrationRef // learn targetCPU/targetMem from usage history
+	Tuning           *tuningRef      // suggest hysteresisPct, cooldown and stepLimit from usage history
+	Learning         *learningRef    // watch demand without scaling, then suggest min/max replicas
+	Anomaly          *anomalyRef     // sit out cycles whose samples are glitches
+	Vertical         *verticalRef    // grow pods when capped at maxReplicas
+	InPlace          *inPlaceRef     // resize running pods before changing replicas
+	Overprovision    *overprovisionRef
+	Schedules        []scheduleRef   // minReplicas floors for recurring local-time windows
+	BlackoutWindows  []window        // no scaling while one is open
+	ScheduleInvalid  string          // names the first bad schedule or window; nothing scales until fixed
+	Shadow           *autoscalerSpec // decided alongside for comparison, never applied
+}
+
+// parseSpec reads the raw spec map, falling back to built-in defaults for
+// anything missing or malformed.
+func parseSpec(spec map[string]interface{}) autoscalerSpec {
+	getStr := func(key, def string) string {
+		if v, ok := spec[key].(string); ok && v != "" {
+			return v
+		}
+		return def
+	}
+	getF64 := func(key string, def float64) float64 {
+		if v, ok := spec[key].(float64); ok {
+			return v
+		}
+		if v, ok := spec[key].(int64); ok {
+			return float64(v)
+		}
+		return def
+	}
+	getI32 := func(key string, def int32) int32 {
+		if v, ok := spec[key].(int64); ok {
+			return int32(v)
+		}
+		if v, ok := spec[key].(float64); ok {
+			return int32(v)
+		}
+		return def
+	}
+	// getQty accepts a resource quantity string ("200m", "300Mi") or, for
+	// older specs, a bare number already in the target unit. unit is the size
+	// of one target unit in the quantity's base unit (1 for cores, 1Mi for bytes).
+	getQty := func(key string, unit, def float64) float64 {
+		if v, ok := spec[key].(string); ok {
+			q, err := resource.ParseQuantity(v)
+			if err != nil {
+				return def
+			}
+			return q.AsApproximateFloat64() / unit
+		}
+		return getF64(key, def)
+	}
+	getBool := func(key string, def bool) bool {
+		if v, ok := spec[key].(bool); ok {
+			return v
+		}
+		return def
+	}
+
+	var ingress *ingressRef
+	if m, ok := spec["ingress"].(map[string]interface{}); ok {
+		ingress = &ingressRef{}
+		ingress.Name, _ = m["name"].(string)
+		ingress.Namespace, _ = m["namespace"].(string)
+		ingress.Host, _ = m["host"].(string)
+		ingress.Path, _ = m["path"].(string)
+	}
+
+	var istio *istioRef
+	if m, ok := spec["istio"].(map[string]interface{}); ok {
+		istio = &istioRef{Reporter: "destination", LatencyQuantile: 0.95}
+		if v, ok := m["reporter"].(string); ok && v != "" {
+			istio.Reporter = v
+		}
+		istio.Workload, _ = m["workload"].(string)
+		istio.Namespace, _ = m["namespace"].(string)
+		if v, ok := m["latencyQuantile"].(float64); ok && v > 0 && v < 1 {
+			istio.LatencyQuantile = v
+		}
+	}
+
+	errorGuard, _ := spec["errorGuard"].(map[string]interface{})
+	errorQuery, _ := errorGuard["query"].(string)
+	maxErrorRatio, _ := errorGuard["maxRatio"].(float64)
+
+	var clusters []clusterMember
+	if list, ok := spec["clusters"].([]interface{}); ok {
+		for _, item := range list {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			c := clusterMember{Weight: 1, KubeconfigKey: "kubeconfig"}
+			c.Name, _ = m["name"].(string)
+			c.PromURL, _ = m["promURL"].(string)
+			if ref, ok := m["kubeconfigSecretRef"].(map[string]interface{}); ok {
+				c.KubeconfigSecret, _ = ref["name"].(string)
+				if k, ok := ref["key"].(string); ok && k != "" {
+					c.KubeconfigKey = k
+				}
+			}
+			switch w := m["weight"].(type) {
+			case int64:
+				c.Weight = float64(w)
+			case float64:
+				c.Weight = w
+			}
+			clusters = append(clusters, c)
+		}
+	}
+
+	var slo *sloRef
+	if m, ok := spec["slo"].(map[string]interface{}); ok {
+		slo = &sloRef{Window: "5m"}
+		slo.Good, _ = m["good"].(string)
+		slo.Total, _ = m["total"].(string)
+		slo.Objective, _ = m["objective"].(float64)
+		if v, ok := m["window"].(string); ok && v != "" {
+			slo.Window = v
+		}
+		if slo.Good == "" || slo.Total == "" || slo.Objective <= 0 || slo.Objective >= 1 {
+			slo = nil // incomplete SLOs are ignored rather than guessed at
+		}
+	}
+
+	var derivative *derivativeRef
+	if m, ok := spec["derivative"].(map[string]interface{}); ok {
+		derivative = &derivativeRef{Lookahead: 3 * time.Minute, Window: "5m"}
+		switch v := m["cpuPerMinute"].(type) {
+		case string:
+			if q, err := resource.ParseQuantity(v); err == nil {
+				derivative.CPUPerMinute = q.AsApproximateFloat64()
+			}
+		case int64:
+			derivative.CPUPerMinute = float64(v)
+		case float64:
+			derivative.CPUPerMinute = v
+		}
+		if v, ok := m["lookahead"].(string); ok {
+			derivative.Lookahead = parseDur(v, derivative.Lookahead)
+		}
+		if v, ok := m["window"].(string); ok && v != "" {
+			derivative.Window = v
+		}
+		if derivative.CPUPerMinute <= 0 {
+			derivative = nil
+		}
+	}
+
+	var calib *calibrationRef
+	if m, ok := spec["calibration"].(map[string]interface{}); ok {
+		calib = &calibrationRef{Window: 7 * 24 * time.Hour, Step: 5 * time.Minute,
+			Utilization: 1, Refresh: time.Hour, MinSamples: 288}
+		if v, ok := m["window"].(string); ok {
+			calib.Window = parseDur(v, calib.Window)
+		}
+		if v, ok := m["step"].(string); ok {
+			calib.Step = parseDur(v, calib.Step)
+		}
+		if v, ok := m["refresh"].(string); ok {
+			calib.Refresh = parseDur(v, calib.Refresh)
+		}
+		switch v := m["utilization"].(type) {
+		case int64:
+			calib.Utilization = float64(v)
+		case float64:
+			calib.Utilization = v
+		}
+		if calib.Utilization <= 0 {
+			calib.Utilization = 1
+		}
+		if v, ok := m["minSamples"].(int64); ok && v >= 0 {
+			calib.MinSamples = int(v)
+		}
+	}
+
+	var tuning *tuningRef
+	if m, ok := spec["tuning"].(map[string]interface{}); ok {
+		tuning = &tuningRef{Window: 7 * 24 * time.Hour, Step: 5 * time.Minute, Refresh: 6 * time.Hour}
+		if v, ok := m["window"].(string); ok {
+			tuning.Window = parseDur(v, tuning.Window)
+		}
+		if v, ok := m["step"].(string); ok {
+			tuning.Step = parseDur(v, tuning.Step)
+		}
+		if v, ok := m["refresh"].(string); ok {
+			tuning.Refresh = parseDur(v, tuning.Refresh)
+		}
+	}
+
+	var learn *learningRef
+	if m, ok := spec["learning"].(map[string]interface{}); ok {
+		learn = &learningRef{Period: 7 * 24 * time.Hour, Step: 5 * time.Minute, MinQuantile: 0.05, MaxQuantile: 0.99}
+		if v, ok := m["period"].(string); ok {
+			learn.Period = parseDur(v, learn.Period)
+		}
+		if v, ok := m["step"].(string); ok {
+			learn.Step = parseDur(v, learn.Step)
+		}
+		for key, q := range map[string]*float64{"minQuantile": &learn.MinQuantile, "maxQuantile": &learn.MaxQuantile} {
+			var v float64
+			switch n := m[key].(type) {
+			case int64:
+				v = float64(n)
+			case float64:
+				v = n
+			default:
+				continue
+			}
+			if v >= 0 && v <= 1 {
+				*q = v
+			}
+		}
+	}
+
+	var anomaly *anomalyRef
+	if m, ok := spec["anomalyFilter"].(map[string]interface{}); ok {
+		anomaly = &anomalyRef{Window: 30 * time.Minute, Step: 30 * time.Second, Threshold: 3.5}
+		if v, ok := m["window"].(string); ok {
+			anomaly.Window = parseDur(v, anomaly.Window)
+		}
+		if v, ok := m["step"].(string); ok {
+			anomaly.Step = parseDur(v, anomaly.Step)
+		}
+		switch v := m["threshold"].(type) {
+		case int64:
+			anomaly.Threshold = float64(v)
+		case float64:
+			anomaly.Threshold = v
+		}
+		if anomaly.Threshold <= 0 {
+			anomaly.Threshold = 3.5
+		}
+	}
+
+	var vertical *verticalRef
+	if m, ok := spec["verticalFallback"].(map[string]interface{}); ok {
+		vertical = &verticalRef{After: 10 * time.Minute}
+		if v, ok := m["after"].(string); ok {
+			vertical.After = parseDur(v, vertical.After)
+		}
+		mode, _ := m["mode"].(string)
+		vertical.Resize = mode == "Resize"
+		vertical.Container, _ = m["container"].(string)
+		if v, ok := m["maxCPU"].(string); ok {
+			if q, err := resource.ParseQuantity(v); err == nil {
+				vertical.MaxCPU = q.AsApproximateFloat64()
+			}
+		}
+		if v, ok := m["maxMemory"].(string); ok {
+			if q, err := resource.ParseQuantity(v); err == nil {
+				vertical.MaxMemMiB = q.AsApproximateFloat64() / (1024 * 1024)
+			}
+		}
+	}
+
+	var inPlace *inPlaceRef
+	if m, ok := spec["inPlaceResize"].(map[string]interface{}); ok {
+		inPlace = &inPlaceRef{}
+		inPlace.Container, _ = m["container"].(string)
+		if v, ok := m["maxCPU"].(string); ok {
+			if q, err := resource.ParseQuantity(v); err == nil {
+				inPlace.MaxCPU = q.AsApproximateFloat64()
+			}
+		}
+		if inPlace.MaxCPU <= 0 {
+			inPlace = nil // pods need a ceiling to fall back to replicas from
+		}
+	}
+
+	var overprovision *overprovisionRef
+	if m, ok := spec["overprovisioning"].(map[string]interface{}); ok {
+		overprovision = &overprovisionRef{Image: "registry.k8s.io/pause:3.9"}
+		overprovision.PriorityClass, _ = m["priorityClassName"].(string)
+		if v, ok := m["replicas"].(int64); ok && v > 0 {
+			overprovision.Replicas = int32(v)
+		}
+		switch v := m["percent"].(type) {
+		case int64:
+			overprovision.Percent = float64(v)
+		case float64:
+			overprovision.Percent = v
+		}
+		if v, ok := m["image"].(string); ok && v != "" {
+			overprovision.Image = v
+		}
+		if overprovision.PriorityClass == "" {
+			overprovision = nil // balloons at the target's priority would crowd it out
+		}
+	}
+
+	var drain *drainRef
+	if m, ok := spec["drain"].(map[string]interface{}); ok {
+		drain = &drainRef{}
+		drain.Query, _ = m["query"].(string)
+		switch v := m["maxPerPod"].(type) {
+		case int64:
+			drain.MaxPerPod = float64(v)
+		case float64:
+			drain.MaxPerPod = v
+		}
+	}
+
+	var podMetric *podMetricRef
+	if m, ok := spec["podMetric"].(map[string]interface{}); ok {
+		podMetric = &podMetricRef{Path: "/", Scheme: "http", Aggregation: aggregationSum, Timeout: 2 * time.Second}
+		if v, ok := m["port"].(int64); ok {
+			podMetric.Port = int32(v)
+		}
+		if v, ok := m["path"].(string); ok && v != "" {
+			podMetric.Path = v
+		}
+		if v, ok := m["scheme"].(string); ok && v == "https" {
+			podMetric.Scheme = v
+		}
+		podMetric.JSONPath, _ = m["jsonPath"].(string)
+		switch v, _ := m["aggregation"].(string); v {
+		case aggregationAvg, aggregationMax:
+			podMetric.Aggregation = v
+		}
+		switch v := m["targetPerReplica"].(type) {
+		case int64:
+			podMetric.TargetPerReplica = float64(v)
+		case float64:
+			podMetric.TargetPerReplica = v
+		}
+		if v, ok := m["timeout"].(string); ok {
+			podMetric.Timeout = parseDur(v, podMetric.Timeout)
+		}
+		if podMetric.Port <= 0 || podMetric.JSONPath == "" || podMetric.TargetPerReplica <= 0 {
+			podMetric = nil // nothing to scrape or to size against
+		}
+	}
+
+	var sqlSource *sqlRef
+	if m, ok := spec["sql"].(map[string]interface{}); ok {
+		sqlSource = &sqlRef{SecretKey: "dsn", Timeout: 5 * time.Second}
+		sqlSource.Driver, _ = m["driver"].(string)
+		sqlSource.Query, _ = m["query"].(string)
+		if ref, ok := m["secretRef"].(map[string]interface{}); ok {
+			sqlSource.SecretName, _ = ref["name"].(string)
+			if v, ok := ref["key"].(string); ok && v != "" {
+				sqlSource.SecretKey = v
+			}
+		}
+		switch v := m["targetPerReplica"].(type) {
+		case int64:
+			sqlSource.TargetPerReplica = float64(v)
+		case float64:
+			sqlSource.TargetPerReplica = v
+		}
+		if v, ok := m["timeout"].(string); ok {
+			sqlSource.Timeout = parseDur(v, sqlSource.Timeout)
+		}
+		if sqlSource.Driver == "" || sqlSource.Query == "" || sqlSource.SecretName == "" || sqlSource.TargetPerReplica <= 0 {
+			sqlSource = nil
+		}
+	}
+
+	var pubsubSource *pubsubRef
+	if m, ok := spec["pubsub"].(map[string]interface{}); ok {
+		pubsubSource = &pubsubRef{Timeout: 10 * time.Second}
+		pubsubSource.Project, _ = m["project"].(string)
+		pubsubSource.Subscription, _ = m["subscription"].(string)
+		switch v := m["targetPerReplica"].(type) {
+		case int64:
+			pubsubSource.TargetPerReplica = float64(v)
+		case float64:
+			pubsubSource.TargetPerReplica = v
+		}
+		if v, ok := m["timeout"].(string); ok {
+			pubsubSource.Timeout = parseDur(v, pubsubSource.Timeout)
+		}
+		if pubsubSource.Project == "" || pubsubSource.Subscription == "" || pubsubSource.TargetPerReplica <= 0 {
+			pubsubSource = nil
+		}
+	}
+
+	var natsSource *natsRef
+	if m, ok := spec["nats"].(map[string]interface{}); ok {
+		natsSource = &natsRef{IncludeAckPending: true, Timeout: 5 * time.Second}
+		natsSource.MonitoringURL, _ = m["monitoringURL"].(string)
+		natsSource.Account, _ = m["account"].(string)
+		natsSource.Stream, _ = m["stream"].(string)
+		natsSource.Consumer, _ = m["consumer"].(string)
+		if v, ok := m["includeAckPending"].(bool); ok {
+			natsSource.IncludeAckPending = v
+		}
+		switch v := m["targetPerReplica"].(type) {
+		case int64:
+			natsSource.TargetPerReplica = float64(v)
+		case float64:
+			natsSource.TargetPerReplica = v
+		}
+		if v, ok := m["timeout"].(string); ok {
+			natsSource.Timeout = parseDur(v, natsSource.Timeout)
+		}
+		if natsSource.MonitoringURL == "" || natsSource.Stream == "" || natsSource.Consumer == "" || natsSource.TargetPerReplica <= 0 {
+			natsSource = nil
+		}
+	}
+
+	var endpoints *endpointsRef
+	if m, ok := spec["endpoints"].(map[string]interface{}); ok {
+		endpoints = &endpointsRef{}
+		endpoints.Service, _ = m["service"].(string)
+		switch v := m["targetPerReplica"].(type) {
+		case int64:
+			endpoints.TargetPerReplica = float64(v)
+		case float64:
+			endpoints.TargetPerReplica = v
+		}
+		if endpoints.Service == "" || endpoints.TargetPerReplica <= 0 {
+			endpoints = nil
+		}
+	}
+
+	var percentile *percentileRef
+	if m, ok := spec["percentile"].(map[string]interface{}); ok {
+		percentile = &percentileRef{Quantile: 0.9, Window: 10 * time.Minute, Step: 30 * time.Second}
+		if v, ok := m["quantile"].(float64); ok && v > 0 && v <= 1 {
+			percentile.Quantile = v
+		}
+		if v, ok := m["window"].(string); ok {
+			percentile.Window = parseDur(v, percentile.Window)
+		}
+		if v, ok := m["step"].(string); ok {
+			percentile.Step = parseDur(v, percentile.Step)
+		}
+	}
+
+	spot, _ := spec["spot"].(map[string]interface{})
+	spotFactor, _ := spot["factor"].(float64)
+	if v, ok := spot["factor"].(int64); ok {
+		spotFactor = float64(v)
+	}
+	var spotLabels map[string]string
+	if m, ok := spot["nodeLabels"].(map[string]interface{}); ok {
+		spotLabels = map[string]string{}
+		for k, v := range m {
+			if sv, ok := v.(string); ok {
+				spotLabels[k] = sv
+			}
+		}
+	}
+
+	// allowedReplicaCounts is either "odd"/"even" or a list of counts
+	var parity string
+	var allowedReplicas []int32
+	switch v := spec["allowedReplicaCounts"].(type) {
+	case string:
+		if v == decision.ParityOdd || v == decision.ParityEven {
+			parity = v
+		}
+	case []interface{}:
+		for _, item := range v {
+			switch n := item.(type) {
+			case int64:
+				allowedReplicas = append(allowedReplicas, int32(n))
+			case float64:
+				allowedReplicas = append(allowedReplicas, int32(n))
+			}
+		}
+	}
+
+	costCap, _ := spec["costCap"].(map[string]interface{})
+	maxHourlyCost, _ := costCap["maxHourly"].(float64)
+	if v, ok := costCap["maxHourly"].(int64); ok {
+		maxHourlyCost = float64(v)
+	}
+	openCostURL, _ := costCap["openCostURL"].(string)
+
+	var webhook *webhookRef
+	if m, ok := spec["decisionWebhook"].(map[string]interface{}); ok {
+		webhook = &webhookRef{Timeout: 5 * time.Second}
+		webhook.URL, _ = m["url"].(string)
+		if v, ok := m["timeout"].(string); ok {
+			webhook.Timeout = parseDur(v, webhook.Timeout)
+		}
+		webhook.FailOpen = m["failurePolicy"] == "Ignore"
+		if webhook.URL == "" {
+			webhook = nil
+		}
+	}
+
+	var approval *approvalRef
+	if m, ok := spec["approval"].(map[string]interface{}); ok {
+		approval = &approvalRef{}
+		switch v := m["maxChangePercent"].(type) {
+		case int64:
+			approval.MaxChangePercent = float64(v)
+		case float64:
+			approval.MaxChangePercent = v
+		}
+		if v, ok := m["maxChangeReplicas"].(int64); ok {
+			approval.MaxChangeReplicas = int32(v)
+		}
+	}
+
+	var gitopsTarget *gitopsRef
+	if m, ok := spec["gitops"].(map[string]interface{}); ok {
+		gitopsTarget = &gitopsRef{Branch: "main", Field: "spec.replicas"}
+		gitopsTarget.Repo, _ = m["repo"].(string)
+		gitopsTarget.Path, _ = m["path"].(string)
+		gitopsTarget.PushBranch, _ = m["pushBranch"].(string)
+		if v, ok := m["branch"].(string); ok && v != "" {
+			gitopsTarget.Branch = v
+		}
+		if v, ok := m["field"].(string); ok && v != "" {
+			gitopsTarget.Field = v
+		}
+		if ref, ok := m["secretRef"].(map[string]interface{}); ok {
+			gitopsTarget.SecretName, _ = ref["name"].(string)
+		}
+		// half-configured GitOps must not fall back to writing the Deployment silently
+		for _, f := range []struct{ name, value string }{
+			{"repo", gitopsTarget.Repo}, {"path", gitopsTarget.Path}, {"secretRef.name", gitopsTarget.SecretName},
+		} {
+			if f.value == "" && gitopsTarget.Invalid == "" {
+				gitopsTarget.Invalid = "spec.gitops." + f.name + " is empty"
+			}
+		}
+	}
+
+	schedules, blackouts, scheduleInvalid := parseSchedules(spec)
+
+	primaryMetric := getStr("primaryMetric", "")
+	switch primaryMetric {
+	case decision.SignalCPU, decision.SignalMemory, decision.SignalRPS, decision.SignalLatency, decision.SignalSLO, decision.SignalExternal:
+	default:
+		primaryMetric = "" // unknown: every signal sizes symmetrically, as before
+	}
+
+	rounding := getStr("rounding", "")
+	switch rounding {
+	case decision.RoundCeil, decision.RoundFloor, decision.RoundNearest:
+	default:
+		rounding = "" // unknown: round up, as before
+	}
+	minChange, _ := spec["minChange"].(map[string]interface{})
+	var minChangeReplicas, minChangeAbove int32
+	for key, v := range map[string]*int32{"replicas": &minChangeReplicas, "above": &minChangeAbove} {
+		switch n := minChange[key].(type) {
+		case int64:
+			*v = int32(n)
+		case float64:
+			*v = int32(n)
+		}
+	}
+
+	budget, _ := spec["scalingBudget"].(map[string]interface{})
+	budgetReplicas, _ := budget["replicas"].(int64)
+	budgetWindow := time.Hour
+	if v, ok := budget["per"].(string); ok {
+		budgetWindow = parseDur(v, budgetWindow)
+	}
+
+	var flap *flapRef
+	if m, ok := spec["flapDetection"].(map[string]interface{}); ok {
+		flap = &flapRef{Reversals: 3, Window: 30 * time.Minute, HysteresisFactor: 2, CooldownFactor: 2, Duration: time.Hour}
+		switch v := m["reversals"].(type) {
+		case int64:
+			flap.Reversals = int32(v)
+		case float64:
+			flap.Reversals = int32(v)
+		}
+		// status.scaleHistory is all there is to count reversals in
+		if flap.Reversals < 1 || flap.Reversals >= maxScaleHistory {
+			flap.Reversals = 3
+		}
+		if v, ok := m["window"].(string); ok {
+			flap.Window = parseDur(v, flap.Window)
+		}
+		if v, ok := m["duration"].(string); ok {
+			flap.Duration = parseDur(v, flap.Duration)
+		}
+		for key, factor := range map[string]*float64{"hysteresisMultiplier": &flap.HysteresisFactor, "cooldownMultiplier": &flap.CooldownFactor} {
+			switch v := m[key].(type) {
+			case int64:
+				*factor = float64(v)
+			case float64:
+				*factor = v
+			}
+			if *factor < 1 {
+				*factor = 2
+			}
+		}
+	}
+
+	var silence *silenceRef
+	if m, ok := spec["alertSilence"].(map[string]interface{}); ok {
+		silence = &silenceRef{Duration: 10 * time.Minute}
+		if v, ok := m["duration"].(string); ok {
+			silence.Duration = parseDur(v, silence.Duration)
+		}
+		names, _ := m["alertNames"].([]interface{})
+		for _, n := range names {
+			if name, ok := n.(string); ok && name != "" {
+				silence.AlertNames = append(silence.AlertNames, name)
+			}
+		}
+	}
+
+	targetRef, _ := spec["targetRef"].(map[string]interface{})
+	targetName, _ := targetRef["name"].(string)
+	targetNamespace, _ := targetRef["namespace"].(string)
+	targetAPIVersion, _ := targetRef["apiVersion"].(string)
+	targetKind, _ := targetRef["kind"].(string)
+	kubeconfigRef, _ := targetRef["kubeconfigSecretRef"].(map[string]interface{})
+	kubeconfigSecret, _ := kubeconfigRef["name"].(string)
+	kubeconfigKey, _ := kubeconfigRef["key"].(string)
+	if kubeconfigKey == "" {
+		kubeconfigKey = "kubeconfig"
+	}
+	if targetName == "" {
+		targetName = getStr("targetDeployment", "nginx-sample-deployment-2")
+	}
+	targetSelector, _ := spec["targetSelector"].(map[string]interface{})
+
+	// spec.shadow is a second policy: its fields laid over the rest of the spec
+	var shadow *autoscalerSpec
+	if m, ok := spec["shadow"].(map[string]interface{}); ok {
+		merged := make(map[string]interface{}, len(spec)+len(m))
+		for k, v := range spec {
+			merged[k] = v
+		}
+		for k, v := range m {
+			merged[k] = v
+		}
+		delete(merged, "shadow")
+		sh := parseSpec(merged)
+		shadow = &sh
+	}
+
+	return autoscalerSpec{
+		TargetDeployment: targetName,
+		TargetSelector:   targetSelector,
+		TargetNamespace:  targetNamespace,
+		TargetKind:       parseTargetKind(targetAPIVersion, targetKind),
+		KubeconfigSecret: kubeconfigSecret,
+		KubeconfigKey:    kubeconfigKey,
+		Clusters:         clusters,
+		PromURL:          getStr("promURL", ""), // empty: discovered at reconcile time
+		PollInterval:     parseDur(getStr("pollInterval", "15s"), 15*time.Second),
+		Cooldown:         parseDur(getStr("cooldown", "60s"), 60*time.Second),
+		ScaleDownDelay:   parseDur(getStr("scaleDownDelayAfterRollout", "0s"), 0),
+		WarmUp:           parseDur(getStr("warmUp", "0s"), 0),
+		ConfirmDelay:     parseDur(getStr("confirmationDelay", "0s"), 0),
+		InitialDelay:     parseDur(getStr("initialDelay", "0s"), 0),
+		MinReplicas:      getI32("minReplicas", 2),
+		MaxReplicas:      getI32("maxReplicas", 20),
+		TargetCPU:        getQty("targetCPU", 1, 0.2),       // cores per replica
+		TargetMem:        getQty("targetMem", 1<<20, 300.0), // MiB per replica
+		TargetRPS:        getF64("targetRPS", 0),
+		TargetLatencyMs:  getF64("targetLatencyMs", 0),
+		ErrorQuery:       errorQuery,
+		PrimaryMetric:    primaryMetric,
+		MaxErrorRatio:    maxErrorRatio,
+		SpotFactor:       spotFactor,
+		SpotNodeLabels:   spotLabels,
+		ZoneBalanced:     getBool("zoneBalanced", false),
+		MultipleOf:       getI32("replicaMultipleOf", 0),
+		Parity:           parity,
+		AllowedReplicas:  allowedReplicas,
+		MaxHourlyCost:    maxHourlyCost,
+		OpenCostURL:      openCostURL,
+		SLO:              slo,
+		Derivative:       derivative,
+		Percentile:       percentile,
+		Ingress:          ingress,
+		Istio:            istio,
+		RateWindows:      parseRateWindows(spec),
+		MetricNames:      parseMetricNames(spec),
+		DecisionWebhook:  webhook,
+		Approval:         approval,
+		GitOps:           gitopsTarget,
+		HysteresisPct:    getF64("hysteresisPct", 10.0),
+		Rounding:         rounding,
+		MinChange:        minChangeReplicas,
+		MinChangeAbove:   minChangeAbove,
+		StepLimit:        getI32("stepLimit", 5),
+		BudgetReplicas:   int32(budgetReplicas),
+		BudgetWindow:     budgetWindow,
+		Flap:             flap,
+		Silence:          silence,
+		RequiredSamples:  getI32("requiredSamples", 0),
+		HeadroomPct:      getF64("headroomPercent", 0),
+		HeadroomReplicas: getI32("headroomReplicas", 0),
+		ForceAdopt:       getBool("forceAdopt", false),
+		DeletionCost:     getBool("deletionCostHints", false),
+		Drain:            drain,
+		PodMetric:        podMetric,
+		SQL:              sqlSource,
+		PubSub:           pubsubSource,
+		NATS:             natsSource,
+		Endpoints:        endpoints,
+		Calibration:      calib,
+		Tuning:           tuning,
+		Learning:         learn,
+		Anomaly:          anomaly,
+		Vertical:         vertical,
+		InPlace:          inPlace,
+		Overprovision:    overprovision,
+		Schedules:        schedules,
+		BlackoutWindows:  blackouts,
+		ScheduleInvalid:  scheduleInvalid,
+		Shadow:           shadow,
+	}
+}
+
+// policy is the subset of the spec the decision engine needs.
+func (s autoscalerSpec) policy() decision.Policy {
+	targetRPS, targetLatency := s.TargetRPS, s.TargetLatencyMs
+	if s.Ingress == nil && s.Istio == nil {
+		targetRPS = 0
+	}
+	if s.Istio == nil {
+		targetLatency = 0
+	}
+	sloObjective := 0.0
+	if s.SLO != nil {
+		sloObjective = s.SLO.Objective
+	}
+	var derivThreshold, drainThreshold, targetExternal float64
+	var derivLookahead time.Duration
+	if s.Derivative != nil {
+		derivThreshold, derivLookahead = s.Derivative.CPUPerMinute, s.Derivative.Lookahead
+	}
+	if s.Drain != nil {
+		drainThreshold = s.Drain.MaxPerPod
+	}
+	if s.hasExternal() {
+		targetExternal = 1 // externalDemand is already in replicas
+	}
+	// A primary that isn't measured would size on nothing; fall back to max()
+	primary := s.PrimaryMetric
+	switch {
+	case primary == decision.SignalRPS && targetRPS <= 0,
+		primary == decision.SignalLatency && targetLatency <= 0,
+		primary == decision.SignalSLO && sloObjective <= 0,
+		primary == decision.SignalExternal && targetExternal <= 0:
+		primary = ""
+	}
+	return decision.Policy{
+		MinReplicas:                s.MinReplicas,
+		MaxReplicas:                s.MaxReplicas,
+		TargetCPU:                  s.TargetCPU,
+		TargetMem:                  s.TargetMem,
+		TargetRPS:                  targetRPS,
+		TargetLatencyMs:            targetLatency,
+		TargetExternal:             targetExternal,
+		HysteresisPct:              s.HysteresisPct,
+		Rounding:                   s.Rounding,
+		MinChangeReplicas:          s.MinChange,
+		MinChangeAbove:             s.MinChangeAbove,
+		StepLimit:                  s.StepLimit,
+		Cooldown:                   s.Cooldown,
+		ZoneBalanced:               s.ZoneBalanced,
+		ReplicaMultipleOf:          s.MultipleOf,
+		Parity:                     s.Parity,
+		AllowedReplicas:            s.AllowedReplicas,
+		SpotFactor:                 s.SpotFactor,
+		MaxHourlyCost:              s.MaxHourlyCost,
+		SLOObjective:               sloObjective,
+		MaxErrorRatio:              s.MaxErrorRatio,
+		HeadroomPct:                s.HeadroomPct,
+		HeadroomReplicas:           s.HeadroomReplicas,
+		ScaleDownDelayAfterRollout: s.ScaleDownDelay,
+		DerivativeThreshold:        derivThreshold,
+		DerivativeLookahead:        derivLookahead,
+		BudgetReplicas:             s.BudgetReplicas,
+		BudgetWindow:               s.BudgetWindow,
+		RequiredSamples:            s.RequiredSamples,
+		ConfirmationDelay:          s.ConfirmDelay,
+		Drain:                      s.Drain != nil,
+		DrainThreshold:             drainThreshold,
+		Primary:                    primary,
+	}
+}
+
+func parseDur(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestParseSpecQuantities(t *testing.T) {
+	cases := []struct {
+		name    string
+		cpu     interface{}
+		mem     interface{}
+		wantCPU float64
+		wantMem float64
+	}{
+		{"quantities", "200m", "300Mi", 0.2, 300},
+		{"whole units", "2", "1Gi", 2, 1024},
+		{"legacy numbers", 0.5, int64(128), 0.5, 128},
+		{"malformed falls back", "lots", "big", 0.2, 300},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := parseSpec(map[string]interface{}{"targetCPU": c.cpu, "targetMem": c.mem})
+			if s.TargetCPU != c.wantCPU || s.TargetMem != c.wantMem {
+				t.Fatalf("got cpu=%g mem=%g, want cpu=%g mem=%g", s.TargetCPU, s.TargetMem, c.wantCPU, c.wantMem)
+			}
+		})
+	}
+}
+
+func TestIngressRequestsQuery(t *testing.T) {
+	got := ingressRequestsQuery(ingressRef{Name: "web", Namespace: "shop", Host: "shop.example.com", Path: "/api"}, "2m")
+	want := `sum(rate(nginx_ingress_controller_requests{namespace="shop",ingress="web",host="shop.example.com",path="/api"}[2m]))`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestIstioQueries(t *testing.T) {
+	s := parseSpec(map[string]interface{}{"istio": map[string]interface{}{"workload": "web", "namespace": "shop"}})
+	if s.Istio == nil || s.Istio.Reporter != "destination" || s.Istio.LatencyQuantile != 0.95 {
+		t.Fatalf("istio defaults not applied: %+v", s.Istio)
+	}
+	m := `reporter="destination",destination_workload_namespace="shop",destination_workload="web"`
+	if got, want := istioRequestsQuery(*s.Istio, "2m"), `sum(rate(istio_requests_total{`+m+`}[2m]))`; got != want {
+		t.Errorf("requests query\ngot  %s\nwant %s", got, want)
+	}
+	want := `histogram_quantile(0.95, sum by (le) (rate(istio_request_duration_milliseconds_bucket{` + m + `}[2m])))`
+	if got := istioLatencyQuery(*s.Istio, "2m"); got != want {
+		t.Errorf("latency query\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestIngressErrorRatioQuery(t *testing.T) {
+	got := ingressErrorRatioQuery(ingressRef{Name: "web", Namespace: "shop"}, "2m")
+	want := `sum(rate(nginx_ingress_controller_requests{namespace="shop",ingress="web",status=~"5.."}[2m])) / ` +
+		`clamp_min(sum(rate(nginx_ingress_controller_requests{namespace="shop",ingress="web"}[2m])), 1e-9)`
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseSpecReplicaConstraints(t *testing.T) {
+	s := parseSpec(map[string]interface{}{"allowedReplicaCounts": "odd", "replicaMultipleOf": int64(3)})
+	if s.Parity != "odd" || s.MultipleOf != 3 || s.AllowedReplicas != nil {
+		t.Fatalf("odd: got %+v", s)
+	}
+	s = parseSpec(map[string]interface{}{"allowedReplicaCounts": []interface{}{int64(3), int64(5)}})
+	if s.Parity != "" || len(s.AllowedReplicas) != 2 || s.AllowedReplicas[1] != 5 {
+		t.Fatalf("list: got parity=%q allowed=%v", s.Parity, s.AllowedReplicas)
+	}
+}
+
+func TestDerivativeSpec(t *testing.T) {
+	s := parseSpec(map[string]interface{}{"derivative": map[string]interface{}{"cpuPerMinute": "100m"}})
+	if s.Derivative == nil || s.Derivative.CPUPerMinute != 0.1 || s.Derivative.Lookahead != 3*time.Minute {
+		t.Fatalf("derivative defaults not applied: %+v", s.Derivative)
+	}
+	if p := s.policy(); p.DerivativeThreshold != 0.1 || p.DerivativeLookahead != 3*time.Minute {
+		t.Fatalf("policy: %+v", p)
+	}
+	if s := parseSpec(map[string]interface{}{"derivative": map[string]interface{}{}}); s.Derivative != nil {
+		t.Fatalf("derivative without a threshold should be ignored: %+v", s.Derivative)
+	}
+	got := cpuSlopeQuery(`sum(x)`, "5m")
+	if want := `deriv((sum(x))[5m:30s]) * 60`; got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseRateWindows(t *testing.T) {
+	if got := parseSpec(map[string]interface{}{}).RateWindows; got != (rateWindows{"2m", "2m", "2m", "2m"}) {
+		t.Fatalf("defaults: %+v", got)
+	}
+	got := parseSpec(map[string]interface{}{
+		"rateWindow":  "5m",
+		"rateWindows": map[string]interface{}{"cpu": "30s", "errors": "1m30s", "latency": "2m]) or vector(1"},
+	}).RateWindows
+	if want := (rateWindows{CPU: "30s", Requests: "5m", Errors: "1m30s", Latency: "5m"}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if cpuQ, _ := usageQueries(DefaultMetricNames, "shop", "web-.*", got.CPU); !strings.Contains(cpuQ, "[30s]") {
+		t.Fatalf("cpu query ignores its window: %s", cpuQ)
+	}
+}
+
+func TestParseMetricNames(t *testing.T) {
+	controller := MetricNames{CPU: "k8s:cpu_seconds:total", PodLabel: "pod_name"}.orDefault()
+	s := parseSpec(map[string]interface{}{
+		"metricNames": map[string]interface{}{
+			"memory":         "node_mem_bytes",
+			"namespaceLabel": "kubernetes_namespace",
+			"imageLabel":     "",
+			"podLabel":       `pod"} or vector(1`,
+		},
+	})
+	got := controller.with(s.MetricNames)
+	want := MetricNames{
+		CPU:            "k8s:cpu_seconds:total",
+		Memory:         "node_mem_bytes",
+		NamespaceLabel: "kubernetes_namespace",
+		PodLabel:       "pod_name",
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	cpuQ, memQ := usageQueries(got, "shop", "web-.*", "2m")
+	if want := `sum(rate(k8s:cpu_seconds:total{kubernetes_namespace="shop",pod_name=~"web-.*"}[2m]))`; cpuQ != want {
+		t.Fatalf("cpu query = %s, want %s", cpuQ, want)
+	}
+	if want := `sum(node_mem_bytes{kubernetes_namespace="shop",pod_name=~"web-.*"})`; memQ != want {
+		t.Fatalf("memory query = %s, want %s", memQ, want)
+	}
+	if q := podCPUQuery(got, "shop", "web-.*", "2m"); !strings.HasPrefix(q, `label_replace(sum by (pod_name) (`) {
+		t.Fatalf("per-pod query doesn't key by pod: %s", q)
+	}
+	if cpuQ, _ := usageQueries(MetricNames{}.orDefault(), "shop", "web-.*", "2m"); !strings.Contains(cpuQ, `container_cpu_usage_seconds_total{namespace="shop",pod=~"web-.*",image!=""}`) {
+		t.Fatalf("default cpu query = %s", cpuQ)
+	}
+}
+
+func TestPrimaryMetricPolicy(t *testing.T) {
+	ingress := map[string]interface{}{"name": "web"}
+	cases := []struct {
+		name string
+		spec map[string]interface{}
+		want string
+	}{
+		{"unset", map[string]interface{}{}, ""},
+		{"cpu", map[string]interface{}{"primaryMetric": "cpu"}, "cpu"},
+		{"rps with ingress", map[string]interface{}{"primaryMetric": "rps", "ingress": ingress, "targetRPS": 50.0}, "rps"},
+		{"rps not measured", map[string]interface{}{"primaryMetric": "rps"}, ""},
+		{"unknown", map[string]interface{}{"primaryMetric": "qps"}, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := parseSpec(c.spec).policy().Primary; got != c.want {
+				t.Fatalf("Primary = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRoundingPolicy(t *testing.T) {
+	cases := []struct {
+		name      string
+		spec      map[string]interface{}
+		rounding  string
+		minChange int32
+		above     int32
+	}{
+		{"unset", map[string]interface{}{}, "", 0, 0},
+		{"floor", map[string]interface{}{"rounding": "floor"}, "floor", 0, 0},
+		{"unknown", map[string]interface{}{"rounding": "banker"}, "", 0, 0},
+		{"min change", map[string]interface{}{"minChange": map[string]interface{}{"replicas": int64(2), "above": int64(50)}}, "", 2, 50},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := parseSpec(c.spec).policy()
+			if p.Rounding != c.rounding || p.MinChangeReplicas != c.minChange || p.MinChangeAbove != c.above {
+				t.Fatalf("policy rounding %q, min change %d above %d; want %q, %d above %d",
+					p.Rounding, p.MinChangeReplicas, p.MinChangeAbove, c.rounding, c.minChange, c.above)
+			}
+		})
+	}
+}
+
+func TestAlertSilence(t *testing.T) {
+	if s := parseSpec(map[string]interface{}{}); s.Silence != nil {
+		t.Fatalf("silence without spec.alertSilence: %+v", s.Silence)
+	}
+	s := parseSpec(map[string]interface{}{"alertSilence": map[string]interface{}{}})
+	if s.Silence == nil || s.Silence.Duration != 10*time.Minute || s.Silence.AlertNames != nil {
+		t.Fatalf("default silence = %+v", s.Silence)
+	}
+	s = parseSpec(map[string]interface{}{"alertSilence": map[string]interface{}{
+		"duration": "15m", "alertNames": []interface{}{"KubePodNotReady", ""},
+	}})
+	if s.Silence.Duration != 15*time.Minute || !reflect.DeepEqual(s.Silence.AlertNames, []string{"KubePodNotReady"}) {
+		t.Fatalf("silence = %+v", s.Silence)
+	}
+
+	e := silenceEvent(scaledEvent(types.NamespacedName{Namespace: "shop", Name: "web"}, "shop/web", "d1", 3, 6, time.Unix(0, 0)), *s.Silence, "web-(7d9c8)-[a-z0-9]+")
+	d := e.Data.(map[string]interface{})
+	if d["duration"] != "15m0s" || d["pods"] != "web-(7d9c8)-[a-z0-9]+" || d["from"] != int32(3) || d["to"] != int32(6) || e.Subject != "shop/web" {
+		t.Fatalf("silence event = %+v", e)
+	}
+}
+
+func TestSchedulesFollowLocalTimeAcrossDST(t *testing.T) {
+	s := parseSpec(map[string]interface{}{
+		"minReplicas": int64(2),
+		"maxReplicas": int64(20),
+		"schedules": []interface{}{map[string]interface{}{
+			"days": []interface{}{"Mon", "Tue", "Wed", "Thu", "Fri"}, "start": "09:00", "end": "17:00",
+			"timezone": "America/New_York", "minReplicas": int64(10),
+		}},
+		"blackoutWindows": []interface{}{map[string]interface{}{
+			"start": "23:00", "end": "01:00", "timezone": "Europe/Berlin",
+		}},
+	})
+	if s.ScheduleInvalid != "" || len(s.Schedules) != 1 || len(s.BlackoutWindows) != 1 {
+		t.Fatalf("schedules = %+v, blackouts = %+v, invalid %q", s.Schedules, s.BlackoutWindows, s.ScheduleInvalid)
+	}
+	for _, c := range []struct {
+		at   time.Time
+		want int32
+	}{
+		{time.Date(2025, 1, 15, 13, 30, 0, 0, time.UTC), 2},  // 08:30 EST: not yet open
+		{time.Date(2025, 1, 15, 14, 30, 0, 0, time.UTC), 10}, // 09:30 EST
+		{time.Date(2025, 7, 15, 13, 30, 0, 0, time.UTC), 10}, // 09:30 EDT: the same UTC hour is now inside
+		{time.Date(2025, 7, 15, 21, 30, 0, 0, time.UTC), 2},  // 17:30 EDT
+		{time.Date(2025, 3, 9, 14, 30, 0, 0, time.UTC), 2},   // the Sunday DST starts
+		{time.Date(2025, 3, 10, 13, 30, 0, 0, time.UTC), 10}, // and the Monday after: 09:30 EDT
+	} {
+		if got := scheduledMin(s, c.at); got != c.want {
+			t.Errorf("minReplicas at %v = %d, want %d", c.at, got, c.want)
+		}
+	}
+	for _, c := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2025, 1, 15, 22, 30, 0, 0, time.UTC), true},  // 23:30 CET
+		{time.Date(2025, 7, 15, 22, 30, 0, 0, time.UTC), true},  // 00:30 CEST, past midnight
+		{time.Date(2025, 7, 15, 23, 30, 0, 0, time.UTC), false}, // 01:30 CEST
+		{time.Date(2025, 1, 15, 21, 30, 0, 0, time.UTC), false}, // 22:30 CET
+	} {
+		if got := inBlackout(s, c.at); got != c.want {
+			t.Errorf("blackout at %v = %v, want %v", c.at, got, c.want)
+		}
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"start": "09:00", "end": "17:00", "timezone": "Mars/Olympus_Mons"},
+		{"start": "9am", "end": "17:00"},
+		{"start": "09:00", "end": "09:00"},
+		{"days": []interface{}{"Someday"}, "start": "09:00", "end": "17:00"},
+	} {
+		s := parseSpec(map[string]interface{}{"blackoutWindows": []interface{}{bad}})
+		if !strings.HasPrefix(s.ScheduleInvalid, "spec.blackoutWindows[0]") {
+			t.Errorf("%v: invalid = %q", bad, s.ScheduleInvalid)
+		}
+	}
+}
+
+func TestConvertV1beta1(t *testing.T) {
+	alpha := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersionV1alpha1,
+		"kind":       "NginxAutoscaler",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "shop"},
+		"spec": map[string]interface{}{
+			"targetDeployment": "web",
+			"minReplicas":      int64(2),
+			"targetCPU":        "200m",
+			"targetMem":        "300Mi",
+			"podMetric":        map[string]interface{}{"port": int64(8080), "path": "/stats", "jsonPath": "$.active", "targetPerReplica": int64(100)},
+			"cooldown":         "2m",
+			"stepLimit":        int64(3),
+			"shadow":           map[string]interface{}{"targetCPU": "150m", "hysteresisPct": int64(5)},
+		},
+		"status": map[string]interface{}{"currentReplicas": int64(4)},
+	}}
+
+	beta, err := convertAutoscaler(alpha, apiVersionV1beta1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSpec := map[string]interface{}{
+		"targetRef":   map[string]interface{}{"name": "web"},
+		"minReplicas": int64(2),
+		"metrics": []interface{}{
+			map[string]interface{}{"type": "CPU", "target": "200m"},
+			map[string]interface{}{"type": "Memory", "target": "300Mi"},
+			map[string]interface{}{"type": "PodMetric", "podMetric": alpha.Object["spec"].(map[string]interface{})["podMetric"]},
+		},
+		"behavior": map[string]interface{}{"cooldown": "2m", "stepLimit": int64(3)},
+		"shadow": map[string]interface{}{
+			"metrics":  []interface{}{map[string]interface{}{"type": "CPU", "target": "150m"}},
+			"behavior": map[string]interface{}{"hysteresisPct": int64(5)},
+		},
+	}
+	if got := beta.Object["spec"]; !reflect.DeepEqual(got, wantSpec) {
+		t.Fatalf("v1beta1 spec = %#v\nwant %#v", got, wantSpec)
+	}
+	if beta.GetAPIVersion() != apiVersionV1beta1 || !reflect.DeepEqual(beta.Object["status"], alpha.Object["status"]) {
+		t.Fatalf("v1beta1 object = %#v", beta.Object)
+	}
+
+	// Back in v1alpha1 the controller reads the same settings
+	back, err := convertAutoscaler(beta, apiVersionV1alpha1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, _, _ := unstructured.NestedMap(alpha.Object, "spec")
+	again, _, _ := unstructured.NestedMap(back.Object, "spec")
+	if !reflect.DeepEqual(parseSpec(again), parseSpec(orig)) {
+		t.Fatalf("round trip changed the spec:\n%#v\nwas\n%#v", again, orig)
+	}
+
+	if _, err := convertAutoscaler(alpha, "autoscaler.malisetti.dev/v2"); err == nil {
+		t.Fatal("converting to an unknown version succeeded")
+	}
+}
+
+func TestConversionReview(t *testing.T) {
+	obj := `{"apiVersion":"autoscaler.malisetti.dev/v1beta1","kind":"NginxAutoscaler","metadata":{"name":"web"},` +
+		`"spec":{"targetRef":{"name":"web"},"metrics":[{"type":"CPU","target":"200m"}],"behavior":{"cooldown":"2m"}}}`
+	body := `{"apiVersion":"apiextensions.k8s.io/v1","kind":"ConversionReview","request":{"uid":"u1",` +
+		`"desiredAPIVersion":"autoscaler.malisetti.dev/v1alpha1","objects":[` + obj + `]}}`
+	rec := httptest.NewRecorder()
+	serveConversion(rec, httptest.NewRequest(http.MethodPost, ConvertPath, strings.NewReader(body)))
+
+	var review apiextensionsv1.ConversionReview
+	if err := json.Unmarshal(rec.Body.Bytes(), &review); err != nil {
+		t.Fatalf("response %q: %v", rec.Body.String(), err)
+	}
+	resp := review.Response
+	if resp == nil || resp.UID != "u1" || resp.Result.Status != metav1.StatusSuccess || len(resp.ConvertedObjects) != 1 {
+		t.Fatalf("response = %+v", resp)
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(resp.ConvertedObjects[0].Raw); err != nil {
+		t.Fatal(err)
+	}
+	spec, _, _ := unstructured.NestedMap(u.Object, "spec")
+	if u.GetAPIVersion() != apiVersionV1alpha1 || spec["targetCPU"] != "200m" || spec["cooldown"] != "2m" || spec["metrics"] != nil {
+		t.Fatalf("converted object = %#v", u.Object)
+	}
+
+	rec = httptest.NewRecorder()
+	serveConversion(rec, httptest.NewRequest(http.MethodPost, ConvertPath, strings.NewReader(strings.Replace(body, "v1alpha1", "v9", 1))))
+	_ = json.Unmarshal(rec.Body.Bytes(), &review)
+	if review.Response.Result.Status != metav1.StatusFailure || review.Response.ConvertedObjects != nil {
+		t.Fatalf("unknown version: response = %+v", review.Response)
+	}
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// spotNodeLabels mark spot/preemptible capacity on the common providers.
+var spotNodeLabels = map[string]string{
+	"cloud.google.com/gke-spot":             "true",
+	"cloud.google.com/gke-preemptible":      "true",
+	"eks.amazonaws.com/capacityType":        "SPOT",
+	"karpenter.sh/capacity-type":            "spot",
+	"kubernetes.azure.com/scalesetpriority": "spot",
+}
+
+// isSpotNode reports whether node carries one of the spot labels, or extra.
+func isSpotNode(node *corev1.Node, extra map[string]string) bool {
+	for _, labels := range []map[string]string{spotNodeLabels, extra} {
+		for k, v := range labels {
+			if node.Labels[k] == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// spotFraction is the share of the target's running pods scheduled on spot nodes.
+func (tc targetCluster) spotFraction(ctx context.Context, dep *appsv1.Deployment, extra map[string]string) (float64, error) {
+	pods, nodes, err := tc.podNodes(ctx, dep)
+	if err != nil || len(pods) == 0 {
+		return 0, err
+	}
+	onSpot := 0
+	for _, p := range pods {
+		if isSpotNode(nodes[p.Spec.NodeName], extra) {
+			onSpot++
+		}
+	}
+	return float64(onSpot) / float64(len(pods)), nil
+}
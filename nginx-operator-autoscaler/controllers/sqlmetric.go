@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/sqlsource"
+)
+
+// sqlRef is spec.sql: a query returning one number (rows in a jobs table,
+// say) that worker replicas are sized on, TargetPerReplica each.
+type sqlRef struct {
+	Driver           string // postgres or mysql
+	Query            string
+	SecretName       string // in the CR's namespace
+	SecretKey        string // holding the DSN; default "dsn"
+	TargetPerReplica float64
+	Timeout          time.Duration
+}
+
+// sqlMetric runs ref's query with the DSN from its Secret, read from the API
+// server each time so a rotated password is picked up.
+func (r *reconciler) sqlMetric(ctx context.Context, crNS string, ref sqlRef) (float64, error) {
+	var secret corev1.Secret
+	if err := r.apiReader.Get(ctx, types.NamespacedName{Namespace: crNS, Name: ref.SecretName}, &secret); err != nil {
+		return 0, fmt.Errorf("sql secret %s/%s: %w", crNS, ref.SecretName, err)
+	}
+	dsn := string(secret.Data[ref.SecretKey])
+	if dsn == "" {
+		return 0, fmt.Errorf("sql secret %s/%s has no %q", crNS, ref.SecretName, ref.SecretKey)
+	}
+	ctx, cancel := context.WithTimeout(ctx, ref.Timeout)
+	defer cancel()
+	v, err := sqlsource.Query(ctx, ref.Driver, dsn, ref.Query)
+	if err != nil {
+		return 0, fmt.Errorf("spec.sql query: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Condition types surfaced in status.conditions.
+const (
+	condTargetAllowed     = "TargetAllowed"
+	condConflicted        = "Conflicted"
+	condTargetAdopted     = "TargetAdopted"
+	condLimited           = "ScalingLimited"
+	condSaturated         = "Saturated"
+	condMetricsAvailable  = "MetricsAvailable"
+	condTargetsFeasible   = "TargetsFeasible"
+	condDegradedActuation = "DegradedActuation"
+	condInPlaceResize     = "InPlaceResize"
+)
+
+// getConditions decodes status.conditions of an unstructured CR.
+func getConditions(u *unstructured.Unstructured) []metav1.Condition {
+	raw, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	out := make([]metav1.Condition, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var c metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &c); err == nil {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// setCondition upserts a condition in status.conditions. It reports whether
+// anything changed, so callers can skip no-op status writes.
+func setCondition(u *unstructured.Unstructured, condType string, status metav1.ConditionStatus, reason, message string) bool {
+	conds := getConditions(u)
+	changed := meta.SetStatusCondition(&conds, metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: u.GetGeneration(),
+	})
+	if !changed {
+		return false
+	}
+
+	raw := make([]interface{}, 0, len(conds))
+	for i := range conds {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&conds[i])
+		if err != nil {
+			continue
+		}
+		raw = append(raw, m)
+	}
+	_ = unstructured.SetNestedSlice(u.Object, raw, "status", "conditions")
+	return true
+}
+
+type statusBaseKey struct{}
+
+// withStatusBase remembers u's status as loaded, for patchStatus to diff against.
+func withStatusBase(ctx context.Context, u *unstructured.Unstructured) context.Context {
+	base := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if status, ok := u.Object["status"]; ok {
+		base.Object["status"] = runtime.DeepCopyJSONValue(status)
+	}
+	return context.WithValue(ctx, statusBaseKey{}, base)
+}
+
+// patchStatus writes what changed in u's status since it was loaded (or last
+// written) as a JSON merge patch, stamping status.observedGeneration. The
+// patch carries no resourceVersion, so a write racing a spec edit or another
+// writer doesn't fail on a conflict; it only sets the fields this reconcile
+// changed, removed ones included.
+func (r *reconciler) patchStatus(ctx context.Context, u *unstructured.Unstructured) error {
+	_ = unstructured.SetNestedField(u.Object, u.GetGeneration(), "status", "observedGeneration")
+	base, _ := ctx.Value(statusBaseKey{}).(*unstructured.Unstructured)
+	if base == nil {
+		base = &unstructured.Unstructured{Object: map[string]interface{}{}}
+	}
+	next := &unstructured.Unstructured{Object: map[string]interface{}{"status": runtime.DeepCopyJSONValue(u.Object["status"])}}
+	data, err := client.MergeFrom(base).Data(next)
+	if err != nil {
+		return err
+	}
+	if err := r.Status().Patch(ctx, u, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return err
+	}
+	base.Object["status"] = next.Object["status"]
+	return nil
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	server "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/malisettirammurthy/promtest"
+)
+
+// k8sClient talks to the envtest API server shared by every integration test.
+var k8sClient client.Client
+
+// TestMain boots envtest (kube-apiserver + etcd) with our CRDs and a running
+// manager. Without KUBEBUILDER_ASSETS (see `setup-envtest use`) integration
+// tests are skipped and only unit tests run.
+func TestMain(m *testing.M) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		os.Exit(m.Run())
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true), zap.WriteTo(os.Stderr)))
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd")},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := env.Start()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "envtest start:", err)
+		os.Exit(1)
+	}
+
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	_ = discoveryv1.AddToScheme(scheme)
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                server.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "manager:", err)
+		os.Exit(1)
+	}
+	if err := SetupNginxAutoscalerController(mgr, Options{InstanceName: "envtest"}); err != nil {
+		fmt.Fprintln(os.Stderr, "setup controller:", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = mgr.Start(ctx) }()
+	k8sClient = mgr.GetClient()
+
+	code := m.Run()
+
+	cancel()
+	_ = env.Stop()
+	os.Exit(code)
+}
+
+func requireEnvtest(t *testing.T) {
+	t.Helper()
+	if k8sClient == nil {
+		t.Skip("KUBEBUILDER_ASSETS not set; skipping envtest integration test")
+	}
+}
+
+// stubProm answers the CPU and memory queries with fixed values.
+func stubProm(t *testing.T, cpuCores, memBytes float64) string {
+	t.Helper()
+	p := promtest.New(t)
+	p.SetInstant("container_cpu_usage_seconds_total", cpuCores)
+	p.SetInstant("container_memory_working_set_bytes", memBytes)
+	return p.URL
+}
+
+func newDeployment(ns, name string, replicas int32) *appsv1.Deployment {
+	labels := map[string]string{"app": name}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx"}}},
+			},
+		},
+	}
+}
+
+func newAutoscaler(ns, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetGroupVersionKind(autoscalerGVK)
+	u.SetNamespace(ns)
+	u.SetName(name)
+	return u
+}
+
+// eventually polls cond until it holds or the timeout expires.
+func eventually(t *testing.T, timeout time.Duration, cond func() (bool, string)) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	var last string
+	for time.Now().Before(deadline) {
+		ok, msg := cond()
+		if ok {
+			return
+		}
+		last = msg
+		time.Sleep(250 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within %s: %s", timeout, last)
+}
+
+func TestReconcileScalesDeploymentAndUpdatesStatus(t *testing.T) {
+	requireEnvtest(t)
+	ctx := context.Background()
+
+	// 1.0 core demand at 0.2 cores/replica => 5 replicas; memory is negligible
+	promURL := stubProm(t, 1.0, 10*1024*1024)
+
+	dep := newDeployment("default", "web", 2)
+	if err := k8sClient.Create(ctx, dep); err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+	cr := newAutoscaler("default", "web-autoscaler", map[string]interface{}{
+		"targetDeployment": "web",
+		"promURL":          promURL,
+		"pollInterval":     "5s",
+		"cooldown":         "0s",
+		"minReplicas":      int64(1),
+		"maxReplicas":      int64(10),
+		"targetCPU":        0.2,
+		"targetMem":        int64(300),
+		"hysteresisPct":    int64(10),
+		"stepLimit":        int64(10),
+	})
+	if err := k8sClient.Create(ctx, cr); err != nil {
+		t.Fatalf("create autoscaler: %v", err)
+	}
+
+	eventually(t, 20*time.Second, func() (bool, string) {
+		var got appsv1.Deployment
+		if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, &got); err != nil {
+			return false, err.Error()
+		}
+		return *got.Spec.Replicas == 5, fmt.Sprintf("replicas=%d", *got.Spec.Replicas)
+	})
+
+	eventually(t, 20*time.Second, func() (bool, string) {
+		u := newAutoscaler("default", "web-autoscaler", nil)
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(u), u); err != nil {
+			return false, err.Error()
+		}
+		desired, _, _ := unstructured.NestedInt64(u.Object, "status", "desiredReplicas")
+		lastScale, _, _ := unstructured.NestedString(u.Object, "status", "lastScaleTime")
+		return desired == 5 && lastScale != "", fmt.Sprintf("desiredReplicas=%d lastScaleTime=%q", desired, lastScale)
+	})
+}
+
+func TestReconcileRespectsConflicts(t *testing.T) {
+	requireEnvtest(t)
+	ctx := context.Background()
+
+	promURL := stubProm(t, 1.0, 0)
+	if err := k8sClient.Create(ctx, newDeployment("default", "shared", 2)); err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+	spec := map[string]interface{}{"targetDeployment": "shared", "promURL": promURL, "pollInterval": "5s"}
+	if err := k8sClient.Create(ctx, newAutoscaler("default", "first", spec)); err != nil {
+		t.Fatalf("create first: %v", err)
+	}
+	// creationTimestamp has second granularity; make sure "second" is younger
+	time.Sleep(1100 * time.Millisecond)
+	if err := k8sClient.Create(ctx, newAutoscaler("default", "second", spec)); err != nil {
+		t.Fatalf("create second: %v", err)
+	}
+
+	eventually(t, 20*time.Second, func() (bool, string) {
+		u := newAutoscaler("default", "second", nil)
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(u), u); err != nil {
+			return false, err.Error()
+		}
+		for _, c := range getConditions(u) {
+			if c.Type == condConflicted && c.Status == metav1.ConditionTrue {
+				return true, ""
+			}
+		}
+		return false, "second autoscaler not marked Conflicted"
+	})
+}
+
+func TestCRDRejectsInvalidSpecs(t *testing.T) {
+	requireEnvtest(t)
+	ctx := context.Background()
+
+	for name, spec := range map[string]map[string]interface{}{
+		"min-above-max": {"targetDeployment": "web", "minReplicas": int64(5), "maxReplicas": int64(2)},
+		"zero-step":     {"targetDeployment": "web", "stepLimit": int64(0)},
+		"fast-poll":     {"targetDeployment": "web", "pollInterval": "1s"},
+	} {
+		if err := k8sClient.Create(ctx, newAutoscaler("default", name, spec)); err == nil {
+			t.Errorf("%s: created, want rejected", name)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// targetKind is what spec.targetRef.apiVersion/kind name. Every kind is
+// read as an appsv1.Deployment, so the rest of the controller need not care.
+type targetKind string
+
+const (
+	targetKindDeployment       targetKind = ""
+	targetKindKnativeService   targetKind = "KnativeService"
+	targetKindDeploymentConfig targetKind = "DeploymentConfig"
+)
+
+// parseTargetKind maps targetRef's apiVersion and kind to a targetKind;
+// anything unrecognised is a Deployment.
+func parseTargetKind(apiVersion, kind string) targetKind {
+	switch {
+	case apiVersion == knativeServingAPIVersion && kind == knativeServiceKind:
+		return targetKindKnativeService
+	case apiVersion == deploymentConfigAPIVersion && kind == deploymentConfigKind:
+		return targetKindDeploymentConfig
+	}
+	return targetKindDeployment
+}
+
+// getTarget reads the target named key into dep: the Deployment itself, the
+// Deployment of a Knative Service's latest ready Revision, or a
+// DeploymentConfig viewed as a Deployment.
+func (tc targetCluster) getTarget(ctx context.Context, kind targetKind, key types.NamespacedName, dep *appsv1.Deployment) error {
+	switch kind {
+	case targetKindKnativeService:
+		depKey, err := tc.knativeDeployment(ctx, key)
+		if err != nil {
+			return err
+		}
+		return tc.Get(ctx, depKey, dep)
+	case targetKindDeploymentConfig:
+		return tc.deploymentConfig(ctx, key, dep)
+	}
+	return tc.Get(ctx, key, dep)
+}
+
+// patchTarget applies patch, computed against dep, to the object dep was
+// read from.
+func (tc targetCluster) patchTarget(ctx context.Context, dep *appsv1.Deployment, patch client.Patch) error {
+	if isDeploymentConfig(dep) {
+		return tc.patchDeploymentConfig(ctx, dep, patch)
+	}
+	return tc.Patch(ctx, dep, patch)
+}
+
+// scaleTarget sets the target's replica count to replicas.
+func (tc targetCluster) scaleTarget(ctx context.Context, kind targetKind, dep *appsv1.Deployment, replicas int32) error {
+	switch kind {
+	case targetKindKnativeService:
+		return tc.pinKnativeScale(ctx, dep, replicas)
+	case targetKindDeploymentConfig:
+		return tc.scaleDeploymentConfig(ctx, dep, replicas)
+	}
+	dep.Spec.Replicas = &replicas
+	return tc.Update(ctx, dep)
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/decision"
+	prom "github.com/malisettirammurthy/nginx-operator-autoscaler/internal/prom"
+)
+
+// tuningRef is spec.tuning: fit a hysteresis band, cooldown and step limit
+// to the CPU demand seen over Window and suggest them in
+// status.recommendations. Nothing applies them.
+type tuningRef struct {
+	Window  time.Duration
+	Step    time.Duration // query_range resolution
+	Refresh time.Duration // how often the fit is recomputed
+}
+
+// tune fits decision.Tune to the replicas targetCPU would have asked for
+// over the window of cpuQ. ok is false when there was too little history,
+// samples saying how much there was.
+func tune(promURL string, ref tuningRef, cpuQ string, targetCPU float64) (t decision.Tuning, samples int, ok bool, err error) {
+	cpu, err := prom.RangeVector(promURL, cpuQ, ref.Window, ref.Step)
+	if err != nil {
+		return decision.Tuning{}, 0, false, err
+	}
+	demand := make([]float64, len(cpu))
+	for i, v := range cpu {
+		demand[i] = v / targetCPU
+		if !math.IsNaN(v) {
+			samples++
+		}
+	}
+	t, ok = decision.Tune(demand, ref.Step)
+	return t, samples, ok, nil
+}
+
+// recommendationsUpdated is when status.recommendations was last fitted.
+func recommendationsUpdated(u *unstructured.Unstructured) time.Time {
+	str, _, _ := unstructured.NestedString(u.Object, "status", "recommendations", "updated")
+	t, _ := time.Parse(time.RFC3339, str)
+	return t
+}
+
+// writeRecommendations records a fit in status.recommendations; with too
+// little history only the sample count and time are kept.
+func writeRecommendations(u *unstructured.Unstructured, t decision.Tuning, samples int, ok bool, now time.Time) {
+	m := map[string]string{
+		"samples": strconv.Itoa(samples),
+		"updated": now.Format(time.RFC3339),
+	}
+	if ok {
+		m["hysteresisPct"] = strconv.FormatFloat(t.HysteresisPct, 'f', -1, 64)
+		m["cooldown"] = t.Cooldown.String()
+		m["stepLimit"] = strconv.Itoa(int(t.StepLimit))
+	}
+	_ = unstructured.SetNestedStringMap(u.Object, m, "status", "recommendations")
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// verticalRef is spec.verticalFallback: once demand has needed more than
+// maxReplicas for After, size each pod up instead of adding pods. Without
+// Resize the requests are only recommended, in status.verticalFallback and
+// on the event bus (for a human or a VPA to act on); with it they are written
+// to the Deployment, never past MaxCPU/MaxMemMiB.
+type verticalRef struct {
+	After     time.Duration
+	Resize    bool
+	Container string  // empty means the first container
+	MaxCPU    float64 // cores per pod; zero leaves CPU alone when resizing
+	MaxMemMiB float64 // zero leaves memory alone when resizing
+}
+
+// verticalState is status.verticalFallback. Scales are the resized requests
+// over the ones the fallback started from; the per-replica targets grow by
+// the same factors while the container still runs the requests recorded.
+type verticalState struct {
+	Container string
+	CPU       string // requests recommended, or applied when Resized
+	Memory    string
+	CPUScale  float64
+	MemScale  float64
+	Resized   bool
+	Time      time.Time
+}
+
+// fallbackContainer is the container spec.verticalFallback sizes, or nil.
+func fallbackContainer(dep *appsv1.Deployment, name string) *corev1.Container {
+	cs := dep.Spec.Template.Spec.Containers
+	for i := range cs {
+		if name == "" || cs[i].Name == name {
+			return &cs[i]
+		}
+	}
+	return nil
+}
+
+// scales are the factors a previous resize grew c's requests by, or 1 when
+// c no longer runs them (a new manifest rolled out over the resize).
+func (st verticalState) scales(c *corev1.Container) (cpu, mem float64) {
+	if !st.Resized || c == nil || c.Name != st.Container ||
+		!sameQuantity(c.Resources.Requests, corev1.ResourceCPU, st.CPU) ||
+		!sameQuantity(c.Resources.Requests, corev1.ResourceMemory, st.Memory) {
+		return 1, 1
+	}
+	return st.CPUScale, st.MemScale
+}
+
+func sameQuantity(rl corev1.ResourceList, name corev1.ResourceName, want string) bool {
+	q, has := rl[name]
+	if want == "" {
+		return !has
+	}
+	w, err := resource.ParseQuantity(want)
+	return err == nil && has && q.Cmp(w) == 0
+}
+
+// growCPU scales a CPU request (cores) by factor up to ceiling (zero: none),
+// rounded up to the millicore. It never shrinks the request.
+func growCPU(req resource.Quantity, factor, ceiling float64) resource.Quantity {
+	want := req.AsApproximateFloat64() * factor
+	if ceiling > 0 {
+		want = math.Min(want, ceiling)
+	}
+	if want <= req.AsApproximateFloat64() {
+		return req
+	}
+	return milliCPU(want)
+}
+
+// milliCPU is cores rounded up to the millicore, ignoring float noise.
+func milliCPU(cores float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(math.Ceil(cores*1000-1e-6)), resource.DecimalSI)
+}
+
+// growMem is growCPU for memory (ceiling in MiB), rounded up to the MiB.
+func growMem(req resource.Quantity, factor, ceilingMiB float64) resource.Quantity {
+	want := req.AsApproximateFloat64() * factor / (1024 * 1024)
+	if ceilingMiB > 0 {
+		want = math.Min(want, ceilingMiB)
+	}
+	if want*1024*1024 <= req.AsApproximateFloat64() {
+		return req
+	}
+	return *resource.NewQuantity(int64(math.Ceil(want))*1024*1024, resource.BinarySI)
+}
+
+// verticalFallback recommends or applies bigger requests for the target
+// while the Saturated condition has held for spec.verticalFallback.after.
+// cpuNeed and memNeed are the replicas each resource alone would need. It
+// reports whether status.verticalFallback changed.
+func (tc targetCluster) verticalFallback(ctx context.Context, u *unstructured.Unstructured, ref verticalRef,
+	dep *appsv1.Deployment, cpuNeed, memNeed, maxReplicas int32, resize bool, now time.Time) (bool, error) {
+	c := fallbackContainer(dep, ref.Container)
+	if c == nil || maxReplicas <= 0 {
+		return false, nil
+	}
+	prev, had := readVertical(u)
+	sat := meta.FindStatusCondition(getConditions(u), condSaturated)
+	if sat == nil || sat.Status != metav1.ConditionTrue || now.Sub(sat.LastTransitionTime.Time) < ref.After {
+		// A recommendation is only good while demand is past maxReplicas
+		if had && !prev.Resized {
+			unstructured.RemoveNestedField(u.Object, "status", "verticalFallback")
+			return true, nil
+		}
+		return false, nil
+	}
+	if had && prev.Resized && now.Sub(prev.Time) < ref.After {
+		return false, nil // let the last resize roll out and show in the metrics
+	}
+
+	next := verticalState{Container: c.Name, Resized: resize, Time: now}
+	next.CPUScale, next.MemScale = prev.scales(c)
+	cpuCeil, memCeil := ref.MaxCPU, ref.MaxMemMiB
+	requests := c.Resources.Requests
+	cpuReq, hasCPU := requests[corev1.ResourceCPU]
+	memReq, hasMem := requests[corev1.ResourceMemory]
+	newCPU, newMem := cpuReq, memReq
+	if hasCPU && (!resize || cpuCeil > 0) {
+		newCPU = growCPU(cpuReq, float64(cpuNeed)/float64(maxReplicas), cpuCeil)
+		next.CPUScale *= newCPU.AsApproximateFloat64() / cpuReq.AsApproximateFloat64()
+	}
+	if hasMem && (!resize || memCeil > 0) {
+		newMem = growMem(memReq, float64(memNeed)/float64(maxReplicas), memCeil)
+		next.MemScale *= newMem.AsApproximateFloat64() / memReq.AsApproximateFloat64()
+	}
+	if hasCPU {
+		next.CPU = newCPU.String()
+	}
+	if hasMem {
+		next.Memory = newMem.String()
+	}
+	if newCPU.Cmp(cpuReq) == 0 && newMem.Cmp(memReq) == 0 {
+		return false, nil // nothing left to grow: at the ceilings, or no requests to scale
+	}
+	if resize {
+		patch := client.MergeFrom(dep.DeepCopy())
+		scaleResources(c, corev1.ResourceCPU, cpuReq, newCPU)
+		scaleResources(c, corev1.ResourceMemory, memReq, newMem)
+		if err := tc.patchTarget(ctx, dep, patch); err != nil {
+			return false, fmt.Errorf("resize %s: %w", c.Name, err)
+		}
+	} else if had && !prev.Resized && prev.Container == next.Container && prev.CPU == next.CPU && prev.Memory == next.Memory {
+		return false, nil // same recommendation as last cycle
+	}
+	writeVertical(u, next)
+	return true, nil
+}
+
+// scaleResources sets c's request for name to to, and grows a limit on it by
+// the same ratio so the request-to-limit headroom is kept.
+func scaleResources(c *corev1.Container, name corev1.ResourceName, from, to resource.Quantity) {
+	if to.Cmp(from) == 0 {
+		return
+	}
+	c.Resources.Requests[name] = to
+	limit, ok := c.Resources.Limits[name]
+	if !ok || from.IsZero() {
+		return
+	}
+	ratio := to.AsApproximateFloat64() / from.AsApproximateFloat64()
+	if name == corev1.ResourceCPU {
+		c.Resources.Limits[name] = milliCPU(limit.AsApproximateFloat64() * ratio)
+	} else {
+		c.Resources.Limits[name] = growMem(limit, ratio, 0)
+	}
+}
+
+// readVertical loads status.verticalFallback; scales are stored as strings.
+func readVertical(u *unstructured.Unstructured) (verticalState, bool) {
+	m, ok, _ := unstructured.NestedMap(u.Object, "status", "verticalFallback")
+	if !ok {
+		return verticalState{}, false
+	}
+	st := verticalState{CPUScale: 1, MemScale: 1}
+	st.Container, _ = m["container"].(string)
+	st.CPU, _ = m["cpu"].(string)
+	st.Memory, _ = m["memory"].(string)
+	st.Resized, _ = m["resized"].(bool)
+	if v, _ := m["cpuScale"].(string); v != "" {
+		st.CPUScale, _ = strconv.ParseFloat(v, 64)
+	}
+	if v, _ := m["memoryScale"].(string); v != "" {
+		st.MemScale, _ = strconv.ParseFloat(v, 64)
+	}
+	if at, _ := m["time"].(string); at != "" {
+		st.Time, _ = time.Parse(time.RFC3339, at)
+	}
+	return st, true
+}
+
+func writeVertical(u *unstructured.Unstructured, st verticalState) {
+	_ = unstructured.SetNestedMap(u.Object, map[string]interface{}{
+		"container":   st.Container,
+		"cpu":         st.CPU,
+		"memory":      st.Memory,
+		"cpuScale":    strconv.FormatFloat(st.CPUScale, 'g', 6, 64),
+		"memoryScale": strconv.FormatFloat(st.MemScale, 'g', 6, 64),
+		"resized":     st.Resized,
+		"time":        st.Time.Format(time.RFC3339),
+	}, "status", "verticalFallback")
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// warmPods returns the names of the target's running pods that started at
+// least warmUp ago, plus how many are running in total.
+func (r *reconciler) warmPods(ctx context.Context, tc targetCluster, dep *appsv1.Deployment, warmUp time.Duration) (warm []string, running int, err error) {
+	pods, err := tc.runningPods(ctx, dep)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := r.clock.Now()
+	for _, p := range pods {
+		if p.Status.StartTime != nil && now.Sub(p.Status.StartTime.Time) >= warmUp {
+			warm = append(warm, p.Name)
+		}
+	}
+	return warm, len(pods), nil
+}
+
+// podRegex matches exactly the given pod names in a PromQL =~ selector.
+// Backslashes are doubled because PromQL unescapes the string literal first.
+func podRegex(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(n), `\`, `\\`)
+	}
+	return strings.Join(quoted, "|")
+}
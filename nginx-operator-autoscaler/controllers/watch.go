@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// replicasChanged passes Deployment events that can leave a CR acting on the
+// wrong replica count: creation, deletion, and spec.replicas edits. Rollouts,
+// status churn and label edits are left to the poll.
+var replicasChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldDep, ok1 := e.ObjectOld.(*appsv1.Deployment)
+		newDep, ok2 := e.ObjectNew.(*appsv1.Deployment)
+		if !ok1 || !ok2 {
+			return false
+		}
+		return replicasOrOne(oldDep) != replicasOrOne(newDep)
+	},
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// autoscalersForDeployment maps a Deployment in this cluster to the CRs that
+// target it, via the target-key index. A CR whose status already records the
+// Deployment's replica count is skipped: that change was our own write.
+func (r *reconciler) autoscalersForDeployment(ctx context.Context, obj client.Object) []ctrl.Request {
+	dep, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return nil
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	if err := r.List(ctx, list, client.MatchingFields{targetIndexKey: dep.Namespace + "/" + dep.Name}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to map Deployment to autoscalers", "deployment", client.ObjectKeyFromObject(dep))
+		return nil
+	}
+	var reqs []ctrl.Request
+	for _, item := range list.Items {
+		recorded, found, _ := unstructured.NestedInt64(item.Object, "status", "currentReplicas")
+		if found && dep.DeletionTimestamp == nil && int32(recorded) == replicasOrOne(dep) {
+			continue
+		}
+		reqs = append(reqs, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: item.GetNamespace(), Name: item.GetName()}})
+	}
+	return reqs
+}
+
+// readinessChanged passes Pod events that change how many replicas are
+// serving: a pod turning Ready or unready, and a Ready pod going away. Pods
+// that are created unready are noise until they become Ready.
+var readinessChanged = predicate.Funcs{
+	CreateFunc: func(e event.CreateEvent) bool {
+		pod, ok := e.Object.(*corev1.Pod)
+		return ok && podReady(pod)
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok1 := e.ObjectOld.(*corev1.Pod)
+		newPod, ok2 := e.ObjectNew.(*corev1.Pod)
+		return ok1 && ok2 && podReady(oldPod) != podReady(newPod)
+	},
+	DeleteFunc: func(e event.DeleteEvent) bool {
+		pod, ok := e.Object.(*corev1.Pod)
+		return ok && podReady(pod)
+	},
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// autoscalersForPod maps a Deployment's pod to the CRs targeting that
+// Deployment. The owning ReplicaSet is named <deployment>-<pod-template-hash>.
+func (r *reconciler) autoscalersForPod(ctx context.Context, obj client.Object) []ctrl.Request {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	owner := metav1.GetControllerOf(pod)
+	hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]
+	if owner == nil || owner.Kind != "ReplicaSet" || hash == "" || !strings.HasSuffix(owner.Name, "-"+hash) {
+		return nil
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(autoscalerGVK.GroupVersion().WithKind(autoscalerGVK.Kind + "List"))
+	key := pod.Namespace + "/" + strings.TrimSuffix(owner.Name, "-"+hash)
+	if err := r.List(ctx, list, client.MatchingFields{targetIndexKey: key}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to map Pod to autoscalers", "pod", client.ObjectKeyFromObject(pod))
+		return nil
+	}
+	reqs := make([]ctrl.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		reqs = append(reqs, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: item.GetNamespace(), Name: item.GetName()}})
+	}
+	return reqs
+}
+
+func podReady(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func replicasOrOne(dep *appsv1.Deployment) int32 {
+	if dep.Spec.Replicas == nil {
+		return 1
+	}
+	return *dep.Spec.Replicas
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// writeLimitedClient makes every write through it wait on one shared token
+// bucket, so thousands of CRs coming due together queue up their Deployment
+// and status updates instead of flooding the API server. Reads pass through.
+type writeLimitedClient struct {
+	client.Client
+	limiter *rate.Limiter
+}
+
+// limitWrites wraps c with a limiter of qps writes/s bursting to burst;
+// qps <= 0 returns c unchanged.
+func limitWrites(c client.Client, qps float64, burst int) client.Client {
+	if qps <= 0 {
+		return c
+	}
+	return &writeLimitedClient{Client: c, limiter: rate.NewLimiter(rate.Limit(qps), max(burst, 1))}
+}
+
+func (c *writeLimitedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *writeLimitedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *writeLimitedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *writeLimitedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *writeLimitedClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return c.Client.DeleteAllOf(ctx, obj, opts...)
+}
+
+func (c *writeLimitedClient) Status() client.SubResourceWriter {
+	return &limitedSubResource{SubResourceClient: c.Client.SubResource("status"), limiter: c.limiter}
+}
+
+func (c *writeLimitedClient) SubResource(subResource string) client.SubResourceClient {
+	return &limitedSubResource{SubResourceClient: c.Client.SubResource(subResource), limiter: c.limiter}
+}
+
+type limitedSubResource struct {
+	client.SubResourceClient
+	limiter *rate.Limiter
+}
+
+func (s *limitedSubResource) Create(ctx context.Context, obj, sub client.Object, opts ...client.SubResourceCreateOption) error {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return s.SubResourceClient.Create(ctx, obj, sub, opts...)
+}
+
+func (s *limitedSubResource) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return s.SubResourceClient.Update(ctx, obj, opts...)
+}
+
+func (s *limitedSubResource) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if err := s.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	return s.SubResourceClient.Patch(ctx, obj, patch, opts...)
+}
+
+// scaleLimiter caps how many targets the controller scales per minute, across
+// every CR. Unlike writes, a scale over the limit is not queued: it is
+// skipped and decided again on fresh metrics once a slot is free.
+type scaleLimiter struct {
+	limiter *rate.Limiter
+}
+
+// newScaleLimiter allows perMinute scales a minute, all of them at once after
+// a quiet spell; perMinute <= 0 returns nil, which allows everything.
+func newScaleLimiter(perMinute int) *scaleLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &scaleLimiter{limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), perMinute)}
+}
+
+// allow takes a slot for one scale at now, or says how long until one frees.
+func (l *scaleLimiter) allow(now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	res := l.limiter.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
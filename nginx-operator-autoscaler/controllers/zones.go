@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// zoneLabel is the well-known node label topology spread constraints key on.
+const zoneLabel = corev1.LabelTopologyZone
+
+// zoneCount is how many distinct zones the target's running pods span.
+func (tc targetCluster) zoneCount(ctx context.Context, dep *appsv1.Deployment) (int32, error) {
+	_, nodes, err := tc.podNodes(ctx, dep)
+	if err != nil {
+		return 0, err
+	}
+	zones := map[string]bool{}
+	for _, n := range nodes {
+		if z := n.Labels[zoneLabel]; z != "" {
+			zones[z] = true
+		}
+	}
+	return int32(len(zones)), nil
+}
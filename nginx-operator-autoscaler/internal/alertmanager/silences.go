@@ -0,0 +1,111 @@
+// Package alertmanager silences the alerts a scale is expected to set off
+// while it and the rollout it starts settle.
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Silencer creates silences for scale events. The event data carries how long
+// the silence lasts ("duration", a Go duration string) and, optionally, which
+// alerts it covers ("alertNames"); without a duration nothing is silenced.
+// It implements events.Sink so it can sit behind an events.Async queue.
+type Silencer struct {
+	url string
+}
+
+// New returns a Silencer for the Alertmanager at baseURL.
+func New(baseURL string) *Silencer {
+	return &Silencer{url: strings.TrimSuffix(baseURL, "/")}
+}
+
+type matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+type silence struct {
+	Matchers  []matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+}
+
+// Send silences the target ("namespace/deployment", optionally suffixed with
+// "@<cluster secret>") from the event time for the duration in its data.
+// Matchers within a silence are ANDed, so workload-level alerts (labelled
+// deployment) and pod-level alerts (labelled pod, matched by the "pods" regex
+// in the data) get a silence each; without "pods" only the former is created.
+func (s *Silencer) Send(ctx context.Context, e events.Event) error {
+	d, _ := e.Data.(map[string]interface{})
+	dur, _ := time.ParseDuration(fmt.Sprint(d["duration"]))
+	if dur <= 0 {
+		return nil
+	}
+	target, _, _ := strings.Cut(e.Subject, "@")
+	ns, name, _ := strings.Cut(target, "/")
+
+	base := []matcher{{Name: "namespace", Value: ns, IsEqual: true}}
+	if names, _ := d["alertNames"].([]string); len(names) > 0 {
+		quoted := make([]string, len(names))
+		for i, n := range names {
+			quoted[i] = regexp.QuoteMeta(n)
+		}
+		base = append(base, matcher{Name: "alertname", Value: strings.Join(quoted, "|"), IsRegex: true, IsEqual: true})
+	}
+	comment := fmt.Sprintf("Scaling %s from %v to %v replicas", e.Subject, d["from"], d["to"])
+	scoped := []matcher{{Name: "deployment", Value: name, IsEqual: true}}
+	if pods, _ := d["pods"].(string); pods != "" {
+		scoped = append(scoped, matcher{Name: "pod", Value: pods, IsRegex: true, IsEqual: true})
+	}
+	for _, m := range scoped {
+		err := s.create(ctx, silence{
+			Matchers:  append(append([]matcher{}, base...), m),
+			StartsAt:  e.Time,
+			EndsAt:    e.Time.Add(dur),
+			CreatedBy: "nginx-operator-autoscaler",
+			Comment:   comment,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Silencer) create(ctx context.Context, sil silence) error {
+	body, err := json.Marshal(sil)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/api/v2/silences", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("alertmanager returned HTTP %d creating a silence", r.StatusCode)
+	}
+	return nil
+}
+
+func (s *Silencer) Close() error { return nil }
@@ -0,0 +1,73 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+)
+
+func TestSend(t *testing.T) {
+	var got []silence
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/silences" || r.Method != http.MethodPost {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		var s silence
+		_ = json.NewDecoder(r.Body).Decode(&s)
+		got = append(got, s)
+		w.Write([]byte(`{"silenceID":"x"}`))
+	}))
+	defer srv.Close()
+
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	err := New(srv.URL+"/").Send(context.Background(), events.Event{
+		Subject: "shop/web",
+		Time:    at,
+		Data: map[string]interface{}{
+			"from": int32(3), "to": int32(6), "duration": "10m",
+			"alertNames": []string{"KubeDeploymentReplicasMismatch", "KubePodNotReady"},
+			"pods":       "web-(7d9c8|5f6b4)-[a-z0-9]+",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("created %d silences, want 2", len(got))
+	}
+	for _, s := range got {
+		if !s.StartsAt.Equal(at) || !s.EndsAt.Equal(at.Add(10*time.Minute)) || s.Comment != "Scaling shop/web from 3 to 6 replicas" {
+			t.Fatalf("silence = %+v", s)
+		}
+		if len(s.Matchers) != 3 || s.Matchers[0] != (matcher{Name: "namespace", Value: "shop", IsEqual: true}) ||
+			s.Matchers[1].Value != "KubeDeploymentReplicasMismatch|KubePodNotReady" || !s.Matchers[1].IsRegex {
+			t.Fatalf("matchers = %+v", s.Matchers)
+		}
+	}
+	if m := got[0].Matchers[2]; m.Name != "deployment" || m.Value != "web" || m.IsRegex {
+		t.Fatalf("workload matcher = %+v", m)
+	}
+	if m := got[1].Matchers[2]; m.Name != "pod" || m.Value != "web-(7d9c8|5f6b4)-[a-z0-9]+" || !m.IsRegex {
+		t.Fatalf("pod matcher = %+v", m)
+	}
+
+	got = nil
+	err = New(srv.URL).Send(context.Background(), events.Event{
+		Subject: "shop/web",
+		Time:    at,
+		Data:    map[string]interface{}{"duration": "10m", "pods": ""},
+	})
+	if err != nil || len(got) != 1 || got[0].Matchers[1].Name != "deployment" {
+		t.Fatalf("without a pod pattern: err %v, silences %+v", err, got)
+	}
+
+	got = nil
+	if err := New(srv.URL).Send(context.Background(), events.Event{Subject: "shop/web", Time: at}); err != nil || len(got) != 0 {
+		t.Fatalf("without a duration: err %v, %d silences", err, len(got))
+	}
+}
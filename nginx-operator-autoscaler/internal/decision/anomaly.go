@@ -0,0 +1,39 @@
+package decision
+
+import "math"
+
+// minBaseline is how many samples Anomalous needs before the latest two to
+// judge them at all.
+const minBaseline = 5
+
+// Anomalous reports whether the latest of values (oldest first) is a glitch
+// to discard: its modified z-score against the median absolute deviation
+// (MAD) of the samples before the latest two exceeds threshold, while the
+// sample just before it does not. Two deviating samples in a row are a real
+// change in level, not a glitch. A window without variation (MAD 0) or with
+// too few samples flags nothing. NaN samples are ignored.
+func Anomalous(values []float64, threshold float64) bool {
+	clean := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			clean = append(clean, v)
+		}
+	}
+	n := len(clean)
+	if n < minBaseline+2 {
+		return false
+	}
+	baseline := clean[:n-2]
+	med := Quantile(baseline, 0.5)
+	dev := make([]float64, len(baseline))
+	for i, v := range baseline {
+		dev[i] = math.Abs(v - med)
+	}
+	mad := Quantile(dev, 0.5)
+	if mad == 0 {
+		return false
+	}
+	// 0.6745 makes the MAD a consistent estimator of the standard deviation
+	z := func(v float64) float64 { return 0.6745 * math.Abs(v-med) / mad }
+	return z(clean[n-1]) > threshold && z(clean[n-2]) <= threshold
+}
@@ -0,0 +1,78 @@
+package decision
+
+// Replica-count parities for Policy.Parity.
+const (
+	ParityOdd  = "odd"
+	ParityEven = "even"
+)
+
+// allowed reports whether c satisfies every replica-count constraint.
+func (p Policy) allowed(c int32, in Input) bool {
+	if p.ZoneBalanced && in.Zones > 1 && c%in.Zones != 0 {
+		return false
+	}
+	if p.ReplicaMultipleOf > 1 && c%p.ReplicaMultipleOf != 0 {
+		return false
+	}
+	switch p.Parity {
+	case ParityOdd:
+		if c%2 == 0 {
+			return false
+		}
+	case ParityEven:
+		if c%2 != 0 {
+			return false
+		}
+	}
+	if len(p.AllowedReplicas) > 0 {
+		for _, a := range p.AllowedReplicas {
+			if a == c {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// fit moves a replica count onto the nearest count the policy allows,
+// preferring to round up, and never leaving [MinReplicas, MaxReplicas].
+func (p Policy) fit(v int32, in Input) int32 {
+	v = clamp32(v, p.MinReplicas, p.MaxReplicas)
+	for c := v; c <= p.MaxReplicas; c++ {
+		if p.allowed(c, in) {
+			return c
+		}
+	}
+	for c := v - 1; c >= p.MinReplicas; c-- {
+		if p.allowed(c, in) {
+			return c
+		}
+	}
+	return v // no allowed count inside the bounds; the bounds win
+}
+
+// fitStep moves current+diff onto an allowed count between it and current,
+// so a step-limited change never overshoots the step (or the budget behind
+// it) and a scale-down never rounds back up. ok is false when no allowed
+// count lies within the step. A step that leaves [MinReplicas, MaxReplicas]
+// is fitted as a whole instead: the bounds win over the step.
+func (p Policy) fitStep(current, diff int32, in Input) (n int32, ok bool) {
+	v := current + diff
+	if v < p.MinReplicas || v > p.MaxReplicas {
+		return p.fit(v, in), true
+	}
+	for ; v != current; v -= sign32(diff) {
+		if p.allowed(v, in) {
+			return v, true
+		}
+	}
+	return current, false
+}
+
+func sign32(v int32) int32 {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
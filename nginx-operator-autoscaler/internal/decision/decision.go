@@ -0,0 +1,514 @@
+// Package decision turns observed demand into a replica count. It is pure:
+// no Kubernetes or Prometheus calls, so every rule can be table/golden tested.
+package decision
+
+import (
+	"math"
+	"time"
+)
+
+// Policy is the per-autoscaler scaling configuration.
+type Policy struct {
+	MinReplicas int32
+	MaxReplicas int32
+	TargetCPU   float64 // cores per replica
+	TargetMem   float64 // MiB per replica
+	TargetRPS   float64 // requests/s per replica; zero ignores request rate
+	// TargetLatencyMs scales replicas in proportion to how far observed
+	// latency is above or below it; zero ignores latency.
+	TargetLatencyMs float64
+	// TargetExternal is how much of Input.External one replica handles (e.g.
+	// queue depth or sessions per pod); zero ignores external metrics.
+	TargetExternal float64
+	// SLOObjective (e.g. 0.999) sizes replicas to keep the error-budget burn
+	// rate, (1 - good/total) / (1 - objective), at or under 1; zero disables.
+	SLOObjective  float64
+	HysteresisPct float64
+	StepLimit     int32
+	Cooldown      time.Duration
+	// HeadroomPct inflates measured demand by this percentage, and
+	// HeadroomReplicas adds a fixed number of idle replicas on top, so spikes
+	// are absorbed while new pods start.
+	HeadroomPct      float64
+	HeadroomReplicas int32
+	// SpotFactor (e.g. 1.3) inflates demand by this much for the fraction of
+	// pods on spot/preemptible nodes (Input.SpotFraction); 0 or 1 disables.
+	SpotFactor float64
+	// ZoneBalanced rounds replica counts up to a multiple of Input.Zones so
+	// topology spread constraints don't leave one zone short.
+	ZoneBalanced bool
+	// ReplicaMultipleOf, Parity and AllowedReplicas restrict replica counts,
+	// e.g. for quorum-based workloads that must never run an even count.
+	ReplicaMultipleOf int32
+	Parity            string // "", ParityOdd or ParityEven
+	AllowedReplicas   []int32
+	// MaxHourlyCost caps desired replicas at what the budget affords, given
+	// Input.ReplicaHourlyCost; zero disables. MinReplicas still wins.
+	MaxHourlyCost float64
+	// MaxErrorRatio blocks scale-down while the observed error ratio exceeds
+	// it (shedding replicas mid-incident makes things worse); zero disables.
+	MaxErrorRatio float64
+	// ScaleDownDelayAfterRollout forbids scaling down for this long after the
+	// target rolled out a new revision; zero disables the window.
+	ScaleDownDelayAfterRollout time.Duration
+	// DerivativeThreshold (cores per minute) sizes for the CPU demand expected
+	// DerivativeLookahead from now whenever Input.CPUSlope exceeds it, so
+	// replicas start before a ramp crosses TargetCPU; zero disables.
+	DerivativeThreshold float64
+	DerivativeLookahead time.Duration
+	// BudgetReplicas caps churn: a token bucket holding this many replica
+	// changes, refilled evenly over BudgetWindow. Every replica added or
+	// removed spends a token, independent of Cooldown; zero disables.
+	BudgetReplicas int32
+	BudgetWindow   time.Duration
+	// RequiredSamples holds a scale until this many consecutive polls have
+	// wanted to move the same way (see Input.PrevDirection); 0 or 1 disables.
+	RequiredSamples int32
+	// ConfirmationDelay records a proposed change as pending and applies it
+	// only if a change the same way is still wanted this long after it was
+	// first proposed. Once confirmed, further moves that way apply at once
+	// until a poll lands inside the band; zero disables.
+	ConfirmationDelay time.Duration
+	// Drain holds scale-down until the least-loaded pod's drain metric
+	// (Input.DrainLoad, e.g. active connections) is at or under
+	// DrainThreshold, then steps down one pod at a time.
+	Drain          bool
+	DrainThreshold float64
+	// Primary names the one signal (a Signal* constant) that sets the target.
+	// Every other signal becomes a guard: it forces a scale-up when it wants
+	// more than Current, and vetoes a scale-down while it wants more than the
+	// primary, but never sets the target itself. Empty sizes on the largest
+	// of all signals.
+	Primary string
+	// Rounding turns each signal's fractional replica estimate into a count:
+	// RoundCeil (the default, ""), RoundFloor or RoundNearest.
+	Rounding string
+	// MinChangeReplicas holds any change smaller than this many replicas
+	// while Current is above MinChangeAbove, so a large deployment isn't
+	// nudged by one replica of rounding noise; 0 or 1 disables. Moving back
+	// inside MinReplicas..MaxReplicas is never held.
+	MinChangeReplicas int32
+	MinChangeAbove    int32
+}
+
+// Rounding modes for Policy.Rounding.
+const (
+	RoundCeil    = "ceil"
+	RoundFloor   = "floor"
+	RoundNearest = "nearest"
+)
+
+// Signals Policy.Primary can name. The CPU trend is always a guard.
+const (
+	SignalCPU      = "cpu"
+	SignalMemory   = "memory"
+	SignalRPS      = "rps"
+	SignalLatency  = "latency"
+	SignalSLO      = "slo"
+	SignalExternal = "external"
+)
+
+// Input is what was observed this cycle.
+type Input struct {
+	Current           int32
+	CPUCores          float64
+	CPUSlope          float64 // change in CPUCores per minute, when the policy has DerivativeThreshold
+	MemMiB            float64
+	RPS               float64   // edge request rate, when the policy has TargetRPS
+	LatencyMs         float64   // observed request latency, when the policy has TargetLatencyMs
+	External          float64   // total demand from the spec's external source, when the policy has TargetExternal
+	ErrorRatio        float64   // e.g. 5xx / all requests, when the policy has MaxErrorRatio
+	GoodRate          float64   // SLO good events/s
+	TotalRate         float64   // SLO total events/s
+	ReplicaHourlyCost float64   // cost of one replica per hour, when the policy has MaxHourlyCost
+	SpotFraction      float64   // share of running pods on spot nodes, 0..1
+	Zones             int32     // topology zones the target spreads across
+	LastScale         time.Time // zero if never scaled
+	LastRollout       time.Time // zero if no rollout has been observed
+	BudgetTokens      float64   // tokens left at BudgetUpdated
+	BudgetUpdated     time.Time // zero means the bucket is full
+	PrevDirection     string    // Result.Direction of the previous poll
+	PrevSamples       int32     // Result.Samples of the previous poll
+	PendingDirection  string    // Result.PendingDirection of the previous poll
+	PendingSince      time.Time // Result.PendingSince of the previous poll
+	DrainLoad         float64   // lowest per-pod drain metric, when the policy has Drain; NaN if unknown
+	Now               time.Time
+}
+
+// Reasons a decision did or did not scale.
+const (
+	ReasonScale            = "Scale"
+	ReasonWithinHysteresis = "WithinHysteresis"
+	ReasonCooldown         = "Cooldown"
+	ReasonRecentRollout    = "RecentRollout"
+	ReasonErrorRateHigh    = "ErrorRateHigh"
+	ReasonBudgetExhausted  = "BudgetExhausted"
+	ReasonUnconfirmed      = "Unconfirmed"
+	ReasonPending          = "Pending"
+	ReasonDraining         = "Draining"
+	ReasonInvalidInput     = "InvalidInput"
+	ReasonGuardVeto        = "GuardVeto"
+	ReasonBelowMinChange   = "BelowMinChange"
+	ReasonStepNotAllowed   = "StepNotAllowed"
+)
+
+// Directions a poll wanted to scale in, for RequiredSamples and ConfirmationDelay.
+const (
+	DirectionUp   = "Up"
+	DirectionDown = "Down"
+)
+
+// Limits that pinned Desired below what demand asked for.
+const (
+	LimitCostCap = "CostCap"
+)
+
+// Result explains a decision: the per-metric demand, the clamped target,
+// and the replica count to apply (equal to Current when not scaling).
+// CooldownRemaining is how long a held-back change must still wait, whether
+// held by the cooldown, the post-rollout scale-down window, the budget or
+// the confirmation delay.
+type Result struct {
+	CPUReplicas       int32
+	MemReplicas       int32
+	RPSReplicas       int32
+	LatencyReplicas   int32
+	SLOReplicas       int32
+	TrendReplicas     int32 // from CPU projected DerivativeLookahead ahead
+	ExternalReplicas  int32 // from Input.External, when the policy has TargetExternal
+	GuardReplicas     int32 // the largest non-primary signal, when the policy has Primary
+	BurnRate          float64
+	Desired           int32
+	LimitedBy         string // a Limit* constant when Desired was capped below demand
+	New               int32
+	Scale             bool
+	Reason            string
+	CooldownRemaining time.Duration
+	// Direction and Samples track the streak of polls wanting the same move,
+	// when the policy has RequiredSamples; Direction is "" inside the band.
+	Direction string
+	Samples   int32
+	// PendingDirection and PendingSince are the change awaiting (or past)
+	// confirmation, when the policy has ConfirmationDelay; empty inside the band.
+	PendingDirection string
+	PendingSince     time.Time
+}
+
+// Decide holds on input that isn't Usable (ReasonInvalidInput); otherwise it
+// applies, in order: per-metric sizing (strictest of CPU, memory, request
+// rate, latency, SLO burn rate, the CPU trend and the external metric, each
+// rounded per Rounding, or the Primary one with the rest as guards, plus spot
+// and headroom), the cost cap, min/max clamping and replica-count constraints
+// (see fit), the hysteresis band, the minimum change, sample confirmation,
+// the confirmation delay, the guard veto, the error-rate and post-rollout
+// scale-down guards, cooldown, the drain gate, and the step limit, narrowed
+// to what the scaling budget has left.
+func Decide(p Policy, in Input) Result {
+	res := Result{New: in.Current}
+	if !p.usableInput(in) {
+		// NaN, ±Inf or a negative rate (counter reset) says nothing about
+		// demand; hold rather than let it read as zero or as infinite load
+		res.Desired = in.Current
+		res.Reason = ReasonInvalidInput
+		return res
+	}
+
+	// replicas_cpu = ceil(totalCPU*headroom / targetCPU), replicas_mem = ceil(totalMemMiB*headroom / targetMem)
+	headroom := 1 + p.HeadroomPct/100
+	res.CPUReplicas = p.replicas(in.CPUCores * headroom / p.TargetCPU)
+	res.MemReplicas = p.replicas(in.MemMiB * headroom / p.TargetMem)
+	if p.TargetRPS > 0 {
+		res.RPSReplicas = p.replicas(in.RPS * headroom / p.TargetRPS)
+	}
+	if p.TargetLatencyMs > 0 && in.LatencyMs > 0 {
+		// latency ~ 1/replicas: replicas_latency = ceil(current * observed / target)
+		res.LatencyReplicas = p.replicas(float64(in.Current) * in.LatencyMs / p.TargetLatencyMs)
+	}
+	if p.SLOObjective > 0 && p.SLOObjective < 1 && in.TotalRate > 0 {
+		// burn ~ 1/replicas like latency: replicas_slo = ceil(current * burnRate)
+		res.BurnRate = (1 - in.GoodRate/in.TotalRate) / (1 - p.SLOObjective)
+		res.SLOReplicas = p.replicas(float64(in.Current) * res.BurnRate)
+	}
+	if p.TargetExternal > 0 {
+		res.ExternalReplicas = p.replicas(in.External * headroom / p.TargetExternal)
+	}
+	if p.DerivativeThreshold > 0 && in.CPUSlope > p.DerivativeThreshold {
+		// Pods take minutes to become ready; size for where a steep ramp will be by then
+		projected := in.CPUCores + in.CPUSlope*p.DerivativeLookahead.Minutes()
+		res.TrendReplicas = p.replicas(projected * headroom / p.TargetCPU)
+	}
+	need := max32(max32(res.CPUReplicas, res.MemReplicas), max32(res.RPSReplicas, res.LatencyReplicas))
+	need = max32(need, max32(res.SLOReplicas, res.TrendReplicas))
+	need = max32(need, res.ExternalReplicas)
+	primary, guarded := res.primary(p.Primary)
+	if guarded {
+		res.GuardReplicas = res.guard(p.Primary)
+		need = primary
+		if res.GuardReplicas > in.Current {
+			// A guard past current capacity forces the scale-up it asks for
+			need = max32(need, res.GuardReplicas)
+		}
+	}
+	want := need
+	if p.SpotFactor > 1 && in.SpotFraction > 0 {
+		// Interruptions take out spot pods; over-provision just that share
+		want = ceilReplicas(float64(need) * (1 + in.SpotFraction*(p.SpotFactor-1)))
+	}
+	want += p.HeadroomReplicas
+	if p.MaxHourlyCost > 0 && in.ReplicaHourlyCost > 0 {
+		// Pin at the budget rather than refusing to scale at all
+		if affordable := int32(math.Floor(p.MaxHourlyCost / in.ReplicaHourlyCost)); want > affordable {
+			want = affordable
+			res.LimitedBy = LimitCostCap
+		}
+	}
+	res.Desired = p.fit(want, in)
+
+	if !OutsideBand(in.Current, res.Desired, p.HysteresisPct) {
+		res.Reason = ReasonWithinHysteresis
+		return res
+	}
+
+	// On a large deployment a one-replica move is rounding noise, not demand
+	if p.MinChangeReplicas > 1 && in.Current > p.MinChangeAbove &&
+		in.Current >= p.MinReplicas && in.Current <= p.MaxReplicas &&
+		abs32(res.Desired-in.Current) < p.MinChangeReplicas {
+		res.Reason = ReasonBelowMinChange
+		return res
+	}
+
+	// One spiky sample must not move replicas; wait for the move to repeat
+	if p.RequiredSamples > 1 {
+		res.Direction, res.Samples = DirectionUp, 1
+		if res.Desired < in.Current {
+			res.Direction = DirectionDown
+		}
+		if in.PrevDirection == res.Direction {
+			res.Samples = in.PrevSamples + 1
+		}
+		if res.Samples < p.RequiredSamples {
+			res.Reason = ReasonUnconfirmed
+			return res
+		}
+	}
+
+	// A transient spike is gone by the time the delay runs out; a sustained
+	// ramp was confirmed once and keeps moving
+	if p.ConfirmationDelay > 0 {
+		res.PendingDirection, res.PendingSince = DirectionUp, in.Now
+		if res.Desired < in.Current {
+			res.PendingDirection = DirectionDown
+		}
+		if in.PendingDirection == res.PendingDirection && !in.PendingSince.IsZero() {
+			res.PendingSince = in.PendingSince
+		}
+		if waited := in.Now.Sub(res.PendingSince); waited < p.ConfirmationDelay {
+			res.Reason = ReasonPending
+			res.CooldownRemaining = p.ConfirmationDelay - waited
+			return res
+		}
+	}
+
+	// A guard still wanting more than the primary holds what is running
+	if res.Desired < in.Current && guarded && res.GuardReplicas > primary {
+		res.Reason = ReasonGuardVeto
+		return res
+	}
+
+	// Never shed replicas during an incident; an unknown (NaN) ratio counts as high
+	if res.Desired < in.Current && p.MaxErrorRatio > 0 && !(in.ErrorRatio <= p.MaxErrorRatio) {
+		res.Reason = ReasonErrorRateHigh
+		return res
+	}
+
+	// Freshly rolled-out pods look idle until caches warm up; don't shrink on that
+	if res.Desired < in.Current && !in.LastRollout.IsZero() {
+		if since := in.Now.Sub(in.LastRollout); since < p.ScaleDownDelayAfterRollout {
+			res.Reason = ReasonRecentRollout
+			res.CooldownRemaining = p.ScaleDownDelayAfterRollout - since
+			return res
+		}
+	}
+
+	if !in.LastScale.IsZero() {
+		if since := in.Now.Sub(in.LastScale); since < p.Cooldown {
+			res.Reason = ReasonCooldown
+			res.CooldownRemaining = p.Cooldown - since
+			return res
+		}
+	}
+
+	step := p.StepLimit
+	// Long-lived connections are shed one pod at a time, and only once one is idle enough
+	if res.Desired < in.Current && p.Drain {
+		if !(in.DrainLoad <= p.DrainThreshold) {
+			res.Reason = ReasonDraining
+			return res
+		}
+		step = 1
+	}
+	if p.BudgetReplicas > 0 {
+		tokens := p.BudgetAvailable(in.BudgetTokens, in.BudgetUpdated, in.Now)
+		if tokens < 1 {
+			res.Reason = ReasonBudgetExhausted
+			res.CooldownRemaining = time.Duration((1 - tokens) / float64(p.BudgetReplicas) * float64(p.BudgetWindow))
+			return res
+		}
+		step = min32(step, int32(tokens))
+	}
+
+	diff := int32(0)
+	if res.Desired > in.Current {
+		diff = min32(res.Desired-in.Current, step)
+	} else if res.Desired < in.Current {
+		diff = -min32(in.Current-res.Desired, step)
+	}
+	if diff == 0 {
+		res.New = p.fit(in.Current, in)
+	} else if n, ok := p.fitStep(in.Current, diff, in); ok {
+		res.New = n
+	} else {
+		// e.g. odd counts only and a step of 1: every count in reach is disallowed
+		res.Reason = ReasonStepNotAllowed
+		return res
+	}
+	res.Scale = res.New != in.Current
+	res.Reason = ReasonScale
+	return res
+}
+
+// signals are the per-signal replica counts by Signal* name.
+func (r Result) signals() map[string]int32 {
+	return map[string]int32{
+		SignalCPU:      r.CPUReplicas,
+		SignalMemory:   r.MemReplicas,
+		SignalRPS:      r.RPSReplicas,
+		SignalLatency:  r.LatencyReplicas,
+		SignalSLO:      r.SLOReplicas,
+		SignalExternal: r.ExternalReplicas,
+	}
+}
+
+// primary is what the signal named name asks for; ok is false when name
+// isn't a signal, and all signals are then sized symmetrically.
+func (r Result) primary(name string) (replicas int32, ok bool) {
+	replicas, ok = r.signals()[name]
+	return replicas, ok
+}
+
+// guard is the most any signal but name asks for, the CPU trend included.
+func (r Result) guard(name string) int32 {
+	g := r.TrendReplicas
+	for signal, replicas := range r.signals() {
+		if signal != name {
+			g = max32(g, replicas)
+		}
+	}
+	return g
+}
+
+// BudgetAvailable is the scaling budget left at now, given tokens left at
+// updated: the bucket refills BudgetReplicas every BudgetWindow, up to full.
+func (p Policy) BudgetAvailable(tokens float64, updated, now time.Time) float64 {
+	capacity := float64(p.BudgetReplicas)
+	if updated.IsZero() || p.BudgetWindow <= 0 {
+		return capacity
+	}
+	tokens += now.Sub(updated).Seconds() / p.BudgetWindow.Seconds() * capacity
+	return math.Min(math.Max(tokens, 0), capacity)
+}
+
+// OutsideBand reports whether desired differs from current by more than ±hysteresisPct.
+func OutsideBand(current, desired int32, hysteresisPct float64) bool {
+	if current == desired {
+		return false
+	}
+	low := float64(current) * (1.0 - hysteresisPct/100.0)
+	high := float64(current) * (1.0 + hysteresisPct/100.0)
+	return float64(desired) < low || float64(desired) > high
+}
+
+// Usable reports whether v is a sample demand can be sized from: finite and
+// not negative. Rates go negative when a counter resets mid-window.
+func Usable(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0) && v >= 0
+}
+
+// usableInput reports whether every signal p sizes from is Usable. CPUSlope
+// may be negative (falling load) but must be finite.
+func (p Policy) usableInput(in Input) bool {
+	switch {
+	case !Usable(in.CPUCores) || !Usable(in.MemMiB):
+		return false
+	case p.TargetRPS > 0 && !Usable(in.RPS):
+		return false
+	case p.TargetLatencyMs > 0 && !Usable(in.LatencyMs):
+		return false
+	case p.TargetExternal > 0 && !Usable(in.External):
+		return false
+	case p.SLOObjective > 0 && (!Usable(in.GoodRate) || !Usable(in.TotalRate)):
+		return false
+	case p.DerivativeThreshold > 0 && (math.IsNaN(in.CPUSlope) || math.IsInf(in.CPUSlope, 0)):
+		return false
+	}
+	return true
+}
+
+// maxEstimate caps replica estimates well below MaxInt32, so adding
+// HeadroomReplicas afterwards can't overflow.
+const maxEstimate = 1 << 30
+
+// ceilReplicas rounds a replica estimate up, saturating at maxEstimate
+// instead of overflowing on absurd samples (1e308) and giving 0 for NaN.
+func ceilReplicas(v float64) int32 {
+	switch {
+	case !(v > 0):
+		return 0
+	case v >= maxEstimate:
+		return maxEstimate
+	}
+	return int32(math.Ceil(v))
+}
+
+// replicas rounds a signal's replica estimate the policy's way, saturating
+// and treating NaN like ceilReplicas.
+func (p Policy) replicas(v float64) int32 {
+	switch p.Rounding {
+	case RoundFloor:
+		v = math.Floor(v)
+	case RoundNearest:
+		v = math.Round(v)
+	}
+	return ceilReplicas(v)
+}
+
+func clamp32(v, lo, hi int32) int32 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
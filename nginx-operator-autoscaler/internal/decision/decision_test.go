@@ -0,0 +1,414 @@
+package decision
+
+import (
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden from current behavior")
+
+// fixture is one documented scenario in testdata/<name>.json. Durations are
+// strings so the fixtures stay readable; sinceLastScale "" means never scaled,
+// sinceRollout "" means no rollout observed, sinceBudgetUpdate "" means a
+// full scaling budget and sincePending "" means nothing is pending.
+type fixture struct {
+	Description string `json:"description"`
+	Policy      struct {
+		MinReplicas                int32   `json:"minReplicas"`
+		MaxReplicas                int32   `json:"maxReplicas"`
+		TargetCPU                  float64 `json:"targetCPU"`
+		TargetMem                  float64 `json:"targetMem"`
+		TargetRPS                  float64 `json:"targetRPS"`
+		TargetLatencyMs            float64 `json:"targetLatencyMs"`
+		TargetExternal             float64 `json:"targetExternal"`
+		HysteresisPct              float64 `json:"hysteresisPct"`
+		StepLimit                  int32   `json:"stepLimit"`
+		Cooldown                   string  `json:"cooldown"`
+		ZoneBalanced               bool    `json:"zoneBalanced"`
+		ReplicaMultipleOf          int32   `json:"replicaMultipleOf"`
+		Parity                     string  `json:"parity"`
+		AllowedReplicas            []int32 `json:"allowedReplicas"`
+		SpotFactor                 float64 `json:"spotFactor"`
+		MaxHourlyCost              float64 `json:"maxHourlyCost"`
+		SLOObjective               float64 `json:"sloObjective"`
+		MaxErrorRatio              float64 `json:"maxErrorRatio"`
+		HeadroomPct                float64 `json:"headroomPct"`
+		HeadroomReplicas           int32   `json:"headroomReplicas"`
+		ScaleDownDelayAfterRollout string  `json:"scaleDownDelayAfterRollout"`
+		DerivativeThreshold        float64 `json:"derivativeThreshold"`
+		DerivativeLookahead        string  `json:"derivativeLookahead"`
+		BudgetReplicas             int32   `json:"budgetReplicas"`
+		BudgetWindow               string  `json:"budgetWindow"`
+		RequiredSamples            int32   `json:"requiredSamples"`
+		ConfirmationDelay          string  `json:"confirmationDelay"`
+		Drain                      bool    `json:"drain"`
+		DrainThreshold             float64 `json:"drainThreshold"`
+		Primary                    string  `json:"primary"`
+		Rounding                   string  `json:"rounding"`
+		MinChangeReplicas          int32   `json:"minChangeReplicas"`
+		MinChangeAbove             int32   `json:"minChangeAbove"`
+	} `json:"policy"`
+	Input struct {
+		Current           int32   `json:"current"`
+		CPUCores          float64 `json:"cpuCores"`
+		CPUSlope          float64 `json:"cpuSlope"`
+		MemMiB            float64 `json:"memMiB"`
+		RPS               float64 `json:"rps"`
+		LatencyMs         float64 `json:"latencyMs"`
+		External          float64 `json:"external"`
+		ErrorRatio        float64 `json:"errorRatio"`
+		GoodRate          float64 `json:"goodRate"`
+		TotalRate         float64 `json:"totalRate"`
+		ReplicaHourlyCost float64 `json:"replicaHourlyCost"`
+		SpotFraction      float64 `json:"spotFraction"`
+		Zones             int32   `json:"zones"`
+		SinceLastScale    string  `json:"sinceLastScale"`
+		SinceRollout      string  `json:"sinceRollout"`
+		BudgetTokens      float64 `json:"budgetTokens"`
+		SinceBudgetUpdate string  `json:"sinceBudgetUpdate"`
+		PrevDirection     string  `json:"prevDirection"`
+		PrevSamples       int32   `json:"prevSamples"`
+		PendingDirection  string  `json:"pendingDirection"`
+		SincePending      string  `json:"sincePending"`
+		DrainLoad         float64 `json:"drainLoad"`
+	} `json:"input"`
+}
+
+// golden is the rendered Result compared against testdata/<name>.golden.
+type golden struct {
+	CPUReplicas       int32  `json:"cpuReplicas"`
+	MemReplicas       int32  `json:"memReplicas"`
+	RPSReplicas       int32  `json:"rpsReplicas,omitempty"`
+	LatencyReplicas   int32  `json:"latencyReplicas,omitempty"`
+	SLOReplicas       int32  `json:"sloReplicas,omitempty"`
+	TrendReplicas     int32  `json:"trendReplicas,omitempty"`
+	ExternalReplicas  int32  `json:"externalReplicas,omitempty"`
+	GuardReplicas     int32  `json:"guardReplicas,omitempty"`
+	BurnRate          string `json:"burnRate,omitempty"`
+	Desired           int32  `json:"desired"`
+	LimitedBy         string `json:"limitedBy,omitempty"`
+	New               int32  `json:"new"`
+	Scale             bool   `json:"scale"`
+	Reason            string `json:"reason"`
+	CooldownRemaining string `json:"cooldownRemaining,omitempty"`
+	Direction         string `json:"direction,omitempty"`
+	Samples           int32  `json:"samples,omitempty"`
+	PendingDirection  string `json:"pendingDirection,omitempty"`
+	PendingFor        string `json:"pendingFor,omitempty"`
+}
+
+func mustDuration(t *testing.T, s string) time.Duration {
+	t.Helper()
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		t.Fatalf("bad duration %q: %v", s, err)
+	}
+	return d
+}
+
+func TestDecideGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures found: %v", err)
+	}
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fx fixture
+			if err := json.Unmarshal(raw, &fx); err != nil {
+				t.Fatalf("decode fixture: %v", err)
+			}
+
+			p := Policy{
+				MinReplicas:                fx.Policy.MinReplicas,
+				MaxReplicas:                fx.Policy.MaxReplicas,
+				TargetCPU:                  fx.Policy.TargetCPU,
+				TargetMem:                  fx.Policy.TargetMem,
+				TargetRPS:                  fx.Policy.TargetRPS,
+				TargetLatencyMs:            fx.Policy.TargetLatencyMs,
+				TargetExternal:             fx.Policy.TargetExternal,
+				HysteresisPct:              fx.Policy.HysteresisPct,
+				StepLimit:                  fx.Policy.StepLimit,
+				Cooldown:                   mustDuration(t, fx.Policy.Cooldown),
+				ZoneBalanced:               fx.Policy.ZoneBalanced,
+				ReplicaMultipleOf:          fx.Policy.ReplicaMultipleOf,
+				Parity:                     fx.Policy.Parity,
+				AllowedReplicas:            fx.Policy.AllowedReplicas,
+				SpotFactor:                 fx.Policy.SpotFactor,
+				MaxHourlyCost:              fx.Policy.MaxHourlyCost,
+				SLOObjective:               fx.Policy.SLOObjective,
+				MaxErrorRatio:              fx.Policy.MaxErrorRatio,
+				HeadroomPct:                fx.Policy.HeadroomPct,
+				HeadroomReplicas:           fx.Policy.HeadroomReplicas,
+				ScaleDownDelayAfterRollout: mustDuration(t, fx.Policy.ScaleDownDelayAfterRollout),
+				DerivativeThreshold:        fx.Policy.DerivativeThreshold,
+				DerivativeLookahead:        mustDuration(t, fx.Policy.DerivativeLookahead),
+				BudgetReplicas:             fx.Policy.BudgetReplicas,
+				BudgetWindow:               mustDuration(t, fx.Policy.BudgetWindow),
+				RequiredSamples:            fx.Policy.RequiredSamples,
+				ConfirmationDelay:          mustDuration(t, fx.Policy.ConfirmationDelay),
+				Drain:                      fx.Policy.Drain,
+				DrainThreshold:             fx.Policy.DrainThreshold,
+				Primary:                    fx.Policy.Primary,
+				Rounding:                   fx.Policy.Rounding,
+				MinChangeReplicas:          fx.Policy.MinChangeReplicas,
+				MinChangeAbove:             fx.Policy.MinChangeAbove,
+			}
+			in := Input{
+				Current:           fx.Input.Current,
+				CPUCores:          fx.Input.CPUCores,
+				CPUSlope:          fx.Input.CPUSlope,
+				MemMiB:            fx.Input.MemMiB,
+				RPS:               fx.Input.RPS,
+				LatencyMs:         fx.Input.LatencyMs,
+				External:          fx.Input.External,
+				ErrorRatio:        fx.Input.ErrorRatio,
+				GoodRate:          fx.Input.GoodRate,
+				TotalRate:         fx.Input.TotalRate,
+				ReplicaHourlyCost: fx.Input.ReplicaHourlyCost,
+				SpotFraction:      fx.Input.SpotFraction,
+				Zones:             fx.Input.Zones,
+				BudgetTokens:      fx.Input.BudgetTokens,
+				PrevDirection:     fx.Input.PrevDirection,
+				PrevSamples:       fx.Input.PrevSamples,
+				PendingDirection:  fx.Input.PendingDirection,
+				DrainLoad:         fx.Input.DrainLoad,
+				Now:               now,
+			}
+			if fx.Input.SinceLastScale != "" {
+				in.LastScale = now.Add(-mustDuration(t, fx.Input.SinceLastScale))
+			}
+			if fx.Input.SinceRollout != "" {
+				in.LastRollout = now.Add(-mustDuration(t, fx.Input.SinceRollout))
+			}
+			if fx.Input.SinceBudgetUpdate != "" {
+				in.BudgetUpdated = now.Add(-mustDuration(t, fx.Input.SinceBudgetUpdate))
+			}
+			if fx.Input.SincePending != "" {
+				in.PendingSince = now.Add(-mustDuration(t, fx.Input.SincePending))
+			}
+
+			res := Decide(p, in)
+			g := golden{
+				CPUReplicas:      res.CPUReplicas,
+				MemReplicas:      res.MemReplicas,
+				RPSReplicas:      res.RPSReplicas,
+				LatencyReplicas:  res.LatencyReplicas,
+				SLOReplicas:      res.SLOReplicas,
+				TrendReplicas:    res.TrendReplicas,
+				ExternalReplicas: res.ExternalReplicas,
+				GuardReplicas:    res.GuardReplicas,
+				Desired:          res.Desired,
+				LimitedBy:        res.LimitedBy,
+				New:              res.New,
+				Scale:            res.Scale,
+				Reason:           res.Reason,
+				Direction:        res.Direction,
+				Samples:          res.Samples,
+			}
+			if res.PendingDirection != "" {
+				g.PendingDirection = res.PendingDirection
+				g.PendingFor = now.Sub(res.PendingSince).String()
+			}
+			if res.BurnRate > 0 {
+				g.BurnRate = strconv.FormatFloat(res.BurnRate, 'f', 2, 64)
+			}
+			if res.CooldownRemaining > 0 {
+				g.CooldownRemaining = res.CooldownRemaining.String()
+			}
+			got, _ := json.MarshalIndent(g, "", "  ")
+			got = append(got, '\n')
+
+			goldenPath := filepath.Join("testdata", name+".golden")
+			if *update {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden (run with -update): %v", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s\n--- got\n%s--- want\n%s", fx.Description, got, want)
+			}
+		})
+	}
+}
+
+func TestOutsideBand(t *testing.T) {
+	cases := []struct {
+		current, desired int32
+		pct              float64
+		want             bool
+	}{
+		{10, 10, 10, false},
+		{10, 11, 10, false}, // exactly on the +10% edge is inside
+		{10, 12, 10, true},
+		{10, 9, 10, false}, // exactly on the -10% edge is inside
+		{10, 8, 10, true},
+		{1, 2, 10, true},
+		{3, 2, 50, false},
+	}
+	for _, c := range cases {
+		if got := OutsideBand(c.current, c.desired, c.pct); got != c.want {
+			t.Errorf("OutsideBand(%d, %d, %v) = %v, want %v", c.current, c.desired, c.pct, got, c.want)
+		}
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	cases := []struct {
+		total   int32
+		weights []float64
+		want    []int32
+	}{
+		{10, []float64{1, 1}, []int32{5, 5}},
+		{10, []float64{3, 1}, []int32{8, 2}}, // 7.5/2.5: tie on remainder goes to the first
+		{7, []float64{1, 1, 1}, []int32{3, 2, 2}},
+		{5, []float64{2, 0, 1}, []int32{3, 0, 2}},
+		{4, []float64{0, 0}, []int32{2, 2}},
+		{0, []float64{1, 2}, []int32{0, 0}},
+	}
+	for _, c := range cases {
+		got := Distribute(c.total, c.weights)
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("Distribute(%d, %v) = %v, want %v", c.total, c.weights, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDecideHoldsOnNonFiniteInput(t *testing.T) {
+	p := Policy{MinReplicas: 2, MaxReplicas: 20, TargetCPU: 0.2, TargetMem: 300, TargetRPS: 50, HysteresisPct: 10, StepLimit: 5}
+	for name, in := range map[string]Input{
+		"cpu NaN":  {Current: 6, CPUCores: math.NaN(), MemMiB: 900},
+		"mem +Inf": {Current: 6, CPUCores: 1, MemMiB: math.Inf(1)},
+		"rps -Inf": {Current: 6, CPUCores: 1, MemMiB: 900, RPS: math.Inf(-1)},
+	} {
+		if res := Decide(p, in); res.Reason != ReasonInvalidInput || res.New != 6 || res.Scale {
+			t.Errorf("%s: Decide = %+v; want held at 6 with %s", name, res, ReasonInvalidInput)
+		}
+	}
+	if res := Decide(p, Input{Current: 6, CPUCores: 1.2, MemMiB: 900, RPS: 300}); res.Reason == ReasonInvalidInput {
+		t.Errorf("finite input rejected: %+v", res)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	cases := []struct {
+		values []float64
+		q      float64
+		want   float64
+	}{
+		{[]float64{1, 2, 3, 4, 5}, 0.5, 3},
+		{[]float64{5, 1, 4, 2, 3}, 0.9, 4.6}, // unsorted input, interpolated
+		{[]float64{1, 2, 3, 4, 5}, 1, 5},
+		{[]float64{1, 2, 3, 4, 5}, 0, 1},
+		{[]float64{2, math.NaN(), 4}, 0.5, 3}, // NaN dropped
+		{[]float64{2, math.Inf(1), 4}, 1, 4},  // Inf dropped
+		{nil, 0.9, 0},
+	}
+	for _, c := range cases {
+		if got := Quantile(c.values, c.q); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("Quantile(%v, %v) = %v, want %v", c.values, c.q, got, c.want)
+		}
+	}
+}
+
+func TestLinearFit(t *testing.T) {
+	cases := []struct {
+		x, y             []float64
+		intercept, slope float64
+		n                int
+		ok               bool
+	}{
+		{[]float64{10, 20, 30}, []float64{0.15, 0.25, 0.35}, 0.05, 0.01, 3, true},
+		{[]float64{10, math.NaN(), 30, 40}, []float64{1, 5, 3, math.Inf(1)}, 0, 0.1, 2, true}, // non-finite pairs dropped
+		{[]float64{20, 20, 20}, []float64{1, 2, 3}, 0, 0, 3, false},                           // no spread in x
+		{[]float64{1}, []float64{1}, 0, 0, 1, false},
+	}
+	for _, c := range cases {
+		intercept, slope, n, ok := LinearFit(c.x, c.y)
+		if ok != c.ok || n != c.n || math.Abs(intercept-c.intercept) > 1e-9 || math.Abs(slope-c.slope) > 1e-9 {
+			t.Errorf("LinearFit(%v, %v) = %v, %v, %d, %v; want %v, %v, %d, %v",
+				c.x, c.y, intercept, slope, n, ok, c.intercept, c.slope, c.n, c.ok)
+		}
+	}
+}
+
+func TestAnomalous(t *testing.T) {
+	base := []float64{1.0, 1.1, 0.9, 1.0, 1.05, 0.95, 1.0}
+	cases := []struct {
+		name   string
+		values []float64
+		want   bool
+	}{
+		{"steady", append(base, 1.0, 1.1), false},
+		{"single spike", append(base, 1.0, 9.0), true},
+		{"counter reset dip", append(base, 1.0, 0.0), true},
+		{"level shift", append(base, 4.0, 4.2), false},
+		{"too few samples", []float64{1, 1, 1, 9}, false},
+		{"flat window", []float64{2, 2, 2, 2, 2, 2, 2, 9}, false},
+		{"NaN ignored", append(append([]float64{math.NaN()}, base...), 1.0, 9.0), true},
+	}
+	for _, c := range cases {
+		if got := Anomalous(c.values, 3.5); got != c.want {
+			t.Errorf("%s: Anomalous(%v) = %v, want %v", c.name, c.values, got, c.want)
+		}
+	}
+}
+
+func TestTune(t *testing.T) {
+	repeat := func(pattern []float64, times int) []float64 {
+		var out []float64
+		for i := 0; i < times; i++ {
+			out = append(out, pattern...)
+		}
+		return out
+	}
+	ramp := make([]float64, 24)
+	for i := range ramp {
+		ramp[i] = float64(1 + 3*i)
+	}
+	cases := []struct {
+		name   string
+		demand []float64
+		want   Tuning
+		ok     bool
+	}{
+		// ±10% around 4.5 replicas: the band covers it, nothing else to absorb
+		{"noisy level", repeat([]float64{4.5, 4.95, 4.05, 4.5}, 6), Tuning{HysteresisPct: 10, Cooldown: 5 * time.Minute, StepLimit: 1}, true},
+		// Levels of 2 and 6 replicas alternating every 15-25m: outlast both, then allow the jump
+		{"excursions", repeat([]float64{2, 2, 2, 2, 2, 6, 6, 6}, 4), Tuning{HysteresisPct: 5, Cooldown: 25 * time.Minute, StepLimit: 4}, true},
+		// A steady ramp of 3 replicas a step needs steps of 3
+		{"ramp", ramp, Tuning{HysteresisPct: 5, Cooldown: 5 * time.Minute, StepLimit: 3}, true},
+		{"too few samples", []float64{1, 2, 3}, Tuning{}, false},
+		{"NaN dropped", append([]float64{math.NaN(), math.NaN()}, ramp[:10]...), Tuning{}, false},
+	}
+	for _, c := range cases {
+		got, ok := Tune(c.demand, 5*time.Minute)
+		if ok != c.ok || got != c.want {
+			t.Errorf("%s: Tune = %+v, %v; want %+v, %v", c.name, got, ok, c.want, c.ok)
+		}
+	}
+}
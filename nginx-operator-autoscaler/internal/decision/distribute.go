@@ -0,0 +1,55 @@
+package decision
+
+import (
+	"math"
+	"sort"
+)
+
+// Distribute splits total replicas across members proportionally to weights
+// using the largest-remainder method, so the parts always add up to total.
+// Non-positive weights get nothing; if every weight is, the split is even.
+func Distribute(total int32, weights []float64) []int32 {
+	out := make([]int32, len(weights))
+	if len(weights) == 0 || total <= 0 {
+		return out
+	}
+
+	sum := 0.0
+	for _, w := range weights {
+		if w > 0 {
+			sum += w
+		}
+	}
+	share := func(i int) float64 {
+		if sum == 0 {
+			return 1 / float64(len(weights))
+		}
+		if weights[i] <= 0 {
+			return 0
+		}
+		return weights[i] / sum
+	}
+
+	type rem struct {
+		i    int
+		frac float64
+	}
+	rems := make([]rem, len(weights))
+	assigned := int32(0)
+	for i := range weights {
+		exact := float64(total) * share(i)
+		out[i] = int32(math.Floor(exact))
+		assigned += out[i]
+		rems[i] = rem{i, exact - math.Floor(exact)}
+	}
+	// Hand the leftovers to the largest remainders; ties go to the earlier member
+	sort.SliceStable(rems, func(a, b int) bool { return rems[a].frac > rems[b].frac })
+	for k := 0; assigned < total; k++ {
+		if share(rems[k%len(rems)].i) == 0 {
+			continue
+		}
+		out[rems[k%len(rems)].i]++
+		assigned++
+	}
+	return out
+}
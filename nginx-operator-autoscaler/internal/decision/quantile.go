@@ -0,0 +1,26 @@
+package decision
+
+import (
+	"math"
+	"sort"
+)
+
+// Quantile returns the q-quantile (0..1) of values, interpolating linearly
+// between neighbours the way PromQL's quantile_over_time does. NaN and ±Inf
+// samples are ignored; no samples give 0.
+func Quantile(values []float64, q float64) float64 {
+	sorted := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) == 0 {
+		return 0
+	}
+	sort.Float64s(sorted)
+	rank := math.Max(0, math.Min(1, q)) * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
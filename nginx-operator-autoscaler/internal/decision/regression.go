@@ -0,0 +1,30 @@
+package decision
+
+import "math"
+
+// LinearFit fits y = intercept + slope*x by least squares over the pairs
+// where both values are finite, returning how many it used. ok is false
+// with fewer than two pairs or when x never varies: there is no slope.
+func LinearFit(x, y []float64) (intercept, slope float64, n int, ok bool) {
+	var sx, sy, sxx, sxy float64
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if math.IsNaN(x[i]) || math.IsInf(x[i], 0) || math.IsNaN(y[i]) || math.IsInf(y[i], 0) {
+			continue
+		}
+		sx += x[i]
+		sy += y[i]
+		sxx += x[i] * x[i]
+		sxy += x[i] * y[i]
+		n++
+	}
+	if n < 2 {
+		return 0, 0, n, false
+	}
+	fn := float64(n)
+	den := fn*sxx - sx*sx
+	if den <= 1e-12*fn*sxx {
+		return 0, 0, n, false
+	}
+	slope = (fn*sxy - sx*sy) / den
+	return (sy - slope*sx) / fn, slope, n, true
+}
@@ -0,0 +1,82 @@
+package decision
+
+import (
+	"math"
+	"time"
+)
+
+// minTuneSamples is how many samples Tune needs to suggest anything.
+const minTuneSamples = 12
+
+// Tuning is a hysteresis band, cooldown and step limit fitted by Tune.
+type Tuning struct {
+	HysteresisPct float64
+	Cooldown      time.Duration
+	StepLimit     int32
+}
+
+// Tune suggests settings for demand, the replicas wanted at each step of a
+// history (oldest first, one sample per step):
+//   - the band covers 95% of the noise, each sample's relative distance from
+//     the median of its neighbours, so noise alone does not scale;
+//   - the cooldown outlasts 90% of the excursions, the time from a change in
+//     the smoothed replica count to its reversal, so a passing spike is not
+//     chased both ways;
+//   - the step limit covers 95% of what demand grew by over one cooldown, so
+//     a real ramp is not held back.
+//
+// The band is kept within 5-50%, the cooldown within one step (at least a
+// minute) and an hour. NaN and negative samples are dropped; ok is false with
+// fewer than minTuneSamples left.
+func Tune(demand []float64, step time.Duration) (t Tuning, ok bool) {
+	clean := make([]float64, 0, len(demand))
+	for _, v := range demand {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) && v >= 0 {
+			clean = append(clean, v)
+		}
+	}
+	n := len(clean)
+	if n < minTuneSamples || step <= 0 {
+		return Tuning{}, false
+	}
+
+	smooth := make([]float64, n)
+	noise := make([]float64, 0, n)
+	for i := range clean {
+		smooth[i] = Quantile(clean[max(0, i-2):min(n, i+3)], 0.5)
+		// the ends have no neighbours on one side; a slope would read as noise there
+		if i >= 2 && i < n-2 && smooth[i] > 0 {
+			noise = append(noise, math.Abs(clean[i]-smooth[i])/smooth[i])
+		}
+	}
+	t.HysteresisPct = math.Max(5, math.Min(50, math.Round(100*Quantile(noise, 0.95))))
+
+	replicas := make([]float64, n)
+	for i, v := range smooth {
+		replicas[i] = math.Ceil(v)
+	}
+	var excursions []float64
+	lastAt, lastDir := -1, 0.0
+	for i := 1; i < n; i++ {
+		dir := replicas[i] - replicas[i-1]
+		if dir == 0 {
+			continue
+		}
+		dir = math.Copysign(1, dir)
+		if lastAt >= 0 && dir != lastDir {
+			excursions = append(excursions, float64(i-lastAt))
+		}
+		lastAt, lastDir = i, dir
+	}
+	floor := max(step, time.Minute)
+	steps := math.Ceil(Quantile(excursions, 0.9))
+	t.Cooldown = min(time.Hour, max(floor, time.Duration(steps)*step))
+
+	k := max(1, int(t.Cooldown/step))
+	growth := make([]float64, 0, n)
+	for i := 0; i+k < n; i++ {
+		growth = append(growth, math.Max(0, replicas[i+k]-replicas[i]))
+	}
+	t.StepLimit = int32(math.Max(1, math.Ceil(Quantile(growth, 0.95))))
+	return t, true
+}
@@ -0,0 +1,62 @@
+// Package decisionhook asks a user-supplied HTTP endpoint to approve, veto or
+// override a scaling decision before it is applied.
+package decisionhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Request is the JSON body POSTed to the webhook.
+type Request struct {
+	Autoscaler string             `json:"autoscaler"`
+	Target     string             `json:"target"`
+	Current    int32              `json:"current"`
+	Desired    int32              `json:"desired"`
+	Proposed   int32              `json:"proposed"`
+	LimitedBy  string             `json:"limitedBy,omitempty"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// Response is what the webhook answers. Allowed=false vetoes the change;
+// Replicas, when set, replaces the proposed count.
+type Response struct {
+	Allowed  bool   `json:"allowed"`
+	Replicas *int32 `json:"replicas,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Review POSTs req to url and decodes the verdict. Anything but a 200 with a
+// JSON body is an error, left to the caller's failure policy.
+func Review(ctx context.Context, url string, timeout time.Duration, req Request) (Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	r, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return Response{}, fmt.Errorf("decision webhook returned HTTP %d", r.StatusCode)
+	}
+
+	var out Response
+	if err := json.NewDecoder(r.Body).Decode(&out); err != nil {
+		return Response{}, fmt.Errorf("decoding decision webhook response: %w", err)
+	}
+	return out, nil
+}
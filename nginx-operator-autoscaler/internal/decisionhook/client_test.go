@@ -0,0 +1,34 @@
+package decisionhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReview(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Proposed != 8 || req.Metrics["cpuCores"] != 1.5 {
+			t.Errorf("unexpected request %+v (%v)", req, err)
+		}
+		w.Write([]byte(`{"allowed":true,"replicas":6,"reason":"freeze window"}`))
+	}))
+	defer srv.Close()
+
+	got, err := Review(context.Background(), srv.URL, time.Second, Request{Current: 4, Proposed: 8, Metrics: map[string]float64{"cpuCores": 1.5}})
+	if err != nil || !got.Allowed || got.Replicas == nil || *got.Replicas != 6 {
+		t.Fatalf("Review = %+v, %v", got, err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if _, err := Review(context.Background(), failing.URL, time.Second, Request{}); err == nil {
+		t.Fatal("HTTP 500: want error")
+	}
+}
@@ -0,0 +1,173 @@
+// Package decisionstore keeps every scaling decision in an embedded bbolt
+// database so capacity reviews don't depend on Prometheus retention.
+package decisionstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("decisions")
+
+// Store is safe for concurrent use.
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+// Open opens (creating if needed) the database at path. Records older than
+// retention are pruned as new ones arrive; zero keeps everything.
+func Open(path string, retention time.Duration) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// OpenReadOnly opens a database for export. bbolt allows one writer per file,
+// so point it at a copy (see Backup) while the controller is running.
+func OpenReadOnly(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o400, &bolt.Options{ReadOnly: true, Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error { return s.db.Close() }
+
+// key orders records by time, then autoscaler.
+func key(at time.Time, autoscaler string) []byte {
+	k := make([]byte, 8, 8+len(autoscaler))
+	binary.BigEndian.PutUint64(k, uint64(at.UnixNano()))
+	return append(k, autoscaler...)
+}
+
+// Record stores one decision, encoded as JSON.
+func (s *Store) Record(autoscaler string, at time.Time, record interface{}) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if s.retention > 0 {
+			cutoff := key(at.Add(-s.retention), "")
+			c := b.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+		}
+		return b.Put(key(at, autoscaler), value)
+	})
+}
+
+// Filter narrows an export. Zero values match everything.
+type Filter struct {
+	Since      time.Time
+	Autoscaler string // namespace/name
+}
+
+// Each calls fn for every matching record in time order.
+func (s *Store) Each(f Filter, fn func(autoscaler string, at time.Time, record map[string]interface{}) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		k, v := c.First()
+		if !f.Since.IsZero() {
+			k, v = c.Seek(key(f.Since, ""))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if len(k) < 8 {
+				continue
+			}
+			name := string(k[8:])
+			if f.Autoscaler != "" && name != f.Autoscaler {
+				continue
+			}
+			var rec map[string]interface{}
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return fmt.Errorf("record %s: %w", k, err)
+			}
+			at := time.Unix(0, int64(binary.BigEndian.Uint64(k[:8]))).UTC()
+			if err := fn(name, at, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Backup writes a consistent copy of the database to w.
+func (s *Store) Backup(w io.Writer) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+// Export writes matching records to w as "json" (one object per line) or
+// "csv" (a column per field seen in any record, sorted by name).
+func (s *Store) Export(w io.Writer, format string, f Filter) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		return s.Each(f, func(_ string, _ time.Time, rec map[string]interface{}) error {
+			return enc.Encode(rec)
+		})
+	case "csv":
+		var rows []map[string]interface{}
+		columns := map[string]bool{}
+		if err := s.Each(f, func(_ string, _ time.Time, rec map[string]interface{}) error {
+			rows = append(rows, rec)
+			for k := range rec {
+				columns[k] = true
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		header := make([]string, 0, len(columns))
+		for k := range columns {
+			header = append(header, k)
+		}
+		sort.Strings(header)
+
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, rec := range rows {
+			line := make([]string, len(header))
+			for i, k := range header {
+				if v, ok := rec[k]; ok {
+					line[i] = fmt.Sprint(v)
+				}
+			}
+			if err := cw.Write(line); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown export format %q (want csv or json)", format)
+	}
+}
@@ -0,0 +1,52 @@
+package decisionstore
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordExport(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "decisions.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	_ = s.Record("shop/web", t0, map[string]interface{}{"desired": 3})
+	_ = s.Record("shop/api", t0.Add(time.Minute), map[string]interface{}{"desired": 4, "skipReason": "Cooldown"})
+	// Two hours later the first two fall out of retention
+	_ = s.Record("shop/web", t0.Add(2*time.Hour), map[string]interface{}{"desired": 5})
+
+	var buf bytes.Buffer
+	if err := s.Export(&buf, "csv", Filter{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "desired\n5\n"; got != want {
+		t.Fatalf("csv after pruning = %q, want %q", got, want)
+	}
+
+	_ = s.Record("shop/api", t0.Add(2*time.Hour+time.Minute), map[string]interface{}{"desired": 6, "skipReason": "Cooldown"})
+	buf.Reset()
+	if err := s.Export(&buf, "csv", Filter{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "desired,skipReason\n5,\n6,Cooldown\n"; got != want {
+		t.Fatalf("csv = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := s.Export(&buf, "json", Filter{Autoscaler: "shop/api"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != `{"desired":6,"skipReason":"Cooldown"}` {
+		t.Fatalf("json = %s", got)
+	}
+
+	if err := s.Export(&buf, "xml", Filter{}); err == nil {
+		t.Fatal("unknown format: want error")
+	}
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Async queues events so a slow sink never holds up a reconcile. When the
+// queue is full new events are dropped (and logged) rather than blocking.
+type Async struct {
+	sink  Sink
+	queue chan Event
+	log   logr.Logger
+}
+
+// NewAsync wraps sink with a queue of size events.
+func NewAsync(sink Sink, size int, log logr.Logger) *Async {
+	return &Async{sink: sink, queue: make(chan Event, size), log: log}
+}
+
+// Emit queues e. A nil Async drops everything.
+func (a *Async) Emit(e Event) {
+	if a == nil {
+		return
+	}
+	select {
+	case a.queue <- e:
+	default:
+		a.log.Info("event queue full; dropping event", "type", e.Type, "source", e.Source)
+	}
+}
+
+// Start delivers queued events until ctx is done; it satisfies manager.Runnable.
+func (a *Async) Start(ctx context.Context) error {
+	defer a.sink.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-a.queue:
+			sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			if err := a.sink.Send(sendCtx, e); err != nil {
+				a.log.Error(err, "failed to publish event", "type", e.Type, "source", e.Source)
+			}
+			cancel()
+		}
+	}
+}
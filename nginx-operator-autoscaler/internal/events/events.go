@@ -0,0 +1,74 @@
+// Package events publishes scaling activity as CloudEvents (spec 1.0,
+// structured JSON mode) to an HTTP endpoint, a Kafka topic or a NATS
+// JetStream subject.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Event is a CloudEvent in structured mode.
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// ContentType is the structured-mode media type.
+const ContentType = "application/cloudevents+json"
+
+// Sink delivers one event.
+type Sink interface {
+	Send(ctx context.Context, e Event) error
+	Close() error
+}
+
+// NewSink builds a sink from a URL: http(s)://host/path POSTs each event,
+// kafka://broker1:9092,broker2:9092/topic produces it to a topic, and
+// nats://[user:pass@]host:4222/subject publishes it to JetStream.
+func NewSink(raw string) (Sink, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpSink{url: raw}, nil
+	case "kafka":
+		topic := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || topic == "" {
+			return nil, fmt.Errorf("kafka sink must look like kafka://broker:9092/topic")
+		}
+		return newKafkaSink(strings.Split(u.Host, ","), topic), nil
+	case "nats", "tls":
+		subject := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || subject == "" {
+			return nil, fmt.Errorf("nats sink must look like nats://host:4222/subject")
+		}
+		server := *u
+		server.Path = ""
+		return &natsSink{url: server.String(), subject: subject}, nil
+	default:
+		return nil, fmt.Errorf("unsupported sink scheme %q", u.Scheme)
+	}
+}
+
+func encode(e Event) ([]byte, error) {
+	if e.SpecVersion == "" {
+		e.SpecVersion = "1.0"
+	}
+	if e.DataContentType == "" {
+		e.DataContentType = "application/json"
+	}
+	return json.Marshal(e)
+}
@@ -0,0 +1,39 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+type httpSink struct {
+	url string
+}
+
+func (s *httpSink) Send(ctx context.Context, e Event) error {
+	body, err := encode(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+
+	r, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode/100 != 2 {
+		return fmt.Errorf("event sink returned HTTP %d", r.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpSink) Close() error { return nil }
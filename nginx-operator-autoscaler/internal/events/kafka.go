@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaSink struct {
+	w *kafka.Writer
+}
+
+func newKafkaSink(brokers []string, topic string) *kafkaSink {
+	return &kafkaSink{w: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{}, // one autoscaler's events stay in order on one partition
+		RequiredAcks: kafka.RequireOne,
+	}}
+}
+
+func (s *kafkaSink) Send(ctx context.Context, e Event) error {
+	body, err := encode(e)
+	if err != nil {
+		return err
+	}
+	return s.w.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(e.Source),
+		Value:   body,
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte(ContentType)}},
+	})
+}
+
+func (s *kafkaSink) Close() error { return s.w.Close() }
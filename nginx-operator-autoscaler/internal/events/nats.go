@@ -0,0 +1,64 @@
+package events
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsSink publishes to a JetStream subject. It connects on first use so a
+// NATS outage at startup doesn't keep the manager from coming up; after that
+// the client reconnects on its own.
+type natsSink struct {
+	url, subject string
+
+	mu sync.Mutex
+	nc *nats.Conn
+	js nats.JetStreamContext
+}
+
+func (s *natsSink) jetStream() (nats.JetStreamContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.js != nil {
+		return s.js, nil
+	}
+	nc, err := nats.Connect(s.url, nats.Name("nginx-operator-autoscaler"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	s.nc, s.js = nc, js
+	return js, nil
+}
+
+func (s *natsSink) Send(ctx context.Context, e Event) error {
+	js, err := s.jetStream()
+	if err != nil {
+		return err
+	}
+	body, err := encode(e)
+	if err != nil {
+		return err
+	}
+	msg := nats.NewMsg(s.subject)
+	msg.Data = body
+	msg.Header.Set("Content-Type", ContentType)
+	msg.Header.Set(nats.MsgIdHdr, e.ID) // JetStream de-duplicates redeliveries by this
+	_, err = js.PublishMsg(msg, nats.Context(ctx))
+	return err
+}
+
+func (s *natsSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.nc != nil {
+		s.nc.Close()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package externalscaler serves KEDA's externalscaler.ExternalScaler gRPC
+// service. externalscaler.proto is copied from kedacore/keda
+// (pkg/scalers/externalscaler) and the .pb.go files are generated from it, so
+// KEDA itself and its dependency tree stay out of our go.mod.
+package externalscaler
+
+//go:generate protoc --go_out=. --go-grpc_out=. externalscaler.proto
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Scaler answers KEDA's questions about one ScaledObject trigger.
+type Scaler interface {
+	IsActive(ctx context.Context, ref *ScaledObjectRef) (bool, error)
+	GetMetricSpec(ctx context.Context, ref *ScaledObjectRef) ([]*MetricSpec, error)
+	GetMetrics(ctx context.Context, ref *ScaledObjectRef, metricName string) ([]*MetricValue, error)
+}
+
+// NewServer returns a gRPC server exposing s as externalscaler.ExternalScaler.
+// StreamIsActive is served by polling IsActive every streamInterval and
+// sending a message whenever the answer changes.
+func NewServer(s Scaler, streamInterval time.Duration, opts ...grpc.ServerOption) *grpc.Server {
+	srv := grpc.NewServer(opts...)
+	RegisterExternalScalerServer(srv, &service{scaler: s, interval: streamInterval})
+	return srv
+}
+
+type service struct {
+	UnimplementedExternalScalerServer
+	scaler   Scaler
+	interval time.Duration
+}
+
+func (s *service) IsActive(ctx context.Context, ref *ScaledObjectRef) (*IsActiveResponse, error) {
+	active, err := s.scaler.IsActive(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &IsActiveResponse{Result: active}, nil
+}
+
+func (s *service) GetMetricSpec(ctx context.Context, ref *ScaledObjectRef) (*GetMetricSpecResponse, error) {
+	specs, err := s.scaler.GetMetricSpec(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &GetMetricSpecResponse{MetricSpecs: specs}, nil
+}
+
+func (s *service) GetMetrics(ctx context.Context, req *GetMetricsRequest) (*GetMetricsResponse, error) {
+	values, err := s.scaler.GetMetrics(ctx, req.GetScaledObjectRef(), req.GetMetricName())
+	if err != nil {
+		return nil, err
+	}
+	return &GetMetricsResponse{MetricValues: values}, nil
+}
+
+func (s *service) StreamIsActive(ref *ScaledObjectRef, stream ExternalScaler_StreamIsActiveServer) error {
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var last *bool
+	for {
+		// Errors are transient from KEDA's point of view; keep the stream open
+		// and try again on the next tick.
+		if active, err := s.scaler.IsActive(ctx, ref); err == nil && (last == nil || *last != active) {
+			if err := stream.Send(&IsActiveResponse{Result: active}); err != nil {
+				return err
+			}
+			last = &active
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package externalscaler
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type stubScaler struct{}
+
+func (stubScaler) IsActive(_ context.Context, ref *ScaledObjectRef) (bool, error) {
+	return ref.GetScalerMetadata()["active"] == "true", nil
+}
+
+func (stubScaler) GetMetricSpec(_ context.Context, ref *ScaledObjectRef) ([]*MetricSpec, error) {
+	return []*MetricSpec{{MetricName: ref.GetNamespace() + "/" + ref.GetName(), TargetSize: 1}}, nil
+}
+
+func (stubScaler) GetMetrics(_ context.Context, ref *ScaledObjectRef, name string) ([]*MetricValue, error) {
+	return []*MetricValue{{MetricName: name, MetricValue: int64(len(ref.GetScalerMetadata()))}}, nil
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(stubScaler{}, 10*time.Millisecond)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := NewExternalScalerClient(conn)
+	ctx := context.Background()
+	ref := &ScaledObjectRef{Name: "web", Namespace: "default", ScalerMetadata: map[string]string{"active": "true", "targetCPU": "200m"}}
+
+	if active, err := client.IsActive(ctx, ref); err != nil || !active.GetResult() {
+		t.Fatalf("IsActive = %v, %v", active.GetResult(), err)
+	}
+
+	spec, err := client.GetMetricSpec(ctx, ref)
+	if err != nil {
+		t.Fatalf("GetMetricSpec: %v", err)
+	}
+	if specs := spec.GetMetricSpecs(); len(specs) != 1 || specs[0].GetMetricName() != "default/web" || specs[0].GetTargetSize() != 1 {
+		t.Fatalf("GetMetricSpec = %v", specs)
+	}
+
+	metrics, err := client.GetMetrics(ctx, &GetMetricsRequest{ScaledObjectRef: ref, MetricName: "m"})
+	if err != nil {
+		t.Fatalf("GetMetrics: %v", err)
+	}
+	if values := metrics.GetMetricValues(); len(values) != 1 || values[0].GetMetricName() != "m" || values[0].GetMetricValue() != 2 {
+		t.Fatalf("GetMetrics = %v", values)
+	}
+
+	stream, err := client.StreamIsActive(ctx, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, err := stream.Recv(); err != nil || !first.GetResult() {
+		t.Fatalf("StreamIsActive = %v, %v", first.GetResult(), err)
+	}
+}
@@ -0,0 +1,64 @@
+// Package gcpauth gets Google Cloud access tokens from the GKE/GCE metadata
+// server, so Workload Identity decides which service account calls Google
+// APIs and no key file is needed.
+package gcpauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenSource returns the default service account's access tokens, fetched
+// through rt and reused until shortly before they expire. GCE_METADATA_HOST
+// overrides the metadata server's address.
+func TokenSource(rt http.RoundTripper) oauth2.TokenSource {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	return oauth2.ReuseTokenSource(nil, &metadataTokenSource{host: host, client: &http.Client{Transport: rt, Timeout: 10 * time.Second}})
+}
+
+// metadataTokenSource fetches the default service account's access token
+// from the metadata server.
+type metadataTokenSource struct {
+	host   string
+	client *http.Client
+}
+
+func (m *metadataTokenSource) Token() (*oauth2.Token, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://"+m.host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	r, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("metadata token: %w", err)
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata token: %s", r.Status)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+		TokenType   string `json:"token_type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("metadata token: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return nil, fmt.Errorf("metadata token: empty access_token")
+	}
+	return &oauth2.Token{
+		AccessToken: tok.AccessToken,
+		TokenType:   tok.TokenType,
+		Expiry:      time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second),
+	}, nil
+}
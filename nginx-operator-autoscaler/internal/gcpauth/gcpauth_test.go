@@ -0,0 +1,33 @@
+package gcpauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTokenSource(t *testing.T) {
+	calls := 0
+	md := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || !strings.HasSuffix(r.URL.Path, "/service-accounts/default/token") {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		calls++
+		w.Write([]byte(`{"access_token":"ya29.test","expires_in":3599,"token_type":"Bearer"}`))
+	}))
+	defer md.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(md.URL, "http://"))
+
+	ts := TokenSource(http.DefaultTransport)
+	for i := 0; i < 3; i++ {
+		tok, err := ts.Token()
+		if err != nil || tok.AccessToken != "ya29.test" || !tok.Valid() {
+			t.Fatalf("Token() = %+v, %v; want a valid ya29.test", tok, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("metadata server asked %d times; want the token reused", calls)
+	}
+}
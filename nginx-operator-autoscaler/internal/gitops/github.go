@@ -0,0 +1,61 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// githubAPI is swapped out in tests.
+var githubAPI = "https://api.github.com"
+
+// OpenPullRequest opens a GitHub pull request from head into base, unless one
+// is already open (GitHub answers 422 for the duplicate). Repositories on
+// other hosts are left for the user to open a merge request.
+func OpenPullRequest(ctx context.Context, repoURL, token, head, base, title string) error {
+	owner, name, ok := githubRepo(repoURL)
+	if !ok {
+		return nil
+	}
+	body, _ := json.Marshal(map[string]string{"title": title, "head": head, "base": base})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/repos/%s/%s/pulls", githubAPI, owner, name), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	r, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	switch r.StatusCode {
+	case http.StatusCreated, http.StatusUnprocessableEntity:
+		return nil
+	default:
+		return fmt.Errorf("github returned HTTP %d opening a pull request", r.StatusCode)
+	}
+}
+
+// githubRepo extracts owner/name from git@github.com:owner/name.git or
+// https://github.com/owner/name(.git).
+func githubRepo(url string) (owner, name string, ok bool) {
+	var rest string
+	switch {
+	case strings.HasPrefix(url, "git@github.com:"):
+		rest = strings.TrimPrefix(url, "git@github.com:")
+	case strings.HasPrefix(url, "https://github.com/"):
+		rest = strings.TrimPrefix(url, "https://github.com/")
+	case strings.HasPrefix(url, "ssh://git@github.com/"):
+		rest = strings.TrimPrefix(url, "ssh://git@github.com/")
+	default:
+		return "", "", false
+	}
+	owner, name, ok = strings.Cut(strings.TrimSuffix(rest, ".git"), "/")
+	return owner, name, ok && owner != "" && name != ""
+}
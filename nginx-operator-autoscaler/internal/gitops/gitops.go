@@ -0,0 +1,228 @@
+// Package gitops writes replica counts into manifests kept in a Git
+// repository, so a GitOps tool (Argo CD, Flux) stays the only writer to the
+// cluster.
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+// Target is one replica field in one file of one repository.
+type Target struct {
+	Repo       string // clone URL, SSH (git@host:org/repo.git) or HTTPS
+	Branch     string // branch the manifest is read from
+	PushBranch string // when set, commits are force-pushed here for review instead of to Branch
+	Path       string // manifest path inside the repo
+	Field      string // dot-separated path to the replica count, e.g. spec.replicas
+	Auth       transport.AuthMethod
+}
+
+// Author signs the commits.
+type Author struct {
+	Name, Email string
+}
+
+// Credentials are the keys of the Secret referenced by spec.gitops.secretRef.
+// An SSH deploy key needs known_hosts; HTTPS uses username/password or a token.
+func Credentials(data map[string][]byte) (transport.AuthMethod, error) {
+	if key := data["identity"]; len(key) > 0 {
+		auth, err := gitssh.NewPublicKeys("git", key, "")
+		if err != nil {
+			return nil, fmt.Errorf("deploy key: %w", err)
+		}
+		known := data["known_hosts"]
+		if len(known) == 0 {
+			return nil, errors.New("an SSH deploy key needs known_hosts in the same Secret")
+		}
+		if auth.HostKeyCallback, err = knownHostsCallback(known); err != nil {
+			return nil, err
+		}
+		return auth, nil
+	}
+	if token := data["token"]; len(token) > 0 {
+		// GitHub, GitLab and Gitea all accept any non-empty user with a token
+		return &githttp.BasicAuth{Username: "x-access-token", Password: string(token)}, nil
+	}
+	if user := data["username"]; len(user) > 0 {
+		return &githttp.BasicAuth{Username: string(user), Password: string(data["password"])}, nil
+	}
+	return nil, errors.New("secret has none of identity, token or username")
+}
+
+// knownHostsCallback verifies host keys against known_hosts content. The
+// go-git helper only reads files, so the content takes a short trip to disk.
+func knownHostsCallback(known []byte) (ssh.HostKeyCallback, error) {
+	f, err := os.CreateTemp("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(known); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return gitssh.NewKnownHostsCallback(f.Name())
+}
+
+// SetReplicas sets t.Field in t.Path to replicas and pushes a commit, unless
+// the file already says so. It reports whether a commit was pushed.
+func SetReplicas(ctx context.Context, t Target, replicas int32, message string, author Author) (bool, error) {
+	fs := memfs.New()
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), fs, &git.CloneOptions{
+		URL:           t.Repo,
+		Auth:          t.Auth,
+		ReferenceName: plumbing.NewBranchReferenceName(t.Branch),
+		SingleBranch:  true,
+		Depth:         1,
+	})
+	if err != nil {
+		return false, fmt.Errorf("clone %s: %w", t.Repo, err)
+	}
+
+	f, err := fs.Open(t.Path)
+	if err != nil {
+		return false, fmt.Errorf("open %s: %w", t.Path, err)
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return false, err
+	}
+	updated, err := setField(data, t.Field, strconv.Itoa(int(replicas)))
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", t.Path, err)
+	}
+	if bytes.Equal(updated, data) {
+		return false, nil
+	}
+
+	out, err := fs.Create(t.Path)
+	if err != nil {
+		return false, err
+	}
+	if _, err := out.Write(updated); err != nil {
+		out.Close()
+		return false, err
+	}
+	if err := out.Close(); err != nil {
+		return false, err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return false, err
+	}
+	if _, err := wt.Add(t.Path); err != nil {
+		return false, err
+	}
+	_, err = wt.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: author.Name, Email: author.Email, When: time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	dst, force := t.Branch, false
+	if t.PushBranch != "" {
+		dst, force = t.PushBranch, true
+	}
+	// The commit advanced the local copy of Branch; push that
+	refspec := config.RefSpec(fmt.Sprintf("%s:%s", plumbing.NewBranchReferenceName(t.Branch), plumbing.NewBranchReferenceName(dst)))
+	if force {
+		refspec = "+" + refspec
+	}
+	err = repo.PushContext(ctx, &git.PushOptions{Auth: t.Auth, RefSpecs: []config.RefSpec{refspec}})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return false, fmt.Errorf("push %s: %w", dst, err)
+	}
+	return true, nil
+}
+
+// setField replaces the plain scalar at a dot-separated path in the first
+// YAML document that has it. Only that token is rewritten, so comments and
+// formatting in the rest of the file survive.
+func setField(data []byte, path, value string) ([]byte, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("no %s field found", path)
+			}
+			return nil, err
+		}
+		node := lookup(&doc, strings.Split(path, "."))
+		if node == nil {
+			continue
+		}
+		if node.Kind != yaml.ScalarNode || node.Style != 0 {
+			return nil, fmt.Errorf("%s is not a plain scalar", path)
+		}
+		return replaceAt(data, node.Line, node.Column, node.Value, value)
+	}
+}
+
+func lookup(n *yaml.Node, path []string) *yaml.Node {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	for _, key := range path {
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// replaceAt swaps the token old at 1-based line/column for value.
+func replaceAt(data []byte, line, column int, old, value string) ([]byte, error) {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if line < 1 || line > len(lines) {
+		return nil, fmt.Errorf("line %d out of range", line)
+	}
+	l := lines[line-1]
+	start := column - 1
+	if start < 0 || start+len(old) > len(l) || string(l[start:start+len(old)]) != old {
+		return nil, fmt.Errorf("unexpected content at line %d", line)
+	}
+	var out []byte
+	out = append(out, l[:start]...)
+	out = append(out, value...)
+	out = append(out, l[start+len(old):]...)
+	lines[line-1] = out
+	return bytes.Join(lines, nil), nil
+}
@@ -0,0 +1,74 @@
+package gitops
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const manifest = `# managed by Argo CD
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3   # autoscaled
+  template: {}
+`
+
+func TestSetField(t *testing.T) {
+	got, err := setField([]byte(manifest), "spec.replicas", "12")
+	if err != nil {
+		t.Fatalf("setField: %v", err)
+	}
+	want := `# managed by Argo CD
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 12   # autoscaled
+  template: {}
+`
+	if string(got) != want {
+		t.Fatalf("setField =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := setField([]byte(manifest), "spec.minReplicas", "1"); err == nil {
+		t.Fatal("missing field: want error")
+	}
+	if _, err := setField([]byte("spec:\n  replicas: \"3\"\n"), "spec.replicas", "1"); err == nil {
+		t.Fatal("quoted value: want error")
+	}
+}
+
+func TestOpenPullRequest(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/repos/acme/deploy/pulls" || r.Header.Get("Authorization") != "Bearer t0k" {
+			t.Errorf("unexpected request %s %v", r.URL, r.Header)
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity) // already open
+	}))
+	defer srv.Close()
+	githubAPI = srv.URL
+
+	if err := OpenPullRequest(context.Background(), "git@github.com:acme/deploy.git", "t0k", "autoscaler/web", "main", "Scale web"); err != nil {
+		t.Fatalf("OpenPullRequest: %v", err)
+	}
+	if err := OpenPullRequest(context.Background(), "git@gitlab.com:acme/deploy.git", "t0k", "autoscaler/web", "main", "Scale web"); err != nil || calls != 1 {
+		t.Fatalf("non-GitHub repo: err=%v calls=%d", err, calls)
+	}
+}
@@ -0,0 +1,72 @@
+// Package grafana marks scale events on Grafana dashboards as annotations.
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Annotator turns scale events into organization-wide Grafana annotations,
+// which every dashboard can overlay with an annotation query on its tags.
+// It implements events.Sink so it can sit behind an events.Async queue.
+type Annotator struct {
+	url, token string
+}
+
+// New returns an Annotator for the Grafana at baseURL, authenticated with a
+// service account token.
+func New(baseURL, token string) *Annotator {
+	return &Annotator{url: strings.TrimSuffix(baseURL, "/"), token: token}
+}
+
+type annotation struct {
+	Time int64    `json:"time"`
+	Tags []string `json:"tags"`
+	Text string   `json:"text"`
+}
+
+// Send creates one annotation. The event subject is the target
+// ("namespace/deployment", optionally suffixed with "@<cluster secret>").
+func (a *Annotator) Send(ctx context.Context, e events.Event) error {
+	target, _, _ := strings.Cut(e.Subject, "@")
+	ns, name, _ := strings.Cut(target, "/")
+	text := fmt.Sprintf("%s: %s", e.Subject, e.Type)
+	if d, ok := e.Data.(map[string]interface{}); ok {
+		text = fmt.Sprintf("Scaled %s from %v to %v replicas", e.Subject, d["from"], d["to"])
+	}
+	body, err := json.Marshal(annotation{
+		Time: e.Time.UnixMilli(),
+		Tags: []string{"nginx-autoscaler", "namespace:" + ns, "deployment:" + name},
+		Text: text,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	r, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("grafana returned HTTP %d creating an annotation", r.StatusCode)
+	}
+	return nil
+}
+
+func (a *Annotator) Close() error { return nil }
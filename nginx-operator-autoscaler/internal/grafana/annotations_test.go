@@ -0,0 +1,41 @@
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/events"
+)
+
+func TestSend(t *testing.T) {
+	var got annotation
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/annotations" || r.Header.Get("Authorization") != "Bearer glsa_x" {
+			t.Errorf("unexpected request %s %v", r.URL, r.Header)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"id":1,"message":"Annotation added"}`))
+	}))
+	defer srv.Close()
+
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	err := New(srv.URL+"/", "glsa_x").Send(context.Background(), events.Event{
+		Subject: "shop/web",
+		Type:    "dev.malisetti.autoscaler.scaled",
+		Time:    at,
+		Data:    map[string]interface{}{"from": int32(3), "to": int32(6)},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.Time != at.UnixMilli() || got.Text != "Scaled shop/web from 3 to 6 replicas" {
+		t.Fatalf("annotation = %+v", got)
+	}
+	if len(got.Tags) != 3 || got.Tags[1] != "namespace:shop" || got.Tags[2] != "deployment:web" {
+		t.Fatalf("tags = %v", got.Tags)
+	}
+}
@@ -0,0 +1,75 @@
+// Package jetstream reads a NATS JetStream consumer's backlog from a
+// server's HTTP monitoring endpoint (/jsz), for scaling stream consumers.
+package jetstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Consumer is the part of a consumer's /jsz entry the autoscaler sizes on.
+type Consumer struct {
+	NumPending    int64 `json:"num_pending"`     // not yet delivered
+	NumAckPending int64 `json:"num_ack_pending"` // delivered, not yet acked
+}
+
+// jsz is the /jsz?consumers=true response, trimmed to what Pending reads.
+type jsz struct {
+	AccountDetails []struct {
+		Name    string `json:"name"`
+		Streams []struct {
+			Name      string `json:"name"`
+			Consumers []struct {
+				Name string `json:"name"`
+				Consumer
+			} `json:"consumer_detail"`
+		} `json:"stream_detail"`
+	} `json:"account_details"`
+}
+
+// Pending returns the counts of consumer on stream, as reported by the
+// server at monitorURL (e.g. http://nats.nats:8222). An empty account
+// matches any. In a cluster only the stream's peers report its consumers, so
+// point monitorURL at one of them rather than at any server.
+func Pending(ctx context.Context, monitorURL, account, stream, consumer string) (Consumer, error) {
+	q := url.Values{"consumers": {"true"}}
+	if account != "" {
+		q.Set("acc", account)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(monitorURL, "/")+"/jsz?"+q.Encode(), nil)
+	if err != nil {
+		return Consumer{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Consumer{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Consumer{}, fmt.Errorf("jsz: %s", resp.Status)
+	}
+	var body jsz
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Consumer{}, fmt.Errorf("jsz: %w", err)
+	}
+	for _, acc := range body.AccountDetails {
+		if account != "" && acc.Name != account {
+			continue
+		}
+		for _, s := range acc.Streams {
+			if s.Name != stream {
+				continue
+			}
+			for _, c := range s.Consumers {
+				if c.Name == consumer {
+					return c.Consumer, nil
+				}
+			}
+		}
+	}
+	return Consumer{}, fmt.Errorf("consumer %s on stream %s not found in %s/jsz", consumer, stream, monitorURL)
+}
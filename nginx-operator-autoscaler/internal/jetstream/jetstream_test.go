@@ -0,0 +1,41 @@
+package jetstream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const sample = `{"account_details":[
+  {"name":"$G","stream_detail":[{"name":"ORDERS","consumer_detail":[{"name":"worker","num_pending":7,"num_ack_pending":1}]}]},
+  {"name":"TEAM","stream_detail":[{"name":"ORDERS","consumer_detail":[
+    {"name":"audit","num_pending":99,"num_ack_pending":0},
+    {"name":"worker","num_pending":120,"num_ack_pending":30}]}]}]}`
+
+func TestPending(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jsz" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		w.Write([]byte(sample))
+	}))
+	defer srv.Close()
+
+	c, err := Pending(context.Background(), srv.URL+"/", "TEAM", "ORDERS", "worker")
+	if err != nil || c.NumPending != 120 || c.NumAckPending != 30 {
+		t.Fatalf("Pending = %+v, %v; want 120 pending, 30 ack-pending", c, err)
+	}
+	if query != "acc=TEAM&consumers=true" {
+		t.Fatalf("query = %q", query)
+	}
+	if c, err := Pending(context.Background(), srv.URL, "", "ORDERS", "worker"); err != nil || c.NumPending != 7 {
+		t.Fatalf("any account: Pending = %+v, %v; want the first match", c, err)
+	}
+	if _, err := Pending(context.Background(), srv.URL, "", "ORDERS", "missing"); err == nil {
+		t.Fatal("unknown consumer: want an error")
+	}
+}
@@ -0,0 +1,65 @@
+// Package opencost estimates what a workload costs from the OpenCost (or
+// Kubecost, which serves the same API) allocation endpoint.
+package opencost
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+type allocationResp struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    []map[string]struct {
+		TotalCost float64 `json:"totalCost"`
+	} `json:"data"`
+}
+
+// ReplicaHourlyCost returns the average hourly cost of one replica of the
+// Deployment ns/name over the last hour, given its current replica count.
+func ReplicaHourlyCost(baseURL, ns, name string, replicas int32) (float64, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return 0, err
+	}
+	u.Path = "/allocation/compute"
+	q := u.Query()
+	q.Set("window", "1h")
+	q.Set("aggregate", "controller")
+	q.Set("filterNamespaces", ns)
+	q.Set("filterControllers", name)
+	u.RawQuery = q.Encode()
+
+	r, err := httpClient.Get(u.String())
+	if err != nil {
+		return 0, err
+	}
+	defer r.Body.Close()
+	if r.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("opencost returned HTTP %d", r.StatusCode)
+	}
+
+	var out allocationResp
+	if err := json.NewDecoder(r.Body).Decode(&out); err != nil {
+		return 0, err
+	}
+	if out.Code != http.StatusOK {
+		return 0, fmt.Errorf("opencost: %s", out.Message)
+	}
+	if len(out.Data) == 0 {
+		return 0, fmt.Errorf("opencost returned no allocation window")
+	}
+	alloc, ok := out.Data[0]["deployment:"+name]
+	if !ok {
+		return 0, fmt.Errorf("opencost has no allocation for deployment %s/%s", ns, name)
+	}
+	if replicas < 1 {
+		replicas = 1
+	}
+	return alloc.TotalCost / float64(replicas), nil
+}
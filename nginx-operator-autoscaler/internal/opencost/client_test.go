@@ -0,0 +1,26 @@
+package opencost
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReplicaHourlyCost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/allocation/compute" || q.Get("filterNamespaces") != "shop" || q.Get("aggregate") != "controller" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"code":200,"data":[{"deployment:web":{"totalCost":1.5}}]}`))
+	}))
+	defer srv.Close()
+
+	got, err := ReplicaHourlyCost(srv.URL, "shop", "web", 3)
+	if err != nil || got != 0.5 {
+		t.Fatalf("ReplicaHourlyCost = %v, %v; want 0.5, nil", got, err)
+	}
+	if _, err := ReplicaHourlyCost(srv.URL, "shop", "api", 3); err == nil {
+		t.Fatal("missing deployment: want error")
+	}
+}
@@ -0,0 +1,87 @@
+// Package policy evaluates an org-wide Rego policy against each proposed
+// scaling action.
+//
+// The policy lives in package nginxautoscaler and may define:
+//
+//	deny[msg]      any message vetoes the change
+//	min_replicas   lower bound on the applied count
+//	max_replicas   upper bound on the applied count
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// Verdict is what the policy said about one proposal.
+type Verdict struct {
+	Deny []string
+	Min  *int32
+	Max  *int32
+}
+
+// Engine is a compiled policy, safe for concurrent use.
+type Engine struct {
+	query rego.PreparedEvalQuery
+}
+
+// Compile parses and prepares a Rego module.
+func Compile(ctx context.Context, module string) (*Engine, error) {
+	q, err := rego.New(
+		rego.Query("data.nginxautoscaler"),
+		rego.Module("policy.rego", module),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{query: q}, nil
+}
+
+// Evaluate runs the policy against input.
+func (e *Engine) Evaluate(ctx context.Context, input map[string]interface{}) (Verdict, error) {
+	rs, err := e.query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return Verdict{}, err
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return Verdict{}, nil
+	}
+	doc, ok := rs[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return Verdict{}, nil
+	}
+
+	var v Verdict
+	if deny, ok := doc["deny"].([]interface{}); ok {
+		for _, d := range deny {
+			v.Deny = append(v.Deny, fmt.Sprint(d))
+		}
+	}
+	if v.Min, err = bound(doc, "min_replicas"); err != nil {
+		return Verdict{}, err
+	}
+	if v.Max, err = bound(doc, "max_replicas"); err != nil {
+		return Verdict{}, err
+	}
+	return v, nil
+}
+
+func bound(doc map[string]interface{}, key string) (*int32, error) {
+	raw, ok := doc[key]
+	if !ok {
+		return nil, nil
+	}
+	n, ok := raw.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("policy %s must be a number, got %T", key, raw)
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", key, err)
+	}
+	b := int32(i)
+	return &b, nil
+}
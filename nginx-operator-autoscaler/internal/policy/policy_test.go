@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"context"
+	"testing"
+)
+
+const testPolicy = `
+package nginxautoscaler
+
+deny[msg] {
+	input.namespace.labels.tier == "frozen"
+	msg := "namespace is frozen"
+}
+
+max_replicas := 10 {
+	input.change > 5
+}
+
+min_replicas := 2
+`
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	e, err := Compile(ctx, testPolicy)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	v, err := e.Evaluate(ctx, map[string]interface{}{
+		"namespace": map[string]interface{}{"labels": map[string]interface{}{"tier": "frozen"}},
+		"change":    8,
+	})
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if len(v.Deny) != 1 || v.Deny[0] != "namespace is frozen" {
+		t.Errorf("deny = %v", v.Deny)
+	}
+	if v.Max == nil || *v.Max != 10 || v.Min == nil || *v.Min != 2 {
+		t.Errorf("bounds = %v, %v", v.Max, v.Min)
+	}
+
+	v, err = e.Evaluate(ctx, map[string]interface{}{"change": 1})
+	if err != nil || len(v.Deny) != 0 || v.Max != nil {
+		t.Fatalf("small change: %+v, %v", v, err)
+	}
+
+	if _, err := Compile(ctx, "package nginxautoscaler\ndeny[msg] {"); err == nil {
+		t.Fatal("broken module: want error")
+	}
+}
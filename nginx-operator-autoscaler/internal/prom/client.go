@@ -1,49 +1,180 @@
 package prom
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// queryTimeout bounds one query, the wait for a slot included, so a
+// Prometheus that stops answering can't hold a reconcile forever.
+var queryTimeout = time.Minute
+
+// httpClient serves every query; rebuilt by Configure and wrapped by
+// EnableFaultInjection.
+var httpClient = &http.Client{Timeout: queryTimeout}
+
 type resp struct {
 	Status string `json:"status"`
 	Data   struct {
 		ResultType string `json:"resultType"`
 		Result     []struct {
-			Value []interface{} `json:"value"`
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+			Values [][]interface{}   `json:"values"`
 		} `json:"result"`
 	} `json:"data"`
 }
 
-// InstantVector runs an instant query and returns a single float64 sum.
+// InstantVector runs an instant query and returns a single float64 sum; an
+// empty result gives 0.
 func InstantVector(promURL, query string) (float64, error) {
-	u, _ := url.Parse(promURL)
-	u.Path = "/api/v1/query"
+	v, _, err := Instant(promURL, query)
+	return v, err
+}
+
+// Instant is InstantVector that also reports whether the result had a
+// sample, so "no series" can be told apart from a real 0.
+func Instant(promURL, query string) (float64, bool, error) {
+	u, _ := url.Parse(viaProxy(promURL))
+	u.Path = apiPath(u.Path, "/api/v1/query")
 	q := u.Query()
 	q.Set("query", query)
 	u.RawQuery = q.Encode()
 
-	r, err := http.Get(u.String())
+	out, err := fetch(u.String())
 	if err != nil {
-		return 0, err
-	}
-	defer r.Body.Close()
-
-	var out resp
-	if err := json.NewDecoder(r.Body).Decode(&out); err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	if out.Status != "success" || len(out.Data.Result) == 0 || len(out.Data.Result[0].Value) < 2 {
-		return 0, nil
+		return 0, false, nil
 	}
 	// value[1] is string numeric
 	s, ok := out.Data.Result[0].Value[1].(string)
 	if !ok {
-		return 0, fmt.Errorf("unexpected result format")
+		return 0, false, fmt.Errorf("unexpected result format")
 	}
 	var f float64
-	_, err = fmt.Sscan(s, &f)
-	return f, err
+	if _, err := fmt.Sscan(s, &f); err != nil {
+		return 0, false, err
+	}
+	return f, true, nil
+}
+
+// fetch gets u with httpClient once a query slot is free, all within
+// queryTimeout.
+func fetch(u string) (resp, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	release, err := acquire(ctx)
+	if err != nil {
+		return resp{}, err
+	}
+	defer release()
+	return get(ctx, httpClient, u)
+}
+
+// RangeVector runs query over the last window at step resolution and returns
+// the samples of the first series, oldest first.
+func RangeVector(promURL, query string, window, step time.Duration) ([]float64, error) {
+	u, _ := url.Parse(viaProxy(promURL))
+	u.Path = apiPath(u.Path, "/api/v1/query_range")
+	end := time.Now()
+	q := u.Query()
+	q.Set("query", query)
+	q.Set("start", strconv.FormatInt(end.Add(-window).Unix(), 10))
+	q.Set("end", strconv.FormatInt(end.Unix(), 10))
+	q.Set("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	u.RawQuery = q.Encode()
+
+	out, err := fetch(u.String())
+	if err != nil {
+		return nil, err
+	}
+	if out.Status != "success" || len(out.Data.Result) == 0 {
+		return nil, nil
+	}
+	values := make([]float64, 0, len(out.Data.Result[0].Values))
+	for _, v := range out.Data.Result[0].Values {
+		if len(v) < 2 {
+			return nil, fmt.Errorf("unexpected result format")
+		}
+		s, ok := v[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected result format")
+		}
+		var f float64
+		if _, err := fmt.Sscan(s, &f); err != nil {
+			return nil, err
+		}
+		values = append(values, f)
+	}
+	return values, nil
+}
+
+// VectorByLabel runs an instant query returning one series per value of
+// label (e.g. `sum by (pod) (...)`) and returns the samples keyed by it.
+func VectorByLabel(promURL, query, label string) (map[string]float64, error) {
+	u, _ := url.Parse(viaProxy(promURL))
+	u.Path = apiPath(u.Path, "/api/v1/query")
+	q := u.Query()
+	q.Set("query", query)
+	u.RawQuery = q.Encode()
+
+	out, err := fetch(u.String())
+	if err != nil {
+		return nil, err
+	}
+	if out.Status != "success" {
+		return nil, fmt.Errorf("prometheus returned status %q", out.Status)
+	}
+	values := make(map[string]float64, len(out.Data.Result))
+	for _, series := range out.Data.Result {
+		if len(series.Value) < 2 {
+			return nil, fmt.Errorf("unexpected result format")
+		}
+		s, ok := series.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected result format")
+		}
+		var f float64
+		if _, err := fmt.Sscan(s, &f); err != nil {
+			return nil, err
+		}
+		values[series.Metric[label]] = f
+	}
+	return values, nil
+}
+
+// Ping runs the cheap `up` query and fails unless Prometheus answers with
+// success. promPing in nginx-controller-autoscaler is the same check.
+func Ping(promURL string) error {
+	u, err := url.Parse(viaProxy(promURL))
+	if err != nil {
+		return err
+	}
+	u.Path = apiPath(u.Path, "/api/v1/query")
+	q := u.Query()
+	q.Set("query", "up")
+	u.RawQuery = q.Encode()
+
+	c := &http.Client{Transport: transport, Timeout: 2 * time.Second}
+	out, err := get(context.Background(), c, u.String())
+	if err != nil {
+		return err
+	}
+	if out.Status != "success" {
+		return fmt.Errorf("prometheus returned status %q", out.Status)
+	}
+	return nil
+}
+
+// apiPath appends the API path p to the path of the configured URL, so a
+// prefix such as Google Managed Prometheus' /v1/projects/... is kept.
+func apiPath(prefix, p string) string {
+	return strings.TrimSuffix(prefix, "/") + p
 }
@@ -0,0 +1,143 @@
+package prom
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/promproxy"
+	"github.com/malisettirammurthy/promtest"
+)
+
+func TestInstantVector(t *testing.T) {
+	p := promtest.New(t)
+	p.SetInstant("cpu", 1.25)
+	p.SetEmpty("mem")
+
+	got, err := InstantVector(p.URL, "sum(cpu)")
+	if err != nil || got != 1.25 {
+		t.Fatalf("InstantVector(cpu) = %v, %v; want 1.25, nil", got, err)
+	}
+
+	got, err = InstantVector(p.URL, "sum(mem)")
+	if err != nil || got != 0 {
+		t.Fatalf("InstantVector(empty) = %v, %v; want 0, nil", got, err)
+	}
+}
+
+func TestRangeVector(t *testing.T) {
+	p := promtest.New(t)
+	p.SetRange("cpu", 0.5, 1.5, 1.0)
+
+	got, err := RangeVector(p.URL, "sum(cpu)", 10*time.Minute, 30*time.Second)
+	if err != nil || len(got) != 3 || got[0] != 0.5 || got[2] != 1.0 {
+		t.Fatalf("RangeVector(cpu) = %v, %v; want [0.5 1.5 1], nil", got, err)
+	}
+
+	got, err = RangeVector(p.URL, "sum(mem)", 10*time.Minute, 30*time.Second)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("RangeVector(unmatched) = %v, %v; want empty, nil", got, err)
+	}
+}
+
+func TestVectorByLabel(t *testing.T) {
+	p := promtest.New(t)
+	p.SetSeries("by (pod)", map[string]float64{"web-a": 0.1, "web-b": 0.4}, "pod")
+
+	got, err := VectorByLabel(p.URL, "sum by (pod) (cpu)", "pod")
+	if err != nil || len(got) != 2 || got["web-a"] != 0.1 || got["web-b"] != 0.4 {
+		t.Fatalf("VectorByLabel = %v, %v; want web-a 0.1, web-b 0.4", got, err)
+	}
+
+	p.SetError(promtest.ErrStatus)
+	if _, err := VectorByLabel(p.URL, "sum by (pod) (cpu)", "pod"); err == nil {
+		t.Fatal("error status: want error")
+	}
+}
+
+func TestInstantVectorErrors(t *testing.T) {
+	p := promtest.New(t)
+	p.SetInstant("cpu", 1)
+
+	p.SetError(promtest.ErrGarbage)
+	if _, err := InstantVector(p.URL, "cpu"); err == nil {
+		t.Fatal("garbage body: want decode error")
+	}
+
+	p.SetError(promtest.ErrNone)
+	p.SetLatency(50 * time.Millisecond)
+	if _, err := InstantVector(p.URL, "cpu"); err != nil {
+		t.Fatalf("slow response: %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	p := promtest.New(t)
+	if err := Ping(p.URL); err != nil {
+		t.Fatalf("Ping healthy: %v", err)
+	}
+	p.SetError(promtest.ErrStatus)
+	if err := Ping(p.URL); err == nil {
+		t.Fatal("Ping with error status: want error")
+	}
+	p.SetError(promtest.ErrHTTP500)
+	if err := Ping(p.URL); err == nil {
+		t.Fatal("Ping with HTTP 500: want error")
+	}
+}
+
+func TestInstantVectorGzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding = %q; want gzip", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"2.5"]}]}}`)
+		gz.Close()
+	}))
+	defer srv.Close()
+
+	got, err := InstantVector(srv.URL, "sum(cpu)")
+	if err != nil || got != 2.5 {
+		t.Fatalf("InstantVector(gzip) = %v, %v; want 2.5, nil", got, err)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	p := promtest.New(t)
+	p.SetInstant("cpu", 1.25)
+
+	Configure(TransportOptions{MaxResponseBytes: 32})
+	t.Cleanup(func() { Configure(TransportOptions{}) })
+	if _, err := InstantVector(p.URL, "sum(cpu)"); err == nil || !strings.Contains(err.Error(), "exceeds 32 bytes") {
+		t.Fatalf("InstantVector(oversized) error = %v; want size limit", err)
+	}
+
+	Configure(TransportOptions{MaxResponseBytes: 1 << 20})
+	if got, err := InstantVector(p.URL, "sum(cpu)"); err != nil || got != 1.25 {
+		t.Fatalf("InstantVector(within limit) = %v, %v; want 1.25, nil", got, err)
+	}
+}
+
+func TestQueryProxy(t *testing.T) {
+	upstream := promtest.New(t)
+	upstream.SetInstant("cpu", 1.25)
+	proxy := httptest.NewServer(promproxy.New(http.DefaultClient, time.Minute, time.Hour, []string{upstream.URL}))
+	defer proxy.Close()
+
+	SetQueryProxy(proxy.URL)
+	t.Cleanup(func() { SetQueryProxy("") })
+	for i := 0; i < 3; i++ {
+		if got, err := InstantVector(upstream.URL, "sum(cpu)"); err != nil || got != 1.25 {
+			t.Fatalf("InstantVector via proxy = %v, %v; want 1.25, nil", got, err)
+		}
+	}
+	if got := len(upstream.Queries()); got != 1 {
+		t.Fatalf("Prometheus saw %d queries through the proxy; want 1", got)
+	}
+}
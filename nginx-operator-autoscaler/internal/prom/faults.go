@@ -0,0 +1,72 @@
+package prom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// garbageValues are the junk samples returned in fault-injection mode.
+var garbageValues = []string{"NaN", "+Inf", "-Inf", "-42", "1e308", "not-a-number"}
+
+// faultTransport wraps a RoundTripper and, with probability rate, fails the
+// request, delays it, or replaces the answer with a garbage sample.
+type faultTransport struct {
+	base http.RoundTripper
+	rate float64
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// EnableFaultInjection makes InstantVector misbehave on a fraction rate of
+// queries. For development and chaos testing only; rate <= 0 is a no-op.
+func EnableFaultInjection(rate float64) {
+	if rate <= 0 {
+		return
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient = &http.Client{Transport: &faultTransport{
+		base: base,
+		rate: rate,
+		rnd:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, Timeout: queryTimeout}
+}
+
+func (f *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	roll := f.rnd.Float64()
+	delay := time.Duration(f.rnd.Int63n(int64(5 * time.Second)))
+	garbage := garbageValues[f.rnd.Intn(len(garbageValues))]
+	f.mu.Unlock()
+
+	switch {
+	case roll < f.rate/3:
+		return nil, errors.New("fault injection: connection refused")
+	case roll < 2*f.rate/3:
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		return f.base.RoundTrip(req)
+	case roll < f.rate:
+		body := fmt.Sprintf(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[%d,%q]}]}}`,
+			time.Now().Unix(), garbage)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(bytes.NewBufferString(body)),
+			Request:    req,
+		}, nil
+	}
+	return f.base.RoundTrip(req)
+}
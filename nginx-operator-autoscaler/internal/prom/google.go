@@ -0,0 +1,45 @@
+package prom
+
+import (
+	"net/http"
+
+	"golang.org/x/oauth2"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/gcpauth"
+)
+
+// googleMonitoringHost serves Google Managed Prometheus' query API.
+const googleMonitoringHost = "monitoring.googleapis.com"
+
+// GoogleManagedURL is the Prometheus-compatible endpoint of project in
+// Google Cloud Monitoring; the /api/v1/... paths are appended to it.
+func GoogleManagedURL(project string) string {
+	return "https://" + googleMonitoringHost + "/v1/projects/" + project + "/location/global/prometheus"
+}
+
+// EnableGoogleAuth attaches an OAuth2 access token to every query sent to
+// monitoring.googleapis.com, fetched from the GKE/GCE metadata server (so
+// Workload Identity supplies the service account) and refreshed before it
+// expires. Queries to any other Prometheus are sent as before. Call it
+// after Configure and before EnableFaultInjection.
+func EnableGoogleAuth() {
+	transport = &googleAuthTransport{
+		base: transport,
+		auth: &oauth2.Transport{Base: transport, Source: gcpauth.TokenSource(transport)},
+	}
+	httpClient = &http.Client{Transport: transport, Timeout: queryTimeout}
+}
+
+// googleAuthTransport sends requests for googleMonitoringHost through auth
+// and everything else through base, so the token never leaves Google.
+type googleAuthTransport struct {
+	base http.RoundTripper
+	auth http.RoundTripper
+}
+
+func (g *googleAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Hostname() == googleMonitoringHost {
+		return g.auth.RoundTrip(req)
+	}
+	return g.base.RoundTrip(req)
+}
@@ -0,0 +1,51 @@
+package prom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestPathPrefixIsKept(t *testing.T) {
+	prefix := "/v1/projects/demo/location/global/prometheus"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != prefix+"/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"3"]}]}}`))
+	}))
+	defer srv.Close()
+
+	if got, err := InstantVector(srv.URL+prefix+"/", "up"); err != nil || got != 3 {
+		t.Fatalf("InstantVector(prefixed) = %v, %v; want 3, nil", got, err)
+	}
+}
+
+type recordingTransport struct{ auth []string }
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.auth = append(r.auth, req.Header.Get("Authorization"))
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestGoogleAuthOnlyForGoogle(t *testing.T) {
+	rec := &recordingTransport{}
+	g := &googleAuthTransport{
+		base: rec,
+		auth: &oauth2.Transport{Base: rec, Source: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "secret"})},
+	}
+	c := &http.Client{Transport: g}
+	for _, u := range []string{GoogleManagedURL("demo") + "/api/v1/query", "http://prometheus.monitoring.svc:9090/api/v1/query"} {
+		r, err := c.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Body.Close()
+	}
+	if len(rec.auth) != 2 || rec.auth[0] != "Bearer secret" || rec.auth[1] != "" {
+		t.Fatalf("Authorization headers = %q; want [Bearer secret, none]", rec.auth)
+	}
+}
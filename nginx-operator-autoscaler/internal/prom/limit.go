@@ -0,0 +1,64 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// querySlots holds one token per query allowed in flight; nil means no limit.
+var querySlots chan struct{}
+
+var (
+	queryQueueSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "nginx_autoscaler_prom_query_queue_seconds",
+		Help:    "Time Prometheus queries waited for one of the --prom-max-concurrent-queries slots.",
+		Buckets: []float64{.001, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	})
+	queriesInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "nginx_autoscaler_prom_queries_in_flight",
+		Help: "Prometheus queries currently being sent or read.",
+	})
+)
+
+// SetMaxConcurrentQueries caps how many queries are in flight at once across
+// all reconciles; the rest wait their turn, so a slow Prometheus costs a
+// queue instead of a goroutine and socket per CR. n <= 0 removes the cap.
+// Ping is exempt, so readiness doesn't queue behind slow queries.
+func SetMaxConcurrentQueries(n int) {
+	if n <= 0 {
+		querySlots = nil
+		return
+	}
+	querySlots = make(chan struct{}, n)
+}
+
+// Collectors are the query limiter's metrics, for the manager to register.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{queryQueueSeconds, queriesInFlight}
+}
+
+// acquire waits for a query slot and returns the func giving it back, or
+// ctx's error if it ends first.
+func acquire(ctx context.Context) (func(), error) {
+	slots := querySlots
+	if slots != nil {
+		start := time.Now()
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			queryQueueSeconds.Observe(time.Since(start).Seconds())
+			return nil, fmt.Errorf("waiting for a Prometheus query slot: %w", ctx.Err())
+		}
+		queryQueueSeconds.Observe(time.Since(start).Seconds())
+	}
+	queriesInFlight.Inc()
+	return func() {
+		queriesInFlight.Dec()
+		if slots != nil {
+			<-slots
+		}
+	}, nil
+}
@@ -0,0 +1,81 @@
+package prom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMaxConcurrentQueries(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	defer srv.Close()
+
+	SetMaxConcurrentQueries(2)
+	t.Cleanup(func() { SetMaxConcurrentQueries(0) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := InstantVector(srv.URL, "up"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Fatalf("peak concurrent queries = %d; want 2", got)
+	}
+	if got := testutil.ToFloat64(queriesInFlight); got != 0 {
+		t.Fatalf("in-flight gauge = %v after all queries returned; want 0", got)
+	}
+}
+
+func TestQueriesGiveUpAfterQueryTimeout(t *testing.T) {
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang // never answers
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	prev := queryTimeout
+	queryTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { queryTimeout = prev })
+
+	start := time.Now()
+	if _, err := InstantVector(srv.URL, "up"); err == nil {
+		t.Fatal("a query Prometheus never answered succeeded")
+	}
+
+	// Every slot taken by a stuck query: the next one stops waiting
+	SetMaxConcurrentQueries(1)
+	t.Cleanup(func() { SetMaxConcurrentQueries(0) })
+	querySlots <- struct{}{}
+	if _, err := InstantVector(srv.URL, "up"); err == nil || !strings.Contains(err.Error(), "query slot") {
+		t.Fatalf("err = %v; want a timeout waiting for a query slot", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("queries took %v to give up", elapsed)
+	}
+}
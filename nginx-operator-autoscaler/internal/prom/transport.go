@@ -0,0 +1,120 @@
+package prom
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/promproxy"
+)
+
+// TransportOptions tune how queries reach Prometheus. The zero value keeps
+// Go's defaults and reads responses of any size.
+type TransportOptions struct {
+	// DialTimeout bounds establishing the TCP connection; 0 keeps 30s.
+	DialTimeout time.Duration
+	// MaxResponseBytes fails a query whose (decompressed) body is larger;
+	// 0 disables the limit.
+	MaxResponseBytes int64
+}
+
+var (
+	// transport is the one Configure built, without fault injection; Ping
+	// uses it directly so readiness isn't subject to injected faults.
+	transport http.RoundTripper = http.DefaultTransport
+	// maxResponseBytes is the limit set by Configure.
+	maxResponseBytes int64
+	// queryProxy is the metrics proxy set by SetQueryProxy, "" to query
+	// Prometheus directly.
+	queryProxy string
+)
+
+// Transport is the transport Configure built, with EnableGoogleAuth applied
+// but without fault injection, for other clients of Prometheus.
+func Transport() http.RoundTripper {
+	return transport
+}
+
+// SetQueryProxy sends every query through the metrics proxy (see package
+// promproxy) at proxyURL instead of straight to Prometheus; "" turns it off.
+func SetQueryProxy(proxyURL string) {
+	queryProxy = proxyURL
+}
+
+// viaProxy is the URL to query for promURL.
+func viaProxy(promURL string) string {
+	if queryProxy == "" {
+		return promURL
+	}
+	return promproxy.URL(queryProxy, promURL)
+}
+
+// Configure rebuilds the client's transport from o. HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY are honored. Call it before EnableFaultInjection, which wraps
+// whatever transport is in place.
+func Configure(o TransportOptions) {
+	dial := o.DialTimeout
+	if dial <= 0 {
+		dial = 30 * time.Second
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	t.DialContext = (&net.Dialer{Timeout: dial, KeepAlive: 30 * time.Second}).DialContext
+	transport = t
+	httpClient = &http.Client{Transport: t, Timeout: queryTimeout}
+	maxResponseBytes = o.MaxResponseBytes
+}
+
+// get fetches u with c and decodes the Prometheus answer. gzip is asked
+// for explicitly and decoded here, so a proxy that passes the encoding
+// through still works, and the size limit applies to the decompressed body.
+func get(ctx context.Context, c *http.Client, u string) (resp, error) {
+	var out resp
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return out, err
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+	r, err := c.Do(req)
+	if err != nil {
+		return out, err
+	}
+	defer r.Body.Close()
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return out, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+	if limit := maxResponseBytes; limit > 0 {
+		body = &limitedReader{r: io.LimitReader(body, limit+1), left: limit + 1, limit: limit}
+	}
+	err = json.NewDecoder(body).Decode(&out)
+	return out, err
+}
+
+// limitedReader fails once more than limit bytes were read, rather than
+// truncating, so an oversized answer is an error and not a bad sample.
+type limitedReader struct {
+	r     io.Reader
+	left  int64
+	limit int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.left -= int64(n)
+	if l.left <= 0 {
+		return n, fmt.Errorf("prometheus response exceeds %d bytes", l.limit)
+	}
+	return n, err
+}
@@ -0,0 +1,279 @@
+// Package promproxy is a caching front for the Prometheus query API, shared
+// by many autoscaler instances: each distinct query is fetched from
+// Prometheus once per TTL and refreshed in the background while someone
+// keeps asking for it, so N controllers polling the same targets cost
+// Prometheus one query instead of N.
+package promproxy
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prefix starts every proxied path: /u/<base64url of the upstream URL>/api/v1/...
+const prefix = "/u/"
+
+// URL is the address on proxy under which upstream's query API is served.
+// Clients append /api/v1/query etc. to it as they would to upstream.
+func URL(proxy, upstream string) string {
+	return strings.TrimSuffix(proxy, "/") + prefix + base64.RawURLEncoding.EncodeToString([]byte(upstream))
+}
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "nginx_autoscaler_proxy_requests_total",
+		Help: "Queries answered by the metrics proxy, from cache (hit) or Prometheus (miss).",
+	}, []string{"result"})
+	refreshes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "nginx_autoscaler_proxy_refreshes_total",
+		Help: "Cached queries the metrics proxy refreshed in the background.",
+	})
+)
+
+// Collectors are the proxy's metrics, for the caller to register.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{requests, refreshes}
+}
+
+// Proxy serves cached answers for /api/v1/query and /api/v1/query_range.
+type Proxy struct {
+	// TTL is how old a cached answer may be before it is fetched again.
+	TTL time.Duration
+	// Idle drops a query nobody asked for in this long from the cache and
+	// from background refreshes.
+	Idle time.Duration
+	// MaxBodyBytes bounds a cached answer; larger ones are passed through
+	// uncached. 0 disables the limit.
+	MaxBodyBytes int64
+	// Client fetches from Prometheus.
+	Client *http.Client
+
+	// upstreams are the Prometheus URLs the proxy may be asked to query; any
+	// other is refused, so it can't be used to reach arbitrary hosts with
+	// Client's credentials.
+	upstreams map[string]bool
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// entry is one distinct query. mu serializes fetches, so concurrent misses
+// for the same query reach Prometheus once.
+type entry struct {
+	upstream, path string
+	params         url.Values
+	window         time.Duration // query_range: end - start, re-anchored at now on refresh
+
+	mu       sync.Mutex
+	body     []byte
+	fetched  time.Time
+	lastUsed time.Time
+}
+
+// New returns a proxy fetching through c from the given upstreams only.
+func New(c *http.Client, ttl, idle time.Duration, upstreams []string) *Proxy {
+	allowed := make(map[string]bool, len(upstreams))
+	for _, u := range upstreams {
+		allowed[strings.TrimSuffix(u, "/")] = true
+	}
+	return &Proxy{TTL: ttl, Idle: idle, Client: c, upstreams: allowed, now: time.Now, entries: map[string]*entry{}}
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upstream, path, err := splitPath(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if !p.upstreams[strings.TrimSuffix(upstream, "/")] {
+		http.Error(w, fmt.Sprintf("upstream %q is not allowed (see --upstream)", upstream), http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key, e, err := p.lookup(upstream, path, r.Form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, status, err := p.answer(r.Context(), e)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if status != http.StatusOK {
+		// Not cached, so don't keep refreshing a failing query
+		p.mu.Lock()
+		delete(p.entries, key)
+		p.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// splitPath decodes /u/<upstream>/api/v1/<endpoint>.
+func splitPath(p string) (upstream, path string, err error) {
+	rest, ok := strings.CutPrefix(p, prefix)
+	if !ok {
+		return "", "", fmt.Errorf("path must start with %s<upstream>", prefix)
+	}
+	enc, path, _ := strings.Cut(rest, "/")
+	path = "/" + path
+	if path != "/api/v1/query" && path != "/api/v1/query_range" {
+		return "", "", fmt.Errorf("only /api/v1/query and /api/v1/query_range are proxied")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return "", "", fmt.Errorf("bad upstream: %w", err)
+	}
+	u, err := url.Parse(string(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("bad upstream %q", raw)
+	}
+	return string(raw), path, nil
+}
+
+// lookup finds or creates the entry for a request. Range queries are keyed
+// by their window rather than start and end, which move with every poll.
+func (p *Proxy) lookup(upstream, path string, form url.Values) (string, *entry, error) {
+	params := url.Values{}
+	var window time.Duration
+	for k, v := range form {
+		params[k] = v
+	}
+	if path == "/api/v1/query_range" {
+		start, err1 := strconv.ParseFloat(form.Get("start"), 64)
+		end, err2 := strconv.ParseFloat(form.Get("end"), 64)
+		if err1 != nil || err2 != nil || end < start {
+			return "", nil, fmt.Errorf("query_range needs numeric start <= end")
+		}
+		window = time.Duration((end - start) * float64(time.Second))
+		params.Del("start")
+		params.Del("end")
+	}
+	params.Del("time")
+	key := upstream + path + "?" + params.Encode() + "&window=" + window.String()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[key]
+	if !ok {
+		e = &entry{upstream: upstream, path: path, params: params, window: window}
+		p.entries[key] = e
+	}
+	return key, e, nil
+}
+
+// answer returns e's cached body while fresh, else fetches it.
+func (p *Proxy) answer(ctx context.Context, e *entry) ([]byte, int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := p.now()
+	e.lastUsed = now
+	if e.body != nil && now.Sub(e.fetched) < p.TTL {
+		requests.WithLabelValues("hit").Inc()
+		return e.body, http.StatusOK, nil
+	}
+	requests.WithLabelValues("miss").Inc()
+	return p.fetchLocked(ctx, e)
+}
+
+// fetchLocked queries Prometheus for e and caches a 200 answer; e.mu is held.
+func (p *Proxy) fetchLocked(ctx context.Context, e *entry) ([]byte, int, error) {
+	now := p.now()
+	params := url.Values{}
+	for k, v := range e.params {
+		params[k] = v
+	}
+	if e.path == "/api/v1/query_range" {
+		params.Set("start", strconv.FormatInt(now.Add(-e.window).Unix(), 10))
+		params.Set("end", strconv.FormatInt(now.Unix(), 10))
+	}
+	u, _ := url.Parse(e.upstream)
+	u.Path = strings.TrimSuffix(u.Path, "/") + e.path
+	u.RawQuery = params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	r, err := p.Client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Body.Close()
+	var body io.Reader = r.Body
+	if p.MaxBodyBytes > 0 {
+		body = io.LimitReader(r.Body, p.MaxBodyBytes+1)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.MaxBodyBytes > 0 && int64(len(b)) > p.MaxBodyBytes {
+		return nil, 0, fmt.Errorf("prometheus response exceeds %d bytes", p.MaxBodyBytes)
+	}
+	if r.StatusCode == http.StatusOK {
+		e.body, e.fetched = b, now
+	}
+	return b, r.StatusCode, nil
+}
+
+// Run refreshes every query asked for within Idle shortly before its TTL
+// runs out, and forgets the rest, until ctx is done.
+func (p *Proxy) Run(ctx context.Context) error {
+	t := time.NewTicker(max(p.TTL/2, time.Second))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+			p.refresh(ctx)
+		}
+	}
+}
+
+func (p *Proxy) refresh(ctx context.Context) {
+	now := p.now()
+	p.mu.Lock()
+	all := make(map[string]*entry, len(p.entries))
+	for key, e := range p.entries {
+		all[key] = e
+	}
+	p.mu.Unlock()
+	for key, e := range all {
+		e.mu.Lock()
+		switch {
+		case now.Sub(e.lastUsed) > p.Idle:
+			p.mu.Lock()
+			delete(p.entries, key)
+			p.mu.Unlock()
+		case now.Sub(e.fetched) >= p.TTL/2:
+			if _, _, err := p.fetchLocked(ctx, e); err == nil {
+				refreshes.Inc()
+			}
+		}
+		e.mu.Unlock()
+	}
+}
+
+// Len is the number of queries cached.
+func (p *Proxy) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.entries)
+}
@@ -0,0 +1,112 @@
+package promproxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/malisettirammurthy/promtest"
+)
+
+func TestProxyCachesAndRefreshes(t *testing.T) {
+	upstream := promtest.New(t)
+	upstream.SetInstant("cpu", 1.5)
+	upstream.SetRange("mem", 1, 2, 3)
+
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := New(http.DefaultClient, 15*time.Second, time.Minute, []string{upstream.URL + "/"})
+	p.now = func() time.Time { return now }
+	srv := httptest.NewServer(p)
+	defer srv.Close()
+	base := URL(srv.URL, upstream.URL)
+
+	get := func(path string, q url.Values) string {
+		t.Helper()
+		r, err := http.Get(base + path + "?" + q.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+		var out struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&out); err != nil || r.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s = %d, %v", path, r.StatusCode, err)
+		}
+		return out.Status
+	}
+	instant := url.Values{"query": {"sum(cpu)"}}
+	rangeAt := func(end time.Time) url.Values {
+		return url.Values{"query": {"sum(mem)"}, "step": {"30"},
+			"start": {strconv.FormatInt(end.Add(-10*time.Minute).Unix(), 10)}, "end": {strconv.FormatInt(end.Unix(), 10)}}
+	}
+
+	// Three controllers polling the same queries cost Prometheus one each
+	for i := 0; i < 3; i++ {
+		if s := get("/api/v1/query", instant); s != "success" {
+			t.Fatalf("status = %q", s)
+		}
+		get("/api/v1/query_range", rangeAt(now.Add(time.Duration(i)*time.Second)))
+	}
+	if got := len(upstream.Queries()); got != 2 {
+		t.Fatalf("upstream saw %d queries; want 2 (one instant, one range)", got)
+	}
+
+	// Past half the TTL the background refresh fetches both again
+	now = now.Add(8 * time.Second)
+	p.refresh(context.Background())
+	if got := len(upstream.Queries()); got != 4 {
+		t.Fatalf("after refresh upstream saw %d queries; want 4", got)
+	}
+	get("/api/v1/query", instant)
+	if got := len(upstream.Queries()); got != 4 {
+		t.Fatalf("refreshed answer not served from cache: upstream saw %d queries", got)
+	}
+
+	// Nobody asks for a while: the queries are forgotten, not refreshed forever
+	now = now.Add(2 * time.Minute)
+	p.refresh(context.Background())
+	if p.Len() != 0 || len(upstream.Queries()) != 4 {
+		t.Fatalf("idle queries kept: %d cached, upstream saw %d", p.Len(), len(upstream.Queries()))
+	}
+}
+
+func TestProxyRejectsOtherPaths(t *testing.T) {
+	p := New(http.DefaultClient, time.Second, time.Minute, []string{"http://prom:9090"})
+	for _, path := range []string{"/api/v1/query", URL("", "http://prom:9090") + "/api/v1/admin/tsdb/delete_series", URL("", "file:///etc") + "/api/v1/query"} {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d; want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestProxyRefusesOtherUpstreams(t *testing.T) {
+	allowed, other := promtest.New(t), promtest.New(t)
+	allowed.SetInstant("cpu", 1)
+	other.SetInstant("cpu", 1)
+	p := New(http.DefaultClient, time.Second, time.Minute, []string{allowed.URL})
+
+	q := "/api/v1/query?query=sum(cpu)"
+	for upstream, want := range map[string]int{
+		allowed.URL:                http.StatusOK,
+		other.URL:                  http.StatusForbidden,
+		"http://169.254.169.254":   http.StatusForbidden,
+		allowed.URL + "@evil:9090": http.StatusForbidden,
+	} {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, URL("", upstream)+q, nil))
+		if rec.Code != want {
+			t.Errorf("query via %s = %d; want %d", upstream, rec.Code, want)
+		}
+	}
+	if got := len(other.Queries()); got != 0 {
+		t.Fatalf("refused upstream saw %d queries; want 0", got)
+	}
+}
@@ -0,0 +1,93 @@
+// Package pubsub reads a Google Cloud Pub/Sub subscription's backlog
+// (num_undelivered_messages) from the Cloud Monitoring API, for scaling
+// subscriber Deployments without running an exporter.
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/gcpauth"
+)
+
+const backlogMetric = "pubsub.googleapis.com/subscription/num_undelivered_messages"
+
+// lookback is how far back a sample is searched for: Pub/Sub writes the
+// metric once a minute and it can take a few more to become readable.
+const lookback = 5 * time.Minute
+
+var (
+	endpoint = "https://monitoring.googleapis.com"
+
+	clientOnce sync.Once
+	client     *http.Client
+)
+
+// httpClient authenticates as the pod's service account via the metadata
+// server; built on first use so GCE_METADATA_HOST is read then.
+func httpClient() *http.Client {
+	clientOnce.Do(func() {
+		if client == nil {
+			base := http.DefaultTransport
+			client = &http.Client{Transport: &oauth2.Transport{Base: base, Source: gcpauth.TokenSource(base)}}
+		}
+	})
+	return client
+}
+
+// Backlog returns the latest num_undelivered_messages of subscription in
+// project. A subscription with no sample in the last few minutes (just
+// created, or a typo) is an error rather than an empty backlog.
+func Backlog(ctx context.Context, project, subscription string) (float64, error) {
+	now := time.Now().UTC()
+	q := url.Values{}
+	q.Set("filter", fmt.Sprintf(`metric.type=%q AND resource.labels.subscription_id=%q`, backlogMetric, subscription))
+	q.Set("interval.startTime", now.Add(-lookback).Format(time.RFC3339))
+	q.Set("interval.endTime", now.Format(time.RFC3339))
+	u := endpoint + "/v3/projects/" + url.PathEscape(project) + "/timeSeries?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("monitoring API: %s", resp.Status)
+	}
+	var body struct {
+		TimeSeries []struct {
+			Points []struct {
+				Value struct {
+					Int64Value  *string  `json:"int64Value"`
+					DoubleValue *float64 `json:"doubleValue"`
+				} `json:"value"`
+			} `json:"points"`
+		} `json:"timeSeries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("monitoring API: %w", err)
+	}
+	// Points come newest first; a subscription has a single series.
+	if len(body.TimeSeries) == 0 || len(body.TimeSeries[0].Points) == 0 {
+		return 0, fmt.Errorf("no %s sample for subscription %s/%s in the last %s", backlogMetric, project, subscription, lookback)
+	}
+	v := body.TimeSeries[0].Points[0].Value
+	switch {
+	case v.Int64Value != nil:
+		return strconv.ParseFloat(*v.Int64Value, 64)
+	case v.DoubleValue != nil:
+		return *v.DoubleValue, nil
+	}
+	return 0, fmt.Errorf("monitoring API: sample without a value")
+}
@@ -0,0 +1,47 @@
+package pubsub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func fakeMonitoring(t *testing.T, body string) *[]string {
+	t.Helper()
+	var filters []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/projects/demo/timeSeries" {
+			http.NotFound(w, r)
+			return
+		}
+		filters = append(filters, r.URL.Query().Get("filter"))
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	oldEndpoint, oldClient := endpoint, client
+	endpoint, client = srv.URL, srv.Client()
+	clientOnce.Do(func() {})
+	t.Cleanup(func() { endpoint, client = oldEndpoint, oldClient })
+	return &filters
+}
+
+func TestBacklogNewestPoint(t *testing.T) {
+	filters := fakeMonitoring(t, `{"timeSeries":[{"points":[{"value":{"int64Value":"1234"}},{"value":{"int64Value":"10"}}]}]}`)
+	v, err := Backlog(context.Background(), "demo", "orders-worker")
+	if err != nil || v != 1234 {
+		t.Fatalf("Backlog = %v, %v; want 1234", v, err)
+	}
+	if len(*filters) != 1 || !strings.Contains((*filters)[0], `resource.labels.subscription_id="orders-worker"`) ||
+		!strings.Contains((*filters)[0], backlogMetric) {
+		t.Fatalf("filters = %q", *filters)
+	}
+}
+
+func TestBacklogNoSamples(t *testing.T) {
+	fakeMonitoring(t, `{}`)
+	if _, err := Backlog(context.Background(), "demo", "typo"); err == nil {
+		t.Fatal("Backlog with no series: want an error, not an empty backlog")
+	}
+}
@@ -0,0 +1,202 @@
+// Package sqlsource reads one number from a SQL database (e.g. the rows of a
+// jobs table waiting for a worker) for scaling on database-backed backlogs.
+package sqlsource
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	// The drivers spec.sql.driver names: postgres and mysql.
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// pools keeps one *sql.DB per driver and DSN, so polls reuse connections
+// instead of dialing the database every cycle.
+var (
+	mu    sync.Mutex
+	pools = map[string]*cached{}
+	tick  uint64
+)
+
+// maxPools bounds pools; past it (many DSNs, e.g. rotated passwords) the
+// least recently used pool is dropped, and closed once no Query holds it.
+const maxPools = 64
+
+type cached struct {
+	db      *sql.DB
+	used    uint64 // tick of the last acquire, for LRU eviction
+	refs    int    // Queries running on db
+	evicted bool   // dropped from pools; close when refs reaches 0
+}
+
+// acquire returns the pool for driver and dsn, held until release.
+func acquire(driver, dsn string) (*cached, error) {
+	dsn, err := sanitizeDSN(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	tick++
+	key := driver + "\x00" + dsn
+	c, ok := pools[key]
+	if !ok {
+		db, err := sql.Open(driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(2)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxIdleTime(5 * time.Minute)
+		if len(pools) >= maxPools {
+			evictLRU()
+		}
+		c = &cached{db: db}
+		pools[key] = c
+	}
+	c.used = tick
+	c.refs++
+	return c, nil
+}
+
+func release(c *cached) {
+	mu.Lock()
+	defer mu.Unlock()
+	c.refs--
+	if c.evicted && c.refs == 0 {
+		_ = c.db.Close()
+	}
+}
+
+// evictLRU drops the least recently used pool. mu must be held.
+func evictLRU() {
+	var lruKey string
+	var lru *cached
+	for k, c := range pools {
+		if lru == nil || c.used < lru.used {
+			lruKey, lru = k, c
+		}
+	}
+	if lru == nil {
+		return
+	}
+	delete(pools, lruKey)
+	lru.evicted = true
+	if lru.refs == 0 {
+		_ = lru.db.Close()
+	}
+}
+
+// fileParams are the postgres connection parameters naming files on the
+// operator's filesystem; a DSN from a Secret must not point the driver at
+// them.
+var fileParams = []string{"sslcert", "sslkey", "sslrootcert"}
+
+// sanitizeDSN refuses DSNs that would make the driver read local files: for
+// mysql allowAllFiles (LOAD DATA LOCAL INFILE of any path) is forced off, and
+// postgres DSNs may not set sslcert, sslkey or sslrootcert.
+func sanitizeDSN(driver, dsn string) (string, error) {
+	switch driver {
+	case "mysql":
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", err
+		}
+		cfg.AllowAllFiles = false
+		return cfg.FormatDSN(), nil
+	case "postgres":
+		opts := dsn
+		if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+			var err error
+			if opts, err = pq.ParseURL(dsn); err != nil {
+				return "", err
+			}
+		}
+		keys, err := postgresKeys(opts)
+		if err != nil {
+			return "", err
+		}
+		for _, k := range keys {
+			for _, f := range fileParams {
+				if k == f {
+					return "", fmt.Errorf("postgres DSN parameter %s is not allowed", k)
+				}
+			}
+		}
+	}
+	return dsn, nil
+}
+
+// postgresKeys returns the parameter names of a key=value connection string,
+// following libpq's quoting: values may be single-quoted, with backslash
+// escapes.
+func postgresKeys(s string) ([]string, error) {
+	var keys []string
+	for i := 0; ; {
+		for i < len(s) && unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		if i == len(s) {
+			return keys, nil
+		}
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("missing \"=\" after %q in postgres DSN", s[i:])
+		}
+		keys = append(keys, strings.TrimSpace(s[i:i+eq]))
+		i += eq + 1
+		for i < len(s) && unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		if i < len(s) && s[i] == '\'' {
+			for i++; ; i++ {
+				if i >= len(s) {
+					return nil, fmt.Errorf("unterminated quoted value in postgres DSN")
+				}
+				if s[i] == '\\' {
+					i++
+				} else if s[i] == '\'' {
+					i++
+					break
+				}
+			}
+			continue
+		}
+		for i < len(s) && !unicode.IsSpace(rune(s[i])) {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+	}
+}
+
+// Query runs query against dsn in a read-only transaction and returns the
+// first column of its first row. NULL (SUM over no rows) and no rows read
+// as 0.
+func Query(ctx context.Context, driver, dsn, query string) (float64, error) {
+	c, err := acquire(driver, dsn)
+	if err != nil {
+		return 0, err
+	}
+	defer release(c)
+	tx, err := c.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var v sql.NullFloat64
+	switch err := tx.QueryRowContext(ctx, query).Scan(&v); {
+	case err == sql.ErrNoRows:
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	return v.Float64, nil
+}
@@ -0,0 +1,109 @@
+package sqlsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/internal/sqltest"
+)
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	db := sqltest.New(t)
+	q := "SELECT count(*) FROM jobs WHERE state = 'queued'"
+
+	for _, c := range []struct {
+		value interface{}
+		want  float64
+	}{
+		{int64(42), 42},
+		{3.5, 3.5},
+		{[]byte("17.25"), 17.25}, // MySQL DECIMAL
+		{nil, 0},                 // SUM over no rows
+	} {
+		db.Set(c.value)
+		got, err := Query(ctx, sqltest.Driver, db.DSN, q)
+		if err != nil || got != c.want {
+			t.Errorf("Query(%v) = %v, %v; want %v, nil", c.value, got, err, c.want)
+		}
+	}
+	if !db.ReadOnly() {
+		t.Error("query did not run in a read-only transaction")
+	}
+	if n := len(db.Queries()); n != 4 || db.Queries()[0] != q {
+		t.Errorf("queries = %q", db.Queries())
+	}
+
+	db.SetError(errors.New("relation \"jobs\" does not exist"))
+	if _, err := Query(ctx, sqltest.Driver, db.DSN, q); err == nil {
+		t.Error("Query ignored a database error")
+	}
+	if _, err := Query(ctx, "oracle", "dsn", q); err == nil {
+		t.Error("Query accepted an unknown driver")
+	}
+}
+
+func TestSanitizeDSN(t *testing.T) {
+	dsn, err := sanitizeDSN("mysql", "app:pw@tcp(db:3306)/jobs?allowAllFiles=true&parseTime=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg, err := mysql.ParseDSN(dsn); err != nil || cfg.AllowAllFiles || !cfg.ParseTime {
+		t.Errorf("mysql DSN = %q, %v; want allowAllFiles off and parseTime kept", dsn, err)
+	}
+
+	for _, c := range []struct {
+		dsn string
+		ok  bool
+	}{
+		{"host=db dbname=jobs sslmode=require", true},
+		{"host=db password='it\\'s sslkey=x' dbname=jobs", true},
+		{"postgres://app:pw@db/jobs?sslmode=verify-full", true},
+		{"host=db sslrootcert=/etc/shadow", false},
+		{"host=db sslkey = '/var/run/secrets/key'", false},
+		{"postgresql://app@db/jobs?sslcert=/etc/passwd", false},
+	} {
+		if _, err := sanitizeDSN("postgres", c.dsn); (err == nil) != c.ok {
+			t.Errorf("sanitizeDSN(%q) = %v; want ok=%v", c.dsn, err, c.ok)
+		}
+	}
+}
+
+func TestPoolEvictionKeepsOtherPools(t *testing.T) {
+	ctx := context.Background()
+	held, recent := sqltest.New(t), sqltest.New(t)
+	h, err := acquire(sqltest.Driver, held.DSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxPools; i++ {
+		if _, err := Query(ctx, sqltest.Driver, recent.DSN, "SELECT 1"); err != nil {
+			t.Fatal(err)
+		}
+		c, err := acquire(sqltest.Driver, fmt.Sprintf("unused-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		release(c)
+	}
+
+	mu.Lock()
+	_, heldCached := pools[sqltest.Driver+"\x00"+held.DSN]
+	_, recentCached := pools[sqltest.Driver+"\x00"+recent.DSN]
+	n := len(pools)
+	mu.Unlock()
+	if heldCached || !recentCached || n > maxPools {
+		t.Errorf("held cached %v, recent cached %v, %d pools; want false, true, <= %d", heldCached, recentCached, n, maxPools)
+	}
+	if err := h.db.PingContext(ctx); err != nil {
+		t.Errorf("an evicted pool was closed while a Query held it: %v", err)
+	}
+	release(h)
+	if err := h.db.PingContext(ctx); err == nil {
+		t.Error("an evicted pool stayed open after its last Query")
+	}
+}
@@ -0,0 +1,153 @@
+// Package sqltest provides a fake database/sql driver for tests. Every query
+// answers one row with one column holding the value set on the DB its DSN
+// names.
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+// Driver is the name the fake driver is registered under.
+const Driver = "sqltest"
+
+var (
+	mu   sync.Mutex
+	dbs  = map[string]*DB{}
+	next int
+)
+
+func init() {
+	sql.Register(Driver, fakeDriver{})
+}
+
+// DB is one fake database, reached through DSN.
+type DB struct {
+	DSN string
+
+	mu       sync.Mutex
+	value    driver.Value
+	err      error
+	queries  []string
+	readOnly bool
+}
+
+// New registers a DB, answering NULL until Set, that is dropped when the test ends.
+func New(t testing.TB) *DB {
+	t.Helper()
+	mu.Lock()
+	defer mu.Unlock()
+	next++
+	db := &DB{DSN: fmt.Sprintf("fake-%d", next)}
+	dbs[db.DSN] = db
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(dbs, db.DSN)
+	})
+	return db
+}
+
+// Set makes every query answer v (int64, float64, []byte, string or nil).
+func (db *DB) Set(v driver.Value) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.value, db.err = v, nil
+}
+
+// SetError makes every query fail with err.
+func (db *DB) SetError(err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.err = err
+}
+
+// Queries returns every query run so far.
+func (db *DB) Queries() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string(nil), db.queries...)
+}
+
+// ReadOnly reports whether the last query ran in a read-only transaction.
+func (db *DB) ReadOnly() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.readOnly
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	db, ok := dbs[dsn]
+	if !ok {
+		return nil, fmt.Errorf("sqltest: no database %q", dsn)
+	}
+	return &conn{db: db}, nil
+}
+
+type conn struct {
+	db       *DB
+	readOnly bool
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{c: c, query: query}, nil }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error)                 { return c, nil }
+
+func (c *conn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.readOnly = opts.ReadOnly
+	return c, nil
+}
+
+func (c *conn) Commit() error   { return nil }
+func (c *conn) Rollback() error { c.readOnly = false; return nil }
+
+type stmt struct {
+	c     *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("sqltest: Exec not supported")
+}
+
+func (s *stmt) Query([]driver.Value) (driver.Rows, error) {
+	db := s.c.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries = append(db.queries, s.query)
+	db.readOnly = s.c.readOnly
+	if db.err != nil {
+		return nil, db.err
+	}
+	return &rows{value: db.value}, nil
+}
+
+type rows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *rows) Columns() []string { return []string{"value"} }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
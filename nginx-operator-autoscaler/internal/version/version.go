@@ -0,0 +1,86 @@
+// Package version reports which build of the controller is running. The
+// values are stamped at link time:
+//
+//	go build -ldflags "-X github.com/malisettirammurthy/nginx-operator-autoscaler/internal/version.Version=v1.2.3 ..."
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Set with -ldflags -X; left empty they fall back to what the Go toolchain
+// recorded in the binary, if anything.
+var (
+	Version   = ""
+	GitCommit = ""
+	BuildDate = ""
+)
+
+// Info is the build a binary came from.
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns this binary's build info. Fields neither stamped nor known to
+// the toolchain read "unknown".
+func Get() Info {
+	info := Info{Version: Version, GitCommit: GitCommit, BuildDate: BuildDate, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.GitCommit == "":
+				info.GitCommit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	for _, f := range []*string{&info.Version, &info.GitCommit, &info.BuildDate} {
+		if *f == "" {
+			*f = "unknown"
+		}
+	}
+	return info
+}
+
+// KeysAndValues is Info for a structured log record.
+func (i Info) KeysAndValues() []interface{} {
+	return []interface{}{"version", i.Version, "gitCommit", i.GitCommit, "buildDate", i.BuildDate, "goVersion", i.GoVersion}
+}
+
+// Handler serves Get() as JSON, for GET /version.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Get())
+	})
+}
+
+// Collector is nginx_autoscaler_build_info: always 1, with the build in its
+// labels, so a fleet's builds can be counted and compared.
+func Collector() prometheus.Collector {
+	i := Get()
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "nginx_autoscaler_build_info",
+		Help: "Build of the running controller; always 1.",
+		ConstLabels: prometheus.Labels{
+			"version":    i.Version,
+			"git_commit": i.GitCommit,
+			"build_date": i.BuildDate,
+			"go_version": i.GoVersion,
+		},
+	})
+	g.Set(1)
+	return g
+}
@@ -0,0 +1,45 @@
+package version
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStampedBuild(t *testing.T) {
+	defer func(v, c, d string) { Version, GitCommit, BuildDate = v, c, d }(Version, GitCommit, BuildDate)
+	Version, GitCommit, BuildDate = "v1.2.3", "abc123", "2025-01-01T00:00:00Z"
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/version", nil))
+	var got Info
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode /version: %v", err)
+	}
+	if got.Version != "v1.2.3" || got.GitCommit != "abc123" || got.BuildDate != "2025-01-01T00:00:00Z" || got.GoVersion == "" {
+		t.Fatalf("/version = %+v", got)
+	}
+
+	want := `
+# HELP nginx_autoscaler_build_info Build of the running controller; always 1.
+# TYPE nginx_autoscaler_build_info gauge
+nginx_autoscaler_build_info{build_date="2025-01-01T00:00:00Z",git_commit="abc123",go_version="` + got.GoVersion + `",version="v1.2.3"} 1
+`
+	if err := testutil.CollectAndCompare(Collector(), strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnstampedBuild(t *testing.T) {
+	defer func(v, c, d string) { Version, GitCommit, BuildDate = v, c, d }(Version, GitCommit, BuildDate)
+	Version, GitCommit, BuildDate = "", "", ""
+
+	for name, v := range map[string]string{"version": Get().Version, "gitCommit": Get().GitCommit, "buildDate": Get().BuildDate} {
+		if v == "" {
+			t.Errorf("%s is empty, want a value or \"unknown\"", name)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	server "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/malisettirammurthy/nginx-operator-autoscaler/controllers"
+	"github.com/malisettirammurthy/promtest"
+)
+
+const namespace = "default"
+
+// TestScaleUpAndDown creates a kind cluster, installs the CRDs, deploys nginx,
+// then raises and lowers fake CPU demand and waits for the replica count to follow.
+func TestScaleUpAndDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true), zap.WriteTo(os.Stderr)))
+
+	cluster, err := startKind(t.TempDir())
+	if err != nil {
+		t.Fatalf("kind: %v", err)
+	}
+	defer cluster.stop()
+
+	cfg, err := clientcmd.BuildConfigFromFlags("", cluster.kubeconfig)
+	if err != nil {
+		t.Fatalf("kubeconfig: %v", err)
+	}
+	if _, err := envtest.InstallCRDs(cfg, envtest.CRDInstallOptions{
+		Paths:              []string{filepath.Join("..", "..", "config", "crd")},
+		ErrorIfPathMissing: true,
+	}); err != nil {
+		t.Fatalf("install CRDs: %v", err)
+	}
+
+	// Fake metrics backend: demand starts low
+	prom := promtest.New(t)
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.1)
+	prom.SetInstant("container_memory_working_set_bytes", 50*1024*1024)
+
+	scheme := runtime.NewScheme()
+	_ = appsv1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                server.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+	})
+	if err != nil {
+		t.Fatalf("manager: %v", err)
+	}
+	if err := controllers.SetupNginxAutoscalerController(mgr, controllers.Options{InstanceName: "e2e"}); err != nil {
+		t.Fatalf("setup controller: %v", err)
+	}
+	go func() { _ = mgr.Start(ctx) }()
+	c := mgr.GetClient()
+
+	if err := c.Create(ctx, nginxDeployment("e2e-nginx", 2)); err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autoscaler.malisetti.dev/v1alpha1",
+		"kind":       "NginxAutoscaler",
+		"metadata":   map[string]interface{}{"name": "e2e-nginx", "namespace": namespace},
+		"spec": map[string]interface{}{
+			"targetDeployment": "e2e-nginx",
+			"promURL":          prom.URL,
+			"pollInterval":     "2s",
+			"cooldown":         "5s",
+			"minReplicas":      int64(2),
+			"maxReplicas":      int64(8),
+			"targetCPU":        0.2,
+			"targetMem":        int64(300),
+			"hysteresisPct":    int64(10),
+			"stepLimit":        int64(10),
+		},
+	}}
+	if err := c.Create(ctx, cr); err != nil {
+		t.Fatalf("create autoscaler: %v", err)
+	}
+
+	// Drive load: 1.2 cores at 0.2/replica => 6 replicas
+	prom.SetInstant("container_cpu_usage_seconds_total", 1.2)
+	waitReplicas(t, ctx, c, "e2e-nginx", 6)
+
+	// Load goes away: back to minReplicas
+	prom.SetInstant("container_cpu_usage_seconds_total", 0.05)
+	waitReplicas(t, ctx, c, "e2e-nginx", 2)
+}
+
+func nginxDeployment(name string, replicas int32) *appsv1.Deployment {
+	labels := map[string]string{"app": name}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx:alpine"}}},
+			},
+		},
+	}
+}
+
+func waitReplicas(t *testing.T, ctx context.Context, c client.Client, name string, want int32) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Minute)
+	var last string
+	for time.Now().Before(deadline) {
+		var dep appsv1.Deployment
+		err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &dep)
+		if err == nil && *dep.Spec.Replicas == want {
+			return
+		}
+		if err != nil {
+			last = err.Error()
+		} else {
+			last = fmt.Sprintf("replicas=%d", *dep.Spec.Replicas)
+		}
+		time.Sleep(time.Second)
+	}
+	t.Fatalf("deployment %s never reached %d replicas (last: %s)", name, want, last)
+}
@@ -0,0 +1,67 @@
+//go:build e2e
+
+// Package e2e drives the controller against a real kind cluster. The manager
+// runs in the test process (no image build needed), Prometheus is faked with
+// promtest, and "load" is driven by changing the fake's CPU answer.
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// kindCluster is a throwaway kind cluster with its own kubeconfig file.
+type kindCluster struct {
+	name       string
+	kubeconfig string
+	reused     bool
+}
+
+// startKind creates a kind cluster, or reuses the one named by E2E_KIND_CLUSTER
+// if it already exists (handy when iterating locally).
+func startKind(dir string) (*kindCluster, error) {
+	name := os.Getenv("E2E_KIND_CLUSTER")
+	if name == "" {
+		name = "nginx-autoscaler-e2e"
+	}
+	c := &kindCluster{name: name, kubeconfig: filepath.Join(dir, "kubeconfig")}
+
+	out, err := run("kind", "get", "clusters")
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range bytes.Fields(out) {
+		if string(existing) == name {
+			c.reused = true
+			_, err := run("kind", "export", "kubeconfig", "--name", name, "--kubeconfig", c.kubeconfig)
+			return c, err
+		}
+	}
+
+	if _, err := run("kind", "create", "cluster", "--name", name, "--kubeconfig", c.kubeconfig, "--wait", "120s"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// stop deletes the cluster unless it was reused or E2E_KEEP_CLUSTER is set.
+func (c *kindCluster) stop() {
+	if c.reused || os.Getenv("E2E_KEEP_CLUSTER") != "" {
+		return
+	}
+	_, _ = run("kind", "delete", "cluster", "--name", c.name)
+}
+
+func run(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("%s %v: %w: %s", name, args, err, stderr.String())
+	}
+	return out, nil
+}
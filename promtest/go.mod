@@ -0,0 +1,3 @@
+module github.com/malisettirammurthy/promtest
+
+go 1.25
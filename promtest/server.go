@@ -0,0 +1,202 @@
+// Package promtest provides a fake Prometheus HTTP API for the tests of both
+// autoscalers (it is its own module so each can require it). It answers
+// /api/v1/query and /api/v1/query_range with canned values chosen by query
+// substring, and can inject latency and several failure modes.
+package promtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ErrorMode selects how the server misbehaves.
+type ErrorMode int
+
+const (
+	// ErrNone serves canned responses normally.
+	ErrNone ErrorMode = iota
+	// ErrHTTP500 answers every request with an HTTP 500.
+	ErrHTTP500
+	// ErrStatus answers with a well-formed {"status":"error"} body.
+	ErrStatus
+	// ErrGarbage answers with a body that is not valid JSON.
+	ErrGarbage
+)
+
+type series struct {
+	match  string
+	labels map[string]string
+	values []float64 // one value for instant queries, many for range queries
+	empty  bool
+}
+
+// Server is a fake Prometheus. Responses are picked by the first registered
+// rule whose match string is a substring of the query; unmatched queries get
+// an empty vector.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	rules   []series
+	latency time.Duration
+	mode    ErrorMode
+	queries []string
+}
+
+// New starts a Server that is closed when the test ends.
+func New(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetInstant answers queries containing match with a single sample of value.
+func (s *Server) SetInstant(match string, value float64) {
+	s.set(series{match: match, values: []float64{value}})
+}
+
+// SetSeries answers queries containing match with one series per label set,
+// e.g. per-pod results of a `sum by (pod)` query.
+func (s *Server) SetSeries(match string, byLabels map[string]float64, label string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = removeRule(s.rules, match)
+	for lv, v := range byLabels {
+		s.rules = append(s.rules, series{match: match, labels: map[string]string{label: lv}, values: []float64{v}})
+	}
+}
+
+// SetRange answers range queries containing match with values, one per step.
+func (s *Server) SetRange(match string, values ...float64) {
+	s.set(series{match: match, values: values})
+}
+
+// SetEmpty answers queries containing match with an empty result.
+func (s *Server) SetEmpty(match string) {
+	s.set(series{match: match, empty: true})
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetError switches the server into (or out of, with ErrNone) a failure mode.
+func (s *Server) SetError(mode ErrorMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode = mode
+}
+
+// Queries returns every PromQL query received so far.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+func (s *Server) set(r series) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = append(removeRule(s.rules, r.match), r)
+}
+
+func removeRule(rules []series, match string) []series {
+	out := rules[:0]
+	for _, r := range rules {
+		if r.match != match {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+type sample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value,omitempty"`
+	Values [][]interface{}   `json:"values,omitempty"`
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	query := r.Form.Get("query")
+
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	latency, mode := s.latency, s.mode
+	var matched []series
+	for _, rule := range s.rules {
+		if strings.Contains(query, rule.match) && (len(matched) == 0 || matched[0].match == rule.match) {
+			matched = append(matched, rule)
+		}
+	}
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch mode {
+	case ErrHTTP500:
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	case ErrStatus:
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"status":"error","errorType":"execution","error":"injected failure"}`)
+		return
+	case ErrGarbage:
+		fmt.Fprint(w, `{"status":"success","data":{"result":[{"value":[`)
+		return
+	}
+
+	isRange := strings.HasSuffix(r.URL.Path, "/query_range")
+	now := float64(time.Now().Unix())
+	result := []sample{}
+	for _, m := range matched {
+		if m.empty {
+			continue
+		}
+		labels := m.labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		if isRange {
+			vals := make([][]interface{}, len(m.values))
+			for i, v := range m.values {
+				vals[i] = []interface{}{now - float64(len(m.values)-1-i)*15, formatValue(v)}
+			}
+			result = append(result, sample{Metric: labels, Values: vals})
+			continue
+		}
+		result = append(result, sample{Metric: labels, Value: []interface{}{now, formatValue(m.values[len(m.values)-1])}})
+	}
+
+	resultType := "vector"
+	if isRange {
+		resultType = "matrix"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": resultType, "result": result},
+	})
+}
+
+// formatValue renders a sample the way Prometheus does, including NaN/Inf.
+func formatValue(v float64) string {
+	return fmt.Sprintf("%g", v)
+}